@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// autoscaleCooldown is the minimum time between two changes of the pool size, so that the
+// load average, which lags behind, reflects the previous change before the next one.
+const autoscaleCooldown = 10 * time.Second
+
+// autoscaler sizes the watch daemon's pool of document workers between min and max: it grows
+// while documents wait for a worker and shrinks when the pool is idle or the system is under
+// CPU or memory pressure, so that OCR-heavy and text-only inboxes both get a sensible pool.
+type autoscaler struct {
+	min, max int
+	// maxLoad is the 1-minute load average per CPU above which the pool shrinks.
+	maxLoad float64
+	// minFreeMem is the percentage of available memory below which the pool shrinks.
+	minFreeMem float64
+	changed    time.Time
+}
+
+// resize returns the pool size to use given the current size, the number of documents
+// waiting for a worker and the number being processed. It changes the size by at most one
+// worker per autoscaleCooldown.
+func (a *autoscaler) resize(workers, queued, running int) int {
+	if a.max <= a.min || time.Since(a.changed) < autoscaleCooldown {
+		return workers
+	}
+	target := workers
+	load, ok := readSystemLoad()
+	switch {
+	case ok && (load.perCPU > a.maxLoad || load.freeMem < a.minFreeMem):
+		target--
+	case queued > workers-running:
+		target++
+	case queued == 0 && running < workers:
+		target--
+	}
+	target = max(a.min, min(a.max, target))
+	if target != workers {
+		a.changed = time.Now()
+		if ok {
+			fmt.Printf("Scaling worker pool to %d (%d queued, load %.2f per CPU, %.0f%% memory free)\n", target, queued, load.perCPU, load.freeMem)
+		} else {
+			fmt.Printf("Scaling worker pool to %d (%d queued)\n", target, queued)
+		}
+	}
+	return target
+}
+
+// systemLoad is the pressure on the machine the daemon runs on.
+type systemLoad struct {
+	// perCPU is the 1-minute load average divided by the number of CPUs.
+	perCPU float64
+	// freeMem is the available memory as a percentage of the total.
+	freeMem float64
+}
+
+// readSystemLoad reads the load from /proc; ok is false where it is not available, and the
+// pool then follows the queue alone.
+func readSystemLoad() (load systemLoad, ok bool) {
+	b, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return load, false
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return load, false
+	}
+	avg, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return load, false
+	}
+	load.perCPU = avg / float64(runtime.NumCPU())
+
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return load, false
+	}
+	defer f.Close()
+	var total, available float64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total = kb
+		case "MemAvailable:":
+			available = kb
+		}
+	}
+	if total == 0 {
+		return load, false
+	}
+	load.freeMem = 100 * available / total
+	return load, true
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"pdf-reader/pii"
+)
+
+// DefaultBatchOutputDir receives the per-document outputs of a -dir run. It is kept apart
+// from the input folder so that a second run never picks up redacted PDFs as inputs.
+const DefaultBatchOutputDir = "batch_output"
+
+// DefaultBatchInclude and DefaultBatchExclude select the files of a -dir run: every PDF, but
+// not hidden files such as .DS_Store or the ._form16.pdf resource forks macOS leaves behind.
+const (
+	DefaultBatchInclude = "*.pdf"
+	DefaultBatchExclude = ".*"
+)
+
+// job is one document to process and the files its outputs are written to.
+type job struct {
+	input       string
+	output      string
+	raw         string
+	review      string
+	redactedPDF string
+	// skip is the reason the input is not processed at all, e.g. an empty file.
+	skip string
+}
+
+// fileFilter selects the files of a -dir run by name.
+type fileFilter struct {
+	include, exclude []string
+}
+
+// parseFileFilter parses the comma-separated glob patterns of -include and -exclude.
+func parseFileFilter(include, exclude string) (fileFilter, error) {
+	var f fileFilter
+	for _, list := range []struct {
+		flag     string
+		value    string
+		patterns *[]string
+	}{{"-include", include, &f.include}, {"-exclude", exclude, &f.exclude}} {
+		for _, p := range strings.Split(list.value, ",") {
+			p = strings.ToLower(strings.TrimSpace(p))
+			if p == "" {
+				continue
+			}
+			if _, err := filepath.Match(p, ""); err != nil {
+				return f, fmt.Errorf("invalid %s pattern %q: %v", list.flag, p, err)
+			}
+			*list.patterns = append(*list.patterns, p)
+		}
+	}
+	return f, nil
+}
+
+// matches reports whether the file name matches an include pattern and no exclude pattern.
+// Names are compared case-insensitively, so *.pdf also selects FORM16.PDF.
+func (f fileFilter) matches(name string) bool {
+	name = strings.ToLower(name)
+	match := func(patterns []string) bool {
+		for _, p := range patterns {
+			if ok, _ := filepath.Match(p, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return match(f.include) && !match(f.exclude)
+}
+
+// sniffPDF checks that the file at path looks like a complete PDF before it is handed to the
+// extractors, and returns why it is not one: an empty file, another type of file that was
+// given a .pdf name, or a truncated download, which lacks the %%EOF marker at its end. An
+// empty reason means the file looks fine.
+func sniffPDF(path string) (reason string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to open input file: %v", err)
+	}
+	if info.Size() == 0 {
+		return "empty file", nil
+	}
+	head := make([]byte, 1024)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read input file: %v", err)
+	}
+	head = head[:n]
+	if !bytes.HasPrefix(bytes.TrimLeft(head, " \r\n\t"), []byte("%PDF-")) {
+		return fmt.Sprintf("not a PDF file (content looks like %s)", http.DetectContentType(head)), nil
+	}
+	// Readers look for the marker in the last 1024 bytes; incremental updates append after
+	// it, so only the last one counts.
+	tail := make([]byte, min(info.Size(), 1024))
+	if _, err := f.ReadAt(tail, info.Size()-int64(len(tail))); err != nil {
+		return "", fmt.Errorf("failed to read input file: %v", err)
+	}
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		return "truncated PDF (no %%EOF marker at the end of the file)", nil
+	}
+	return "", nil
+}
+
+// batchJobs lists every file directly inside dir that filter selects, in name order, and
+// derives the output names from the input name: form16.pdf -> form16_filtered.txt,
+// form16_raw.txt and, when the matching options are enabled, form16_review.txt and
+// form16_redacted.pdf. Selected files that are not PDFs are listed with the reason to skip
+// them, so that junk in the folder is reported instead of failing the run.
+func batchJobs(dir, outDir string, filter fileFilter, opts runOptions) ([]job, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input directory: %v", err)
+	}
+	var jobs []job
+	for _, e := range entries {
+		if e.IsDir() || !filter.matches(e.Name()) {
+			continue
+		}
+		base := filepath.Join(outDir, strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())))
+		j := job{
+			input:  filepath.Join(dir, e.Name()),
+			output: base + "_filtered.txt",
+			raw:    base + "_raw.txt",
+		}
+		if j.skip, err = sniffPDF(j.input); err != nil {
+			j.skip = err.Error()
+		}
+		if opts.reviewFile != "" {
+			j.review = base + "_review.txt"
+		}
+		if opts.redactedPDF != "" {
+			j.redactedPDF = base + "_redacted.pdf"
+		}
+		jobs = append(jobs, j)
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no files matching -include %s found in %s", strings.Join(filter.include, ","), dir)
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].input < jobs[k].input })
+	return jobs, nil
+}
+
+// SaveBatchSummary writes the combined report of a -dir run: one block per document with its
+// status, the PII categories removed and the files written, followed by the totals.
+func SaveBatchSummary(docs []pii.ManifestDocument, categories map[string][]string, outputFile string) error {
+	file, err := pii.CreateOutput(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create batch summary: %v", err)
+	}
+	defer file.Close()
+
+	failed, skipped := 0, 0
+	file.WriteString("=== BATCH SUMMARY ===\n\n")
+	for _, d := range docs {
+		file.WriteString(fmt.Sprintf("Document: %s\n", d.Input))
+		file.WriteString(fmt.Sprintf("Status: %s\n", d.Status))
+		if d.SkipReason != "" {
+			skipped++
+			file.WriteString(fmt.Sprintf("Skipped: %s\n", d.SkipReason))
+		}
+		if d.Error != "" {
+			failed++
+			file.WriteString(fmt.Sprintf("Error: %s\n", d.Error))
+		}
+		if c := categories[d.Input]; len(c) > 0 {
+			file.WriteString(fmt.Sprintf("Removed PII fields: %s\n", strings.Join(c, ", ")))
+		}
+		if len(d.Outputs) > 0 {
+			file.WriteString(fmt.Sprintf("Outputs: %s\n", strings.Join(d.Outputs, ", ")))
+		}
+		if len(d.Warnings) > 0 {
+			file.WriteString(fmt.Sprintf("Warnings: %d\n", len(d.Warnings)))
+		}
+		file.WriteString("\n")
+	}
+	file.WriteString(fmt.Sprintf("TOTAL: %d documents, %d processed, %d skipped, %d failed\n", len(docs), len(docs)-skipped-failed, skipped, failed))
+	return nil
+}
+
+// SaveBatchCSV writes the -dir run as a spreadsheet-friendly CSV report: one row per document
+// with its status, total matches and warnings, a column per PII category holding the number
+// of matches removed, and the error of failed documents or the reason of skipped ones.
+func SaveBatchCSV(docs []pii.ManifestDocument, counts map[string]map[string]int, outputFile string) error {
+	file, err := pii.CreateOutput(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create batch CSV report: %v", err)
+	}
+	defer file.Close()
+
+	fieldSet := make(map[string]bool)
+	for _, c := range counts {
+		for field := range c {
+			fieldSet[field] = true
+		}
+	}
+	fields := make([]string, 0, len(fieldSet))
+	for field := range fieldSet {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	w := csv.NewWriter(file)
+	header := append([]string{"document", "status", "matches", "warnings"}, fields...)
+	w.Write(append(header, "error"))
+	for _, d := range docs {
+		total := 0
+		for _, n := range counts[d.Input] {
+			total += n
+		}
+		row := []string{csvCell(d.Input), d.Status, fmt.Sprint(total), fmt.Sprint(len(d.Warnings))}
+		for _, field := range fields {
+			row = append(row, fmt.Sprint(counts[d.Input][field]))
+		}
+		w.Write(append(row, csvCell(cmp.Or(d.Error, d.SkipReason))))
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write batch CSV report: %v", err)
+	}
+	return nil
+}
+
+// csvCell keeps spreadsheet applications from evaluating a cell taken from a file name or an
+// error message as a formula.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// printBatchTable prints one row per document of a -dir run to the console: status, total
+// matches removed and warnings. The status column is coloured on terminals.
+func printBatchTable(docs []pii.ManifestDocument, matches map[string]int) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DOCUMENT\tSTATUS\tMATCHES\tWARNINGS")
+	for _, d := range docs {
+		// Every status cell is painted, so the escape codes add the same width to each row
+		// and the columns stay aligned.
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", filepath.Base(d.Input), paint(statusColor(d.Status), d.Status), matches[d.Input], len(d.Warnings))
+	}
+	tw.Flush()
+}
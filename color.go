@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"pdf-reader/pii"
+)
+
+// ANSI colour codes used in console summaries.
+const (
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorBold   = "\033[1m"
+	colorReset  = "\033[0m"
+)
+
+// stdoutColor and stderrColor report whether escape codes may be written to each stream. They
+// are off when NO_COLOR is set (https://no-color.org), TERM is "dumb" or the stream is not a
+// terminal, so redirected output stays plain.
+var (
+	stdoutColor = colorEnabled(os.Stdout)
+	stderrColor = colorEnabled(os.Stderr)
+)
+
+func colorEnabled(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in the given colour when stdout supports it.
+func paint(color, s string) string {
+	if !stdoutColor {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// severityColor maps a severity to the colour its counts are printed in.
+func severityColor(s pii.Severity) string {
+	switch s {
+	case pii.SeverityHigh:
+		return colorRed
+	case pii.SeverityMedium:
+		return colorYellow
+	}
+	return colorGreen
+}
+
+// statusColor maps a document status to the colour it is printed in.
+func statusColor(status string) string {
+	switch status {
+	case "ok":
+		return colorGreen
+	case "skipped":
+		return colorYellow
+	}
+	return colorRed
+}
+
+// severitySummary formats match counts per severity, e.g. "high 3, medium 4, low 12", each
+// in its severity colour.
+func severitySummary(counts map[pii.Severity]int) string {
+	parts := make([]string, 0, 3)
+	for _, s := range []pii.Severity{pii.SeverityHigh, pii.SeverityMedium, pii.SeverityLow} {
+		parts = append(parts, paint(severityColor(s), fmt.Sprintf("%s %d", s, counts[s])))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// logError logs a failure, in red when stderr is a terminal.
+func logError(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if stderrColor {
+		msg = colorRed + msg + colorReset
+	}
+	log.Print(msg)
+}
+
+// warningPrinter echoes the warnings of a document to the console, in yellow when the stream
+// supports it; the pii package only records them.
+type warningPrinter struct {
+	out   io.Writer
+	color bool
+	// printed is the number of warnings already echoed.
+	printed int
+}
+
+// stdoutWarnings returns a warningPrinter writing to stdout.
+func stdoutWarnings() *warningPrinter {
+	return &warningPrinter{out: os.Stdout, color: stdoutColor}
+}
+
+// flush echoes the warnings of ws not printed yet. ws is the warning list of one document,
+// which only grows as the document goes through the pipeline.
+func (p *warningPrinter) flush(ws pii.Warnings) {
+	for _, w := range ws[min(p.printed, len(ws)):] {
+		msg := "[WARN] " + w.String()
+		if p.color {
+			msg = colorYellow + msg + colorReset
+		}
+		fmt.Fprintln(p.out, msg)
+	}
+	p.printed = max(p.printed, len(ws))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"pdf-reader/pii"
+)
+
+// runData implements "pdf-reader data": it installs refreshed gazetteer and dictionary data
+// from an internal URL, so that the lists evolve without a new release, and reports the age
+// and soundness of the installed data set.
+func runData(args []string) error {
+	usage := fmt.Errorf("usage: data update -sha256 HEX [-url https://.../index.json] [-dir %s] | data status [-dir %s]", pii.DefaultDataDir, pii.DefaultDataDir)
+	if len(args) == 0 {
+		return usage
+	}
+	fs := flag.NewFlagSet("data "+args[0], flag.ExitOnError)
+	dir := fs.String("dir", pii.DefaultDataDir, "data directory, read by runs with -data-dir")
+	switch args[0] {
+	case "update":
+		source := fs.String("url", "", "URL of the data index (default: the source of the installed data set)")
+		pin := fs.String("sha256", "", "SHA-256 the data index must have, as published with the data release")
+		timeout := fs.Duration("timeout", time.Minute, "time allowed for the whole download")
+		fs.Parse(args[1:])
+		if *pin == "" || fs.NArg() != 0 {
+			return usage
+		}
+		if *source == "" {
+			current, err := pii.LoadDataSet(*dir)
+			if err != nil && !errors.Is(err, pii.ErrNoDataSet) {
+				// An unsound data set still names its source.
+				return fmt.Errorf("%v; give -url to reinstall", err)
+			}
+			if current == nil {
+				return fmt.Errorf("no data set in %s yet; give the -url of the data index", *dir)
+			}
+			*source = current.Source
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		rec, changed, err := pii.UpdateDataSet(ctx, http.DefaultClient, *dir, *source, *pin)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			fmt.Printf("Data set %s in %s is up to date\n", rec.Version, *dir)
+			return nil
+		}
+		fmt.Println(paint(colorGreen, fmt.Sprintf("Installed data set %s (published %s) in %s", rec.Version, rec.Published.Format("2006-01-02"), *dir)))
+		for _, f := range rec.Files {
+			fmt.Printf("  %-22s %9d bytes  sha256 %s\n", f.Name, f.Size, f.SHA256)
+		}
+	case "status":
+		fs.Parse(args[1:])
+		if fs.NArg() != 0 {
+			return usage
+		}
+		d, err := pii.LoadDataSet(*dir)
+		if errors.Is(err, pii.ErrNoDataSet) {
+			fmt.Printf("No data set in %s: the bundled gazetteer, address keywords and english_words.txt are used\n", *dir)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Data set: %s (profile %s)\n", d.Version, d.Profile)
+		fmt.Printf("Source: %s (index sha256 %s)\n", d.Source, d.IndexSHA256)
+		fmt.Printf("Published: %s, installed %s\n", d.Published.Format("2006-01-02"), d.Updated.Format("2006-01-02 15:04:05 MST"))
+		for _, f := range d.Files {
+			fmt.Printf("  %-22s %9d bytes  sha256 %s\n", f.Name, f.Size, f.SHA256)
+		}
+		if msg := dataAge(d); d.Stale(time.Now()) {
+			fmt.Println(paint(colorYellow, msg))
+		} else {
+			fmt.Println(paint(colorGreen, msg))
+		}
+	default:
+		return usage
+	}
+	return nil
+}
+
+// dataAge describes the age of a data set, and asks for an update once it is stale.
+func dataAge(d *pii.DataSet) string {
+	days := int(d.Age(time.Now()).Hours() / 24)
+	if d.Stale(time.Now()) {
+		return fmt.Sprintf("Data set %s is %d days old (more than %d); run data update", d.Version, days, int(pii.DataMaxAge.Hours()/24))
+	}
+	return fmt.Sprintf("Data set %s is %d days old", d.Version, days)
+}
+
+// loadDataSet loads the data set of a run from dir, or returns nil when none is installed so
+// that the bundled lists are used. A stale data set is used with a warning; an unsound one is
+// refused.
+func loadDataSet(dir string) (*pii.DataSet, error) {
+	d, err := pii.LoadDataSet(dir)
+	if errors.Is(err, pii.ErrNoDataSet) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v; reinstall it with data update -url", err)
+	}
+	if d.Stale(time.Now()) {
+		log.Printf("[WARN] %s", dataAge(d))
+	}
+	return d, nil
+}
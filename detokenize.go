@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"pdf-reader/pii"
+)
+
+// DefaultRestoredFile is where the detokenize subcommand writes the restored text.
+const DefaultRestoredFile = "restored_output.txt"
+
+// runDetokenize implements "pdf-reader detokenize": it replaces the tokens of a pseudonymised
+// output with the original values from the vault. Only holders of the vault passphrase can
+// restore a document.
+func runDetokenize(args []string) error {
+	fs := flag.NewFlagSet("detokenize", flag.ExitOnError)
+	vaultFile := fs.String("vault", "", "encrypted vault written by -vault (passphrase from $"+pii.VaultPassphraseEnv+")")
+	in := fs.String("in", DefaultOutputFile, "pseudonymised file")
+	out := fs.String("out", DefaultRestoredFile, "restored file")
+	fs.Parse(args)
+
+	if *vaultFile == "" {
+		return fmt.Errorf("-vault is required")
+	}
+	if _, err := os.Stat(*vaultFile); err != nil {
+		return fmt.Errorf("failed to open vault: %v", err)
+	}
+	if samePath(*in, *out) {
+		return fmt.Errorf("-out must not overwrite the pseudonymised input")
+	}
+	vault, err := pii.LoadVault(*vaultFile, os.Getenv(pii.VaultPassphraseEnv))
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", *in, err)
+	}
+	text, restored, unknown := vault.Detokenize(string(data))
+	if err := pii.CheckHold(*out); err != nil {
+		return err
+	}
+	if err := writePrivateFile(*out, []byte(text)); err != nil {
+		return fmt.Errorf("failed to write restored text: %v", err)
+	}
+	fmt.Printf("Restored %d tokens from %s to %s\n", restored, *in, *out)
+	if len(unknown) > 0 {
+		fmt.Println(paint(colorYellow, fmt.Sprintf("%d tokens not in the vault: %s", len(unknown), strings.Join(unknown, ", "))))
+	}
+	return nil
+}
+
+// writePrivateFile writes data to path readable by its owner only. The restored text holds the
+// original PII again, so an existing file is truncated and made 0600 before anything is
+// written to it; os.WriteFile would keep the mode of a file that is already there.
+func writePrivateFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	err = f.Chmod(0o600)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
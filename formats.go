@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"pdf-reader/pii"
+)
+
+// formatList is a flag.Value accepting repeated and/or comma-separated -format values.
+type formatList []string
+
+func (f *formatList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *formatList) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(pii.FormatNames(), name) {
+			return fmt.Errorf("unknown format %q (available: %s)", name, strings.Join(pii.FormatNames(), ", "))
+		}
+		for _, existing := range *f {
+			if existing == name {
+				return nil
+			}
+		}
+		*f = append(*f, name)
+	}
+	return nil
+}
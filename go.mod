@@ -2,4 +2,4 @@ module pdf-reader
 
 go 1.24.3
 
-
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"pdf-reader/pii"
+)
+
+// grpcService is the full name of the Redactor service of redactor.proto.
+const grpcService = "pdfreader.v1.Redactor"
+
+// gRPC status codes returned by the service.
+const (
+	grpcOK                = 0
+	grpcInvalidArgument   = 3
+	grpcResourceExhausted = 8
+	grpcUnimplemented     = 12
+	grpcInternal          = 13
+)
+
+// grpcError is an RPC failure with its gRPC status code.
+type grpcError struct {
+	code int
+	msg  string
+}
+
+func (e *grpcError) Error() string {
+	return e.msg
+}
+
+// grpcHandler serves the Redactor service with the server's filter. gRPC is plain HTTP/2 with
+// length-prefixed protobuf messages and the status in trailers, so the standard library's
+// server carries it without a gRPC runtime; the messages are encoded by hand (see
+// protowire.go).
+func (s *server) grpcHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /"+grpcService+"/RedactDocument", s.grpc(s.rpcRedactDocument))
+	mux.HandleFunc("POST /"+grpcService+"/RedactStream", s.grpc(s.rpcRedactStream))
+	mux.HandleFunc("/", s.grpc(func(w http.ResponseWriter, r *http.Request) error {
+		return &grpcError{grpcUnimplemented, "unknown method " + r.URL.Path}
+	}))
+	return mux
+}
+
+// grpc wraps an RPC: it checks the request is a gRPC call, sends the response headers and
+// writes the status of the RPC's error as trailers.
+func (s *server) grpc(rpc func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.WriteHeader(http.StatusOK)
+
+		code, msg := grpcOK, ""
+		if err := rpc(w, r); err != nil {
+			var gerr *grpcError
+			if !errors.As(err, &gerr) {
+				gerr = &grpcError{grpcInternal, err.Error()}
+			}
+			code, msg = gerr.code, gerr.msg
+			log.Printf("gRPC %s: %s", r.URL.Path, msg)
+		}
+		w.Header().Set("Grpc-Status", strconv.Itoa(code))
+		if msg != "" {
+			w.Header().Set("Grpc-Message", grpcPercentEncode(msg))
+		}
+	}
+}
+
+// readGRPCMessage reads one length-prefixed message of at most limit bytes; io.EOF means the
+// client closed its side of the stream.
+func readGRPCMessage(r io.Reader, limit int64) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, &grpcError{grpcInvalidArgument, fmt.Sprintf("failed to read message: %v", err)}
+	}
+	if header[0] != 0 {
+		return nil, &grpcError{grpcUnimplemented, "compressed messages are not supported"}
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if int64(size) > limit {
+		return nil, &grpcError{grpcResourceExhausted, fmt.Sprintf("message of %d bytes exceeds the limit of %d", size, limit)}
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, &grpcError{grpcInvalidArgument, fmt.Sprintf("failed to read message: %v", err)}
+	}
+	return msg, nil
+}
+
+// writeGRPCMessage writes one length-prefixed message and flushes it to the client.
+func writeGRPCMessage(w http.ResponseWriter, msg protoBuffer) error {
+	if uint64(len(msg)) > math.MaxUint32 {
+		return &grpcError{grpcResourceExhausted, "response too large"}
+	}
+	header := []byte{0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(header[1:], uint32(len(msg)))
+	if _, err := w.Write(append(header, msg...)); err != nil {
+		return err
+	}
+	return http.NewResponseController(w).Flush()
+}
+
+// grpcPercentEncode escapes a status message for the grpc-message trailer.
+func grpcPercentEncode(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		if c := msg[i]; c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// rpcRedactDocument implements Redactor.RedactDocument.
+func (s *server) rpcRedactDocument(w http.ResponseWriter, r *http.Request) error {
+	msg, err := readGRPCMessage(r.Body, s.maxUpload)
+	if err != nil {
+		if err == io.EOF {
+			return &grpcError{grpcInvalidArgument, "missing request message"}
+		}
+		return err
+	}
+	var document []byte
+	var filename, purpose, requester, ticket, password string
+	err = parseProto(msg, func(field, wire int, v uint64, p []byte) error {
+		if wire != wireBytes {
+			return nil
+		}
+		switch field {
+		case 1:
+			document = p
+		case 2:
+			filename = string(p)
+		case 3:
+			purpose = string(p)
+		case 4:
+			requester = string(p)
+		case 5:
+			ticket = string(p)
+		case 6:
+			password = string(p)
+		}
+		return nil
+	})
+	if err != nil {
+		return &grpcError{grpcInvalidArgument, fmt.Sprintf("failed to parse request: %v", err)}
+	}
+	if len(document) == 0 {
+		return &grpcError{grpcInvalidArgument, "document is required"}
+	}
+	basis, err := pii.NewProcessingBasis(purpose, requester, ticket)
+	if err != nil {
+		return &grpcError{grpcInvalidArgument, err.Error()}
+	}
+	if basis == nil && s.requirePurpose {
+		return &grpcError{grpcInvalidArgument, "a processing purpose is required"}
+	}
+
+	if basis != nil {
+		log.Printf("Redacting gRPC document %s (%d bytes) %s", filename, len(document), basis)
+	} else {
+		log.Printf("Redacting gRPC document %s (%d bytes)", filename, len(document))
+	}
+	data, err := s.redactPDF(r.Context(), s.filter, bytes.NewReader(document), password)
+	if err != nil {
+		if errors.As(err, new(unreadableError)) {
+			return &grpcError{grpcInvalidArgument, err.Error()}
+		}
+		return err
+	}
+	data.ProcessingBasis = basis
+	return writeGRPCMessage(w, encodeFilteredData(pii.NewReport(data)))
+}
+
+// rpcRedactStream implements Redactor.RedactStream: the chunks are fed to a ChunkScanner as
+// they arrive, and the lines each one completes are sent back before the next is read. The
+// processing basis comes from the purpose, requester and ticket metadata of the call, and the
+// whole stream is limited like one upload.
+func (s *server) rpcRedactStream(w http.ResponseWriter, r *http.Request) error {
+	basis, err := pii.NewProcessingBasis(r.Header.Get("purpose"), r.Header.Get("requester"), r.Header.Get("ticket"))
+	if err != nil {
+		return &grpcError{grpcInvalidArgument, err.Error()}
+	}
+	if basis == nil && s.requirePurpose {
+		return &grpcError{grpcInvalidArgument, "a processing purpose is required"}
+	}
+	if basis != nil {
+		log.Printf("Redacting gRPC stream %s", basis)
+	}
+
+	var out bytes.Buffer
+	var total int64
+	scanner := s.filter.NewChunkScanner(&out)
+	for {
+		msg, err := readGRPCMessage(r.Body, s.maxUpload)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var text []byte
+		err = parseProto(msg, func(field, wire int, v uint64, p []byte) error {
+			if field == 1 && wire == wireBytes {
+				text = append(text, p...)
+			}
+			return nil
+		})
+		if err != nil {
+			return &grpcError{grpcInvalidArgument, fmt.Sprintf("failed to parse chunk: %v", err)}
+		}
+		if total += int64(len(text)); total > s.maxUpload {
+			return &grpcError{grpcResourceExhausted, fmt.Sprintf("stream exceeds the limit of %d bytes", s.maxUpload)}
+		}
+		if _, err := scanner.Write(text); err != nil {
+			if errors.Is(err, pii.ErrLineTooLong) {
+				return &grpcError{grpcResourceExhausted, err.Error()}
+			}
+			return err
+		}
+		if out.Len() == 0 {
+			continue
+		}
+		chunk := protoBuffer{}
+		chunk.bytes(1, out.Bytes())
+		if err := writeGRPCMessage(w, chunk); err != nil {
+			return err
+		}
+		out.Reset()
+	}
+	stats, err := scanner.Close()
+	if err != nil {
+		return err
+	}
+	chunk := protoBuffer{}
+	chunk.bytes(1, out.Bytes())
+	chunk.countMap(2, stats.MatchCounts)
+	return writeGRPCMessage(w, chunk)
+}
+
+// encodeFilteredData encodes a report as the FilteredData message of redactor.proto.
+func encodeFilteredData(report pii.Report) protoBuffer {
+	b := protoBuffer{}
+	b.varint(1, uint64(report.Version))
+	b.string(2, report.CleanedText)
+	b.strings(3, report.RemovedFields)
+	for _, category := range slices.Sorted(maps.Keys(report.RetainedFields)) {
+		values := protoBuffer{}
+		values.strings(1, report.RetainedFields[category])
+		entry := protoBuffer{}
+		entry.string(1, category)
+		entry.message(2, values)
+		b.message(4, entry)
+	}
+	b.countMap(5, report.MatchCounts)
+	for _, m := range report.Matches {
+		match := protoBuffer{}
+		match.string(1, m.Type)
+		match.string(2, m.Entity)
+		match.varint(3, uint64(m.Line))
+		match.varint(4, uint64(m.Start))
+		match.varint(5, uint64(m.End))
+		match.double(6, m.Confidence)
+		match.string(7, m.Value)
+		match.string(8, m.ValueHash)
+		match.string(9, m.Fingerprint)
+		if e := m.Explanation; e != nil {
+			explanation := protoBuffer{}
+			explanation.string(1, e.Detector)
+			explanation.string(2, e.Rule)
+			explanation.string(3, e.Pattern)
+			explanation.strings(4, e.Evidence)
+			explanation.strings(5, e.Policy)
+			match.message(10, explanation)
+		}
+		b.message(6, match)
+	}
+	for _, warning := range report.Warnings {
+		wb := protoBuffer{}
+		wb.string(1, warning.Code)
+		wb.string(2, warning.Message)
+		wb.varint(3, uint64(warning.Line))
+		b.message(7, wb)
+	}
+	if basis := report.ProcessingBasis; basis != nil {
+		bb := protoBuffer{}
+		bb.string(1, basis.Purpose)
+		bb.string(2, basis.Requester)
+		bb.string(3, basis.Ticket)
+		b.message(8, bb)
+	}
+	return b
+}
@@ -1,371 +1,1076 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	mrand "math/rand/v2"
 	"os"
-	"os/exec"
-	"regexp"
+	"os/signal"
+	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
+	"time"
+
+	"pdf-reader/pii"
 )
 
-// Hard-coded input PDF; change the value below to process a different file.
-const DefaultPDFFile = "test.pdf"
-
-// PIIFilter contains regex patterns for identifying PII data in Form 16
-type PIIFilter struct {
-	PhonePattern   *regexp.Regexp
-	EmailPattern   *regexp.Regexp
-	GSTPattern     *regexp.Regexp
-	PANPattern     *regexp.Regexp
-	AadhaarPattern *regexp.Regexp
-	TANPattern     *regexp.Regexp
-	AddressPattern *regexp.Regexp
-	// Pattern for detecting organisation / company names so they are not redacted as addresses.
-	OrganizationPattern *regexp.Regexp
-	// Additional pattern that looks for generic address-related keywords (e.g., House, Road,
-	// Block, Sector, Opp., Near, etc.) to catch address lines that don't explicitly mention a
-	// city or state name.
-	AddressKeywordPattern *regexp.Regexp
-}
+// Default file names used when the corresponding flag is not supplied.
+const (
+	DefaultPDFFile       = "test.pdf"
+	DefaultOutputFile    = "filtered_output.txt"
+	DefaultRawOutputFile = "extracted_text.txt"
+)
 
-// FilteredData represents the cleaned data structure
-type FilteredData struct {
-	CleanedText    string
-	RemovedFields  []string
-	RetainedFields map[string][]string
-}
+func main() {
+	cleanupOnSignal()
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		if err := runServe(os.Args[2:]); err != nil {
+			log.Fatalf("Server error: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "detokenize" {
+		if err := runDetokenize(os.Args[2:]); err != nil {
+			log.Fatalf("Detokenize error: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "scan-logs" {
+		if err := runScanLogs(os.Args[2:]); err != nil {
+			log.Fatalf("Scan error: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "redact-fields" {
+		if err := runRedactFields(os.Args[2:]); err != nil {
+			log.Fatalf("Redact fields error: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "suppress" {
+		if err := runSuppress(os.Args[2:]); err != nil {
+			log.Fatalf("Suppress error: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "policy" {
+		if err := runPolicy(os.Args[2:]); err != nil {
+			log.Fatalf("Policy error: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "watch" {
+		if err := runWatch(os.Args[2:]); err != nil {
+			log.Fatalf("Watch error: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "preset" {
+		if err := runPreset(os.Args[2:]); err != nil {
+			log.Fatalf("Preset error: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		if err := runVerify(os.Args[2:]); err != nil {
+			log.Fatalf("Verify error: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		if err := runSchema(os.Args[2:]); err != nil {
+			log.Fatalf("Schema error: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "data" {
+		if err := runData(os.Args[2:]); err != nil {
+			log.Fatalf("Data error: %v", err)
+		}
+		return
+	}
 
-// NewPIIFilter creates a new PII filter with Form 16 specific regex patterns
-func NewPIIFilter() *PIIFilter {
-	return &PIIFilter{
-		// Indian phone number patterns (10 digits starting with 6-9)
-		PhonePattern: regexp.MustCompile(`(?:\+91|91)?[-\.\s]?[6-9]\d{9}|\b[6-9]\d{9}\b`),
+	failuresFile := "failures_report.txt"
 
-		// Email pattern
-		EmailPattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Z|a-z]{2,}\b`),
+	var opts runOptions
+	var pdfFile, outputFile, rawOutputFile string
+	flag.StringVar(&pdfFile, "in", DefaultPDFFile, "input Form 16 PDF, or - to redact already-extracted text from stdin to stdout")
+	flag.StringVar(&outputFile, "out", DefaultOutputFile, "filtered output file")
+	flag.StringVar(&rawOutputFile, "raw", DefaultRawOutputFile, "raw extracted text file")
+	goldenFile := flag.String("assert-equal", "", "compare every -format output with this golden file, named per format like -out, and exit non-zero on drift")
+	flag.StringVar(&opts.engine, "engine", "regex", "address detection engine: regex or multi (single multi-pattern keyword scan)")
+	flag.StringVar(&opts.extractor, "extractor", "auto", "text extraction engine: auto (native, then pdftotext and OCR fallbacks), native, pdftotext, ocr, or native-words/pdftotext-words (one line per visual line, linked to word boxes)")
+	flag.StringVar(&opts.password, "password", "", "password of encrypted PDFs, e.g. the PAN followed by the date of birth (DDMMYYYY) for Form 16 downloaded from TRACES; when omitted it is asked for on the terminal, which keeps it out of the shell history")
+	gstPolicy := flag.String("gst", string(pii.GSTRedact), "GSTIN handling: redact, mask or retain")
+	emailPolicy := flag.String("email", "", "e-mail addresses: redact, keep-domain ([EMAIL_REDACTED]@infosys.com) or org-token ([EMAIL_REDACTED]@[ORG_1]); overrides the -config email_policy (default redact)")
+	profile := flag.String("profile", pii.DefaultProfile, "country profile of the detectors (identifiers, address gazetteer, phone regions): "+strings.Join(pii.ProfileNames(), ", "))
+	document := flag.String("document", documentAuto, "document type, with its own recognizers and retained fields: auto (detected from the title of each document), "+strings.Join(pii.DocumentProfileNames(), ", "))
+	phoneRegions := flag.String("phone-regions", "", "comma-separated regions whose national phone numbers are detected: "+strings.Join(pii.PhoneRegionNames(), ", ")+"; international numbers (+44, +1...) are always detected; overrides the -config phone_regions (default IN)")
+	redact := flag.String("redact", "", "comma-separated entity types to redact, leaving all others in the text, e.g. pan,aadhaar,phone (see the README for the types; add custom to keep the -denylist)")
+	keep := flag.String("keep", "", "comma-separated entity types left in the text, e.g. tan,gst to reconcile TDS")
+	packs := flag.String("packs", "", "comma-separated optional detector packs for foreign tax identifiers: us (SSN), gb (National Insurance number), au (Tax File Number)")
+	amountWords := flag.String("amount-words", string(pii.AmountWordsKeep), "amounts in words (\"Rupees Ten Thousand Only\"): keep, digits (rewrite as Rs. 10,000) or redact (no special treatment)")
+	dates := flag.String("dates", string(pii.DateBirth), "date categories to redact, comma-separated: dob (\"Date of Birth\"), period (\"From ... To\", assessment year), other; or all, none")
+	pageHeaders := flag.String("page-headers", string(pii.HeaderFooterKeep), "lines repeated at the top or bottom of every page (certificate number, PAN, TAN): keep, dedupe (first occurrence only, findings counted once) or remove (listed in the report only)")
+	configFile := flag.String("config", "", "YAML or JSON file with custom detectors, placeholders and disabled entity types")
+	presetFile := flag.String("preset", "", "preset bundle (.bundle) holding the policy, dictionaries, gazetteer and templates of a team, instead of -config (see the preset subcommand)")
+	policyKey := flag.String("policy-key", "", "compliance public key (PEM); the -config or -preset must then be signed with it (see the policy subcommand) and flags overriding it are refused")
+	dataDir := flag.String("data-dir", pii.DefaultDataDir, "directory of the gazetteer and dictionary data installed with the data subcommand, used instead of the bundled lists when present")
+	flag.StringVar(&opts.allowlist, "allowlist", "", "file with one value per line that is never redacted, e.g. the employer's own TAN or GSTIN (listed under Allowlisted in the report)")
+	flag.StringVar(&opts.glossary, "glossary", "", "file with one term per line that the dictionary stage never redacts, on top of the built-in glossary of tax terms (cess, HRA, u/s, 80C...)")
+	flag.StringVar(&opts.denylist, "denylist", "", "file with one value per line that is always redacted as [CUSTOM_REDACTED]: a literal (project code, employee name) or a /regular expression/")
+	suppressionsFile := flag.String("suppressions", "", "store of false positives marked with the suppress subcommand, left in the text; JSON matches then carry the fingerprint to mark them by")
+	wordList := flag.String("wordlist", "", "comma-separated word lists merged into the dictionary of the dictionary stage, replacing the embedded english_words.txt; \"default\" keeps it (or the -preset's or -data-dir's), e.g. default,tax_glossary.txt,company_glossary.txt")
+	flag.StringVar(&opts.addressKeywords, "address-keywords", "", "file with one address keyword per line, replacing the built-in list (House, Road, Near...)")
+	matchValues := flag.String("match-values", string(pii.MatchValueNone), "original values in the JSON matches list: none, hash (SHA-256) or plain")
+	flag.BoolVar(&opts.timings, "timings", false, "record the time each pipeline stage and detector took in the text and JSON reports, to find what makes a slow document slow")
+	flag.BoolVar(&opts.explain, "explain", false, "record why each match was redacted (detector, rule: regex, anchor, gazetteer..., evidence and policy settings) in the JSON matches and the text report; gazetteer explanations name the matched place")
+	lang := flag.String("lang", "en", "language of placeholders and report headers: "+strings.Join(pii.LocaleNames(), ", "))
+	langFile := flag.String("lang-file", "", "JSON file with extra or overriding translations ({\"placeholders\": {...}, \"strings\": {...}})")
+	flag.BoolVar(&opts.strict, "strict", false, "strict policy: reject identifiers failing checksums (Aadhaar) and blank letterhead images in raster redacted PDFs")
+	flag.BoolVar(&opts.numbered, "numbered", false, "replace each distinct value with a token numbered per document ([PAN_1], [PAN_2]...) instead of its placeholder, so that e.g. the employee's and the employer's PAN stay apart; -vault tokens take precedence")
+	flag.BoolVar(&opts.panContext, "pan-context", false, "only redact PAN-shaped tokens with a PAN label on the same line or just above it")
+	flag.Float64Var(&opts.minConfidence, "min-confidence", 0, "only redact findings with at least this confidence (0-1), scored from pattern strength, nearby labels and checksums; e.g. 0.6 keeps unlabelled 12-digit numbers")
+	flag.DurationVar(&opts.perfBudget, "perf-budget", 0, "warn when redaction takes longer than this per MB of text (e.g. 500ms)")
+	seed := flag.String("seed", "", "seed for randomised features; reuse it to reproduce a run (default: random)")
+	flag.StringVar(&opts.reviewFile, "review", "", "also write a review copy with only Aadhaar/PAN-class identifiers masked to this file")
+	flag.StringVar(&opts.redactedPDF, "redacted-pdf", "", "also write a PDF with detected PII blacked out to this file")
+	flag.StringVar(&opts.pdfMode, "pdf-mode", "auto", "redacted PDF engine: text (rebuilt text layer), raster (image-only, needs pdftoppm) or auto (text, raster fallback)")
+	flag.Var(&opts.formats, "format", "output format(s), repeatable or comma-separated: "+strings.Join(pii.FormatNames(), ", "))
+	flag.BoolVar(&opts.splitEmployers, "split-employers", false, "for PDFs holding several Form 16 certificates, also write outputs per certificate (name_employerN.txt)")
+	flag.BoolVar(&opts.splitParts, "split-parts", false, "also write outputs per Form 16 part (name_part_a.txt, name_part_b.txt, name_annexures.txt)")
+	legalHold := flag.String("legal-hold", "", "put every output of this run under legal hold for the given reason (case or ticket); held files are never overwritten")
+	flag.BoolVar(&opts.coverPage, "cover-page", false, "prepend a redaction notice (categories, policy, run ID, verification) to the redacted PDF")
+	flag.StringVar(&opts.policy, "policy", "default", "name of the redaction policy, recorded in the manifest and on the cover page")
+	purpose := flag.String("purpose", "", "purpose of processing, recorded in the manifest and JSON reports (record of processing)")
+	requester := flag.String("requester", "", "person or team that requested the run, recorded with -purpose")
+	ticket := flag.String("ticket", "", "ticket or request ID authorising the run, recorded with -purpose")
+	inputDir := flag.String("dir", "", "process every *.pdf in this directory instead of -in")
+	include := flag.String("include", DefaultBatchInclude, "comma-separated file name patterns selecting the files of a -dir run, e.g. *.pdf,*.tmp; selected files that are empty, not PDFs or truncated are skipped with the reason")
+	exclude := flag.String("exclude", DefaultBatchExclude, "comma-separated file name patterns of -dir files to leave out, e.g. .*,draft_*")
+	outputDir := flag.String("out-dir", DefaultBatchOutputDir, "directory for per-document outputs, the batch summary and failures report in -dir mode")
+	flag.IntVar(&opts.workers, "workers", 1, "filter the pages of multi-page documents concurrently on this many workers (0 = one per CPU, 1 = single pass over the whole text)")
+	flag.StringVar(&opts.consistencyFile, "consistency", "", "write a cross-document consistency report (employer TAN, periods, totals per employee) to this file")
+	vaultFile := flag.String("vault", "", "pseudonymise instead of redacting: replace each value with a stable token ([PAN_1]) and keep the originals in this encrypted vault (passphrase from $"+pii.VaultPassphraseEnv+"; restore with the detokenize subcommand)")
+	tmpDir := flag.String("tmp-dir", "", "directory for the private per-job work directories of OCR and rasterisation, e.g. a tmpfs such as /dev/shm (default: system temporary directory)")
+	signKeyFile := flag.String("sign-key", "", "Ed25519 private key (PEM, from policy keygen) signing every output and redacted PDF with a provenance header (tool version, run ID, policy, input hash), checked by recipients with the verify subcommand")
+	auditLogFile := flag.String("audit-log", "", "append a record per document (input hash, entities removed with keyed hashes of their values, tool version) to this JSONL file, each record chained to the previous one by its hash; needs -audit-key")
+	auditKeyFile := flag.String("audit-key", "", "file holding the secret key of the deployment that the values in the -audit-log are hashed with (HMAC-SHA256), at least 16 bytes")
+	manifestFile := flag.String("manifest", "run_manifest.json", "write the run manifest to this file (empty to disable)")
+	flag.Parse()
+	if opts.engine != "regex" && opts.engine != "multi" {
+		log.Fatalf("Unknown -engine %q (expected regex or multi)", opts.engine)
+	}
+	if _, ok := pii.ExtractorByName(opts.extractor); !ok && opts.extractor != "auto" {
+		log.Fatalf("Unknown -extractor %q (expected auto, native, pdftotext, ocr, native-words or pdftotext-words)", opts.extractor)
+	}
+	if opts.workers < 0 {
+		log.Fatalf("Invalid -workers %d (expected 0 or more)", opts.workers)
+	}
+	opts.promptPassword = opts.password == "" && isTerminal(os.Stdin)
+	if opts.minConfidence < 0 || opts.minConfidence > 1 {
+		log.Fatalf("Invalid -min-confidence %g (expected 0 to 1)", opts.minConfidence)
+	}
+	if err := pii.SetTempRoot(*tmpDir); err != nil {
+		log.Fatalf("Invalid -tmp-dir: %v", err)
+	}
+	var err error
+	if opts.gstPolicy, err = pii.ParseGSTPolicy(*gstPolicy); err != nil {
+		log.Fatalf("Invalid -gst: %v", err)
+	}
+	if *emailPolicy != "" {
+		if opts.emailPolicy, err = pii.ParseEmailPolicy(*emailPolicy); err != nil {
+			log.Fatalf("Invalid -email: %v", err)
+		}
+	}
+	if opts.profile, err = pii.LookupProfile(*profile); err != nil {
+		log.Fatalf("Invalid -profile: %v", err)
+	}
+	if opts.document, err = lookupDocument(*document); err != nil {
+		log.Fatalf("Invalid -document: %v", err)
+	}
+	if *phoneRegions != "" {
+		if opts.phoneRegions, err = pii.ParsePhoneRegions(*phoneRegions); err != nil {
+			log.Fatalf("Invalid -phone-regions: %v", err)
+		}
+	}
+	opts.redact, opts.keep = pii.ParseEntityTypes(*redact), pii.ParseEntityTypes(*keep)
+	if opts.packs, err = pii.ParseDetectorPacks(*packs); err != nil {
+		log.Fatalf("Invalid -packs: %v", err)
+	}
+	if opts.amountWords, err = pii.ParseAmountWordsPolicy(*amountWords); err != nil {
+		log.Fatalf("Invalid -amount-words: %v", err)
+	}
+	if opts.dates, err = pii.ParseDatePolicy(*dates); err != nil {
+		log.Fatalf("Invalid -dates: %v", err)
+	}
+	if opts.headerFooters, err = pii.ParseHeaderFooterPolicy(*pageHeaders); err != nil {
+		log.Fatalf("Invalid -page-headers: %v", err)
+	}
+	loaded, err := loadPolicy(*configFile, *presetFile, *policyKey, flag.CommandLine)
+	if err != nil {
+		log.Fatalf("Invalid -config or -preset: %v", err)
+	}
+	opts.configOpts, opts.stages = loaded.opts, loaded.stages
+	opts.wordLists = parseWordLists(*wordList)
+	if opts.matchValues, err = pii.ParseMatchValuePolicy(*matchValues); err != nil {
+		log.Fatalf("Invalid -match-values: %v", err)
+	}
+	if opts.locale, err = pii.LoadLocale(*lang, *langFile); err != nil {
+		log.Fatalf("Invalid -lang: %v", err)
+	}
+	if loaded.preset != nil {
+		opts.locale = loaded.preset.Locale(opts.locale)
+		opts.words = loaded.preset.Words
+	}
+	if opts.data, err = loadPolicyData(*dataDir, loaded); err != nil {
+		log.Fatalf("Invalid -data-dir: %v", err)
+	}
+	if opts.basis, err = pii.NewProcessingBasis(*purpose, *requester, *ticket); err != nil {
+		log.Fatalf("Invalid -purpose/-requester/-ticket: %v", err)
+	}
 
-		// GST Number pattern (15 digits) - employer's GSTIN
-		GSTPattern: regexp.MustCompile(`\b\d{2}[A-Z]{5}\d{4}[A-Z]{1}[A-Z\d]{1}[Z]{1}[A-Z\d]{1}\b`),
+	if *suppressionsFile != "" {
+		if opts.suppressions, err = pii.LoadSuppressions(*suppressionsFile); err != nil {
+			log.Fatalf("Invalid -suppressions: %v", err)
+		}
+	}
 
-		// PAN Number pattern
-		PANPattern: regexp.MustCompile(`\b[A-Z]{5}[0-9]{4}[A-Z]{1}\b`),
+	if *vaultFile != "" {
+		if opts.vault, err = pii.LoadVault(*vaultFile, os.Getenv(pii.VaultPassphraseEnv)); err != nil {
+			log.Fatalf("Invalid -vault: %v", err)
+		}
+	}
 
-		// Aadhaar Number pattern (12 digits)
-		AadhaarPattern: regexp.MustCompile(`\b\d{4}\s?\d{4}\s?\d{4}\b|\b\d{12}\b`),
+	if len(opts.formats) == 0 {
+		opts.formats = formatList{"txt"}
+	}
+	if pdfFile == StreamInput || flag.Arg(0) == StreamInput {
+		switch {
+		case flag.NArg() > 1 || flag.NArg() == 1 && flag.Arg(0) != StreamInput:
+			log.Fatalf("Unexpected arguments %v: flags must come before -, and no output names are taken when reading from stdin", flag.Args())
+		case *inputDir != "" || *goldenFile != "" || opts.redactedPDF != "" || opts.splitEmployers || opts.splitParts || opts.consistencyFile != "" || *legalHold != "" || *auditLogFile != "" || *signKeyFile != "":
+			log.Fatalf("Reading text from stdin cannot be combined with -dir, -assert-equal, -redacted-pdf, -split-employers, -split-parts, -consistency, -legal-hold, -audit-log or -sign-key")
+		case len(opts.formats) != 1 || opts.formats[0] != "txt" && opts.formats[0] != "json":
+			log.Fatalf("Reading text from stdin writes a single txt or json output to stdout")
+		}
+		if err := redactStream(os.Stdin, os.Stdout, opts); err != nil {
+			log.Fatalf("Stream error: %v", err)
+		}
+		if opts.vault != nil {
+			if err := opts.vault.Save(*vaultFile, os.Getenv(pii.VaultPassphraseEnv)); err != nil {
+				log.Fatalf("Error saving vault: %v", err)
+			}
+			fmt.Fprintf(os.Stderr, "Vault: %s (%d tokens)\n", *vaultFile, opts.vault.Len())
+		}
+		return
+	}
+	if *seed == "" {
+		*seed = pii.NewSeed()
+	}
+	opts.rng = pii.NewRunRand(*seed)
+	manifest := pii.NewManifest(*seed)
+	manifest.Policy = opts.policy
+	manifest.PolicySignature = loaded.signature
+	if loaded.preset != nil {
+		manifest.Preset = loaded.preset.Record()
+	}
+	if opts.data != nil {
+		manifest.Data = opts.data.Record()
+	}
+	manifest.ProcessingBasis = opts.basis
+	opts.runID = manifest.RunID
 
-		// TAN (Tax Deduction Account Number)
-		TANPattern: regexp.MustCompile(`(?i)\b[A-Z]{4}[0-9]{5}[A-Z]\b`),
+	switch opts.pdfMode {
+	case "auto", "text", "raster":
+	default:
+		log.Fatalf("Unknown -pdf-mode %q (expected auto, text or raster)", opts.pdfMode)
+	}
 
-		// Address pattern – matches well-known Indian states or major city names.
-		// Stand-alone 6-digit numbers (potential amounts) have been removed to avoid false positives.
-		AddressPattern: regexp.MustCompile(`(?i)\b(?:Ahmedabad|Bangalore|Bengaluru|Mumbai|Bombay|Chennai|Kolkata|Calcutta|Hyderabad|Delhi|New Delhi|Pune|Jaipur|Surat|Lucknow|Kanpur|Nagpur|Indore|Thane|Bhopal|Visakhapatnam|Vizag|Vadodara|Baroda|Firozabad|Ludhiana|Patna|Agra|Nashik|Faridabad|Meerut|Rajkot|Kalyan|Vasai|Varanasi|Srinagar|Aurangabad|Dhanbad|Amritsar|Ranchi|Gwalior|Jabalpur|Coimbatore|Guwahati|Chandigarh|Hubli|Dharwad|Mysore|Mysuru|Noida|Ghaziabad|Kozhikode|Calicut|Trivandrum|Thiruvananthapuram|Kochi|Ernakulam|Madurai|Tiruchirappalli|Trichy|Salem|Guntur|Vijayawada|Nellore|Warangal|Karimnagar|Raipur|Bhubaneswar|Cuttack|Shimla|Dehradun|Gangtok|Shillong|Imphal|Aizawl|Kohima|Itanagar|Agartala|Gandhinagar|Allahabad|Prayagraj|Gorakhpur|Bareilly|Jodhpur|Udaipur|Kolhapur|Solapur|Ahmednagar|Mangaluru|Mangalore|Béngaluru|Bilaspur|Durgapur|Siliguri|Asansol|Dibrugarh|Panipat|Rohtak|Hisar|Jamshhedpur|Bokaro|Rourkela|Belgaum|Belagavi|Saharanpur|Aligarh|Moradabad|Muzaffarpur|Gaya|Darbhanga|Bhagalpur|Kota|Ajmer|Mathura|Haldwani|Nainital|Pithoragarh|Kullu|Manali|Shimoga|Tumkur|Davangere|Mangalore|Goa|Panaji|Vile Parle|Maharashtra|Gujarat|Karnataka|Tamil Nadu|Uttar Pradesh|Madhya Pradesh|Rajasthan|Punjab|Haryana|Bihar|West Bengal|Odisha|Kerala|Telangana|Andhra Pradesh|Chhattisgarh|Uttarakhand|Himachal Pradesh|Assam|Jharkhand|Tripura|Manipur|Mizoram|Nagaland|Arunachal Pradesh|Sikkim|Meghalaya|Puducherry|Ladakh|Jammu and Kashmir|Andaman and Nicobar Islands|Lakshadweep|Daman and Diu|Dadra and Nagar Haveli)\b`),
+	var jobs []job
+	if *inputDir != "" {
+		if *goldenFile != "" {
+			log.Fatalf("-assert-equal compares a single output and cannot be combined with -dir")
+		}
+		filter, err := parseFileFilter(*include, *exclude)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if err := os.MkdirAll(*outputDir, 0o755); err != nil {
+			log.Fatalf("Error creating output directory: %v", err)
+		}
+		if jobs, err = batchJobs(*inputDir, *outputDir, filter, opts); err != nil {
+			log.Fatalf("%v", err)
+		}
+		failuresFile = filepath.Join(*outputDir, failuresFile)
+	} else {
+		// Positional output names are still accepted for compatibility with older scripts; the
+		// -out and -raw flags take precedence when both are given.
+		setFlags := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+		if flag.NArg() > 0 && !setFlags["out"] {
+			outputFile = flag.Arg(0)
+		}
+		if flag.NArg() > 1 && !setFlags["raw"] {
+			rawOutputFile = flag.Arg(1)
+		}
+		if err := validatePaths(pdfFile, outputFile, rawOutputFile); err != nil {
+			log.Fatalf("%v", err)
+		}
+		if opts.redactedPDF != "" && samePath(opts.redactedPDF, pdfFile) {
+			log.Fatalf("-redacted-pdf must not overwrite the input PDF")
+		}
+		jobs = []job{{input: pdfFile, output: outputFile, raw: rawOutputFile, review: opts.reviewFile, redactedPDF: opts.redactedPDF}}
+	}
 
-		// Organisation keywords (case-insensitive) used to identify company names so they are
-		// not mistaken for addresses.
-		OrganizationPattern: regexp.MustCompile(`(?i)\b(?:Pvt\.?\s*Ltd\.?|Private\s+Limited|Ltd\.?|Limited|LLP|L\.L\.P\.?|LLC|L\.L\.C\.?|Inc\.?|Incorporated|Corp\.?|Corporation|Company|Co\.?\s*Ltd\.?|PLC|Pte\.?\s*Ltd\.?)\b`),
+	var signKey ed25519.PrivateKey
+	if *signKeyFile != "" {
+		if *goldenFile != "" {
+			log.Fatalf("-assert-equal compares unsigned outputs and cannot be combined with -sign-key")
+		}
+		if signKey, err = pii.LoadPolicyPrivateKey(*signKeyFile); err != nil {
+			log.Fatalf("Invalid -sign-key: %v", err)
+		}
+	}
 
-		// Generic keywords that frequently appear in Indian street addresses but are unlikely to
-		// appear in normal narrative text.
-		AddressKeywordPattern: regexp.MustCompile(`(?i)\b(?:House|Block|Tower|Flat|Floor|Flr|Road|Rd\.?|Street|St\.?|Lane|Ln\.?|Sector|Plot|Opp\.?|Near|Behind)\b`),
+	var auditLog *pii.AuditLog
+	if *auditLogFile != "" {
+		if *auditKeyFile == "" {
+			log.Fatalf("-audit-log needs -audit-key: unkeyed hashes of identifiers can be brute-forced")
+		}
+		if opts.auditKey, err = pii.LoadAuditKey(*auditKeyFile); err != nil {
+			log.Fatalf("Invalid -audit-key: %v", err)
+		}
+		if auditLog, err = pii.OpenAuditLog(*auditLogFile); err != nil {
+			log.Fatalf("Invalid -audit-log: %v", err)
+		}
+		defer auditLog.Close()
 	}
-}
 
-// FilterPII removes or masks PII data from text
-func (pf *PIIFilter) FilterPII(text string) FilteredData {
-	result := FilteredData{
-		CleanedText:    text,
-		RemovedFields:  []string{},
-		RetainedFields: make(map[string][]string),
+	var failures []pii.Failure
+	var result documentResult
+	var records []pii.CertificateRecord
+	categories := make(map[string][]string)
+	matches := make(map[string]int)
+	counts := make(map[string]map[string]int)
+	skipped := 0
+	for _, j := range jobs {
+		if j.skip != "" {
+			skipped++
+			fmt.Println(paint(colorYellow, fmt.Sprintf("Skipping %s: %s", j.input, j.skip)))
+			doc := pii.ManifestDocument{Input: j.input, Status: "skipped", SkipReason: j.skip}
+			appendAudit(auditLog, doc, nil, manifest.RunID)
+			manifest.Documents = append(manifest.Documents, doc)
+			continue
+		}
+		docOpts := opts
+		docOpts.reviewFile, docOpts.redactedPDF = j.review, j.redactedPDF
+		doc := pii.ManifestDocument{Input: j.input, RawOutput: j.raw, ReviewCopy: j.review, RedactedPDF: j.redactedPDF, Status: "ok"}
+		result, err = processDocument(j.input, j.output, j.raw, docOpts)
+		doc.Outputs, doc.Warnings, doc.Extraction = result.outputs, result.warnings, result.extraction
+		doc.RedactedPDFVerification = result.verification
+		categories[j.input] = result.categories
+		matches[j.input] = result.matches
+		counts[j.input] = result.matchCounts
+		records = append(records, result.records...)
+		if err != nil {
+			failure := pii.Failure{Document: j.input, Error: err.Error()}
+			if pe, ok := err.(*pii.PanicError); ok {
+				failure.StackHash = pe.StackHash
+			}
+			failures = append(failures, failure)
+			doc.Status, doc.Error = "failed", err.Error()
+			logError("Error processing %s: %v", j.input, err)
+		} else if signKey != nil {
+			if err := signOutputs(doc, pii.Provenance{RunID: manifest.RunID, Policy: opts.policy}, signKey); err != nil {
+				log.Fatalf("Error signing outputs: %v", err)
+			}
+		}
+		if err == nil && *legalHold != "" {
+			if err := holdOutputs(doc, *legalHold); err != nil {
+				log.Fatalf("Error placing legal hold: %v", err)
+			}
+			doc.LegalHold = *legalHold
+		}
+		appendAudit(auditLog, doc, result.audit, manifest.RunID)
+		manifest.Documents = append(manifest.Documents, doc)
 	}
+	// Every job closes its work directories; any left open is a leak.
+	for _, dir := range pii.ActiveWorkspaces() {
+		logError("Temporary work directory was not removed: %s", dir)
+	}
+	pii.CleanupWorkspaces()
 
-	// Find and remove phone numbers
-	phoneMatches := pf.PhonePattern.FindAllString(text, -1)
-	if len(phoneMatches) > 0 {
-		result.RemovedFields = append(result.RemovedFields, "Phone Numbers")
-		result.CleanedText = pf.PhonePattern.ReplaceAllString(result.CleanedText, "[PHONE_REDACTED]")
+	if opts.vault != nil {
+		if err := opts.vault.Save(*vaultFile, os.Getenv(pii.VaultPassphraseEnv)); err != nil {
+			log.Fatalf("Error saving vault: %v", err)
+		}
+		fmt.Printf("Vault: %s (%d tokens)\n", *vaultFile, opts.vault.Len())
 	}
 
-	// Find and remove email addresses
-	emailMatches := pf.EmailPattern.FindAllString(text, -1)
-	if len(emailMatches) > 0 {
-		result.RemovedFields = append(result.RemovedFields, "Email Addresses")
-		result.CleanedText = pf.EmailPattern.ReplaceAllString(result.CleanedText, "[EMAIL_REDACTED]")
+	if auditLog != nil {
+		fmt.Printf("Audit log: %s (last hash %s)\n", *auditLogFile, auditLog.Last())
 	}
 
-	// Find and remove Aadhaar numbers
-	aadhaarMatches := pf.AadhaarPattern.FindAllString(text, -1)
-	if len(aadhaarMatches) > 0 {
-		result.RemovedFields = append(result.RemovedFields, "Aadhaar Numbers")
-		result.CleanedText = pf.AadhaarPattern.ReplaceAllString(result.CleanedText, "[AADHAAR_REDACTED]")
+	if opts.consistencyFile != "" {
+		// The employee tokens are keyed from the run's seed, so a rerun with -seed links the
+		// same documents while the tokens of different runs cannot be correlated.
+		key := make([]byte, 32)
+		for i := range key {
+			key[i] = byte(opts.rng.Uint32())
+		}
+		bundles, unassigned := pii.CheckConsistency(records, key)
+		if err := pii.SaveConsistencyReport(bundles, unassigned, opts.consistencyFile); err != nil {
+			log.Fatalf("Error saving consistency report: %v", err)
+		}
+		inconsistent := 0
+		for _, b := range bundles {
+			if len(b.Issues) > 0 {
+				inconsistent++
+			}
+		}
+		msg := fmt.Sprintf("%d of %d employee bundles inconsistent", inconsistent, len(bundles))
+		if inconsistent > 0 {
+			msg = paint(colorRed, msg)
+		}
+		fmt.Printf("Consistency report: %s (%s)\n", opts.consistencyFile, msg)
 	}
 
-	// Find and remove PAN numbers
-	panMatches := pf.PANPattern.FindAllString(text, -1)
-	if len(panMatches) > 0 {
-		result.RemovedFields = append(result.RemovedFields, "PAN Numbers")
-		result.CleanedText = pf.PANPattern.ReplaceAllString(result.CleanedText, "[PAN_REDACTED]")
+	if *manifestFile != "" {
+		if err := pii.SaveManifest(manifest, *manifestFile); err != nil {
+			log.Fatalf("Error saving manifest: %v", err)
+		}
 	}
 
-	// Mask GST numbers as they are now considered sensitive
-	if pf.GSTPattern.MatchString(text) {
-		result.RemovedFields = append(result.RemovedFields, "GST Numbers")
-		result.CleanedText = pf.GSTPattern.ReplaceAllString(result.CleanedText, "[GST_REDACTED]")
+	if *inputDir != "" {
+		summaryFile := filepath.Join(*outputDir, "batch_summary.txt")
+		if err := SaveBatchSummary(manifest.Documents, categories, summaryFile); err != nil {
+			log.Fatalf("Error saving batch summary: %v", err)
+		}
+		csvFile := filepath.Join(*outputDir, "batch_summary.csv")
+		if err := SaveBatchCSV(manifest.Documents, counts, csvFile); err != nil {
+			log.Fatalf("Error saving batch CSV report: %v", err)
+		}
+		fmt.Println()
+		printBatchTable(manifest.Documents, matches)
+		failed := fmt.Sprintf("%d failed", len(failures))
+		if len(failures) > 0 {
+			failed = paint(colorRed, failed)
+		}
+		fmt.Printf("\nProcessed %d documents, %d skipped, %s. Batch summary: %s (CSV: %s)\n", len(jobs)-skipped, skipped, failed, summaryFile, csvFile)
 	}
 
-	// Find and remove TAN numbers
-	tanMatches := pf.TANPattern.FindAllString(text, -1)
-	if len(tanMatches) > 0 {
-		result.RemovedFields = append(result.RemovedFields, "TAN Numbers")
-		result.CleanedText = pf.TANPattern.ReplaceAllString(result.CleanedText, "[TAN_REDACTED]")
+	if len(failures) > 0 {
+		if err := pii.SaveFailuresReport(failures, failuresFile); err != nil {
+			log.Fatalf("Error saving failures report: %v", err)
+		}
+		fmt.Println(paint(colorRed, "Failures report: "+failuresFile))
+		os.Exit(1)
 	}
 
-	// Detect and redact address lines containing Indian city/state names or PIN codes
-	lines := strings.Split(result.CleanedText, "\n")
-	addressFound := false
-	orgFound := false
-	for i, line := range lines {
-		// Trim leading/trailing spaces before matching to make detection resilient to PDF
-		trimmed := strings.TrimSpace(line)
+	if *goldenFile != "" {
+		if len(result.outputs) == 0 {
+			log.Fatalf("Golden check failed: no output was produced")
+		}
+		if err := pii.AssertEqualOutputs(jobs[0].output, *goldenFile, opts.formats); err != nil {
+			log.Fatalf("Golden check failed: %v", err)
+		}
+		fmt.Printf("Output matches golden file(s) %s\n", strings.Join(pii.OutputPaths(*goldenFile, opts.formats), ", "))
+	}
+}
 
-		// Detect organisation names: redact entire line
-		if pf.OrganizationPattern.MatchString(trimmed) {
-			lines[i] = "[ORG_REDACTED]"
-			orgFound = true
+// signOutputs signs the outputs and redacted PDF of the document with key under the
+// provenance p of the run.
+func signOutputs(doc pii.ManifestDocument, p pii.Provenance, key ed25519.PrivateKey) error {
+	p.Tool, p.ToolVersion, p.Created = "pdf-reader", pii.ToolVersion, time.Now().UTC()
+	p.InputSHA256, _ = pii.HashFile(doc.Input)
+	for _, path := range append([]string{doc.RedactedPDF}, doc.Outputs...) {
+		if path == "" {
 			continue
 		}
-
-		if pf.AddressPattern.MatchString(trimmed) || pf.AddressKeywordPattern.MatchString(trimmed) {
-			lines[i] = "[ADDRESS_REDACTED]"
-			addressFound = true
+		if err := pii.SignArtifact(path, p, key); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
 		}
 	}
-	if addressFound {
-		result.RemovedFields = append(result.RemovedFields, "Addresses")
+	return nil
+}
+
+// appendAudit records the document in the -audit-log, if one is open. A record that cannot be
+// written ends the run, since the log would no longer account for every document.
+func appendAudit(auditLog *pii.AuditLog, doc pii.ManifestDocument, entities []pii.AuditEntity, runID string) {
+	if auditLog == nil {
+		return
 	}
-	if orgFound {
-		result.RemovedFields = append(result.RemovedFields, "Organizations")
+	rec := pii.AuditRecord{RunID: runID, Input: doc.Input, Status: doc.Status}
+	if doc.Status == "ok" {
+		// Nothing was removed from a document whose outputs were not all written.
+		rec.Entities = entities
+	}
+	// A failed document may not be readable at all; its record then has no input hash.
+	rec.InputSHA256, _ = pii.HashFile(doc.Input)
+	if err := auditLog.Append(rec); err != nil {
+		log.Fatalf("Error writing audit log: %v", err)
 	}
-	result.CleanedText = strings.Join(lines, "\n")
-
-	return result
 }
 
-// ReadPDF is deprecated; the program now relies exclusively on 'pdftotext'.
-func ReadPDF(_ string) (string, error) {
-	return "", fmt.Errorf("internal PDF extraction disabled; use pdftotext")
+// cleanupOnSignal removes the open work directories when the process is interrupted or
+// terminated, since the deferred cleanups of the running jobs are skipped on exit.
+func cleanupOnSignal() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		pii.CleanupWorkspaces()
+		if isTerminal(os.Stdin) {
+			// An interrupted password prompt leaves echo off.
+			setEcho(true)
+		}
+		fmt.Fprintf(os.Stderr, "Interrupted (%v), temporary files removed\n", sig)
+		os.Exit(1)
+	}()
 }
 
-// FallbackReadPDFWithPdftotext attempts to extract text using the external 'pdftotext' command-line tool when the internal extractor returns no content.
-func FallbackReadPDFWithPdftotext(filename string) (string, error) {
-	// Use the -layout flag to keep original layout and output to stdout ("-").
-	cmd := exec.Command("pdftotext", "-layout", filename, "-")
-	out, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("fallback extraction failed: %v", err)
+// holdOutputs puts every file written for a document under legal hold.
+func holdOutputs(doc pii.ManifestDocument, reason string) error {
+	paths := append([]string{doc.RawOutput, doc.ReviewCopy, doc.RedactedPDF}, doc.Outputs...)
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := pii.PlaceHold(path, reason); err != nil {
+			return err
+		}
 	}
-	return string(out), nil
+	return nil
 }
 
-// SaveFilteredData saves the filtered data to a file
-func SaveFilteredData(data FilteredData, outputFile string) error {
-	file, err := os.Create(outputFile)
+// validatePaths checks the input exists and that no output would overwrite it or each other.
+func validatePaths(pdfFile, outputFile, rawOutputFile string) error {
+	info, err := os.Stat(pdfFile)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("PDF file does not exist: %s", pdfFile)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
-	}
-	defer file.Close()
-
-	// Write header
-	file.WriteString("=== FILTERED PDF DATA ===\n\n")
-
-	// Write summary
-	file.WriteString("FILTERING SUMMARY:\n")
-	file.WriteString(fmt.Sprintf("- Removed PII Fields: %v\n", data.RemovedFields))
-	file.WriteString(fmt.Sprintf("- Retained Business Fields: %v\n", getKeys(data.RetainedFields)))
-	file.WriteString("\n")
-
-	// Write retained business data
-	if len(data.RetainedFields) > 0 {
-		file.WriteString("RETAINED BUSINESS DATA:\n")
-		for fieldType, values := range data.RetainedFields {
-			file.WriteString(fmt.Sprintf("%s:\n", fieldType))
-			for _, value := range values {
-				file.WriteString(fmt.Sprintf("  - %s\n", value))
-			}
-		}
-		file.WriteString("\n")
+		return fmt.Errorf("cannot read PDF file %s: %v", pdfFile, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("input %s is a directory", pdfFile)
+	}
+	if outputFile == "" || rawOutputFile == "" {
+		return fmt.Errorf("output file names must not be empty")
+	}
+	if samePath(outputFile, pdfFile) || samePath(rawOutputFile, pdfFile) {
+		return fmt.Errorf("output files must not overwrite the input PDF")
+	}
+	if samePath(outputFile, rawOutputFile) {
+		return fmt.Errorf("-out and -raw must be different files")
 	}
-
-	// Write cleaned text
-	file.WriteString("CLEANED TEXT CONTENT:\n")
-	file.WriteString(strings.Repeat("=", 50) + "\n")
-	file.WriteString(data.CleanedText)
-
 	return nil
 }
 
-// SaveRawText saves the unfiltered extracted PDF text to a file for comparison
-func SaveRawText(text string, outputFile string) error {
-	file, err := os.Create(outputFile)
-	if err != nil {
-		return fmt.Errorf("failed to create raw text file: %v", err)
-	}
-	defer file.Close()
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
 
-	// Optionally add a simple header for clarity
-	file.WriteString("=== RAW PDF TEXT (NO REDACTIONS) ===\n\n")
-	_, err = file.WriteString(text)
-	return err
+// runOptions carries the command-line settings that affect how each document is processed.
+type runOptions struct {
+	engine    string
+	extractor string
+	// password opens encrypted PDFs; with promptPassword it is asked for on the terminal when
+	// a PDF cannot be opened without one.
+	password       string
+	promptPassword bool
+	gstPolicy      pii.GSTPolicy
+	// profile holds the country assumptions of the detectors.
+	profile pii.Profile
+	// document is the document type of every input; nil detects it per document.
+	document *pii.DocumentProfile
+	// emailPolicy, when set, overrides the config file's e-mail policy.
+	emailPolicy pii.EmailPolicy
+	// phoneRegions, when set, overrides the config file's phone regions.
+	phoneRegions []string
+	// redact, when set, lists the only entity types redacted; keep lists entity types left in
+	// the text on top of the config file's disabled ones.
+	redact, keep []string
+	// packs are the optional detector packs enabled on top of the config file's.
+	packs      []string
+	panContext bool
+	strict     bool
+	// numbered writes [PAN_1], [PAN_2]... per distinct value instead of the placeholders.
+	numbered bool
+	// minConfidence leaves findings scored below it unredacted.
+	minConfidence float64
+	// allowlist, when set, is the file holding the values that are never redacted.
+	allowlist string
+	// glossary, when set, is the file holding the terms the dictionary stage leaves.
+	glossary string
+	// denylist, when set, is the file holding the values that are always redacted.
+	denylist string
+	// suppressions holds the false positives marked by reviewers.
+	suppressions *pii.SuppressionStore
+	// addressKeywords, when set, is the file holding the address keyword list.
+	addressKeywords string
+	perfBudget      time.Duration
+	// amountWords selects how amounts written in words are treated.
+	amountWords pii.AmountWordsPolicy
+	// dates lists the date categories that are redacted.
+	dates pii.DatePolicy
+	// headerFooters selects how repeated page headers and footers are treated.
+	headerFooters pii.HeaderFooterPolicy
+	// locale translates placeholders and report headers.
+	locale pii.Locale
+	// matchValues selects whether the matches list carries the original values.
+	matchValues pii.MatchValuePolicy
+	// explain attaches the detector, rule and policy behind each match to the report.
+	explain bool
+	// timings records the time each stage and detector took in the report.
+	timings bool
+	// configOpts are the filter options from the -config file.
+	configOpts []pii.Option
+	// stages is the pipeline of the -config file or -preset, or pii.DefaultStages.
+	stages pii.Stages
+	// words, when set, is the dictionary of the -preset, used instead of english_words.txt.
+	words map[string]struct{}
+	// wordLists are the -wordlist files merged into the dictionary (see mergeWordLists).
+	wordLists []string
+	// data, when set, is the data set of -data-dir: its gazetteer and address keywords replace
+	// the profile's, and its dictionary english_words.txt.
+	data       *pii.DataSet
+	reviewFile string
+	// redactedPDF, when set, is where the redacted PDF is written using pdfMode.
+	redactedPDF string
+	pdfMode     string
+	// splitEmployers writes one set of outputs per certificate of a multi-employer bundle.
+	splitEmployers bool
+	// splitParts writes one set of outputs per Form 16 part (Part A, Part B, annexures).
+	splitParts bool
+	// coverPage prepends a redaction notice naming policy and runID to the redacted PDF.
+	coverPage bool
+	policy    string
+	runID     string
+	// basis is the record-of-processing entry copied into the manifest and JSON reports.
+	basis   *pii.ProcessingBasis
+	formats formatList
+	// workers is the size of the page worker pool; 1 filters the whole text in one pass.
+	workers int
+	// consistencyFile, when set, receives the cross-document consistency report.
+	consistencyFile string
+	// vault, when set, pseudonymises every document with tokens shared across the run.
+	vault *pii.Vault
+	// auditKey, when set, keys the hashes of the values removed, for the -audit-log.
+	auditKey []byte
+	// rng is the run's seeded random source; randomised features must not use any other.
+	rng *mrand.Rand
 }
 
-// Helper function to get map keys
-func getKeys(m map[string][]string) []string {
-	keys := make([]string, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
-	}
-	return keys
+// documentResult summarises one processed document for the manifest and batch reports.
+type documentResult struct {
+	outputs    []string
+	warnings   pii.Warnings
+	extraction *pii.Metadata
+	categories []string
+	// matches is the total number of replacements across all categories.
+	matches int
+	// matchCounts is the number of replacements per removed category.
+	matchCounts map[string]int
+	// verification is the re-scan result stated on the redacted PDF's cover page.
+	verification string
+	// records are the certificates found, for the -consistency report.
+	records []pii.CertificateRecord
+	// audit lists the values removed, hashed, for the -audit-log.
+	audit []pii.AuditEntity
 }
 
-// LoadWordSet reads a newline-separated list of English words from the supplied
-// file path and returns a set for O(1) existence checks.
-func LoadWordSet(path string) (map[string]struct{}, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+// wordSet returns the dictionary of the run: the -wordlist lists merged, or else the -preset's
+// word list, the -data-dir's, or the embedded english_words.txt. It is nil when the pipeline
+// has no dictionary stage.
+func (o runOptions) wordSet() (map[string]struct{}, error) {
+	stages := o.stages
+	if stages == nil {
+		stages = pii.DefaultStages
 	}
-	defer file.Close()
-
-	set := make(map[string]struct{})
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		w := strings.TrimSpace(scanner.Text())
-		if w == "" {
-			continue
-		}
-		set[strings.ToLower(w)] = struct{}{}
+	if !stages.Has(pii.StageDictionary) {
+		return nil, nil
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	fallback := o.words
+	if fallback == nil && o.data != nil {
+		fallback = o.data.Words
 	}
-	return set, nil
+	return mergeWordLists(o.wordLists, fallback)
 }
 
-// RedactUnknownWords scans the provided text and replaces every alphabetic
-// token that is NOT found in the supplied word-set with the placeholder
-// "[WORD_REDACTED]". It returns the redacted text and a slice containing the
-// unique set of words that were redacted.
-func RedactUnknownWords(text string, dict map[string]struct{}) (string, []string) {
-	wordPattern := regexp.MustCompile(`(?i)\b[[:alpha:]]+\b`)
+// documentAuto is the -document value that detects the document type of each input.
+const documentAuto = "auto"
 
-	redactedSet := make(map[string]struct{})
+// lookupDocument returns the document profile selected with -document, or nil for
+// documentAuto.
+func lookupDocument(name string) (*pii.DocumentProfile, error) {
+	if name == documentAuto {
+		return nil, nil
+	}
+	return pii.LookupDocumentProfile(name)
+}
 
-	redactedText := wordPattern.ReplaceAllStringFunc(text, func(token string) string {
-		if strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]") {
-			return token
+// newFilter builds the PII filter configured by the command-line options.
+func newFilter(opts runOptions) (*pii.Filter, error) {
+	filterOpts := []pii.Option{pii.WithProfile(opts.profile), pii.WithGSTPolicy(opts.gstPolicy), pii.WithPANContext(opts.panContext), pii.WithAmountWords(opts.amountWords), pii.WithDatePolicy(opts.dates), pii.WithHeaderFooterPolicy(opts.headerFooters), pii.WithLocale(opts.locale), pii.WithMatchValues(opts.matchValues)}
+	if opts.strict {
+		filterOpts = append(filterOpts, pii.WithStrictValidation(true))
+	}
+	if opts.numbered {
+		filterOpts = append(filterOpts, pii.WithNumberedPlaceholders(true))
+	}
+	if opts.minConfidence > 0 {
+		filterOpts = append(filterOpts, pii.WithMinConfidence(opts.minConfidence))
+	}
+	if opts.explain {
+		filterOpts = append(filterOpts, pii.WithExplain(true))
+	}
+	if opts.timings {
+		filterOpts = append(filterOpts, pii.WithTimings(true))
+	}
+	if opts.vault != nil {
+		filterOpts = append(filterOpts, pii.WithVault(opts.vault))
+	}
+	if opts.document != nil {
+		filterOpts = append(filterOpts, pii.WithDocumentProfile(opts.document))
+	}
+	// Config file settings come after the locale so their placeholders win, and before the
+	// command-line address keywords so those win. The installed data set comes before both.
+	if opts.data != nil {
+		filterOpts = append(filterOpts, opts.data.Options(opts.profile.Name)...)
+	}
+	filterOpts = append(filterOpts, opts.configOpts...)
+	if opts.emailPolicy != "" {
+		filterOpts = append(filterOpts, pii.WithEmailPolicy(opts.emailPolicy))
+	}
+	if len(opts.packs) > 0 {
+		filterOpts = append(filterOpts, pii.WithDetectorPacks(opts.packs...))
+	}
+	if len(opts.redact) > 0 {
+		filterOpts = append(filterOpts, pii.WithOnly(opts.redact...))
+	}
+	if len(opts.keep) > 0 {
+		filterOpts = append(filterOpts, pii.WithDisabled(opts.keep...))
+	}
+	if len(opts.phoneRegions) > 0 {
+		filterOpts = append(filterOpts, pii.WithPhoneRegions(opts.phoneRegions...))
+	}
+	if opts.engine == "multi" {
+		filterOpts = append(filterOpts, pii.WithMultiPatternEngine())
+	}
+	if opts.allowlist != "" {
+		values, err := pii.LoadAllowlist(opts.allowlist)
+		if err != nil {
+			return nil, err
 		}
-		lower := strings.ToLower(token)
-		// Relax rule: keep very short words (<=3 letters) unconditionally.
-		if len(lower) <= 3 {
-			return token
+		filterOpts = append(filterOpts, pii.WithAllowlist(values...))
+	}
+	if opts.glossary != "" {
+		terms, err := pii.LoadGlossary(opts.glossary)
+		if err != nil {
+			return nil, err
 		}
-		if _, ok := dict[lower]; ok {
-			return token // English word, keep it
+		filterOpts = append(filterOpts, pii.WithGlossary(terms...))
+	}
+	if opts.denylist != "" {
+		pattern, err := pii.LoadDenylist(opts.denylist)
+		if err != nil {
+			return nil, err
 		}
-		redactedSet[lower] = struct{}{}
-		return "[WORD_REDACTED]"
-	})
-
-	words := make([]string, 0, len(redactedSet))
-	for w := range redactedSet {
-		words = append(words, w)
+		filterOpts = append(filterOpts, pii.WithDenylist(pattern))
+	}
+	if opts.suppressions != nil {
+		filterOpts = append(filterOpts, pii.WithSuppressions(opts.suppressions))
+	}
+	if opts.addressKeywords != "" {
+		set, err := pii.LoadWordSet(opts.addressKeywords)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load address keywords: %v", err)
+		}
+		keywords := make([]string, 0, len(set))
+		for kw := range set {
+			keywords = append(keywords, kw)
+		}
+		sort.Strings(keywords)
+		filterOpts = append(filterOpts, pii.WithAddressKeywords(keywords...))
 	}
-	return redactedText, words
+	filter := pii.NewFilter(filterOpts...)
+	if err := filter.CheckEntityTypes(opts.redact...); err != nil {
+		return nil, fmt.Errorf("invalid -redact: %v", err)
+	}
+	if err := filter.CheckEntityTypes(opts.keep...); err != nil {
+		return nil, fmt.Errorf("invalid -keep: %v", err)
+	}
+	return filter, nil
 }
 
-func main() {
-	pdfFile := DefaultPDFFile
-	outputFile := "filtered_output.txt"
-	rawOutputFile := "extracted_text.txt"
-
-	// Allow overriding output file names via optional CLI args (positions 1 and 2)
-	if len(os.Args) > 1 {
-		outputFile = os.Args[1]
+// printTextStats prints the word, page, dictionary and placeholder counts of a document.
+func printTextStats(stats pii.TextStats) {
+	fmt.Printf("Words: %d on %d page(s)\n", stats.Words, len(stats.Pages))
+	if len(stats.Pages) > 1 {
+		for _, p := range stats.Pages {
+			fmt.Printf("  Page %d: %d characters, %d words\n", p.Page, p.Characters, p.Words)
+		}
+	}
+	if stats.DictionaryWords > 0 {
+		fmt.Printf("Dictionary hit rate: %.1f%% (%d of %d words)\n", stats.DictionaryHitRate*100, stats.DictionaryHits, stats.DictionaryWords)
+	}
+	placeholders := make([]string, 0, len(stats.Placeholders))
+	for p := range stats.Placeholders {
+		placeholders = append(placeholders, p)
 	}
-	if len(os.Args) > 2 {
-		rawOutputFile = os.Args[2]
+	sort.Strings(placeholders)
+	for i, p := range placeholders {
+		placeholders[i] = fmt.Sprintf("%s %d", p, stats.Placeholders[p])
 	}
+	fmt.Printf("Placeholders: %d", stats.PlaceholderTotal())
+	if len(placeholders) > 0 {
+		fmt.Printf(" (%s)", strings.Join(placeholders, ", "))
+	}
+	fmt.Println()
+}
 
-	// Check if PDF file exists
-	if _, err := os.Stat(pdfFile); os.IsNotExist(err) {
-		log.Fatalf("PDF file does not exist: %s", pdfFile)
+// pipeline returns the pipeline the documents of the run go through, with piiFilter and the
+// dictionary words; with progress each step is printed.
+func (o runOptions) pipeline(piiFilter *pii.Filter, words map[string]struct{}, progress bool) pii.Pipeline {
+	p := pii.Pipeline{Password: o.password, Filter: piiFilter, Stages: o.stages, Words: words, Workers: o.workers, Formats: o.formats}
+	// "auto" is no engine of its own: a nil Extractor tries them in turn.
+	p.Extractor, _ = pii.ExtractorByName(o.extractor)
+	if progress {
+		p.Progress = func(msg string) { fmt.Println(msg) }
 	}
+	return p
+}
 
-	fmt.Printf("Reading PDF file: %s\n", pdfFile)
+// timingSummary lists the time of each stage of timings in order, with the three slowest
+// detectors after detect.
+func timingSummary(timings *pii.Timings) string {
+	parts := make([]string, len(timings.Stages))
+	for i, t := range timings.Stages {
+		parts[i] = fmt.Sprintf("%s %v", t.Name, t.Duration.Round(time.Microsecond))
+		if t.Name != pii.StageDetect || len(timings.Detectors) == 0 {
+			continue
+		}
+		slowest := make([]string, 0, 3)
+		for _, d := range timings.Detectors[:min(3, len(timings.Detectors))] {
+			slowest = append(slowest, fmt.Sprintf("%s %v", d.Name, d.Duration.Round(time.Microsecond)))
+		}
+		parts[i] += " (" + strings.Join(slowest, ", ") + ")"
+	}
+	return strings.Join(parts, ", ")
+}
 
-	var (
-		pdfText string
-		err     error
-	)
+// processDocument runs the full extraction and redaction pipeline for a single PDF. A panic
+// anywhere in the pipeline is recovered and returned as a *PanicError so the caller can
+// record the failure and carry on with other documents.
+func processDocument(pdfFile, outputFile, rawOutputFile string, opts runOptions) (result documentResult, err error) {
+	// Warnings are echoed after each step, and whatever is left once the document is done.
+	warnings := stdoutWarnings()
+	defer func() {
+		if r := recover(); r != nil {
+			err = pii.NewPanicError(r)
+		}
+		warnings.flush(result.warnings)
+	}()
 
-	pdfText, err = FallbackReadPDFWithPdftotext(pdfFile)
+	fmt.Printf("Reading PDF file: %s\n", pdfFile)
+
+	// Initialize PII filter
+	piiFilter, err := newFilter(opts)
+	if err != nil {
+		return result, err
+	}
+	wordSet, err := opts.wordSet()
 	if err != nil {
-		log.Fatalf("Error extracting text with pdftotext: %v", err)
+		return result, err
 	}
+	pipeline := opts.pipeline(piiFilter, wordSet, true)
 
-	if strings.TrimSpace(pdfText) == "" {
-		fmt.Println("No text could be extracted from the PDF. Exiting.")
-		return
+	ctx := context.Background()
+	extraction, err := pipeline.Extract(ctx, pdfFile)
+	for attempt := 0; errors.Is(err, pii.ErrEncrypted) && opts.promptPassword && attempt < passwordAttempts; attempt++ {
+		if attempt > 0 {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		if pipeline.Password, err = promptPassword(pdfFile); err != nil {
+			return result, err
+		}
+		extraction, err = pipeline.Extract(ctx, pdfFile)
+	}
+	opts.password = pipeline.Password
+	result.warnings, result.extraction = extraction.Warnings, &extraction.Metadata
+	warnings.flush(result.warnings)
+	if errors.Is(err, pii.ErrNoText) {
+		// Nothing was redacted, so the document must not pass for done: batch runs report it
+		// as failed and the watch daemon quarantines it.
+		result.warnings.Add(pii.WarnNoText, "%v", err)
+		if opts.extractor == "auto" {
+			return result, fmt.Errorf("%w; install pdftoppm and tesseract to OCR scanned documents", err)
+		}
+		return result, err
+	}
+	if err != nil {
+		return result, err
 	}
+	pdfText := extraction.Pages.Text()
 
 	fmt.Printf("Extracted %d characters from PDF\n", len(pdfText))
 
 	// Save raw extracted text (before any redaction)
-	if err := SaveRawText(pdfText, rawOutputFile); err != nil {
-		log.Fatalf("Error saving raw extracted text: %v", err)
+	if err := pii.SaveRawText(pdfText, rawOutputFile); err != nil {
+		return result, fmt.Errorf("error saving raw extracted text: %v", err)
 	}
 
-	// Initialize PII filter
-	piiFilter := NewPIIFilter()
-
 	// Filter PII data
-	fmt.Println("Filtering PII data...")
-	filteredData := piiFilter.FilterPII(pdfText)
-
-	// Redacting non-dictionary English words using offline list...
-	fmt.Println("Redacting non-dictionary English words using offline list...")
-	wordSet, err := LoadWordSet("english_words.txt")
+	run, err := pipeline.Redact(ctx, extraction)
+	result.warnings = run.Data.Warnings
+	warnings.flush(result.warnings)
 	if err != nil {
-		log.Fatalf("Failed to load english word list: %v", err)
+		return result, err
+	}
+	// Everything from here on sees the text as rewritten by the stages before detect.
+	pdfText = run.Text
+	filteredData := run.Data
+	filteredData.ProcessingBasis = opts.basis
+	result.warnings = filteredData.Warnings
+	if opts.auditKey != nil {
+		result.audit = pii.AuditEntities(filteredData, opts.auditKey)
+	}
+	if opts.consistencyFile != "" {
+		result.records = piiFilter.CertificateRecords(pdfFile, pdfText)
+	}
+	if opts.reviewFile != "" {
+		if err := pii.SaveReviewCopy(piiFilter.ReviewCopy(pdfText), opts.reviewFile); err != nil {
+			return result, fmt.Errorf("error saving review copy: %v", err)
+		}
+	}
+	if perMB, ok := pii.PerfBudget(opts.perfBudget).Check(run.Elapsed, len(pdfText)); !ok {
+		result.warnings.Add(pii.WarnPerfBudget, "redaction took %v per MB, over the %v budget", perMB, opts.perfBudget)
+	}
+	filteredData.Warnings = result.warnings
+
+	// Multi-employer bundles: redact each certificate on its own so findings can be reported,
+	// and optionally written, per employer.
+	var certificates []pii.FilteredData
+	if certs := piiFilter.SplitCertificates(pdfText); len(certs) > 1 {
+		fmt.Printf("Found %d certificates in the document\n", len(certs))
+		for _, c := range certs {
+			certData := piiFilter.FilterPII(c.Text)
+			certData.ProcessingBasis = opts.basis
+			if err := pipeline.Finish(ctx, &certData, &result.warnings); err != nil {
+				return result, fmt.Errorf("certificate %d: %v", c.Index, err)
+			}
+			certificates = append(certificates, certData)
+			filteredData.Certificates = append(filteredData.Certificates, pii.CertificateFindings{
+				Index:       c.Index,
+				Fields:      c.Fields,
+				Categories:  certData.Categories(),
+				MatchCounts: certData.MatchCounts,
+			})
+		}
+	}
+
+	// The split outputs and the redacted PDF are written first and the reports of the document
+	// last, so that the outputs stage in their timings covers everything up to them.
+	outputsStarted := time.Now()
+	if opts.splitParts {
+		for _, part := range pii.SplitParts(pdfText) {
+			partData := piiFilter.FilterPII(part.Text)
+			partData.ProcessingBasis = opts.basis
+			if err := pipeline.Finish(ctx, &partData, &result.warnings); err != nil {
+				return result, fmt.Errorf("%s: %v", part.Name, err)
+			}
+			written, err := pipeline.Write(partData, pii.PartPath(outputFile, part.Name))
+			result.outputs = append(result.outputs, written...)
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+	if opts.splitEmployers {
+		for i, certData := range certificates {
+			written, err := pipeline.Write(certData, pii.CertificatePath(outputFile, i+1))
+			result.outputs = append(result.outputs, written...)
+			if err != nil {
+				return result, err
+			}
+		}
 	}
-	updatedText, nonEnglishWords := RedactUnknownWords(filteredData.CleanedText, wordSet)
-	filteredData.CleanedText = updatedText
-	if len(nonEnglishWords) > 0 {
-		filteredData.RemovedFields = append(filteredData.RemovedFields, "Non-Dictionary Words")
+	if opts.redactedPDF != "" {
+		fmt.Println("Writing redacted PDF...")
+		var notice *pii.RedactionNotice
+		if opts.coverPage {
+			notice = &pii.RedactionNotice{Policy: opts.policy, RunID: opts.runID}
+		}
+		boxes, err := pii.SaveRedactedPDFMode(ctx, opts.pdfMode, piiFilter, pdfFile, opts.password, opts.redactedPDF, notice, &result.warnings)
+		if err != nil {
+			return result, fmt.Errorf("error writing redacted PDF: %v", err)
+		}
+		if notice != nil {
+			result.verification = notice.Verification
+			fmt.Printf("Redacted PDF verification: %s\n", notice.Verification)
+		}
+		fmt.Printf("Redacted PDF: %s (%d regions blacked out)\n", opts.redactedPDF, boxes)
 	}
+	filteredData.Timings.AddStage(pii.StageOutputs, outputsStarted)
 
-	// Save filtered data (after both PII and dictionary redaction)
-	err = SaveFilteredData(filteredData, outputFile)
+	// Save filtered data (after both PII and dictionary redaction) in every requested format,
+	// listed before the split outputs.
+	filteredData.Warnings = result.warnings
+	written, err := pipeline.Write(filteredData, outputFile)
+	result.outputs = append(written, result.outputs...)
 	if err != nil {
-		log.Fatalf("Error saving filtered data: %v", err)
+		return result, err
 	}
 
 	// Print summary
 	fmt.Printf("\n=== PROCESSING COMPLETE ===\n")
 	fmt.Printf("Input file: %s\n", pdfFile)
-	fmt.Printf("Filtered output file: %s\n", outputFile)
+	fmt.Printf("Filtered output file(s): %s\n", strings.Join(result.outputs, ", "))
 	fmt.Printf("Raw text file: %s\n", rawOutputFile)
+	if opts.reviewFile != "" {
+		fmt.Printf("Review copy: %s\n", opts.reviewFile)
+	}
 	fmt.Printf("Original text length: %d characters\n", len(pdfText))
 	fmt.Printf("Filtered text length: %d characters\n", len(filteredData.CleanedText))
+	printTextStats(filteredData.Stats)
 
+	result.categories = filteredData.Categories()
+	result.matchCounts = make(map[string]int, len(filteredData.RemovedFields))
+	for _, field := range filteredData.RemovedFields {
+		result.matchCounts[field] = filteredData.MatchCounts[field]
+	}
 	if len(filteredData.RemovedFields) > 0 {
-		fmt.Printf("Removed PII fields: %s\n", strings.Join(filteredData.RemovedFields, ", "))
+		colored := make([]string, len(result.categories))
+		bySeverity := make(map[pii.Severity]int)
+		for i, field := range filteredData.RemovedFields {
+			severity := piiFilter.LabelSeverity(field)
+			bySeverity[severity] += filteredData.MatchCounts[field]
+			result.matches += filteredData.MatchCounts[field]
+			colored[i] = paint(severityColor(severity), result.categories[i])
+		}
+		fmt.Printf("Removed PII fields: %s\n", strings.Join(colored, ", "))
+		fmt.Printf("Matches by severity: %s\n", severitySummary(bySeverity))
 	}
 
 	if len(filteredData.RetainedFields) > 0 {
-		fmt.Printf("Retained business data: %s\n", strings.Join(getKeys(filteredData.RetainedFields), ", "))
+		fmt.Printf("Retained business data: %s\n", strings.Join(filteredData.RetainedKeys(), ", "))
+	}
+	if filteredData.Timings != nil {
+		fmt.Printf("Timings: %s\n", timingSummary(filteredData.Timings))
 	}
 
 	fmt.Println("\nFiltered data has been saved successfully!")
+	return result, nil
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// passwordAttempts is how many times the password of an encrypted PDF is asked for.
+const passwordAttempts = 3
+
+// isTerminal reports whether f is an interactive terminal rather than a file or a pipe. Other
+// character devices such as /dev/null are told apart by asking stty for the terminal settings;
+// where stty is not installed every character device is taken for a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	cmd := exec.Command("stty", "-g")
+	cmd.Stdin = f
+	err = cmd.Run()
+	return err == nil || errors.Is(err, exec.ErrNotFound)
+}
+
+// promptPassword asks for the password of the encrypted PDF pdfFile on the terminal. Echo is
+// turned off with stty where it is available.
+func promptPassword(pdfFile string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%s is encrypted. Password: ", pdfFile)
+	if setEcho(false) == nil {
+		defer func() {
+			setEcho(true)
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// setEcho turns terminal echo on or off.
+func setEcho(on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
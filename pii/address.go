@@ -0,0 +1,142 @@
+package pii
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// addressNumberAfter matches a house, flat or plot number right after an address keyword:
+	// "Flat 12", "Plot No. 5", "Tower B".
+	addressNumberAfter = regexp.MustCompile(`^\s*(?:No\.?\s*|#\s*)?(?:\d+[A-Za-z]?|[A-Z])\b`)
+	// addressNumberBefore matches a number right before an address keyword: "4th Floor",
+	// "12, Road".
+	addressNumberBefore = regexp.MustCompile(`\b\d+(?:st|nd|rd|th)?[\s,]*$`)
+	// columnGapPattern separates the columns pdftotext -layout sets side by side on one line.
+	columnGapPattern = regexp.MustCompile(`[ \t]*\t[ \t]*| {2,}`)
+)
+
+// keywordPattern builds a case-insensitive whole-word pattern for a list of literal keywords.
+func keywordPattern(keywords []string) *regexp.Regexp {
+	quoted := make([]string, len(keywords))
+	for i, kw := range keywords {
+		quoted[i] = regexp.QuoteMeta(kw)
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+}
+
+// isAddressLine reports whether a line is part of an address. A known place name is enough
+// on its own. Address keywords also occur in ordinary narrative ("near-cash perquisites",
+// "Block period"), so a keyword only counts when a second keyword or a house number next to
+// it backs it up.
+func (pf *Filter) isAddressLine(line string) bool {
+	var keywords [][]int
+	if pf.addressMatcher != nil {
+		for _, loc := range pf.addressMatcher.FindAllStringIndex(line) {
+			if !pf.isAddressKeyword(line[loc[0]:loc[1]]) {
+				return true
+			}
+			keywords = append(keywords, loc)
+		}
+	} else {
+		if pf.AddressPattern.MatchString(line) {
+			return true
+		}
+		keywords = pf.AddressKeywordPattern.FindAllStringIndex(line, -1)
+	}
+	return addressSignals(line, keywords)
+}
+
+// isAddressKeyword reports whether a match of the combined matcher is a keyword rather than
+// a place name.
+func (pf *Filter) isAddressKeyword(match string) bool {
+	for _, kw := range pf.addressKeywords {
+		if strings.EqualFold(kw, match) {
+			return true
+		}
+	}
+	return false
+}
+
+// addressSignals reports whether the keyword matches in line are backed by a second distinct
+// keyword or by a number adjacent to one of them.
+func addressSignals(line string, keywords [][]int) bool {
+	seen := make(map[string]bool)
+	for _, loc := range keywords {
+		seen[strings.ToLower(line[loc[0]:loc[1]])] = true
+		if len(seen) > 1 {
+			return true
+		}
+		if addressNumberAfter.MatchString(line[loc[1]:]) || addressNumberBefore.MatchString(line[:loc[0]]) {
+			return true
+		}
+	}
+	return false
+}
+
+// layoutColumns returns the byte ranges of the columns of line, without the gaps between them.
+func layoutColumns(line string) [][]int {
+	var columns [][]int
+	prev := 0
+	for _, gap := range append(columnGapPattern.FindAllStringIndex(line, -1), []int{len(line), len(line)}) {
+		if gap[0] > prev {
+			columns = append(columns, []int{prev, gap[0]})
+		}
+		prev = gap[1]
+	}
+	return columns
+}
+
+// addressColumns returns the byte ranges of line to redact once the address check has
+// matched it. pdftotext -layout puts an address column on the same line as labels and
+// amounts, so only the columns carrying an address signal are returned, neighbouring ones
+// merged; when the signals are spread over several columns, the columns with a place name
+// or keyword are. whole is set when the ranges cover all of the line's text, which is then
+// replaced as a whole.
+func (pf *Filter) addressColumns(line string) (ranges [][]int, whole bool) {
+	columns := layoutColumns(line)
+	marked := make([]bool, len(columns))
+	found := false
+	for i, c := range columns {
+		marked[i] = pf.isAddressLine(line[c[0]:c[1]])
+		found = found || marked[i]
+	}
+	if !found {
+		for i, c := range columns {
+			column := line[c[0]:c[1]]
+			marked[i] = pf.AddressPattern.MatchString(column) || pf.AddressKeywordPattern.MatchString(column)
+			found = found || marked[i]
+		}
+	}
+	if !found {
+		return [][]int{{0, len(line)}}, true
+	}
+	for i, c := range columns {
+		switch {
+		case !marked[i]:
+		case i > 0 && marked[i-1]:
+			ranges[len(ranges)-1][1] = c[1]
+		default:
+			ranges = append(ranges, []int{c[0], c[1]})
+		}
+	}
+	if len(ranges) == 1 && ranges[0][0] == columns[0][0] && ranges[0][1] == columns[len(columns)-1][1] {
+		return [][]int{{0, len(line)}}, true
+	}
+	return ranges, false
+}
+
+// withRegions returns spans with those overlapping a region replaced by the regions, sorted
+// by start offset.
+func withRegions(spans, regions []span) []span {
+	out := make([]span, 0, len(spans)+len(regions))
+	for _, s := range spans {
+		if !overlapsAny(regions, s.start, s.end) {
+			out = append(out, s)
+		}
+	}
+	out = append(out, regions...)
+	sort.Slice(out, func(i, j int) bool { return out[i].start < out[j].start })
+	return out
+}
@@ -0,0 +1,74 @@
+package pii
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// allowlistedKey is the RetainedFields key listing the allowlisted values that were found and
+// left in the text.
+const allowlistedKey = "Allowlisted"
+
+// LoadAllowlist reads an allowlist file: one value per line, such as the employer's own TAN or
+// GSTIN, matched exactly and case-sensitively. Blank lines and lines starting with # are
+// skipped.
+func LoadAllowlist(path string) ([]string, error) {
+	return readList(path, "allowlist")
+}
+
+// readList reads the non-blank lines of a list file, skipping # comments; kind names the list
+// in errors.
+func readList(path, kind string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", kind, err)
+	}
+	defer file.Close()
+
+	var values []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		v := strings.TrimSpace(scanner.Text())
+		if v == "" || strings.HasPrefix(v, "#") {
+			continue
+		}
+		values = append(values, v)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", kind, err)
+	}
+	return values, nil
+}
+
+// allowlistPattern matches any of the values of allowlist, longest first.
+func allowlistPattern(allowlist map[string]bool) *regexp.Regexp {
+	values := slices.Collect(maps.Keys(allowlist))
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+	for i, v := range values {
+		values[i] = regexp.QuoteMeta(v)
+	}
+	return regexp.MustCompile(strings.Join(values, "|"))
+}
+
+// lineAllowed reports whether the whole of line, trimmed, is an allowlisted value, which the
+// address and organisation checks leave alone.
+func (pf *Filter) lineAllowed(line string) bool {
+	return pf.allowlist[strings.TrimSpace(line)]
+}
+
+// wholeLineMatch reports whether the organisation or address check redacts trimmed.
+func (pf *Filter) wholeLineMatch(trimmed string) bool {
+	return pf.lineEnabled(EntityOrganization) && pf.OrganizationPattern.MatchString(trimmed) ||
+		pf.lineEnabled(EntityAddress) && pf.isAddressLine(trimmed)
+}
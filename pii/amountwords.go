@@ -0,0 +1,393 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AmountWordsPolicy controls how amounts written in words ("Rupees Twelve Lakh Thirty
+// Thousand Only") are treated. They are business data, not PII, but the dictionary filter
+// would otherwise replace words such as "Lakh" and "Rupees".
+type AmountWordsPolicy string
+
+const (
+	// AmountWordsKeep leaves amounts in words untouched by the dictionary filter (default).
+	AmountWordsKeep AmountWordsPolicy = "keep"
+	// AmountWordsDigits rewrites amounts in words as digits, e.g. "Rs. 12,30,000".
+	AmountWordsDigits AmountWordsPolicy = "digits"
+	// AmountWordsRedact gives amounts in words no special treatment.
+	AmountWordsRedact AmountWordsPolicy = "redact"
+)
+
+// ParseAmountWordsPolicy validates a policy name supplied on the command line.
+func ParseAmountWordsPolicy(s string) (AmountWordsPolicy, error) {
+	switch p := AmountWordsPolicy(strings.ToLower(s)); p {
+	case AmountWordsKeep, AmountWordsDigits, AmountWordsRedact:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown amount-in-words policy %q (expected keep, digits or redact)", s)
+}
+
+// amountsInWordsKey is the RetainedFields key listing every amount in words with its value
+// and the result of the cross-check against the figure.
+const amountsInWordsKey = "Amounts in Words"
+
+// Kinds of words accepted inside an amount in words.
+const (
+	amountNumber = iota
+	amountScale
+	amountCurrency
+	amountPaise
+	amountAnd
+	amountOnly
+)
+
+type amountWord struct {
+	kind  int
+	value int64
+}
+
+// amountVocabulary maps every word that may appear in an amount in words, lower-cased.
+var amountVocabulary = func() map[string]amountWord {
+	v := map[string]amountWord{
+		"hundred": {amountScale, 100}, "thousand": {amountScale, 1000},
+		"lakh": {amountScale, 100000}, "lakhs": {amountScale, 100000},
+		"lac": {amountScale, 100000}, "lacs": {amountScale, 100000},
+		"million": {amountScale, 1000000},
+		"crore":   {amountScale, 10000000}, "crores": {amountScale, 10000000},
+		"rupee": {kind: amountCurrency}, "rupees": {kind: amountCurrency}, "rs": {kind: amountCurrency},
+		"paisa": {kind: amountPaise}, "paise": {kind: amountPaise},
+		"and": {kind: amountAnd}, "only": {kind: amountOnly},
+	}
+	units := []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen",
+		"eighteen", "nineteen"}
+	for i, w := range units {
+		v[w] = amountWord{amountNumber, int64(i)}
+	}
+	for i, w := range []string{"twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"} {
+		v[w] = amountWord{amountNumber, int64(20 + 10*i)}
+	}
+	return v
+}()
+
+var (
+	amountTokenPattern = regexp.MustCompile(`[A-Za-z]+`)
+	// amountGapPattern is what may separate two words of the same amount; "Rs" may also be
+	// followed by a period.
+	amountGapPattern = regexp.MustCompile(`^[\s,\-]*$`)
+)
+
+// AmountInWords is an amount written in words found in a line of text. Start and End are byte
+// offsets into the line; Paise is the amount in paise (hundredths of a rupee).
+type AmountInWords struct {
+	Text       string
+	Start, End int
+	Paise      int64
+}
+
+// Rupees formats the amount with Indian digit grouping, e.g. "Rs. 12,30,000" or
+// "Rs. 1,250.50" when there are paise.
+func (a AmountInWords) Rupees() string {
+	s := "Rs. " + groupIndian(a.Paise/100)
+	if p := a.Paise % 100; p != 0 {
+		s += fmt.Sprintf(".%02d", p)
+	}
+	return s
+}
+
+// groupIndian writes n with the lakh/crore grouping used on Form 16: 1230000 -> 12,30,000.
+func groupIndian(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	if len(s) <= 3 {
+		return s
+	}
+	head, tail := s[:len(s)-3], s[len(s)-3:]
+	var parts []string
+	for len(head) > 2 {
+		parts = append([]string{head[len(head)-2:]}, parts...)
+		head = head[:len(head)-2]
+	}
+	parts = append([]string{head}, parts...)
+	return strings.Join(parts, ",") + "," + tail
+}
+
+// FindAmountsInWords returns the amounts written in words in a single line, in order. A run
+// of number words counts as an amount only when it carries a currency word ("Rupees", "Rs",
+// "Paise") or ends in "Only", so ordinary prose such as "one hundred employees" is ignored.
+// "Rupees Ten and Paise Fifty Only" and "Ten Rupees and Fifty Paise" both parse.
+func FindAmountsInWords(line string) []AmountInWords {
+	var amounts []AmountInWords
+	locs := amountTokenPattern.FindAllStringIndex(line, -1)
+	for i := 0; i < len(locs); {
+		n := amountRun(line, locs, i)
+		if n == 0 {
+			i++
+			continue
+		}
+		run := locs[i : i+n]
+		if a, ok := parseAmountRun(line, run); ok {
+			amounts = append(amounts, a)
+		}
+		i += n
+	}
+	return amounts
+}
+
+// amountRun returns how many tokens starting at locs[i] form a candidate amount: vocabulary
+// words separated only by spaces, commas and hyphens, starting with a currency or number word.
+// Trailing connectors and currency words that are not followed by a number are dropped.
+func amountRun(line string, locs [][]int, i int) int {
+	first, ok := amountVocabulary[strings.ToLower(line[locs[i][0]:locs[i][1]])]
+	if !ok || (first.kind != amountNumber && first.kind != amountCurrency) {
+		return 0
+	}
+	end := i + 1
+	for end < len(locs) {
+		if _, ok := amountVocabulary[strings.ToLower(line[locs[end][0]:locs[end][1]])]; !ok {
+			break
+		}
+		gap := line[locs[end-1][1]:locs[end][0]]
+		if strings.EqualFold(line[locs[end-1][0]:locs[end-1][1]], "rs") {
+			gap = strings.TrimPrefix(gap, ".")
+		}
+		if !amountGapPattern.MatchString(gap) {
+			break
+		}
+		end++
+	}
+	for end > i {
+		w := amountVocabulary[strings.ToLower(line[locs[end-1][0]:locs[end-1][1]])]
+		if w.kind != amountAnd && !(w.kind == amountCurrency && end-1 == i) {
+			break
+		}
+		end--
+	}
+	return end - i
+}
+
+// parseAmountRun evaluates a run found by amountRun. Number words before a "Paise" word (after
+// the last "and") or after it are read as paise, everything else as rupees.
+func parseAmountRun(line string, run [][]int) (AmountInWords, bool) {
+	words := make([]amountWord, len(run))
+	paiseAt, hasNumber, hasMarker := -1, false, false
+	for k, loc := range run {
+		words[k] = amountVocabulary[strings.ToLower(line[loc[0]:loc[1]])]
+		switch words[k].kind {
+		case amountNumber:
+			hasNumber = true
+		case amountCurrency, amountOnly:
+			hasMarker = true
+		case amountPaise:
+			hasMarker = true
+			if paiseAt < 0 {
+				paiseAt = k
+			}
+		}
+	}
+	if !hasNumber || !hasMarker {
+		return AmountInWords{}, false
+	}
+
+	rupeeWords, paiseWords := words, []amountWord(nil)
+	if paiseAt >= 0 {
+		if after := numberWords(words[paiseAt+1:]); len(after) > 0 {
+			rupeeWords, paiseWords = words[:paiseAt], after
+		} else {
+			split := 0
+			for k := paiseAt - 1; k >= 0; k-- {
+				if words[k].kind == amountAnd {
+					split = k
+					break
+				}
+			}
+			rupeeWords, paiseWords = words[:split], words[split:paiseAt]
+		}
+	}
+	rupees, ok := evalNumberWords(rupeeWords)
+	if !ok {
+		return AmountInWords{}, false
+	}
+	paise, ok := evalNumberWords(paiseWords)
+	if !ok || paise >= 100 {
+		return AmountInWords{}, false
+	}
+	start, end := run[0][0], run[len(run)-1][1]
+	return AmountInWords{Text: line[start:end], Start: start, End: end, Paise: rupees*100 + paise}, true
+}
+
+// numberWords returns the leading number and scale words of words, skipping connectors.
+func numberWords(words []amountWord) []amountWord {
+	var out []amountWord
+	for _, w := range words {
+		switch w.kind {
+		case amountNumber, amountScale:
+			out = append(out, w)
+		case amountAnd:
+		default:
+			return out
+		}
+	}
+	return out
+}
+
+// evalNumberWords adds up number words the usual way: "Hundred" multiplies the current
+// group, larger scales close it, so "Twelve Lakh Thirty Thousand Five Hundred" is 1230500.
+// Words other than numbers and scales are ignored; an empty list is zero.
+func evalNumberWords(words []amountWord) (int64, bool) {
+	var total, current int64
+	lastScale := int64(0)
+	for _, w := range words {
+		switch w.kind {
+		case amountNumber:
+			current += w.value
+		case amountScale:
+			if current == 0 {
+				current = 1
+			}
+			if w.value == 100 {
+				current *= 100
+				continue
+			}
+			if lastScale != 0 && w.value >= lastScale {
+				return 0, false
+			}
+			total += current * w.value
+			current, lastScale = 0, w.value
+		}
+	}
+	return total + current, true
+}
+
+// normalizeAmountWords rewrites every amount in words in line as digits. A leading "Rs." is
+// replaced together with the words so "Rs. Thirty Thousand Only" becomes "Rs. 30,000".
+func normalizeAmountWords(line string) string {
+	amounts := FindAmountsInWords(line)
+	if len(amounts) == 0 {
+		return line
+	}
+	var b strings.Builder
+	last := 0
+	for _, a := range amounts {
+		b.WriteString(line[last:a.Start])
+		b.WriteString(a.Rupees())
+		last = a.End
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// redactUnknownWordsKeepingAmounts runs RedactUnknownWords over text but leaves every amount
+// in words untouched.
+func redactUnknownWordsKeepingAmounts(text string, dict map[string]struct{}, placeholder string) (string, []string) {
+	seen := make(map[string]struct{})
+	var words []string
+	redact := func(s string) string {
+		out, found := redactUnknownWords(s, dict, placeholder)
+		for _, w := range found {
+			if _, ok := seen[w]; !ok {
+				seen[w] = struct{}{}
+				words = append(words, w)
+			}
+		}
+		return out
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		var b strings.Builder
+		last := 0
+		for _, a := range FindAmountsInWords(line) {
+			b.WriteString(redact(line[last:a.Start]))
+			b.WriteString(a.Text)
+			last = a.End
+		}
+		b.WriteString(redact(line[last:]))
+		lines[i] = b.String()
+	}
+	sort.Strings(words)
+	return strings.Join(lines, "\n"), words
+}
+
+// amountFigurePattern matches an amount written in digits: after a currency sign (₹ 1200,
+// Rs. 1,200.50), in group 1, or with Indian or Western digit grouping or paise (12,00,000,
+// 1,200,000.00, 1200.00), in group 2. Bare digit runs are left out, as they are more often
+// challan numbers, BSR codes or other identifiers than amounts.
+var amountFigurePattern = regexp.MustCompile(`(?:₹|\bRs\.?|\bINR)\s*(\d[\d,]*(?:\.\d{1,2})?)|\b(\d{1,3}(?:,\d{2,3})+(?:\.\d{1,2})?|\d+\.\d{1,2})\b`)
+
+// amountLabelPattern matches the labels of lines holding amounts; the grouped figures of
+// amountFigurePattern are only taken as amounts on such lines.
+var amountLabelPattern = regexp.MustCompile(`(?i)\b(?:amount|total|salary|gross|net|tax|income|deductions?|deducted|deposited|paid|relief|allowances?|perquisites?|rebate|cess|surcharge|exempt|balance|Rs\.?|INR)\b|₹`)
+
+// lastAmountFigure returns the last amount in s and its value in paise: a figure after a
+// currency sign, or a grouped or decimal one when s has an amount label.
+func lastAmountFigure(s string) (string, int64, bool) {
+	labelled := amountLabelPattern.MatchString(s)
+	figure := ""
+	for _, m := range amountFigurePattern.FindAllStringSubmatchIndex(s, -1) {
+		// Dotted dates and version numbers (01.04.2023) are not amounts.
+		if m[1] < len(s) && s[m[1]] == '.' || m[0] > 0 && s[m[0]-1] == '.' {
+			continue
+		}
+		switch {
+		case m[2] >= 0:
+			figure = s[m[2]:m[3]]
+		case labelled:
+			figure = s[m[4]:m[5]]
+		}
+	}
+	if figure == "" {
+		return "", 0, false
+	}
+	figure = strings.TrimRight(figure, ",")
+	whole, frac, _ := strings.Cut(strings.ReplaceAll(figure, ",", ""), ".")
+	rupees, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	paise := int64(0)
+	if frac != "" {
+		if len(frac) == 1 {
+			frac += "0"
+		}
+		paise, _ = strconv.ParseInt(frac, 10, 64)
+	}
+	return figure, rupees*100 + paise, true
+}
+
+// crossCheckAmounts converts the amounts in words of lines[n] (passed as line, after token
+// redaction) to figures and compares each with the figure it restates: the last amount (see
+// lastAmountFigure) before it on the same line or, failing that, on the nearest non-blank line
+// within contextWindow lines above. It returns the values to retain and a warning for every disagreement, which is
+// usually an OCR or typing error in the certificate.
+func crossCheckAmounts(lines []string, n int, line string) ([]string, []Warning) {
+	var retained []string
+	var warnings []Warning
+	for _, a := range FindAmountsInWords(line) {
+		figure, value, ok := lastAmountFigure(line[:a.Start])
+		for k := n - 1; !ok && k >= 0 && k >= n-contextWindow; k-- {
+			if strings.TrimSpace(lines[k]) != "" {
+				figure, value, ok = lastAmountFigure(lines[k])
+				break
+			}
+		}
+		entry := fmt.Sprintf("%s = %s", a.Text, a.Rupees())
+		switch {
+		case !ok:
+		case value == a.Paise:
+			entry += fmt.Sprintf(" (matches %s)", figure)
+		default:
+			entry += fmt.Sprintf(" (MISMATCH: figure is %s)", figure)
+			warnings = append(warnings, Warning{
+				Code:    WarnAmountMismatch,
+				Message: fmt.Sprintf("amount in words %q is %s but the figure is %s", a.Text, a.Rupees(), figure),
+				Line:    n + 1,
+			})
+		}
+		retained = append(retained, entry)
+	}
+	return retained, warnings
+}
@@ -0,0 +1,36 @@
+package pii
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCrossCheckAmounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		retained string
+		warnings int
+	}{
+		{"figure on the same line", "Total (Rs.) 12,00,000.00 Rupees Twelve Lakh Only", "(matches 12,00,000.00)", 0},
+		{"figure on the line above", "Gross Salary 12,00,000.00\nRupees Twelve Lakh Only", "(matches 12,00,000.00)", 0},
+		{"currency sign", "₹ 1200000\nRupees Twelve Lakh Only", "(matches 1200000)", 0},
+		{"mismatch", "Gross Salary 1,20,000.00\nRupees Twelve Lakh Only", "(MISMATCH: figure is 1,20,000.00)", 1},
+		{"challan number above", "Challan identification number: challan no. 1234567\nRupees Twelve Lakh Only", "= Rs. 12,00,000", 0},
+		{"BSR code above", "BSR Code 0510002 deposited on 07.06.2023\nRupees Twelve Lakh Only", "= Rs. 12,00,000", 0},
+		{"TAN above", "TAN of the Deductor: MUMA12345B\nRupees Twelve Lakh Only", "= Rs. 12,00,000", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := strings.Split(tt.text, "\n")
+			n := len(lines) - 1
+			retained, warnings := crossCheckAmounts(lines, n, lines[n])
+			if len(retained) != 1 || !strings.HasSuffix(retained[0], tt.retained) {
+				t.Errorf("retained %q, want one ending in %q", retained, tt.retained)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("got warnings %v, want %d", warnings, tt.warnings)
+			}
+		})
+	}
+}
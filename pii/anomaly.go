@@ -0,0 +1,77 @@
+package pii
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// VolumeRule is the expected number of findings of one entity type in a document type. A
+// count outside [Min, Max] raises a WarnFindingVolume warning: no PAN in a Form 16 usually
+// means the extraction failed, and many Aadhaar numbers mean the wrong file was uploaded.
+// Limits are per certificate, so bundles of several Form 16s scale them. A nil limit is not
+// checked.
+type VolumeRule struct {
+	DocumentType string `json:"document_type" yaml:"document_type"`
+	Entity       string `json:"entity" yaml:"entity"`
+	Min          *int   `json:"min,omitempty" yaml:"min"`
+	Max          *int   `json:"max,omitempty" yaml:"max"`
+}
+
+func intPtr(n int) *int { return &n }
+
+// defaultVolumeRules are the checks applied unless replaced with WithVolumeRules.
+var defaultVolumeRules = []VolumeRule{
+	{DocumentType: DocumentForm16, Entity: EntityPAN, Min: intPtr(1)},
+	{DocumentType: DocumentForm16, Entity: EntityAadhaar, Max: intPtr(2)},
+	{DocumentType: DocumentForm26AS, Entity: EntityPAN, Min: intPtr(1)},
+	{DocumentType: DocumentAIS, Entity: EntityPAN, Min: intPtr(1)},
+}
+
+// validate reports rules that can never be met or name unknown document types.
+func (r VolumeRule) validate() error {
+	if names := append(DocumentProfileNames(), DocumentOther); !slices.Contains(names, r.DocumentType) {
+		return fmt.Errorf("unknown document type %q (expected %s)", r.DocumentType, strings.Join(names, ", "))
+	}
+	if (r.Min != nil && *r.Min < 0) || (r.Max != nil && *r.Max < 0) {
+		return fmt.Errorf("%s/%s: limits must not be negative", r.DocumentType, r.Entity)
+	}
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return fmt.Errorf("%s/%s: min %d is greater than max %d", r.DocumentType, r.Entity, *r.Min, *r.Max)
+	}
+	return nil
+}
+
+// checkVolumes compares the findings of text per entity type with the rules for its
+// document type.
+func (pf *Filter) checkVolumes(text string, matches []Match) []Warning {
+	docType := DocumentOther
+	if pf.documentType != nil {
+		docType = pf.documentType.Name
+	}
+	counts := make(map[string]int)
+	for _, m := range matches {
+		counts[m.Entity]++
+	}
+	scale := 1
+	if docType == DocumentForm16 {
+		scale = len(pf.SplitCertificates(text))
+	}
+
+	var warnings []Warning
+	for _, r := range pf.volumeRules {
+		if r.DocumentType != docType || pf.isDisabled(r.Entity) {
+			continue
+		}
+		n := counts[r.Entity]
+		if r.Min != nil && n < *r.Min*scale {
+			warnings = append(warnings, Warning{Code: WarnFindingVolume, Message: fmt.Sprintf(
+				"%d %s findings in a %s document, expected at least %d; check the extraction", n, r.Entity, docType, *r.Min*scale)})
+		}
+		if r.Max != nil && n > *r.Max*scale {
+			warnings = append(warnings, Warning{Code: WarnFindingVolume, Message: fmt.Sprintf(
+				"%d %s findings in a %s document, expected at most %d; check that this is the right file", n, r.Entity, docType, *r.Max*scale)})
+		}
+	}
+	return warnings
+}
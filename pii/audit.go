@@ -0,0 +1,271 @@
+package pii
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// auditGenesis is the previous hash of the first record of an audit log.
+var auditGenesis = strings.Repeat("0", sha256.Size*2)
+
+// MinAuditKeySize is the shortest key LoadAuditKey accepts.
+const MinAuditKeySize = 16
+
+// AuditEntity is one value removed from a document, identified by the HMAC-SHA256 of the
+// original text under the audit key of the deployment (see AuditValueHMAC), so that a
+// compliance team holding the value and the key can prove it was removed. A plain hash would
+// not do: Aadhaar, phone and PAN numbers are few enough to be brute-forced from the log.
+type AuditEntity struct {
+	Entity    string `json:"entity"`
+	Line      int    `json:"line"`
+	ValueHMAC string `json:"value_hmac"`
+}
+
+// AuditRecord is one line of an audit log: a document processed by a run. Seq, PrevHash and
+// Hash chain the records, each hash covering the record and, through PrevHash, every record
+// before it. The chain is not keyed, so whoever edits, reorders or removes lines can also
+// recompute the hashes after them: VerifyAuditLog on its own only catches careless edits and
+// damage, and proves a log untouched when its last hash matches one kept elsewhere (see
+// AuditLog.Last).
+type AuditRecord struct {
+	Seq         int           `json:"seq"`
+	Time        time.Time     `json:"time"`
+	ToolVersion string        `json:"tool_version"`
+	RunID       string        `json:"run_id"`
+	Input       string        `json:"input"`
+	InputSHA256 string        `json:"input_sha256,omitempty"`
+	Status      string        `json:"status"`
+	Entities    []AuditEntity `json:"entities"`
+	PrevHash    string        `json:"prev_hash"`
+	// Hash is the SHA-256 of the line up to the hash field; it is always written last.
+	Hash string `json:"hash"`
+}
+
+// LoadAuditKey reads the audit key of a deployment: the contents of the file at path, without
+// surrounding whitespace, of at least MinAuditKeySize bytes.
+func LoadAuditKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit key: %v", err)
+	}
+	key := bytes.TrimSpace(data)
+	if len(key) < MinAuditKeySize {
+		return nil, fmt.Errorf("audit key %s is shorter than %d bytes", path, MinAuditKeySize)
+	}
+	return key, nil
+}
+
+// AuditValueHMAC returns the hex HMAC-SHA256 of value under key, as recorded in AuditEntity.
+func AuditValueHMAC(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AuditEntities lists the values removed from data with the HMAC of their original text
+// under key.
+func AuditEntities(data FilteredData, key []byte) []AuditEntity {
+	entities := make([]AuditEntity, 0, len(data.Matches))
+	for _, m := range data.Matches {
+		if m.Start < 0 || m.End > len(data.source) || m.Start > m.End {
+			continue
+		}
+		entities = append(entities, AuditEntity{Entity: m.Entity, Line: m.Line, ValueHMAC: AuditValueHMAC(key, data.source[m.Start:m.End])})
+	}
+	return entities
+}
+
+// HashFile returns the hex SHA-256 of the file at path.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %v", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash file: %v", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// AuditChainError reports the first line of an audit log that breaks the hash chain.
+type AuditChainError struct {
+	Line   int
+	Reason string
+}
+
+func (e *AuditChainError) Error() string {
+	return fmt.Sprintf("audit log line %d: %s", e.Line, e.Reason)
+}
+
+// AuditLog appends records to an audit log file, continuing the chain of the records already
+// in it. It is not safe for concurrent use.
+type AuditLog struct {
+	file *os.File
+	seq  int
+	last string
+}
+
+// OpenAuditLog opens the audit log at path for appending, creating it when missing. A log
+// whose chain does not verify is refused rather than extended, since records appended to it
+// would prove nothing.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	seq, last, err := verifyAuditLog(path, "")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	return &AuditLog{file: f, seq: seq, last: last}, nil
+}
+
+// Append chains rec to the log and writes it as one line, synced to disk before returning.
+// Seq, ToolVersion, PrevHash and Hash are set by Append, and Time when it is zero.
+func (l *AuditLog) Append(rec AuditRecord) error {
+	l.seq++
+	rec.Seq, rec.ToolVersion, rec.PrevHash, rec.Hash = l.seq, ToolVersion, l.last, ""
+	if rec.Time.IsZero() {
+		rec.Time = time.Now().UTC()
+	}
+	if rec.Entities == nil {
+		rec.Entities = []AuditEntity{}
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %v", err)
+	}
+	body, ok := bytes.CutSuffix(b, []byte(`,"hash":""}`))
+	if !ok {
+		return fmt.Errorf("failed to encode audit record: unexpected layout")
+	}
+	sum := sha256.Sum256(body)
+	rec.Hash = hex.EncodeToString(sum[:])
+	line := fmt.Sprintf("%s,\"hash\":%q}\n", body, rec.Hash)
+	if _, err := l.file.WriteString(line); err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	l.last = rec.Hash
+	return nil
+}
+
+// Last returns the hash of the last record. Records removed from the end of a log leave a
+// valid chain, so keeping the last hash of each run elsewhere, e.g. in the ticket of the run,
+// detects that as well.
+func (l *AuditLog) Last() string {
+	return l.last
+}
+
+// Close closes the log file.
+func (l *AuditLog) Close() error {
+	return l.file.Close()
+}
+
+// VerifyAuditLog checks the hash chain of the audit log at path and returns the number of
+// records in it. A broken chain is reported as an *AuditChainError naming the first line
+// that does not verify. Since the chain is not keyed, that alone does not prove the log
+// untouched: known, when set, is a hash kept elsewhere, such as the last hash printed by a
+// run, and one of the records must have it, which proves every record up to it unchanged.
+func VerifyAuditLog(path, known string) (int, error) {
+	n, _, err := verifyAuditLog(path, known)
+	if err != nil && os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	return n, err
+}
+
+// FindAuditValue returns the records of the audit log at path that removed value, whose HMAC
+// under key is looked up in their entities. The chain is verified first.
+func FindAuditValue(path string, key []byte, value string) ([]AuditRecord, error) {
+	if _, err := VerifyAuditLog(path, ""); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer f.Close()
+	want := AuditValueHMAC(key, value)
+	var found []AuditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %v", err)
+		}
+		for _, e := range rec.Entities {
+			if hmac.Equal([]byte(e.ValueHMAC), []byte(want)) {
+				found = append(found, rec)
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	return found, nil
+}
+
+// verifyAuditLog checks the chain of the log at path, and that a record has the hash known if
+// set, and returns the number of records and the hash of the last one, or auditGenesis for an
+// empty log.
+func verifyAuditLog(path, known string) (int, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, auditGenesis, err
+		}
+		return 0, "", fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer f.Close()
+	last, n := auditGenesis, 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		b := scanner.Bytes()
+		var rec AuditRecord
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return n, "", &AuditChainError{Line: line, Reason: fmt.Sprintf("not a record: %v", err)}
+		}
+		body, ok := bytes.CutSuffix(b, []byte(fmt.Sprintf(",\"hash\":%q}", rec.Hash)))
+		if !ok {
+			return n, "", &AuditChainError{Line: line, Reason: "hash is not the last field"}
+		}
+		sum := sha256.Sum256(body)
+		switch {
+		case rec.Seq != n+1:
+			return n, "", &AuditChainError{Line: line, Reason: fmt.Sprintf("sequence number %d, expected %d", rec.Seq, n+1)}
+		case rec.PrevHash != last:
+			return n, "", &AuditChainError{Line: line, Reason: "previous hash does not match the record before it"}
+		case rec.Hash != hex.EncodeToString(sum[:]):
+			return n, "", &AuditChainError{Line: line, Reason: "record was modified after it was written"}
+		}
+		if rec.Hash == known {
+			known = ""
+		}
+		last = rec.Hash
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return n, "", fmt.Errorf("failed to read audit log: %v", err)
+	}
+	if known != "" {
+		return n, "", &AuditChainError{Line: n + 1, Reason: fmt.Sprintf("no record has the hash %s: the log was rewritten or cut", known)}
+	}
+	return n, last, nil
+}
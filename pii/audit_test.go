@@ -0,0 +1,158 @@
+package pii
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testAuditKey = []byte("0123456789abcdef0123456789abcdef")
+
+// writeAuditLog writes a log of one record per input and returns its path and last hash.
+func writeAuditLog(t *testing.T, dir string, inputs ...string) (string, string) {
+	t.Helper()
+	path := filepath.Join(dir, "audit.jsonl")
+	l, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, input := range inputs {
+		data := NewFilter().FilterPII("PAN of the Employee: ABCPE1234F\nInput: " + input)
+		if err := l.Append(AuditRecord{RunID: "run", Input: input, Status: "ok", Entities: AuditEntities(data, testAuditKey)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path, l.Last()
+}
+
+func TestVerifyAuditLogTampering(t *testing.T) {
+	tests := []struct {
+		name string
+		// tamper edits the lines of a log of four records.
+		tamper func(lines []string) []string
+		// rewrite writes the log again with a record changed and the chain recomputed, as anyone
+		// editing the log can.
+		rewrite bool
+		// chainOK is whether the chain alone still verifies; the last hash never does.
+		chainOK bool
+	}{
+		{"untouched", func(lines []string) []string { return lines }, false, true},
+		{"modified line", func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], `"status":"ok"`, `"status":"failed"`, 1)
+			return lines
+		}, false, false},
+		{"reordered lines", func(lines []string) []string {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, false, false},
+		{"removed line", func(lines []string) []string { return slices.Delete(lines, 1, 2) }, false, false},
+		{"truncated", func(lines []string) []string { return lines[:3] }, false, true},
+		{"rewritten chain", nil, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path, last := writeAuditLog(t, dir, "a.pdf", "b.pdf", "c.pdf", "d.pdf")
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if tt.rewrite {
+				os.Remove(path)
+				path, _ = writeAuditLog(t, dir, "a.pdf", "b.pdf", "x.pdf", "d.pdf")
+			} else if err := os.WriteFile(path, []byte(strings.Join(tt.tamper(lines), "\n")+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = VerifyAuditLog(path, "")
+			var chainErr *AuditChainError
+			if tt.chainOK && err != nil {
+				t.Errorf("VerifyAuditLog = %v, want the chain to verify", err)
+			}
+			if !tt.chainOK && !errors.As(err, &chainErr) {
+				t.Errorf("VerifyAuditLog = %v, want an *AuditChainError", err)
+			}
+			_, err = VerifyAuditLog(path, last)
+			if tt.name == "untouched" {
+				if err != nil {
+					t.Errorf("VerifyAuditLog with the last hash = %v", err)
+				}
+			} else if !errors.As(err, &chainErr) {
+				t.Errorf("VerifyAuditLog with the last hash = %v, want an *AuditChainError", err)
+			}
+		})
+	}
+}
+
+// A hash printed by an earlier run still verifies after later runs appended to the log.
+func TestVerifyAuditLogEarlierHash(t *testing.T) {
+	dir := t.TempDir()
+	path, first := writeAuditLog(t, dir, "a.pdf")
+	if _, last := writeAuditLog(t, dir, "b.pdf", "c.pdf"); last == first {
+		t.Fatal("appending did not change the last hash")
+	}
+	if n, err := VerifyAuditLog(path, first); err != nil || n != 3 {
+		t.Errorf("VerifyAuditLog = %d, %v, want 3 records", n, err)
+	}
+}
+
+func TestAuditEntitiesKeyed(t *testing.T) {
+	data := NewFilter().FilterPII("PAN of the Employee: ABCPE1234F")
+	entities := AuditEntities(data, testAuditKey)
+	if len(entities) != 1 {
+		t.Fatalf("AuditEntities = %+v, want the PAN", entities)
+	}
+	plain := sha256.Sum256([]byte("ABCPE1234F"))
+	switch got := entities[0].ValueHMAC; {
+	case got == hex.EncodeToString(plain[:]):
+		t.Error("value hash is unkeyed")
+	case got != AuditValueHMAC(testAuditKey, "ABCPE1234F"):
+		t.Errorf("value hash %s is not the HMAC of the PAN", got)
+	case got == AuditEntities(data, []byte("another key of 32 bytes........"))[0].ValueHMAC:
+		t.Error("value hash does not depend on the key")
+	}
+
+	path, _ := writeAuditLog(t, t.TempDir(), "a.pdf", "b.pdf")
+	records, err := FindAuditValue(path, testAuditKey, "ABCPE1234F")
+	if err != nil || len(records) != 2 {
+		t.Errorf("FindAuditValue = %d records, %v, want 2", len(records), err)
+	}
+	if records, _ := FindAuditValue(path, []byte("wrong key, 16 bytes+"), "ABCPE1234F"); len(records) != 0 {
+		t.Errorf("FindAuditValue with another key found %d records", len(records))
+	}
+}
+
+func TestLoadAuditKey(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"key", "0123456789abcdef0123456789abcdef\n", false},
+		{"short key", "secret\n", true},
+		{"blank", "   \n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_"))
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			key, err := LoadAuditKey(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadAuditKey = %q, %v, wantErr %v", key, err, tt.wantErr)
+			}
+			if !tt.wantErr && string(key) != strings.TrimSpace(tt.content) {
+				t.Errorf("LoadAuditKey = %q", key)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package pii
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Block kinds of the block model, in the order they appear in a certificate.
+const (
+	BlockHeader        = "header"
+	BlockEmployer      = "employer"
+	BlockEmployee      = "employee"
+	BlockQuarterTable  = "quarter_table"
+	BlockSalaryDetails = "salary_details"
+	BlockVerification  = "verification"
+	BlockAnnexure      = "annexure"
+)
+
+// Redaction status of a block.
+const (
+	BlockClean    = "clean"
+	BlockRedacted = "redacted"
+)
+
+var (
+	// employerLabelPattern and employeeLabelPattern head the side-by-side name and address
+	// columns of Part A.
+	employerLabelPattern = regexp.MustCompile(`(?i)Name\s+and\s+address\s+of\s+the\s+Employer`)
+	employeeLabelPattern = regexp.MustCompile(`(?i)Name\s+and\s+address\s+of\s+the\s+Employee`)
+	// certificateDetailsPattern ends the address columns: the CIT (TDS), assessment year and
+	// period row belongs to the certificate header again.
+	certificateDetailsPattern = regexp.MustCompile(`(?i)CIT\s*\(TDS\)|Assessment\s+Year`)
+	quarterTablePattern       = regexp.MustCompile(`(?i)Summary\s+of\s+(?:the\s+)?amount\s+paid|^\s*Quarter\(?s?\)?\s`)
+	verificationPattern       = regexp.MustCompile(`(?i)^\s*Verification\b`)
+)
+
+// Block is one section of a certificate in the block model. Employer and employee blocks
+// share their lines side by side; each holds its own column. Kinds may repeat, e.g. the
+// header continues after the address columns and every certificate of a bundle has its own
+// blocks.
+type Block struct {
+	Kind string `json:"kind"`
+	// Certificate is the 1-based index of the certificate within a bundle.
+	Certificate int `json:"certificate"`
+	// StartLine and EndLine are the 1-based, inclusive line range of the input text.
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+	// Status is BlockRedacted when anything in the block was replaced, BlockClean otherwise.
+	Status      string         `json:"status"`
+	MatchCounts map[string]int `json:"match_counts,omitempty"`
+	// Lines is the redacted text of the block, without page breaks. Column lines are cut at
+	// the column boundary of the original layout.
+	Lines []string `json:"lines"`
+
+	// from and to are the byte range of each line the block covers; to == 0 means the line
+	// end. Both are 0 for blocks spanning the whole width.
+	from, to int
+}
+
+// blockModel divides text into blocks and fills them from the redacted lines of data. The
+// columns of the employer and employee blocks are redacted from the matches on their lines,
+// since the cleaned line no longer has the original column positions.
+func (pf *Filter) blockModel(text string, data FilteredData) []Block {
+	lines := strings.Split(text, "\n")
+	cleaned := strings.Split(data.CleanedText, "\n")
+	if len(cleaned) != len(lines) {
+		return nil
+	}
+	byLine := make(map[int][]Match)
+	for _, m := range data.Matches {
+		byLine[m.Line-1] = append(byLine[m.Line-1], m)
+	}
+	replace := make(map[string]func(string) string)
+	for _, d := range pf.tokenDetectors() {
+		if _, ok := replace[d.entity]; !ok {
+			replace[d.entity] = d.replace
+		}
+	}
+	replace[EntityAddress] = placeholder(pf.placeholderFor(EntityAddress))
+	if pf.vault != nil {
+		replace[EntityAddress] = func(column string) string {
+			return pf.vault.Token(EntityAddress, strings.TrimSpace(column))
+		}
+	}
+
+	blocks := splitBlocks(lines)
+	offset := 0
+	starts := make([]int, len(lines))
+	for i, line := range lines {
+		starts[i] = offset
+		offset += len(line) + 1
+	}
+	for i := range blocks {
+		b := &blocks[i]
+		b.MatchCounts = make(map[string]int)
+		for n := b.StartLine - 1; n < b.EndLine; n++ {
+			if b.to == 0 && b.from == 0 {
+				b.Lines = append(b.Lines, strings.ReplaceAll(cleaned[n], "\f", ""))
+				for _, m := range byLine[n] {
+					b.MatchCounts[m.Type]++
+				}
+				continue
+			}
+			b.Lines = append(b.Lines, b.column(lines[n], starts[n], cleaned[n], byLine[n], replace))
+		}
+		if len(b.MatchCounts) > 0 {
+			b.Status = BlockRedacted
+		} else {
+			b.Status = BlockClean
+		}
+	}
+	return blocks
+}
+
+// column returns the block's column of line, which starts at byte lineStart of the text,
+// redacted with the line's matches. A whole-line match leaves the cleaned line, which is then
+// the placeholder, in both columns; the token matches inside an address column are replaced
+// with it.
+func (b *Block) column(line string, lineStart int, cleaned string, matches []Match, replace map[string]func(string) string) string {
+	matches = slices.Clone(matches)
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End > matches[j].End
+	})
+	from, to := min(b.from, len(line)), len(line)
+	if b.to > 0 {
+		to = min(b.to, len(line))
+	}
+	// A match crossing the column boundary belongs to the column it starts in.
+	for _, m := range matches {
+		s, e := m.Start-lineStart, m.End-lineStart
+		if s < from && e > from {
+			from = min(e, to)
+		}
+		if s < to && e > to {
+			to = e
+		}
+	}
+	for _, m := range matches {
+		if (m.Entity == EntityAddress || m.Entity == EntityOrganization) && m.Start == lineStart && m.End == lineStart+len(line) {
+			for _, m := range matches {
+				b.MatchCounts[m.Type]++
+			}
+			return strings.TrimSpace(cleaned)
+		}
+	}
+	var out strings.Builder
+	prev := from
+	for _, m := range matches {
+		s, e := m.Start-lineStart, m.End-lineStart
+		if s < from || s >= to {
+			continue
+		}
+		b.MatchCounts[m.Type]++
+		if s < prev {
+			continue
+		}
+		out.WriteString(line[prev:s])
+		if r := replace[m.Entity]; r != nil {
+			out.WriteString(r(line[s:e]))
+		} else {
+			out.WriteString(defaultDetectorPlaceholder)
+		}
+		prev = e
+	}
+	out.WriteString(line[prev:to])
+	return strings.TrimRight(strings.ReplaceAll(out.String(), "\f", ""), " ")
+}
+
+// splitBlocks assigns the lines of every certificate to blocks by their section headings.
+func splitBlocks(lines []string) []Block {
+	var blocks []Block
+	starts := certificateStarts(lines)
+	for c, start := range starts {
+		end := len(lines)
+		if c+1 < len(starts) {
+			end = starts[c+1]
+		}
+		open := func(kind string, n, from, to int) {
+			blocks = append(blocks, Block{Kind: kind, Certificate: c + 1, StartLine: n + 1, EndLine: n + 1, from: from, to: to})
+		}
+		open(BlockHeader, start, 0, 0)
+		columns := false
+		for n := start; n < end; n++ {
+			line := lines[n]
+			kind := ""
+			switch {
+			case employerLabelPattern.MatchString(line):
+				if loc := employeeLabelPattern.FindStringIndex(line); loc != nil {
+					open(BlockEmployer, n, 0, loc[0])
+					open(BlockEmployee, n, loc[0], 0)
+				} else {
+					open(BlockEmployer, n, 0, 0)
+				}
+				columns = true
+				continue
+			case columns && certificateDetailsPattern.MatchString(line):
+				kind = BlockHeader
+			case quarterTablePattern.MatchString(line):
+				kind = BlockQuarterTable
+			case partBPattern.MatchString(line):
+				kind = BlockSalaryDetails
+			case verificationPattern.MatchString(line):
+				kind = BlockVerification
+			case annexurePattern.MatchString(line):
+				kind = BlockAnnexure
+			}
+			last := &blocks[len(blocks)-1]
+			if n > start && kind != "" && kind != last.Kind {
+				columns = false
+				open(kind, n, 0, 0)
+				continue
+			}
+			// Extend the open block, and its column neighbour.
+			last.EndLine = n + 1
+			if len(blocks) > 1 && blocks[len(blocks)-2].Kind == BlockEmployer && last.Kind == BlockEmployee {
+				blocks[len(blocks)-2].EndLine = n + 1
+			}
+		}
+	}
+	return blocks
+}
+
+// blockModelReport is the document written by SaveBlockModel.
+type blockModelReport struct {
+	RemovedFields   []string         `json:"removed_fields"`
+	ProcessingBasis *ProcessingBasis `json:"processing_basis,omitempty"`
+	Blocks          []Block          `json:"blocks"`
+}
+
+// SaveBlockModel writes the block model of the filtered data as indented JSON, so renderers
+// can rebuild a structured view of the certificate instead of a flat text dump.
+func SaveBlockModel(data FilteredData, outputFile string) error {
+	b, err := json.MarshalIndent(blockModelReport{
+		RemovedFields:   data.RemovedFields,
+		ProcessingBasis: data.ProcessingBasis,
+		Blocks:          data.Blocks,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode block model: %v", err)
+	}
+	if err := WriteOutput(outputFile, append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write block model: %v", err)
+	}
+	return nil
+}
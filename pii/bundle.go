@@ -0,0 +1,123 @@
+package pii
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// formHeaderPattern matches the "FORM NO. 16" title printed at the top of every certificate.
+	formHeaderPattern = regexp.MustCompile(`(?i)^\s*FORM\s+NO\.?\s*16\b`)
+	// partAPattern marks the first part of a certificate; Part B pages repeat the form title
+	// but belong to the certificate that precedes them.
+	partAPattern = regexp.MustCompile(`(?i)^\s*PART\s*[-–]?\s*A\b`)
+
+	certificateNoPattern  = regexp.MustCompile(`(?i)Certificate\s+No\.?\s*:?\s*([A-Z0-9]{4,})`)
+	assessmentYearPattern = regexp.MustCompile(`\b(20\d{2}-\d{2})\b`)
+	periodPattern         = regexp.MustCompile(`(?i)\bFrom\s+(\d{1,2}[-/][A-Za-z0-9]{2,3}[-/]\d{2,4})\s+To\s+(\d{1,2}[-/][A-Za-z0-9]{2,3}[-/]\d{2,4})`)
+)
+
+// partAWindow is how many lines after a form title are searched for the PART A marker.
+const partAWindow = 5
+
+// Certificate is one logical Form 16 inside a PDF. Employees who changed jobs during the year
+// often receive a single PDF with one certificate per employer.
+type Certificate struct {
+	Index int
+	Text  string
+	// Fields holds identifying data used to tell the certificates apart: certificate number,
+	// assessment year and period with the employer. The employer TAN is masked.
+	Fields map[string]string
+}
+
+// CertificateFindings summarises the redaction of one certificate within a bundle.
+type CertificateFindings struct {
+	Index       int               `json:"index"`
+	Fields      map[string]string `json:"fields"`
+	Categories  []string          `json:"categories"`
+	MatchCounts map[string]int    `json:"match_counts"`
+}
+
+// SplitCertificates splits text at every form title that is followed by a PART A marker.
+// Text without any recognisable boundary is returned as a single certificate.
+func (pf *Filter) SplitCertificates(text string) []Certificate {
+	lines := strings.Split(text, "\n")
+	starts := certificateStarts(lines)
+	certs := make([]Certificate, len(starts))
+	for i, start := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		body := strings.Join(lines[start:end], "\n")
+		if end < len(lines) {
+			body += "\n"
+		}
+		certs[i] = Certificate{Index: i + 1, Text: body, Fields: pf.certificateFields(body)}
+	}
+	return certs
+}
+
+// certificateStarts returns the first line of every certificate; the first is always 0.
+func certificateStarts(lines []string) []int {
+	var starts []int
+	for i, line := range lines {
+		if !formHeaderPattern.MatchString(line) {
+			continue
+		}
+		for k := i + 1; k < len(lines) && k <= i+partAWindow; k++ {
+			if partAPattern.MatchString(lines[k]) {
+				starts = append(starts, i)
+				break
+			}
+		}
+	}
+	// Anything before the first title (a cover page, say) belongs to the first certificate.
+	if len(starts) == 0 {
+		return []int{0}
+	}
+	starts[0] = 0
+	return starts
+}
+
+// certificateTexts returns the text of every certificate in text, split as by
+// SplitCertificates.
+func certificateTexts(text string) []string {
+	lines := strings.Split(text, "\n")
+	starts := certificateStarts(lines)
+	texts := make([]string, len(starts))
+	for i, start := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		texts[i] = strings.Join(lines[start:end], "\n")
+	}
+	return texts
+}
+
+func (pf *Filter) certificateFields(text string) map[string]string {
+	fields := make(map[string]string)
+	if m := certificateNoPattern.FindStringSubmatch(text); m != nil {
+		fields["Certificate No."] = m[1]
+	}
+	if m := assessmentYearPattern.FindStringSubmatch(text); m != nil {
+		fields["Assessment Year"] = m[1]
+	}
+	if m := periodPattern.FindStringSubmatch(text); m != nil {
+		fields["Period"] = m[1] + " to " + m[2]
+	}
+	if tan := pf.TANPattern.FindString(text); tan != "" {
+		fields["Employer TAN"] = maskSample(tan)
+	}
+	return fields
+}
+
+// CertificatePath returns the output name of one certificate when -split-employers is set:
+// filtered_output.txt -> filtered_output_employer2.txt.
+func CertificatePath(path string, index int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s_employer%d%s", strings.TrimSuffix(path, ext), index, ext)
+}
@@ -0,0 +1,125 @@
+package pii
+
+import "strings"
+
+// Finding is a detector match offered to the callback of FilterWithCallback before it is
+// applied. Unlike Match, it always carries the matched text: the callback runs in-process
+// and needs it to decide.
+type Finding struct {
+	Entity   string
+	Type     string
+	Severity Severity
+	// Line is 1-based; Start and End are byte offsets into the text passed to
+	// FilterWithCallback. Organisation findings cover the whole line, address findings the
+	// line or its address columns.
+	Line  int
+	Start int
+	End   int
+	Text  string
+	// Replacement is what the filter writes when the finding is redacted.
+	Replacement string
+	// Confidence is the score of the finding, as in Match.
+	Confidence float64
+	// Explanation is set under WithExplain, as in Match.
+	Explanation *Explanation
+}
+
+// Action is a callback's decision on a Finding.
+type Action struct {
+	keep        bool
+	custom      bool
+	replacement string
+}
+
+var (
+	// ActionRedact applies the filter's own replacement.
+	ActionRedact = Action{}
+	// ActionKeep leaves the text as it is; the finding is not counted or reported.
+	ActionKeep = Action{keep: true}
+)
+
+// ActionReplace redacts the finding with s instead of the filter's replacement.
+func ActionReplace(s string) Action {
+	return Action{custom: true, replacement: s}
+}
+
+// FilterWithCallback is FilterPII with a decision per finding: fn is called for every match,
+// in text order, and its Action redacts it, keeps it or replaces it with a custom string.
+// Kept findings are left out of Matches and MatchCounts. Retained values (GSTRetain) and allowlisted
+// values are not offered. fn runs on the caller's goroutine.
+func (pf *Filter) FilterWithCallback(text string, fn func(Finding) Action) FilteredData {
+	if fn == nil {
+		return pf.FilterPII(text)
+	}
+	doc := pf.forDocument(text)
+	result := doc.filterText(text, fn)
+	doc.finish(text, &result)
+	return result
+}
+
+// offerSpans passes the spans of line n (0-based, starting at byte lineStart) to fn and
+// returns those it does not keep, with custom replacements applied.
+func offerSpans(fn func(Finding) Action, spans []span, n, lineStart int, line string) []span {
+	kept := spans[:0]
+	for _, s := range spans {
+		if s.retainAs != "" {
+			kept = append(kept, s)
+			continue
+		}
+		match := line[s.start:s.end]
+		a := fn(Finding{
+			Entity:      s.detector.entity,
+			Type:        s.detector.label,
+			Severity:    s.detector.severity,
+			Line:        n + 1,
+			Start:       lineStart + s.start,
+			End:         lineStart + s.end,
+			Text:        match,
+			Replacement: s.detector.replace(match),
+			Confidence:  s.confidence,
+			Explanation: s.explanation,
+		})
+		if a.keep {
+			continue
+		}
+		s.replacement, s.custom = a.replacement, a.custom
+		kept = append(kept, s)
+	}
+	return kept
+}
+
+// offerLine passes an address or organisation finding, covering line[start:end] (the whole
+// line or its address columns), to fn, if set, and returns its Match and replacement, or false
+// when it is kept. matched is the text the check matched.
+func (pf *Filter) offerLine(fn func(Finding) Action, entity, label string, n, lineStart int, line string, start, end int, matched string) (Match, string, bool) {
+	m := pf.newMatch(entity, label, n, lineStart, line, start, end, lineConfidence[entity])
+	if pf.explain {
+		m.Explanation = pf.explainLine(entity, matched)
+	}
+	replacement := pf.placeholderFor(entity)
+	if pf.vault != nil {
+		replacement = pf.vault.Token(entity, strings.TrimSpace(line[start:end]))
+	}
+	if fn == nil {
+		return m, replacement, true
+	}
+	a := fn(Finding{
+		Entity:      entity,
+		Type:        label,
+		Severity:    defaultSeverities[entity],
+		Line:        n + 1,
+		Start:       lineStart + start,
+		End:         lineStart + end,
+		Text:        line[start:end],
+		Replacement: replacement,
+		Confidence:  lineConfidence[entity],
+		Explanation: m.Explanation,
+	})
+	switch {
+	case a.keep:
+		return m, "", false
+	case a.custom:
+		return m, a.replacement, true
+	}
+	return m, replacement, true
+}
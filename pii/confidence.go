@@ -0,0 +1,134 @@
+package pii
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Confidence scoring. A match starts from the strength of its detector's pattern alone, loses
+// embeddedPenalty when it is cut out of a longer run of letters or digits (ten digits of a
+// twelve-digit challan number), gains contextBoost when a corroborating label is on its line
+// or the lines above, and gains or loses checksumBoost when its format carries a check
+// character. Scores are clamped to [0, 1] and rounded to two decimals.
+const (
+	embeddedPenalty = 0.3
+	contextBoost    = 0.4
+	checksumBoost   = 0.15
+	// defaultConfidence scores the matches of custom detectors, and those of third-party
+	// recognizers that leave Match.Confidence unset.
+	defaultConfidence = 0.8
+)
+
+// detectorScore grades the matches of one built-in entity type.
+type detectorScore struct {
+	base     float64
+	context  *regexp.Regexp
+	checksum func(string) bool
+}
+
+// detectorScores holds the grading of the built-in detectors. The 12-digit Aadhaar rule is
+// the weakest: challan, receipt and bank reference numbers share its shape, so without an
+// Aadhaar label it stays at or below 0.5 even when the Verhoeff check digit happens to fit.
+var detectorScores = map[string]detectorScore{
+	EntityPFAccount:        {base: 0.9, context: regexp.MustCompile(`(?i)\bE?PF\b|provident`)},
+	EntityDrivingLicence:   {base: 0.6, context: regexp.MustCompile(`(?i)licen[cs]e|\bDL\b`)},
+	EntityBankAccount:      {base: 0.85, context: bankAccountLabelPattern},
+	EntityFolio:            {base: 0.85, context: folioLabelPattern},
+	EntityChallan:          {base: 0.8, context: challanLabelPattern},
+	EntityEmployeeID:       {base: 0.9, context: employeeIDLabelPattern},
+	EntityESI:              {base: 0.9, context: esiLabelPattern},
+	EntityIFSC:             {base: 0.85, context: ifscLabelPattern},
+	EntityPreviousEmployer: {base: 0.85, context: previousEmployerLabelPattern},
+	EntityDesignation:      {base: 0.8, context: designationLabelPattern},
+	EntityPassport:         {base: 0.5, context: regexp.MustCompile(`(?i)passport`)},
+	EntityVoterID:          {base: 0.5, context: regexp.MustCompile(`(?i)voter|\bEPIC\b|election`)},
+	EntityPhone:            {base: 0.6, context: regexp.MustCompile(`(?i)phone|mobile|\bmob\b|\btel\b|contact`)},
+	EntityEmail:            {base: 0.95},
+	EntityUAN:              {base: 0.9, context: uanLabelPattern},
+	EntityAadhaar:          {base: 0.35, context: aadhaarLabelPattern, checksum: ValidAadhaar},
+	EntityPAN:              {base: 0.55, context: panContextPattern, checksum: ValidPAN},
+	EntityGST:              {base: 0.7, context: regexp.MustCompile(`(?i)\bGST`), checksum: validGSTIN},
+	EntityTAN:              {base: 0.7, context: regexp.MustCompile(`(?i)\bTAN\b`)},
+	EntityPINCode:          {base: 0.7, context: pinLabelPattern},
+	EntityDOB:              {base: 0.85},
+	EntityDate:             {base: 0.6},
+	EntityName:             {base: 0.7},
+	EntityIndicName:        {base: 0.8, context: indicNameLabelPattern},
+	EntityIndicAddress:     {base: 0.75},
+	EntitySSN:              {base: 0.75, context: regexp.MustCompile(`(?i)\bSSN\b|social\s+security`)},
+	EntityNINO:             {base: 0.8, context: regexp.MustCompile(`(?i)\bNI(?:NO)?\b|national\s+insurance`)},
+	EntityTFN:              {base: 0.6, context: tfnLabelPattern, checksum: ValidTFN},
+	EntityCustom:           {base: 1}, // named by the user, so never dropped by a minimum
+}
+
+// lineConfidence scores the whole-line address and organisation checks, which have no
+// per-match evidence to grade.
+var lineConfidence = map[string]float64{
+	EntityAddress:      0.7,
+	EntityOrganization: 0.8,
+}
+
+// confidence scores the match of d at bytes start to end of lines[n].
+func (d *tokenDetector) confidence(lines []string, n, start, end int) float64 {
+	if d.recognizer != nil {
+		if c := d.scores[[2]int{n, start}]; c > 0 {
+			return c
+		}
+		return defaultConfidence
+	}
+	s, ok := detectorScores[d.entity]
+	if d.score != nil {
+		s, ok = *d.score, true
+	}
+	if !ok {
+		return defaultConfidence
+	}
+	line, match := lines[n], lines[n][start:end]
+	score := s.base
+	if start > 0 && isAlnum(line[start-1]) || end < len(line) && isAlnum(line[end]) {
+		score -= embeddedPenalty
+	}
+	if s.context != nil && hasContext(lines, n, s.context, d.window) {
+		score += contextBoost
+	}
+	if s.checksum != nil {
+		if s.checksum(match) {
+			score += checksumBoost
+		} else {
+			score -= checksumBoost
+		}
+	}
+	return math.Round(min(max(score, 0), 1)*100) / 100
+}
+
+// lineEnabled reports whether the whole-line check of entity (address or organisation) runs:
+// it is not disabled and its score meets the minimum confidence.
+func (pf *Filter) lineEnabled(entity string) bool {
+	return !pf.isDisabled(entity) && lineConfidence[entity] >= pf.minConfidence
+}
+
+// isAlnum reports whether c is an ASCII letter or digit.
+func isAlnum(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// gstinChars is the alphabet of the GSTIN check character.
+const gstinChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// validGSTIN reports whether the last character of a GSTIN is its mod-36 check character.
+func validGSTIN(s string) bool {
+	if len(s) != 15 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 14; i++ {
+		v := strings.IndexByte(gstinChars, s[i])
+		if v < 0 {
+			return false
+		}
+		p := v * (1 + i%2)
+		sum += p/36 + p%36
+	}
+	return s[14] == gstinChars[(36-sum%36)%36]
+}
@@ -0,0 +1,243 @@
+package pii
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config tunes detection per organisation without code changes. It is loaded from a YAML or
+// JSON file and merged with the built-in defaults through Options:
+//
+//	strict: true
+//	email_policy: keep-domain
+//	phone_regions: [IN, GB, US]
+//	numbered_placeholders: true
+//	allowlist: [BLRI01234E]
+//	glossary: [Acme Payroll, Flexi Basket]
+//	denylist: [Project Zeus, '/PRJ-\d{4}/']
+//	packs: [us, gb]
+//	disabled: [tan]
+//	placeholders:
+//	  pan: "[PAN]"
+//	address_keywords: [House, Road, Nagar]
+//	detectors:
+//	  - type: employee_id
+//	    label: Employee IDs
+//	    pattern: 'EMP\d{6}'
+//	    placeholder: "[EMP_ID]"
+//	    severity: high
+//	volume_rules:
+//	  - {document_type: form16, entity: employee_id, min: 1}
+//	pipeline: [normalize, detect, {name: scrub, command: [python3, scrub.py]}, verify, outputs]
+type Config struct {
+	// Strict enables structural validation such as the Aadhaar checksum.
+	Strict bool `json:"strict" yaml:"strict"`
+	// EmailPolicy keeps or tokenises the domains of e-mail addresses: redact (default),
+	// keep-domain or org-token.
+	EmailPolicy string `json:"email_policy" yaml:"email_policy"`
+	// PhoneRegions lists the regions whose national phone numbers are detected (default IN).
+	PhoneRegions []string `json:"phone_regions" yaml:"phone_regions"`
+	// NumberedPlaceholders writes [PAN_1], [PAN_2]... per distinct value instead of the
+	// placeholders (see WithNumberedPlaceholders).
+	NumberedPlaceholders bool `json:"numbered_placeholders" yaml:"numbered_placeholders"`
+	// Packs enables optional detector packs for foreign identifiers (see DetectorPackNames).
+	Packs []string `json:"packs" yaml:"packs"`
+	// Denylist lists extra values redacted as [CUSTOM_REDACTED]: literals, or regular
+	// expressions between slashes (see CompileDenylist).
+	Denylist []string `json:"denylist" yaml:"denylist"`
+	// Allowlist lists values that are never redacted (see WithAllowlist).
+	Allowlist []string `json:"allowlist" yaml:"allowlist"`
+	// Glossary lists terms the dictionary stage leaves, on top of the built-in tax glossary
+	// (see WithGlossary).
+	Glossary []string `json:"glossary" yaml:"glossary"`
+	// Disabled lists entity types (built-in or custom) that are not detected.
+	Disabled []string `json:"disabled" yaml:"disabled"`
+	// Placeholders overrides the replacement text per entity type.
+	Placeholders map[string]string `json:"placeholders" yaml:"placeholders"`
+	// AddressKeywords, when not empty, replaces the built-in address keyword list.
+	AddressKeywords []string `json:"address_keywords" yaml:"address_keywords"`
+	// Detectors adds custom regex detectors after the built-in ones.
+	Detectors []DetectorConfig `json:"detectors" yaml:"detectors"`
+	// VolumeRules adds or replaces expected finding ranges per document type.
+	VolumeRules []VolumeRule `json:"volume_rules" yaml:"volume_rules"`
+	// Pipeline, when not empty, replaces DefaultStages: stages may be dropped (dictionary,
+	// normalize), reordered around detect, or added as plugin stages.
+	Pipeline Stages `json:"pipeline" yaml:"pipeline"`
+}
+
+// DetectorConfig is one custom detector of a Config.
+type DetectorConfig struct {
+	Type        string `json:"type" yaml:"type"`
+	Label       string `json:"label" yaml:"label"`
+	Pattern     string `json:"pattern" yaml:"pattern"`
+	Placeholder string `json:"placeholder" yaml:"placeholder"`
+	// Severity is low (default), medium or high.
+	Severity string `json:"severity" yaml:"severity"`
+	// Enabled defaults to true; set it to false to keep a detector in the file but off.
+	Enabled *bool `json:"enabled" yaml:"enabled"`
+}
+
+// LoadConfig reads a config file; files ending in .json are parsed as JSON and everything
+// else as YAML. The config is validated so that mistakes are reported before any document
+// is processed.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	return parseConfig(path, b)
+}
+
+// parseConfig parses and validates the contents b of the config file at path.
+func parseConfig(path string, b []byte) (*Config, error) {
+	var c Config
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &c)
+	} else {
+		err = yaml.Unmarshal(b, &c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	if _, err := c.Options(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	return &c, nil
+}
+
+// parseSeverity maps a severity name to its value; the empty name is SeverityLow.
+func parseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "", "low":
+		return SeverityLow, nil
+	case "medium":
+		return SeverityMedium, nil
+	case "high":
+		return SeverityHigh, nil
+	}
+	return 0, fmt.Errorf("unknown severity %q (expected low, medium or high)", s)
+}
+
+// Options converts the config into filter options. Custom detector types must not reuse a
+// built-in type, and every entity type named in Disabled or Placeholders must exist.
+func (c *Config) Options() ([]Option, error) {
+	known := builtinEntityTypes()
+
+	var opts []Option
+	for i, d := range c.Detectors {
+		if d.Type == "" || d.Pattern == "" {
+			return nil, fmt.Errorf("detector %d: type and pattern are required", i+1)
+		}
+		if known[d.Type] {
+			return nil, fmt.Errorf("detector %d: type %q is already defined", i+1, d.Type)
+		}
+		known[d.Type] = true
+		pattern, err := regexp.Compile(d.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("detector %q: %v", d.Type, err)
+		}
+		severity, err := parseSeverity(d.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("detector %q: %v", d.Type, err)
+		}
+		if d.Enabled != nil && !*d.Enabled {
+			continue
+		}
+		opts = append(opts, WithExtraDetector(Detector{
+			Type:        d.Type,
+			Label:       d.Label,
+			Pattern:     pattern,
+			Placeholder: d.Placeholder,
+			Severity:    severity,
+		}))
+	}
+	for _, entity := range c.Disabled {
+		if !known[entity] {
+			return nil, fmt.Errorf("disabled: unknown entity type %q", entity)
+		}
+	}
+	if len(c.Disabled) > 0 {
+		opts = append(opts, WithDisabled(c.Disabled...))
+	}
+	for entity, p := range c.Placeholders {
+		if !known[entity] {
+			return nil, fmt.Errorf("placeholders: unknown entity type %q", entity)
+		}
+		opts = append(opts, WithPlaceholder(entity, p))
+	}
+	if len(c.AddressKeywords) > 0 {
+		opts = append(opts, WithAddressKeywords(c.AddressKeywords...))
+	}
+	for _, r := range c.VolumeRules {
+		if !known[r.Entity] {
+			return nil, fmt.Errorf("volume_rules: unknown entity type %q", r.Entity)
+		}
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("volume_rules: %v", err)
+		}
+	}
+	if len(c.VolumeRules) > 0 {
+		opts = append(opts, WithVolumeRules(c.VolumeRules...))
+	}
+	if c.Strict {
+		opts = append(opts, WithStrictValidation(true))
+	}
+	if c.NumberedPlaceholders {
+		opts = append(opts, WithNumberedPlaceholders(true))
+	}
+	if c.EmailPolicy != "" {
+		policy, err := ParseEmailPolicy(c.EmailPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("email_policy: %v", err)
+		}
+		opts = append(opts, WithEmailPolicy(policy))
+	}
+	if len(c.PhoneRegions) > 0 {
+		regions, err := ParsePhoneRegions(strings.Join(c.PhoneRegions, ","))
+		if err != nil {
+			return nil, fmt.Errorf("phone_regions: %v", err)
+		}
+		opts = append(opts, WithPhoneRegions(regions...))
+	}
+	if len(c.Packs) > 0 {
+		packs, err := ParseDetectorPacks(strings.Join(c.Packs, ","))
+		if err != nil {
+			return nil, fmt.Errorf("packs: %v", err)
+		}
+		opts = append(opts, WithDetectorPacks(packs...))
+	}
+	if len(c.Denylist) > 0 {
+		pattern, err := CompileDenylist(c.Denylist)
+		if err != nil {
+			return nil, fmt.Errorf("denylist: %v", err)
+		}
+		opts = append(opts, WithDenylist(pattern))
+	}
+	if len(c.Allowlist) > 0 {
+		opts = append(opts, WithAllowlist(c.Allowlist...))
+	}
+	if len(c.Glossary) > 0 {
+		opts = append(opts, WithGlossary(c.Glossary...))
+	}
+	if len(c.Pipeline) > 0 {
+		if err := c.Pipeline.validate(); err != nil {
+			return nil, fmt.Errorf("pipeline: %v", err)
+		}
+	}
+	return opts, nil
+}
+
+// Stages returns the pipeline of the config, or DefaultStages when it defines none.
+func (c *Config) Stages() Stages {
+	if c == nil || len(c.Pipeline) == 0 {
+		return DefaultStages
+	}
+	return c.Pipeline
+}
@@ -0,0 +1,264 @@
+package pii
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// employeePANLabel marks the column holding the employee's PAN in Part A.
+	employeePANLabel = regexp.MustCompile(`(?i)PAN\s+of\s+the\s+Employee`)
+	// totalRowPattern matches the "Total (Rs.)" row of the Part A quarterly summary.
+	totalRowPattern = regexp.MustCompile(`(?i)^\s*Total\s*\(Rs\.?\)`)
+	// figurePattern matches the amounts in a summary row.
+	figurePattern = regexp.MustCompile(`\d[\d,]*\.\d{2}\b`)
+)
+
+// periodLayouts are the date formats Form 16 prints periods in.
+var periodLayouts = []string{"02-Jan-2006", "2-Jan-2006", "02/01/2006", "02-01-2006", "02.01.2006"}
+
+// CertificateRecord is the structured data of one certificate used to check the documents of
+// an employee against each other. Identifiers are kept only in unexported fields; the
+// employer TAN is reported masked and the employee only by token.
+type CertificateRecord struct {
+	Document       string
+	Index          int
+	AssessmentYear string
+	EmployerTAN    string
+	PeriodFrom     string
+	PeriodTo       string
+	// TotalPaid and TotalTax are the amount paid/credited and the tax deducted from the
+	// "Total (Rs.)" row of Part A; HasTotals is false when the row was not found.
+	TotalPaid float64
+	TotalTax  float64
+	HasTotals bool
+
+	employeePAN string
+	employerTAN string
+	from, to    time.Time
+}
+
+// CertificateRecords extracts a record for every certificate in the text of document.
+func (pf *Filter) CertificateRecords(document, text string) []CertificateRecord {
+	var records []CertificateRecord
+	for _, c := range pf.SplitCertificates(text) {
+		r := CertificateRecord{
+			Document:       document,
+			Index:          c.Index,
+			AssessmentYear: c.Fields["Assessment Year"],
+			EmployerTAN:    c.Fields["Employer TAN"],
+			employerTAN:    pf.TANPattern.FindString(c.Text),
+			employeePAN:    pf.employeePAN(c.Text),
+		}
+		if m := periodPattern.FindStringSubmatch(c.Text); m != nil {
+			r.PeriodFrom, r.PeriodTo = m[1], m[2]
+			r.from, r.to = parsePeriodDate(m[1]), parsePeriodDate(m[2])
+		}
+		r.TotalPaid, r.TotalTax, r.HasTotals = partATotals(c.Text)
+		records = append(records, r)
+	}
+	return records
+}
+
+// employeePAN returns the PAN printed in the "PAN of the Employee" column: on the label's
+// line after the label, or in the few lines below it starting near the label's column.
+func (pf *Filter) employeePAN(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		loc := employeePANLabel.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		if pan := pf.PANPattern.FindString(line[loc[1]:]); ValidPAN(pan) {
+			return pan
+		}
+		for k := i + 1; k < len(lines) && k <= i+contextWindow; k++ {
+			for _, m := range pf.PANPattern.FindAllStringIndex(lines[k], -1) {
+				if d := m[0] - loc[0]; d >= -headerTolerance && d <= loc[1]-loc[0] && ValidPAN(lines[k][m[0]:m[1]]) {
+					return lines[k][m[0]:m[1]]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// partATotals reads the amount paid and tax deducted from the first "Total (Rs.)" row.
+func partATotals(text string) (paid, tax float64, ok bool) {
+	for _, line := range strings.Split(text, "\n") {
+		if !totalRowPattern.MatchString(line) {
+			continue
+		}
+		figures := figurePattern.FindAllString(line, -1)
+		if len(figures) < 2 {
+			return 0, 0, false
+		}
+		paid, _ = strconv.ParseFloat(strings.ReplaceAll(figures[0], ",", ""), 64)
+		tax, _ = strconv.ParseFloat(strings.ReplaceAll(figures[1], ",", ""), 64)
+		return paid, tax, true
+	}
+	return 0, 0, false
+}
+
+func parsePeriodDate(s string) time.Time {
+	for _, layout := range periodLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// EmployeeBundle is the consistency check of all certificates of one employee.
+type EmployeeBundle struct {
+	// Employee is a keyed token of the employee PAN; it links documents without revealing
+	// the PAN.
+	Employee     string
+	Certificates []CertificateRecord
+	Issues       []string
+}
+
+// EmployeeToken returns the token identifying an employee PAN under key.
+func EmployeeToken(key []byte, pan string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(pan))
+	return hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// CheckConsistency groups certificates by employee and checks that each employee's bundle
+// belongs together: one assessment year, the same totals wherever the same employer appears
+// twice, and employment periods that follow each other without gaps or overlaps. Records
+// without an employee PAN are returned separately.
+func CheckConsistency(records []CertificateRecord, key []byte) (bundles []EmployeeBundle, unassigned []CertificateRecord) {
+	byEmployee := make(map[string]*EmployeeBundle)
+	for _, r := range records {
+		if r.employeePAN == "" {
+			unassigned = append(unassigned, r)
+			continue
+		}
+		token := EmployeeToken(key, r.employeePAN)
+		b, ok := byEmployee[token]
+		if !ok {
+			b = &EmployeeBundle{Employee: token}
+			byEmployee[token] = b
+		}
+		b.Certificates = append(b.Certificates, r)
+	}
+	for _, b := range byEmployee {
+		b.Issues = checkBundle(b.Certificates)
+		bundles = append(bundles, *b)
+	}
+	sort.Slice(bundles, func(i, j int) bool { return bundles[i].Employee < bundles[j].Employee })
+	return bundles, unassigned
+}
+
+func checkBundle(certs []CertificateRecord) []string {
+	var issues []string
+	var years []string
+	for _, c := range certs {
+		if c.AssessmentYear != "" && !slices.Contains(years, c.AssessmentYear) {
+			years = append(years, c.AssessmentYear)
+		}
+	}
+	if len(years) > 1 {
+		sort.Strings(years)
+		issues = append(issues, fmt.Sprintf("mixed assessment years: %s", strings.Join(years, ", ")))
+	}
+
+	// The same employer twice is a duplicate of one certificate; its figures must agree.
+	byEmployer := make(map[string]CertificateRecord)
+	var periods []CertificateRecord
+	for _, c := range certs {
+		if c.employerTAN == "" {
+			issues = append(issues, fmt.Sprintf("%s #%d: employer TAN not found", c.Document, c.Index))
+			continue
+		}
+		prev, seen := byEmployer[c.employerTAN]
+		if !seen {
+			byEmployer[c.employerTAN] = c
+			periods = append(periods, c)
+			continue
+		}
+		if prev.PeriodFrom != c.PeriodFrom || prev.PeriodTo != c.PeriodTo {
+			issues = append(issues, fmt.Sprintf("employer %s appears with different periods in %s #%d and %s #%d",
+				c.EmployerTAN, prev.Document, prev.Index, c.Document, c.Index))
+		}
+		if prev.HasTotals && c.HasTotals && (prev.TotalPaid != c.TotalPaid || prev.TotalTax != c.TotalTax) {
+			issues = append(issues, fmt.Sprintf("employer %s has different totals in %s #%d and %s #%d",
+				c.EmployerTAN, prev.Document, prev.Index, c.Document, c.Index))
+		}
+	}
+
+	sort.SliceStable(periods, func(i, j int) bool { return periods[i].from.Before(periods[j].from) })
+	for i := 1; i < len(periods); i++ {
+		prev, cur := periods[i-1], periods[i]
+		if prev.to.IsZero() || cur.from.IsZero() {
+			continue
+		}
+		switch next := prev.to.AddDate(0, 0, 1); {
+		case cur.from.After(next):
+			issues = append(issues, fmt.Sprintf("gap between %s and %s", prev.PeriodTo, cur.PeriodFrom))
+		case cur.from.Before(next):
+			issues = append(issues, fmt.Sprintf("periods overlap: %s to %s and %s to %s",
+				prev.PeriodFrom, prev.PeriodTo, cur.PeriodFrom, cur.PeriodTo))
+		}
+	}
+	return issues
+}
+
+// SaveConsistencyReport writes the bundles and unassigned certificates as a text report.
+func SaveConsistencyReport(bundles []EmployeeBundle, unassigned []CertificateRecord, outputFile string) error {
+	file, err := CreateOutput(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create consistency report: %v", err)
+	}
+	defer file.Close()
+
+	file.WriteString("=== CONSISTENCY REPORT ===\n")
+	for _, b := range bundles {
+		file.WriteString(fmt.Sprintf("\nEmployee %s (%d certificates)\n", b.Employee, len(b.Certificates)))
+		for _, c := range b.Certificates {
+			file.WriteString("  " + c.summary() + "\n")
+		}
+		if len(b.Issues) == 0 {
+			file.WriteString("  Status: consistent\n")
+			continue
+		}
+		file.WriteString("  Status: INCONSISTENT\n")
+		for _, issue := range b.Issues {
+			file.WriteString(fmt.Sprintf("  - %s\n", issue))
+		}
+	}
+	if len(unassigned) > 0 {
+		file.WriteString("\nNo employee PAN found:\n")
+		for _, c := range unassigned {
+			file.WriteString("  " + c.summary() + "\n")
+		}
+	}
+	return nil
+}
+
+// summary describes a certificate on one line of the report.
+func (c CertificateRecord) summary() string {
+	s := fmt.Sprintf("%s #%d: AY %s, employer %s, %s to %s", c.Document, c.Index,
+		orUnknown(c.AssessmentYear), orUnknown(c.EmployerTAN), orUnknown(c.PeriodFrom), orUnknown(c.PeriodTo))
+	if c.HasTotals {
+		s += fmt.Sprintf(", paid %.2f, tax %.2f", c.TotalPaid, c.TotalTax)
+	}
+	return s
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "?"
+	}
+	return s
+}
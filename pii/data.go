@@ -0,0 +1,328 @@
+package pii
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultDataDir is the directory data sets are installed in and read from, relative to the
+// working directory like english_words.txt.
+const DefaultDataDir = "data"
+
+// DataRecordFile is the file of a data directory recording the installed data set (see
+// DataRecord). It is written last by UpdateDataSet, so a directory without it holds no data
+// set.
+const DataRecordFile = "data.json"
+
+// DataMaxAge is the age past which a data set is reported as stale: place names and
+// vocabulary drift, and a gazetteer that is never refreshed misses new districts.
+const DataMaxAge = 180 * 24 * time.Hour
+
+// maxDataFileSize bounds every file downloaded by UpdateDataSet.
+const maxDataFileSize = 64 << 20
+
+// dataFiles are the files a data set may hold, in the format of the preset bundle files of the
+// same name: the dictionary, the address gazetteer and the address keywords.
+var dataFiles = []string{PresetWordsFile, PresetPlacesFile, PresetAddressKeywordsFile}
+
+// ErrNoDataSet means a data directory has no DataRecordFile; the bundled gazetteer and
+// dictionary are used.
+var ErrNoDataSet = errors.New("no data set installed")
+
+// DataIndex is the index published at a data source URL: the version of the data set and the
+// hash of each of its files. Files are fetched from their URL, resolved against the index URL
+// and defaulting to their name, so the index and its files can be served from one directory.
+type DataIndex struct {
+	Version string `json:"version"`
+	// Published is when the data set was built; the age of a data set is counted from it.
+	Published time.Time `json:"published"`
+	// Profile is the country profile whose gazetteer and address keywords the files replace
+	// (default DefaultProfile).
+	Profile string          `json:"profile,omitempty"`
+	Files   []DataIndexFile `json:"files"`
+}
+
+// DataIndexFile is one file of a DataIndex.
+type DataIndexFile struct {
+	Name   string `json:"name"`
+	URL    string `json:"url,omitempty"`
+	SHA256 string `json:"sha256"`
+}
+
+// DataRecord is the DataRecordFile of an installed data set, and its entry in the manifest.
+type DataRecord struct {
+	Dir       string    `json:"dir"`
+	Version   string    `json:"version"`
+	Published time.Time `json:"published"`
+	Profile   string    `json:"profile"`
+	// Source and IndexSHA256 are the index URL and the pinned hash it was installed from.
+	Source      string       `json:"source"`
+	IndexSHA256 string       `json:"index_sha256"`
+	Updated     time.Time    `json:"updated"`
+	Files       []PresetFile `json:"files"`
+}
+
+// DataSet is an installed data set, as read by LoadDataSet.
+type DataSet struct {
+	DataRecord
+	// Words, Places and AddressKeywords are nil when the data set has no such file.
+	Words           map[string]struct{}
+	Places          []string
+	AddressKeywords []string
+}
+
+// LoadDataSet reads the data set installed in dir and checks that it is sound: every file
+// recorded must be present, unmodified (its SHA-256 is checked against the record) and valid.
+// A directory without a data set is reported as ErrNoDataSet.
+func LoadDataSet(dir string) (*DataSet, error) {
+	b, err := os.ReadFile(filepath.Join(dir, DataRecordFile))
+	if os.IsNotExist(err) {
+		return nil, ErrNoDataSet
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data record: %v", err)
+	}
+	d := &DataSet{}
+	if err := json.Unmarshal(b, &d.DataRecord); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", filepath.Join(dir, DataRecordFile), err)
+	}
+	d.Dir = dir
+	for _, f := range d.Files {
+		if !slices.Contains(dataFiles, f.Name) {
+			return nil, fmt.Errorf("data set %s: unknown file %q", d.Version, f.Name)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, f.Name))
+		if err != nil {
+			return nil, fmt.Errorf("data set %s: failed to read %s: %v", d.Version, f.Name, err)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, fmt.Errorf("data set %s: %s does not match its recorded sha256 (modified or partly updated)", d.Version, f.Name)
+		}
+		if err := d.add(f.Name, data); err != nil {
+			return nil, fmt.Errorf("data set %s: %v", d.Version, err)
+		}
+	}
+	return d, nil
+}
+
+// add validates the contents of the data file name and sets them on d.
+func (d *DataSet) add(name string, data []byte) error {
+	if !utf8.Valid(data) {
+		return fmt.Errorf("%s is not UTF-8 text", name)
+	}
+	switch name {
+	case PresetWordsFile:
+		words := listLines(data, false)
+		if len(words) == 0 {
+			return fmt.Errorf("%s is empty", name)
+		}
+		d.Words = make(map[string]struct{}, len(words))
+		for _, w := range words {
+			d.Words[strings.ToLower(w)] = struct{}{}
+		}
+	case PresetPlacesFile:
+		if d.Places = listLines(data, true); len(d.Places) == 0 {
+			return fmt.Errorf("%s is empty", name)
+		}
+	case PresetAddressKeywordsFile:
+		if d.AddressKeywords = listLines(data, true); len(d.AddressKeywords) == 0 {
+			return fmt.Errorf("%s is empty", name)
+		}
+	}
+	return nil
+}
+
+// Age returns how old the data set is at now, counted from when it was published.
+func (d *DataSet) Age(now time.Time) time.Duration {
+	return now.Sub(d.Published)
+}
+
+// Stale reports whether the data set is older than DataMaxAge at now.
+func (d *DataSet) Stale(now time.Time) bool {
+	return d.Age(now) > DataMaxAge
+}
+
+// Record returns the manifest entry of the data set.
+func (d *DataSet) Record() *DataRecord {
+	r := d.DataRecord
+	return &r
+}
+
+// Options returns the filter options of the data set for a run with the given profile: its
+// gazetteer and address keywords replace those of the profile they were published for, and
+// leave other profiles alone. Apply them after WithProfile and before config options, so that
+// a -config or -preset still wins. The dictionary is used by the run directly.
+func (d *DataSet) Options(profile string) []Option {
+	if !strings.EqualFold(profile, d.Profile) {
+		return nil
+	}
+	var opts []Option
+	if len(d.Places) > 0 {
+		opts = append(opts, WithPlaces(d.Places...))
+	}
+	if len(d.AddressKeywords) > 0 {
+		opts = append(opts, WithAddressKeywords(d.AddressKeywords...))
+	}
+	return opts
+}
+
+// UpdateDataSet installs in dir the data set whose index is published at source. The index
+// must hash to pin, and every file to the hash the index gives it, so neither a compromised
+// server nor a corrupted transfer can change the data the detectors use. Files are validated
+// before anything is written; the DataRecordFile is replaced last, so an interrupted update
+// leaves a data set that LoadDataSet refuses rather than a mix of versions. It returns the
+// record of the installed data set and whether it changed.
+func UpdateDataSet(ctx context.Context, client *http.Client, dir, source, pin string) (*DataRecord, bool, error) {
+	base, err := url.Parse(source)
+	if err != nil || (base.Scheme != "https" && base.Scheme != "http") {
+		return nil, false, fmt.Errorf("data source %q is not an http(s) URL", source)
+	}
+	b, err := fetchDataFile(ctx, client, source)
+	if err != nil {
+		return nil, false, err
+	}
+	sum := sha256.Sum256(b)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, pin) {
+		return nil, false, fmt.Errorf("data index sha256 %s does not match the pinned %s", got, pin)
+	}
+	var index DataIndex
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, false, fmt.Errorf("failed to parse data index: %v", err)
+	}
+	if index.Version == "" || len(index.Files) == 0 {
+		return nil, false, fmt.Errorf("data index has no version or no files")
+	}
+
+	rec := &DataRecord{
+		Dir:         dir,
+		Version:     index.Version,
+		Published:   index.Published,
+		Profile:     index.Profile,
+		Source:      source,
+		IndexSHA256: hex.EncodeToString(sum[:]),
+		Updated:     time.Now().UTC(),
+	}
+	if rec.Profile == "" {
+		rec.Profile = DefaultProfile
+	}
+	if current, err := LoadDataSet(dir); err == nil && current.Version == rec.Version && current.IndexSHA256 == rec.IndexSHA256 {
+		return current.Record(), false, nil
+	}
+
+	set := &DataSet{}
+	contents := make(map[string][]byte)
+	for _, f := range index.Files {
+		if !slices.Contains(dataFiles, f.Name) {
+			return nil, false, fmt.Errorf("data index: unknown file %q (expected %s)", f.Name, strings.Join(dataFiles, ", "))
+		}
+		if _, ok := contents[f.Name]; ok {
+			return nil, false, fmt.Errorf("data index: %s is listed twice", f.Name)
+		}
+		ref, err := url.Parse(cmp.Or(f.URL, f.Name))
+		if err != nil {
+			return nil, false, fmt.Errorf("data index: bad URL for %s: %v", f.Name, err)
+		}
+		data, err := fetchDataFile(ctx, client, base.ResolveReference(ref).String())
+		if err != nil {
+			return nil, false, err
+		}
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, f.SHA256) {
+			return nil, false, fmt.Errorf("%s: sha256 %s does not match the index (%s)", f.Name, got, f.SHA256)
+		}
+		if err := set.add(f.Name, data); err != nil {
+			return nil, false, err
+		}
+		contents[f.Name] = data
+		rec.Files = append(rec.Files, PresetFile{Name: f.Name, Size: len(data), SHA256: hex.EncodeToString(sum[:])})
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, false, fmt.Errorf("failed to create data directory: %v", err)
+	}
+	for _, name := range dataFiles {
+		path := filepath.Join(dir, name)
+		data, ok := contents[name]
+		if !ok {
+			// A file dropped from the data set is removed, so the bundled list is used again.
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, false, fmt.Errorf("failed to remove %s: %v", name, err)
+			}
+			continue
+		}
+		if err := replaceFile(path, data); err != nil {
+			return nil, false, err
+		}
+	}
+	b, err = json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode data record: %v", err)
+	}
+	if err := replaceFile(filepath.Join(dir, DataRecordFile), append(b, '\n')); err != nil {
+		return nil, false, err
+	}
+	return rec, true, nil
+}
+
+// fetchDataFile downloads rawURL with client, up to maxDataFileSize bytes.
+func fetchDataFile(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %v", rawURL, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", rawURL, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxDataFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %v", rawURL, err)
+	}
+	if len(b) > maxDataFileSize {
+		return nil, fmt.Errorf("failed to fetch %s: larger than %d MB", rawURL, maxDataFileSize>>20)
+	}
+	return b, nil
+}
+
+// replaceFile writes data to path through a temporary file renamed over it, so that runs
+// never read a partly written file.
+func replaceFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".data-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", filepath.Base(path), err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", filepath.Base(path), err)
+	}
+	return nil
+}
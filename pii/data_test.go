@@ -0,0 +1,120 @@
+package pii
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDataServer serves a data index listing files, and files at their names. The index
+// gives each file the hash of indexed, which defaults to its served contents.
+func testDataServer(t *testing.T, files, indexed map[string]string) (*httptest.Server, string) {
+	t.Helper()
+	index := DataIndex{Version: "2026.10", Published: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}
+	for name, data := range files {
+		if s, ok := indexed[name]; ok {
+			data = s
+		}
+		sum := sha256.Sum256([]byte(data))
+		index.Files = append(index.Files, DataIndexFile{Name: name, SHA256: hex.EncodeToString(sum[:])})
+	}
+	b, err := json.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if name == "index.json" {
+			w.Write(b)
+			return
+		}
+		data, ok := files[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	t.Cleanup(srv.Close)
+	sum := sha256.Sum256(b)
+	return srv, hex.EncodeToString(sum[:])
+}
+
+func TestUpdateDataSet(t *testing.T) {
+	files := map[string]string{
+		PresetPlacesFile: "Pune\nNashik\n",
+		PresetWordsFile:  "salary\nemployer\n",
+	}
+	tests := []struct {
+		name    string
+		files   map[string]string
+		indexed map[string]string
+		// pin replaces the hash of the served index when set.
+		pin     string
+		wantErr string
+	}{
+		{"valid", files, nil, "", ""},
+		{"pin mismatch", files, nil, strings.Repeat("0", 64), "does not match the pinned"},
+		{"file hash mismatch", files, map[string]string{PresetPlacesFile: "Pune\n"}, "", "does not match the index"},
+		{"unknown file", map[string]string{"passwords.txt": "x\n"}, nil, "", "unknown file"},
+		{"empty file", map[string]string{PresetPlacesFile: "\n"}, nil, "", "is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, pin := testDataServer(t, tt.files, tt.indexed)
+			if tt.pin != "" {
+				pin = tt.pin
+			}
+			dir := filepath.Join(t.TempDir(), "data")
+			rec, changed, err := UpdateDataSet(context.Background(), srv.Client(), dir, srv.URL+"/index.json", pin)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("UpdateDataSet error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Stat(dir); !os.IsNotExist(err) {
+					t.Errorf("a rejected update wrote %s", dir)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateDataSet: %v", err)
+			}
+			if !changed || rec.Version != "2026.10" || rec.Profile != DefaultProfile || len(rec.Files) != 2 {
+				t.Errorf("UpdateDataSet = %+v, changed %v", rec, changed)
+			}
+			set, err := LoadDataSet(dir)
+			if err != nil {
+				t.Fatalf("LoadDataSet: %v", err)
+			}
+			if len(set.Places) != 2 || len(set.Words) != 2 || set.AddressKeywords != nil {
+				t.Errorf("LoadDataSet = places %v, words %v, keywords %v", set.Places, set.Words, set.AddressKeywords)
+			}
+			if _, changed, err := UpdateDataSet(context.Background(), srv.Client(), dir, srv.URL+"/index.json", pin); err != nil || changed {
+				t.Errorf("second UpdateDataSet changed %v, error %v", changed, err)
+			}
+		})
+	}
+}
+
+func TestLoadDataSetModified(t *testing.T) {
+	srv, pin := testDataServer(t, map[string]string{PresetPlacesFile: "Pune\n"}, nil)
+	dir := t.TempDir()
+	if _, _, err := UpdateDataSet(context.Background(), srv.Client(), dir, srv.URL+"/index.json", pin); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, PresetPlacesFile), "Mumbai\n")
+	if _, err := LoadDataSet(dir); err == nil || !strings.Contains(err.Error(), "recorded sha256") {
+		t.Errorf("LoadDataSet of a modified file: error = %v", err)
+	}
+	if _, err := LoadDataSet(t.TempDir()); err != ErrNoDataSet {
+		t.Errorf("LoadDataSet of an empty directory: error = %v, want ErrNoDataSet", err)
+	}
+}
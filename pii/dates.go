@@ -0,0 +1,150 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DateCategory classifies a date by the label it appears under.
+type DateCategory string
+
+const (
+	// DateBirth is a date of birth ("Date of Birth", "DOB"); it is personal data.
+	DateBirth DateCategory = "dob"
+	// DatePeriod is part of a period ("Period", "From ... To", "Assessment Year"); Form 16
+	// periods are business data.
+	DatePeriod DateCategory = "period"
+	// DateOther is any other date: challan and deposit dates, the signature date...
+	DateOther DateCategory = "other"
+)
+
+// DatePolicy is the set of date categories that are redacted. The default redacts dates of
+// birth only.
+type DatePolicy []DateCategory
+
+// ParseDatePolicy parses a comma-separated list of date categories, or "all" or "none".
+func ParseDatePolicy(s string) (DatePolicy, error) {
+	switch s {
+	case "all":
+		return DatePolicy{DateBirth, DatePeriod, DateOther}, nil
+	case "none", "":
+		return DatePolicy{}, nil
+	}
+	var p DatePolicy
+	for _, part := range strings.Split(s, ",") {
+		c := DateCategory(strings.TrimSpace(part))
+		switch c {
+		case DateBirth, DatePeriod, DateOther:
+			if !slices.Contains(p, c) {
+				p = append(p, c)
+			}
+		default:
+			return nil, fmt.Errorf("unknown date category %q (expected dob, period, other, all or none)", c)
+		}
+	}
+	return p, nil
+}
+
+// String returns the policy in the form accepted by ParseDatePolicy.
+func (p DatePolicy) String() string {
+	if len(p) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(p))
+	for i, c := range p {
+		parts[i] = string(c)
+	}
+	return strings.Join(parts, ",")
+}
+
+// monthNames are the month spellings accepted inside dates.
+const monthNames = `Jan(?:uary)?|Feb(?:ruary)?|Mar(?:ch)?|Apr(?:il)?|May|June?|July?|Aug(?:ust)?|Sep(?:t(?:ember)?)?|Oct(?:ober)?|Nov(?:ember)?|Dec(?:ember)?`
+
+// dateExpr matches dd/mm/yyyy (also with - or .), dd-Mon-yyyy, "12 June 1985" and ISO
+// yyyy-mm-dd dates. Two-digit years are accepted after a month name or number.
+const dateExpr = `(?i)\b(?:` +
+	`\d{1,2}[-/.]\d{1,2}[-/.](?:\d{4}|\d{2})` +
+	`|\d{1,2}[-/. ](?:` + monthNames + `)[-/., ]\s*(?:\d{4}|\d{2})` +
+	`|\d{4}-\d{2}-\d{2}` +
+	`)\b`
+
+var (
+	// dobLabelPattern matches the labels printed before or above a date of birth.
+	dobLabelPattern = regexp.MustCompile(`(?i)\b(?:date\s+of\s+birth|birth\s*date|d\.?o\.?b\b\.?|born(?:\s+on)?)`)
+	// periodLabelPattern matches the labels of period and assessment-year dates.
+	periodLabelPattern = regexp.MustCompile(`(?i)\b(?:period|from|to|till|up\s*to|assessment\s+year|financial\s+year|previous\s+year|quarter|ending|ended)\b`)
+)
+
+// validDate rejects numeric matches whose day or month is out of range, such as version
+// numbers or amounts with dots.
+func validDate(match string) bool {
+	fields := strings.FieldsFunc(match, func(r rune) bool { return r == '-' || r == '/' || r == '.' })
+	if len(fields) != 3 {
+		return true
+	}
+	day, month := fields[0], fields[1]
+	if len(fields[0]) == 4 {
+		day, month = fields[2], fields[1]
+	}
+	d, err := strconv.Atoi(day)
+	if err != nil || d < 1 || d > 31 {
+		return false
+	}
+	if m, err := strconv.Atoi(month); err == nil && (m < 1 || m > 12) {
+		return false
+	}
+	return true
+}
+
+// dateLabels pairs each labelled date category with the labels that introduce it.
+var dateLabels = []struct {
+	category DateCategory
+	pattern  *regexp.Regexp
+}{{DateBirth, dobLabelPattern}, {DatePeriod, periodLabelPattern}}
+
+// headerTolerance is how many columns a header label on the line above may be shifted from
+// the date below it and still be taken as its column heading.
+const headerTolerance = 6
+
+// classifyDate decides the category of the date starting at byte start of lines[n]. The
+// nearest label before the date on the same line wins; otherwise the label on the line
+// above whose column is closest to the date, as in a table header.
+func classifyDate(lines []string, n, start int) DateCategory {
+	best, bestPos := DateOther, -1
+	for _, l := range dateLabels {
+		if locs := l.pattern.FindAllStringIndex(lines[n][:start], -1); len(locs) > 0 {
+			if pos := locs[len(locs)-1][0]; pos > bestPos {
+				best, bestPos = l.category, pos
+			}
+		}
+	}
+	if bestPos >= 0 || n == 0 {
+		return best
+	}
+
+	bestDist := -1
+	for _, l := range dateLabels {
+		for _, loc := range l.pattern.FindAllStringIndex(lines[n-1], -1) {
+			dist := loc[0] - start
+			if dist < 0 {
+				dist = -dist
+			}
+			if dist <= headerTolerance && (bestDist < 0 || dist < bestDist) {
+				best, bestDist = l.category, dist
+			}
+		}
+	}
+	return best
+}
+
+// acceptDate returns the accept check of the date detectors: dates of birth for the
+// EntityDOB detector (birth true), the other redacted categories for EntityDate.
+func (pf *Filter) acceptDate(birth bool) func(lines []string, n, start int) bool {
+	return func(lines []string, n, start int) bool {
+		c := classifyDate(lines, n, start)
+		return (c == DateBirth) == birth && slices.Contains(pf.Dates, c)
+	}
+}
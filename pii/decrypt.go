@@ -0,0 +1,369 @@
+package pii
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+// passwordPadding pads passwords of the RC4 and AES-128 security handlers to 32 bytes.
+var passwordPadding = []byte{
+	0x28, 0xbf, 0x4e, 0x5e, 0x4e, 0x75, 0x8a, 0x41, 0x64, 0x00, 0x4e, 0x56, 0xff, 0xfa, 0x01, 0x08,
+	0x2e, 0x2e, 0x00, 0xb6, 0xd0, 0x68, 0x3e, 0x80, 0x2f, 0x0c, 0xa9, 0xfe, 0x64, 0x53, 0x69, 0x7a,
+}
+
+// Crypt filter methods of an encryption dictionary.
+const (
+	cryptNone  = "None"
+	cryptRC4   = "V2"
+	cryptAESV2 = "AESV2"
+	cryptAESV3 = "AESV3"
+)
+
+// pdfSecurity decrypts the strings and streams of a PDF encrypted with the standard security
+// handler: RC4 (revisions 2 and 3), AES-128 (revision 4) and AES-256 (revisions 5 and 6).
+// TRACES issues Form 16 with a user password, the PAN followed by the date of birth.
+type pdfSecurity struct {
+	key             []byte
+	stream, str     string
+	encryptMetadata bool
+}
+
+// newSecurity authenticates password against the encryption dictionary enc, as the user or
+// the owner password, and returns the decryptor of the file. id is the first element of the
+// trailer's /ID. A wrong password is reported as ErrEncrypted.
+func (d *pdfDocument) newSecurity(enc pdfDict, id []byte, password string) (*pdfSecurity, error) {
+	if filter, _ := d.resolve(enc["Filter"]).(pdfName); filter != "Standard" {
+		return nil, fmt.Errorf("%w: unsupported security handler %s", ErrEncrypted, filter)
+	}
+	v := d.number(enc["V"])
+	r := d.number(enc["R"])
+	o, _ := d.resolve(enc["O"]).(pdfString)
+	u, _ := d.resolve(enc["U"]).(pdfString)
+	s := &pdfSecurity{stream: cryptRC4, str: cryptRC4, encryptMetadata: true}
+	if b, ok := d.resolve(enc["EncryptMetadata"]).(bool); ok {
+		s.encryptMetadata = b
+	}
+	if v >= 4 {
+		s.stream = d.cryptMethod(enc, "StmF")
+		s.str = d.cryptMethod(enc, "StrF")
+	}
+
+	switch {
+	case r >= 2 && r <= 4:
+		if len(o) < 32 || len(u) < 32 {
+			return nil, fmt.Errorf("%w: malformed encryption dictionary", ErrEncrypted)
+		}
+		n := 5
+		if r >= 3 {
+			n = 16
+			if bits := d.number(enc["Length"]); v < 4 && bits >= 40 && bits <= 128 {
+				n = bits / 8
+			}
+		}
+		p := uint32(int32(d.number(enc["P"])))
+		check := func(padded []byte) []byte {
+			key := fileKey(padded, o[:32], p, id, r, n, s.encryptMetadata)
+			want, got := []byte(u[:32]), userHash(key, id, r)
+			if r >= 3 {
+				want, got = want[:16], got[:16]
+			}
+			if !bytes.Equal(want, got) {
+				return nil
+			}
+			return key
+		}
+		pw := padPassword([]byte(password))
+		if s.key = check(pw); s.key == nil {
+			s.key = check(ownerToUser(pw, o[:32], r, n))
+		}
+	case r == 5 || r == 6:
+		oe, _ := d.resolve(enc["OE"]).(pdfString)
+		ue, _ := d.resolve(enc["UE"]).(pdfString)
+		if len(o) < 48 || len(u) < 48 || len(oe) < 32 || len(ue) < 32 {
+			return nil, fmt.Errorf("%w: malformed encryption dictionary", ErrEncrypted)
+		}
+		pw := []byte(password)
+		if len(pw) > 127 {
+			pw = pw[:127]
+		}
+		if bytes.Equal(hashR6(r, pw, u[32:40], nil), u[:32]) {
+			s.key = aesUnwrap(hashR6(r, pw, u[40:48], nil), ue[:32])
+		} else if bytes.Equal(hashR6(r, pw, o[32:40], u[:48]), o[:32]) {
+			s.key = aesUnwrap(hashR6(r, pw, o[40:48], u[:48]), oe[:32])
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported security handler revision %d", ErrEncrypted, r)
+	}
+	if s.key == nil {
+		if password == "" {
+			return nil, fmt.Errorf("%w: a password is required", ErrEncrypted)
+		}
+		return nil, fmt.Errorf("%w: incorrect password", ErrEncrypted)
+	}
+	return s, nil
+}
+
+// number resolves obj as an integer, 0 when it is not a number.
+func (d *pdfDocument) number(obj interface{}) int {
+	f, _ := d.resolve(obj).(float64)
+	return int(f)
+}
+
+// cryptMethod returns the method of the crypt filter named by the /StmF or /StrF entry of enc.
+func (d *pdfDocument) cryptMethod(enc pdfDict, entry pdfName) string {
+	name, _ := d.resolve(enc[entry]).(pdfName)
+	if name == "" || name == "Identity" {
+		return cryptNone
+	}
+	cf := d.dict(d.dict(enc["CF"])[name])
+	method, _ := d.resolve(cf["CFM"]).(pdfName)
+	if method == "" {
+		return cryptNone
+	}
+	return string(method)
+}
+
+// padPassword truncates or pads password to 32 bytes (Algorithm 2, step a).
+func padPassword(password []byte) []byte {
+	out := make([]byte, 0, 32)
+	out = append(out, password[:min(len(password), 32)]...)
+	return append(out, passwordPadding[:32-len(out)]...)
+}
+
+// fileKey computes the file encryption key of revisions 2 to 4 (Algorithm 2).
+func fileKey(padded, o []byte, p uint32, id []byte, r, n int, encryptMetadata bool) []byte {
+	h := md5.New()
+	h.Write(padded)
+	h.Write(o)
+	binary.Write(h, binary.LittleEndian, p)
+	h.Write(id)
+	if r >= 4 && !encryptMetadata {
+		h.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}
+	key := h.Sum(nil)
+	if r >= 3 {
+		for i := 0; i < 50; i++ {
+			sum := md5.Sum(key[:n])
+			key = sum[:]
+		}
+	}
+	return key[:n]
+}
+
+// userHash computes the /U value of revisions 2 to 4 for key (Algorithms 4 and 5); only the
+// first 16 bytes are significant for revisions 3 and 4.
+func userHash(key, id []byte, r int) []byte {
+	if r == 2 {
+		return rc4Crypt(key, passwordPadding)
+	}
+	h := md5.New()
+	h.Write(passwordPadding)
+	h.Write(id)
+	return rc4Rounds(key, h.Sum(nil), false)
+}
+
+// ownerToUser recovers the padded user password from the owner password (Algorithm 7).
+func ownerToUser(padded, o []byte, r, n int) []byte {
+	sum := md5.Sum(padded)
+	key := sum[:]
+	if r >= 3 {
+		for i := 0; i < 50; i++ {
+			sum = md5.Sum(key)
+			key = sum[:]
+		}
+	}
+	key = key[:n]
+	if r == 2 {
+		return rc4Crypt(key, o)
+	}
+	return rc4Rounds(key, o, true)
+}
+
+// rc4Rounds applies RC4 twenty times with key XORed with the round number, rounds 0 to 19, or
+// 19 down to 0 when reverse is set.
+func rc4Rounds(key, data []byte, reverse bool) []byte {
+	round := make([]byte, len(key))
+	for i := 0; i < 20; i++ {
+		x := byte(i)
+		if reverse {
+			x = byte(19 - i)
+		}
+		for j := range key {
+			round[j] = key[j] ^ x
+		}
+		data = rc4Crypt(round, data)
+	}
+	return data
+}
+
+func rc4Crypt(key, data []byte) []byte {
+	c, err := rc4.NewCipher(key)
+	if err != nil {
+		return nil
+	}
+	out := make([]byte, len(data))
+	c.XORKeyStream(out, data)
+	return out
+}
+
+// hashR6 computes the password hash of revisions 5 and 6 (Algorithm 2.B); udata is the /U
+// value when the owner password is checked.
+func hashR6(r int, password, salt, udata []byte) []byte {
+	h := sha256.New()
+	h.Write(password)
+	h.Write(salt)
+	h.Write(udata)
+	k := h.Sum(nil)
+	if r == 5 {
+		return k
+	}
+	for i := 0; ; i++ {
+		block := append(append(append([]byte{}, password...), k...), udata...)
+		k1 := bytes.Repeat(block, 64)
+		c, _ := aes.NewCipher(k[:16])
+		e := make([]byte, len(k1))
+		cipher.NewCBCEncrypter(c, k[16:32]).CryptBlocks(e, k1)
+		// The first 16 bytes of e as a big-endian number modulo 3 equal the sum of the bytes
+		// modulo 3, as 256 ≡ 1 (mod 3).
+		sum := 0
+		for _, b := range e[:16] {
+			sum += int(b)
+		}
+		var next hash.Hash
+		switch sum % 3 {
+		case 0:
+			next = sha256.New()
+		case 1:
+			next = sha512.New384()
+		default:
+			next = sha512.New()
+		}
+		next.Write(e)
+		k = next.Sum(nil)
+		if i >= 63 && int(e[len(e)-1]) <= i-31 {
+			break
+		}
+	}
+	return k[:32]
+}
+
+// aesUnwrap decrypts the /UE or /OE value with the intermediate key: AES-256 in CBC mode with
+// a zero IV and no padding.
+func aesUnwrap(key, wrapped []byte) []byte {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil
+	}
+	out := make([]byte, 32)
+	cipher.NewCBCDecrypter(c, make([]byte, aes.BlockSize)).CryptBlocks(out, wrapped[:32])
+	return out
+}
+
+// objectKey derives the key of object num gen (Algorithm 1). AES-256 uses the file key as is.
+func (s *pdfSecurity) objectKey(num, gen int, method string) []byte {
+	if method == cryptAESV3 {
+		return s.key
+	}
+	h := md5.New()
+	h.Write(s.key)
+	h.Write([]byte{byte(num), byte(num >> 8), byte(num >> 16), byte(gen), byte(gen >> 8)})
+	if method == cryptAESV2 {
+		h.Write([]byte("sAlT"))
+	}
+	return h.Sum(nil)[:min(len(s.key)+5, 16)]
+}
+
+// decryptBytes decrypts data of object num gen with method.
+func (s *pdfSecurity) decryptBytes(num, gen int, method string, data []byte) []byte {
+	switch method {
+	case cryptRC4:
+		return rc4Crypt(s.objectKey(num, gen, method), data)
+	case cryptAESV2, cryptAESV3:
+		c, err := aes.NewCipher(s.objectKey(num, gen, method))
+		if err != nil || len(data) < 2*aes.BlockSize {
+			return nil
+		}
+		iv, body := data[:aes.BlockSize], data[aes.BlockSize:]
+		body = body[:len(body)-len(body)%aes.BlockSize]
+		out := make([]byte, len(body))
+		cipher.NewCBCDecrypter(c, iv).CryptBlocks(out, body)
+		if pad := int(out[len(out)-1]); pad >= 1 && pad <= aes.BlockSize && pad <= len(out) {
+			out = out[:len(out)-pad]
+		}
+		return out
+	}
+	return data
+}
+
+// decryptObject decrypts, in place where possible, the strings and stream data of the object
+// obj defined as num gen, and returns it. Cross-reference streams are not encrypted, and
+// neither are metadata streams when /EncryptMetadata is false.
+func (s *pdfSecurity) decryptObject(num, gen int, obj interface{}) interface{} {
+	switch v := obj.(type) {
+	case pdfString:
+		return pdfString(s.decryptBytes(num, gen, s.str, v))
+	case pdfArray:
+		for i, item := range v {
+			v[i] = s.decryptObject(num, gen, item)
+		}
+	case pdfDict:
+		for k, item := range v {
+			v[k] = s.decryptObject(num, gen, item)
+		}
+	case *pdfStream:
+		kind, _ := v.dict["Type"].(pdfName)
+		if kind == "XRef" {
+			return v
+		}
+		s.decryptObject(num, gen, v.dict)
+		if kind != "Metadata" || s.encryptMetadata {
+			v.raw = s.decryptBytes(num, gen, s.stream, v.raw)
+		}
+	}
+	return obj
+}
+
+// decrypt authenticates password, or else the empty user password of files that only restrict
+// permissions, and decrypts every object defined directly in the file, gens holding their
+// generation numbers. Objects inside object streams are decrypted with their stream. The
+// /Encrypt entry is removed from the trailer once the document is decrypted.
+func (d *pdfDocument) decrypt(gens map[int]int, password string) error {
+	ref := d.trailer["Encrypt"]
+	enc := d.dict(ref)
+	if enc == nil {
+		return fmt.Errorf("%w: missing encryption dictionary", ErrEncrypted)
+	}
+	var id []byte
+	if ids, ok := d.resolve(d.trailer["ID"]).(pdfArray); ok && len(ids) > 0 {
+		first, _ := d.resolve(ids[0]).(pdfString)
+		id = first
+	}
+	s, err := d.newSecurity(enc, id, password)
+	if err != nil && password != "" {
+		if open, openErr := d.newSecurity(enc, id, ""); openErr == nil {
+			s, err = open, nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	skip := -1
+	if r, ok := ref.(pdfRef); ok {
+		skip = r.num
+	}
+	for num, obj := range d.objects {
+		if num != skip {
+			d.objects[num] = s.decryptObject(num, gens[num], obj)
+		}
+	}
+	delete(d.trailer, "Encrypt")
+	return nil
+}
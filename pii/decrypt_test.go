@@ -0,0 +1,159 @@
+package pii
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testEncryptedPDF returns a one-page PDF showing text, encrypted with the standard security
+// handler of revision r (3 for RC4, 6 for AES-256) under the user and owner passwords.
+func testEncryptedPDF(r int, user, owner, text string) []byte {
+	id := []byte("0123456789abcdef")
+	var enc string
+	var s *pdfSecurity
+	switch r {
+	case 3:
+		// Algorithm 3: /O is the padded user password encrypted with the owner key.
+		sum := md5.Sum(padPassword([]byte(owner)))
+		key := sum[:]
+		for i := 0; i < 50; i++ {
+			sum = md5.Sum(key)
+			key = sum[:]
+		}
+		o := rc4Rounds(key, padPassword([]byte(user)), false)
+		s = &pdfSecurity{key: fileKey(padPassword([]byte(user)), o, uint32(0xfffffffc), id, 3, 16, true), stream: cryptRC4, str: cryptRC4}
+		u := append(userHash(s.key, id, 3)[:16], make([]byte, 16)...)
+		enc = fmt.Sprintf("<< /Filter /Standard /V 2 /R 3 /Length 128 /P -4 /O <%x> /U <%x> >>", o, u)
+	case 6:
+		s = &pdfSecurity{key: []byte("file key of 32 bytes for AES-256"), stream: cryptAESV3, str: cryptAESV3}
+		uSalts, oSalts := []byte("uvalsaltukeysalt"), []byte("ovalsaltokeysalt")
+		u := append(hashR6(6, []byte(user), uSalts[:8], nil), uSalts...)
+		o := append(hashR6(6, []byte(owner), oSalts[:8], u), oSalts...)
+		ue := aesWrap(hashR6(6, []byte(user), uSalts[8:], nil), s.key)
+		oe := aesWrap(hashR6(6, []byte(owner), oSalts[8:], u), s.key)
+		enc = fmt.Sprintf("<< /Filter /Standard /V 5 /R 6 /Length 256 /P -4 /CF << /StdCF << /CFM /AESV3 /Length 32 >> >> /StmF /StdCF /StrF /StdCF /O <%x> /U <%x> /OE <%x> /UE <%x> >>", o, u, oe, ue)
+	}
+
+	w := newPDFWriter()
+	catalog, pages, page, font, stream, encrypt := w.alloc(), w.alloc(), w.alloc(), w.alloc(), w.alloc(), w.alloc()
+	w.writeObject(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	w.writeObject(pages, fmt.Sprintf("<< /Type /Pages /Kids [%d 0 R] /Count 1 >>", page))
+	w.writeObject(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>", pages, font, stream))
+	w.writeObject(font, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	w.writeStream(stream, "", s.encryptBytes(stream, 0, s.stream, []byte(fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text))))
+	w.writeObject(encrypt, enc)
+	data := w.finish(catalog)
+	return append(data, fmt.Sprintf("trailer\n<< /Encrypt %d 0 R /ID [<%x> <%x>] >>\n", encrypt, id, id)...)
+}
+
+// aesWrap encrypts the 32-byte file key as /UE and /OE hold it: AES-256 in CBC mode with a
+// zero IV and no padding.
+func aesWrap(key, fileKey []byte) []byte {
+	c, _ := aes.NewCipher(key)
+	out := make([]byte, 32)
+	cipher.NewCBCEncrypter(c, make([]byte, aes.BlockSize)).CryptBlocks(out, fileKey)
+	return out
+}
+
+// encryptBytes is the inverse of decryptBytes, with a fixed IV for AES.
+func (s *pdfSecurity) encryptBytes(num, gen int, method string, data []byte) []byte {
+	if method == cryptRC4 {
+		return rc4Crypt(s.objectKey(num, gen, method), data)
+	}
+	pad := aes.BlockSize - len(data)%aes.BlockSize
+	for i := 0; i < pad; i++ {
+		data = append(data, byte(pad))
+	}
+	c, _ := aes.NewCipher(s.objectKey(num, gen, method))
+	iv := []byte("initialisation v")
+	out := make([]byte, len(data))
+	cipher.NewCBCEncrypter(c, iv).CryptBlocks(out, data)
+	return append(iv, out...)
+}
+
+func TestParsePDFEncrypted(t *testing.T) {
+	const text = "PAN of the Employee: ABCPE1234F"
+	tests := []struct {
+		name     string
+		password string
+		// wantErr is part of the error message; empty when the document opens.
+		wantErr string
+	}{
+		{"user password", "ABCPE123401011990", ""},
+		{"owner password", "owner secret", ""},
+		{"wrong password", "ABCPE123401011991", "incorrect password"},
+		{"no password", "", "a password is required"},
+		{"long wrong password", strings.Repeat("x", 300), "incorrect password"},
+	}
+	for _, r := range []int{3, 6} {
+		data := testEncryptedPDF(r, "ABCPE123401011990", "owner secret", text)
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("R%d/%s", r, tt.name), func(t *testing.T) {
+				doc, err := parsePDF(data, tt.password)
+				if tt.wantErr != "" {
+					if !errors.Is(err, ErrEncrypted) || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("parsePDF error = %v, want ErrEncrypted: %s", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				pages, err := doc.extractPages()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(pages) != 1 || !strings.Contains(pages[0], text) {
+					t.Errorf("extractPages = %q, want %q", pages, text)
+				}
+			})
+		}
+	}
+}
+
+// A damaged encryption dictionary is reported as ErrEncrypted, not a panic.
+func TestParsePDFMalformedEncryption(t *testing.T) {
+	tests := []struct {
+		name        string
+		old, new    string
+		wantErrText string
+	}{
+		{"short /U", "/U <", "/U <00> /X <", "malformed encryption dictionary"},
+		{"unknown revision", "/R 3", "/R 9", "unsupported security handler revision 9"},
+		{"unknown handler", "/Filter /Standard", "/Filter /Adobe.PubSec", "unsupported security handler"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := strings.Replace(string(testEncryptedPDF(3, "user", "owner", "text")), tt.old, tt.new, 1)
+			_, err := parsePDF([]byte(data), "user")
+			if !errors.Is(err, ErrEncrypted) || !strings.Contains(err.Error(), tt.wantErrText) {
+				t.Errorf("parsePDF error = %v, want ErrEncrypted: %s", err, tt.wantErrText)
+			}
+		})
+	}
+}
+
+// The -password of the CLI reaches the native extractor, which reports a wrong one as
+// ErrEncrypted.
+func TestExtractTextPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "form16.pdf")
+	if err := os.WriteFile(path, testEncryptedPDF(6, "ABCPE123401011990", "owner secret", "FORM NO. 16"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var warnings Warnings
+	if _, _, err := ExtractText(context.Background(), path, "native", "wrong", &warnings); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("ExtractText with a wrong password = %v, want ErrEncrypted", err)
+	}
+	pages, _, err := ExtractText(context.Background(), path, "native", "ABCPE123401011990", &warnings)
+	if err != nil || !strings.Contains(pages.Text(), "FORM NO. 16") {
+		t.Errorf("ExtractText = %q, %v", pages.Text(), err)
+	}
+}
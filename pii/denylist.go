@@ -0,0 +1,62 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LoadDenylist reads a denylist file, one entry per line (see CompileDenylist). Blank lines and
+// lines starting with # are skipped.
+func LoadDenylist(path string) (*regexp.Regexp, error) {
+	entries, err := readList(path, "denylist")
+	if err != nil {
+		return nil, err
+	}
+	return CompileDenylist(entries)
+}
+
+// CompileDenylist builds the pattern of the denylist entries: an entry between slashes is a
+// regular expression (/PRJ-\d{4}/), any other entry a literal string such as a project code or
+// an employee name, matched case-insensitively as whole words with any run of spaces between
+// its words.
+func CompileDenylist(entries []string) (*regexp.Regexp, error) {
+	alts := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if len(e) > 2 && strings.HasPrefix(e, "/") && strings.HasSuffix(e, "/") {
+			expr := e[1 : len(e)-1]
+			if _, err := regexp.Compile(expr); err != nil {
+				return nil, fmt.Errorf("denylist entry %s: %v", e, err)
+			}
+			alts = append(alts, "(?:"+expr+")")
+			continue
+		}
+		words := strings.Fields(e)
+		for i, w := range words {
+			words[i] = regexp.QuoteMeta(w)
+		}
+		expr := "(?i:" + strings.Join(words, `\s+`) + ")"
+		if isWordByte(e[0]) {
+			expr = `\b` + expr
+		}
+		if isWordByte(e[len(e)-1]) {
+			expr += `\b`
+		}
+		alts = append(alts, expr)
+	}
+	if len(alts) == 0 {
+		return nil, fmt.Errorf("denylist is empty")
+	}
+	return regexp.Compile(strings.Join(alts, "|"))
+}
+
+// WithDenylist redacts every match of pattern (see CompileDenylist) as EntityCustom, even where
+// no other detector fires. The denylist runs ahead of the other recognizers, so its matches
+// win on overlap.
+func WithDenylist(pattern *regexp.Regexp) Option {
+	return func(pf *Filter) {
+		pf.denylistPattern = pattern
+		WithoutRecognizers(EntityCustom)(pf)
+		pf.recognizers = append([]Recognizer{builtinRecognizer{name: EntityCustom, pf: pf}}, pf.recognizers...)
+	}
+}
@@ -0,0 +1,201 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Document types, as detected by DocumentType and selected with WithDocumentProfile.
+const (
+	// DocumentForm16 is a Form 16 certificate or a bundle of them.
+	DocumentForm16 = "form16"
+	// DocumentForm12BA is a Form 12BA statement of perquisites on its own; printed as an
+	// annexure of a Form 16 it is part of the certificate.
+	DocumentForm12BA = "form12ba"
+	// DocumentForm26AS is the Form 26AS annual tax statement: the TDS and TCS credited to a
+	// PAN, tax paid by challan and refunds.
+	DocumentForm26AS = "form26as"
+	// DocumentAIS is the Annual Information Statement: the financial transactions reported
+	// against a PAN (interest, dividends, securities, mutual funds...).
+	DocumentAIS = "ais"
+	// DocumentPayslip is a monthly salary slip.
+	DocumentPayslip = "payslip"
+	// DocumentOther is any text that is not recognised as one of the documents above.
+	DocumentOther = "other"
+)
+
+// RetainedFields keys of the structured Form 26AS and AIS fields. Figures are copied as
+// printed.
+const (
+	FieldTDSCredits     = "TDS Credits"
+	FieldTCSCredits     = "TCS Credits"
+	FieldTaxPaid        = "Tax Paid"
+	FieldAISInformation = "AIS Information"
+)
+
+var (
+	form26ASTitlePattern = regexp.MustCompile(`(?i)^\s*(?:FORM\s+(?:NO\.?\s*)?26\s*AS\b|Annual\s+Tax\s+Statement\b)`)
+	aisTitlePattern      = regexp.MustCompile(`(?i)^\s*(?:AIS\s*[-:]\s*)?Annual\s+Information\s+Statement\b`)
+	// statementPartPattern heads the parts of Form 26AS, lettered (PART A, PART-A1) in the old
+	// layout and numbered (PART-I, PART II) since 2023.
+	statementPartPattern = regexp.MustCompile(`(?i)^\s*PART\s*[-–]?\s*([A-H]\d?|I{1,3}|IV|VI{0,3})\b`)
+	// creditRowPattern starts a numbered row of a deductor or collector table.
+	creditRowPattern = regexp.MustCompile(`^\s*(\d{1,3})\s`)
+	// informationCodePattern is the code of an AIS information category, e.g. SFT-016(Int)
+	// or TDS-194A.
+	informationCodePattern = regexp.MustCompile(`\b((?:SFT|TDS|TCS)-\d{3}[A-Z]?(?:\([A-Za-z]+\))?)`)
+	financialYearLabel     = regexp.MustCompile(`(?i)Financial\s+Year`)
+
+	// Labels the statement identifiers are only detected next to.
+	bankAccountLabelPattern = regexp.MustCompile(`(?i)\b(?:A/?c|Account)\s*(?:No\b|Number)|\bBank\s+Account`)
+	folioLabelPattern       = regexp.MustCompile(`(?i)\bFolio\b`)
+	challanLabelPattern     = regexp.MustCompile(`(?i)\bBSR\b|\bChallan\s+Serial`)
+)
+
+// DocumentProfile tailors the filter to one type of tax document: the document is recognised
+// by its title, the identifiers only found in it are detected in it alone, and its business
+// figures are parsed into the retained fields.
+type DocumentProfile struct {
+	Name string
+	// Title matches the title line of the document.
+	Title *regexp.Regexp
+	// Fields parses the retained business figures.
+	Fields func(text string) map[string][]string
+}
+
+// documentProfiles are the built-in document types in detection order. A Form 16 refers to
+// Form 26AS in its notes and carries Form 12BA as an annexure, so its own title is looked for
+// first.
+var documentProfiles = []*DocumentProfile{
+	{Name: DocumentForm16, Title: formHeaderPattern, Fields: formFields},
+	{Name: DocumentForm12BA, Title: form12BATitlePattern, Fields: form12BAFields},
+	{Name: DocumentForm26AS, Title: form26ASTitlePattern, Fields: form26ASFields},
+	{Name: DocumentAIS, Title: aisTitlePattern, Fields: aisFields},
+	{Name: DocumentPayslip, Title: payslipTitlePattern, Fields: payslipFields},
+}
+
+// DocumentProfileNames lists the document types WithDocumentProfile accepts.
+func DocumentProfileNames() []string {
+	names := make([]string, 0, len(documentProfiles))
+	for _, p := range documentProfiles {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// LookupDocumentProfile returns the built-in profile of a document type.
+func LookupDocumentProfile(name string) (*DocumentProfile, error) {
+	for _, p := range documentProfiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown document type %q (expected %s)", name, strings.Join(DocumentProfileNames(), ", "))
+}
+
+// WithDocumentProfile redacts every document as the given type instead of detecting it from
+// its title, e.g. for statements whose title page was not extracted.
+func WithDocumentProfile(p *DocumentProfile) Option {
+	return func(pf *Filter) {
+		pf.document = p
+	}
+}
+
+// detectDocument returns the first profile whose title is found on a line of text, or nil.
+func detectDocument(text string) *DocumentProfile {
+	lines := strings.Split(text, "\n")
+	for _, p := range documentProfiles {
+		for _, line := range lines {
+			if p.Title.MatchString(line) {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// DocumentType classifies text by the title of the document (DocumentForm16, DocumentForm12BA,
+// DocumentForm26AS, DocumentAIS or DocumentPayslip), and as DocumentOther when none is found.
+func DocumentType(text string) string {
+	if p := detectDocument(text); p != nil {
+		return p.Name
+	}
+	return DocumentOther
+}
+
+// documentFields parses the retained business figures of text as the document type being
+// redacted. Text of no known type is parsed as a Form 16.
+func (pf *Filter) documentFields(text string) map[string][]string {
+	if pf.documentType == nil {
+		return formFields(text)
+	}
+	return pf.documentType.Fields(text)
+}
+
+// form26ASFields parses the business figures of a Form 26AS: the assessment year, one entry
+// per deductor of Part A and collector of Part B with the amount paid, the tax deducted or
+// collected and the tax deposited, and the total tax of each challan of Part C. Deductors and
+// collectors are numbered as in the statement, as their names and TANs are redacted.
+func form26ASFields(text string) map[string][]string {
+	fields := make(map[string][]string)
+	part := ""
+	for _, line := range strings.Split(text, "\n") {
+		if m := statementPartPattern.FindStringSubmatch(line); m != nil {
+			part = statementPart(strings.ToUpper(m[1]))
+			continue
+		}
+		row := creditRowPattern.FindStringSubmatch(line)
+		figures := figurePattern.FindAllString(line, -1)
+		switch {
+		case row == nil:
+		case part == "A" && len(figures) >= 3:
+			f := figures[len(figures)-3:]
+			fields[FieldTDSCredits] = append(fields[FieldTDSCredits], fmt.Sprintf("%s: amount paid %s, tax deducted %s, TDS deposited %s", row[1], f[0], f[1], f[2]))
+		case part == "B" && len(figures) >= 3:
+			f := figures[len(figures)-3:]
+			fields[FieldTCSCredits] = append(fields[FieldTCSCredits], fmt.Sprintf("%s: amount paid %s, tax collected %s, TCS deposited %s", row[1], f[0], f[1], f[2]))
+		case part == "C" && len(figures) > 0:
+			// Tax, surcharge, cess, others and the total, which is the last figure.
+			fields[FieldTaxPaid] = append(fields[FieldTaxPaid], fmt.Sprintf("%s: %s", row[1], figures[len(figures)-1]))
+		}
+	}
+	if assessmentYearLabel.MatchString(text) {
+		fields[FieldAssessmentYear] = assessmentYearPattern.FindAllString(text, -1)
+	}
+	return fields
+}
+
+// statementPart returns the letter of a Form 26AS part: the old layout's Part A, B and C are
+// Part I, II and III of the new one, and A1, A2 are sub-parts of A.
+func statementPart(part string) string {
+	switch part {
+	case "I":
+		return "A"
+	case "II":
+		return "B"
+	case "III":
+		return "C"
+	}
+	return part[:1]
+}
+
+// aisFields parses the business figures of an Annual Information Statement: the assessment
+// and financial years and one entry per information category row with its code and amount.
+// The description and source columns are left out, as they name the reporting bank or
+// company.
+func aisFields(text string) map[string][]string {
+	fields := make(map[string][]string)
+	for _, line := range strings.Split(text, "\n") {
+		code := informationCodePattern.FindStringSubmatch(line)
+		figures := figurePattern.FindAllString(line, -1)
+		if code == nil || len(figures) == 0 {
+			continue
+		}
+		fields[FieldAISInformation] = append(fields[FieldAISInformation], fmt.Sprintf("%s: %s", code[1], figures[len(figures)-1]))
+	}
+	if assessmentYearLabel.MatchString(text) || financialYearLabel.MatchString(text) {
+		fields[FieldAssessmentYear] = assessmentYearPattern.FindAllString(text, -1)
+	}
+	return fields
+}
@@ -0,0 +1,57 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// EmailPolicy controls how e-mail addresses are replaced by FilterPII.
+type EmailPolicy string
+
+const (
+	// EmailRedact replaces the whole address with [EMAIL_REDACTED] (default).
+	EmailRedact EmailPolicy = "redact"
+	// EmailKeepDomain replaces the local part only, so the employer domain stays available
+	// for routing: [EMAIL_REDACTED]@infosys.com.
+	EmailKeepDomain EmailPolicy = "keep-domain"
+	// EmailOrgToken replaces the local part and maps the domain to a token that is stable
+	// across the documents of a run: [EMAIL_REDACTED]@[ORG_1]. With a vault the domain tokens
+	// are stored in it and can be restored by Detokenize.
+	EmailOrgToken EmailPolicy = "org-token"
+)
+
+// ParseEmailPolicy validates a policy name supplied on the command line or in a config file.
+func ParseEmailPolicy(s string) (EmailPolicy, error) {
+	switch p := EmailPolicy(strings.ToLower(s)); p {
+	case EmailRedact, EmailKeepDomain, EmailOrgToken:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown e-mail policy %q (expected redact, keep-domain or org-token)", s)
+}
+
+// domainTokenEntity names the tokens EmailOrgToken hands out for domains, e.g. [ORG_1].
+const domainTokenEntity = "org"
+
+// emailReplacer returns the replacement of e-mail addresses under the filter's EmailPolicy;
+// local replaces the part before the @.
+func (pf *Filter) emailReplacer(local func(string) string) func(string) string {
+	if pf.EmailPolicy != EmailKeepDomain && pf.EmailPolicy != EmailOrgToken {
+		return local
+	}
+	return func(email string) string {
+		at := strings.LastIndexByte(email, '@')
+		domain := strings.ToLower(email[at+1:])
+		if pf.EmailPolicy == EmailOrgToken {
+			tokens := pf.vault
+			if tokens == nil {
+				tokens = pf.domainTokens
+			}
+			domain = tokens.Token(domainTokenEntity, domain)
+		}
+		return local(email[:at]) + "@" + domain
+	}
+}
+
+// keptDomainPattern matches a domain left by EmailKeepDomain after its placeholder or token.
+var keptDomainPattern = regexp.MustCompile(`\]@[A-Za-z0-9.-]+`)
@@ -0,0 +1,66 @@
+package pii
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// builtinEntityTypes returns the set of built-in entity types: those of every profile and
+// detector pack, and the dictionary filter's EntityWord.
+func builtinEntityTypes() map[string]bool {
+	known := make(map[string]bool, len(defaultPlaceholders))
+	for entity := range defaultPlaceholders {
+		known[entity] = true
+	}
+	for _, name := range ProfileNames() {
+		p, _ := LookupProfile(name)
+		for _, id := range p.Identifiers {
+			known[id.Entity] = true
+		}
+	}
+	return known
+}
+
+// EntityTypes returns, sorted, the entity types WithDisabled and WithOnly accept for pf: the
+// built-in types of every profile and pack, and the types of its extra detectors and
+// recognizers. EntityWord, which is not detected, is left out.
+func (pf *Filter) EntityTypes() []string {
+	known := builtinEntityTypes()
+	for _, r := range pf.recognizers {
+		known[r.Name()] = true
+	}
+	delete(known, EntityWord)
+	types := make([]string, 0, len(known))
+	for t := range known {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// CheckEntityTypes returns an error naming the first of types that is not one of
+// pf.EntityTypes.
+func (pf *Filter) CheckEntityTypes(types ...string) error {
+	known := pf.EntityTypes()
+	for _, t := range types {
+		if !slices.Contains(known, t) {
+			return fmt.Errorf("unknown entity type %q (expected %s)", t, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// ParseEntityTypes splits a comma-separated list of entity types ("pan,aadhaar"), lower-cased
+// and without duplicates. The types are checked against a filter with CheckEntityTypes.
+func ParseEntityTypes(s string) []string {
+	var types []string
+	for _, t := range strings.Split(s, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	return types
+}
@@ -0,0 +1,33 @@
+package pii
+
+import "regexp"
+
+var (
+	// uanLabelPattern matches the labels printed next to, or above, an EPF Universal Account
+	// Number.
+	uanLabelPattern = regexp.MustCompile(`(?i)\bUAN\b|Universal\s+Account\s+(?:No|Number)`)
+	// aadhaarLabelPattern matches Aadhaar labels, which claim a 12-digit number for Aadhaar
+	// even when a UAN label appears earlier on the line.
+	aadhaarLabelPattern = regexp.MustCompile(`(?i)\bAadhaa?r\b|\bUID\b`)
+)
+
+// uanContext reports whether the 12-digit number starting at byte start of lines[n] is a
+// UAN: the nearest label before it on the line is a UAN label, or, with no label on the line,
+// a UAN label appears on one of the lines above.
+func uanContext(lines []string, n, start int) bool {
+	before := lines[n][:start]
+	uan := lastMatch(uanLabelPattern, before)
+	if aadhaar := lastMatch(aadhaarLabelPattern, before); uan >= 0 || aadhaar >= 0 {
+		return uan > aadhaar
+	}
+	return n > 0 && hasContext(lines, n-1, uanLabelPattern, contextWindow-1)
+}
+
+// lastMatch returns the start of the last match of pattern in s, or -1.
+func lastMatch(pattern *regexp.Regexp, s string) int {
+	locs := pattern.FindAllStringIndex(s, -1)
+	if len(locs) == 0 {
+		return -1
+	}
+	return locs[len(locs)-1][0]
+}
@@ -0,0 +1,18 @@
+package pii
+
+import "errors"
+
+// Sentinel errors returned, possibly wrapped, by the extractors and readers of this package.
+// Test for them with errors.Is.
+var (
+	// ErrNoText means extraction succeeded but the document has no text, as for a scanned
+	// certificate when OCR is not available.
+	ErrNoText = errors.New("no text could be extracted from the PDF")
+	// ErrEncrypted means the PDF is encrypted and could not be opened.
+	ErrEncrypted = errors.New("document is encrypted")
+	// ErrUnsupportedFormat means the input is not in a format this package reads: not a PDF,
+	// an unknown structured format, or a report of a newer layout version.
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	// ErrLineTooLong means a ChunkScanner was sent a line longer than MaxChunkLine.
+	ErrLineTooLong = errors.New("line too long")
+)
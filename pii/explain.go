@@ -0,0 +1,213 @@
+package pii
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Rules name the kind of check behind a redaction in an Explanation.
+const (
+	// RuleRegex is a detector pattern matching on its own.
+	RuleRegex = "regex"
+	// RuleAnchor is a detector pattern that only fires next to, or below, a label ("Name",
+	// "Date of Birth", "UAN").
+	RuleAnchor = "anchor"
+	// RuleGazetteer is an address line naming a state or city of the profile.
+	RuleGazetteer = "gazetteer"
+	// RuleKeyword is an address line with street keywords, or an organisation line with a
+	// company suffix.
+	RuleKeyword = "keyword"
+	// RuleDenylist is a denylist entry.
+	RuleDenylist = "denylist"
+	// RuleRecognizer is a third-party Recognizer.
+	RuleRecognizer = "recognizer"
+)
+
+// Explanation records why a match was redacted, so that a false positive can be traced to the
+// detector and setting behind it without reading the source. It is attached to Matches and
+// Findings when enabled with WithExplain.
+type Explanation struct {
+	// Detector is the entity type of the detector, or the name of a third-party recognizer.
+	Detector string `json:"detector"`
+	// Rule is the kind of check that fired: RuleRegex, RuleAnchor, RuleGazetteer...
+	Rule string `json:"rule"`
+	// Pattern is the regular expression of a regex or anchor rule, the place name of a
+	// gazetteer rule or the keywords of a keyword rule. The denylist is not repeated, as its
+	// entries are often names.
+	Pattern string `json:"pattern,omitempty"`
+	// Evidence lists what raised or lowered the confidence: the label found nearby, the check
+	// character, a match cut out of a longer token.
+	Evidence []string `json:"evidence,omitempty"`
+	// Policy lists the settings that made the detector run or shaped its replacement, in the
+	// form of the command-line flags: "profile=IN", "gst=mask", "dates=dob,period"...
+	Policy []string `json:"policy,omitempty"`
+}
+
+// String formats the explanation on one line for the text report.
+func (e *Explanation) String() string {
+	s := e.Detector + " " + e.Rule
+	if e.Pattern != "" {
+		s += " " + e.Pattern
+	}
+	if len(e.Evidence) > 0 {
+		s += "; " + strings.Join(e.Evidence, ", ")
+	}
+	if len(e.Policy) > 0 {
+		s += " [" + strings.Join(e.Policy, " ") + "]"
+	}
+	return s
+}
+
+// detectorRule returns the rule kind of a detector.
+func detectorRule(d *tokenDetector) string {
+	switch {
+	case d.recognizer != nil:
+		return RuleRecognizer
+	case d.entity == EntityCustom:
+		return RuleDenylist
+	case d.context != nil || d.accept != nil:
+		return RuleAnchor
+	}
+	return RuleRegex
+}
+
+// detectorPolicy returns the settings behind the matches of d.
+func (pf *Filter) detectorPolicy(d *tokenDetector) []string {
+	var policy []string
+	switch {
+	case d.recognizer != nil:
+	case slices.Contains(pf.profile.Recognizers, d.entity):
+		policy = append(policy, "profile="+pf.profile.Name)
+	default:
+		for _, pack := range slices.Sorted(maps.Keys(detectorPacks)) {
+			if slices.Contains(detectorPacks[pack], d.entity) {
+				policy = append(policy, "packs="+pack)
+			}
+		}
+	}
+	if d.document != "" {
+		policy = append(policy, "document="+d.document)
+	}
+	switch d.entity {
+	case EntityCustom:
+		policy = append(policy, "denylist")
+	case EntityGST:
+		policy = append(policy, "gst="+string(pf.GSTPolicy))
+	case EntityEmail:
+		policy = append(policy, "email="+string(pf.EmailPolicy))
+	case EntityPhone:
+		if d.pattern == pf.foreignPhonePattern {
+			policy = append(policy, "phone-regions="+strings.Join(pf.PhoneRegions, ","))
+		}
+	case EntityAadhaar:
+		if pf.strict {
+			policy = append(policy, "strict")
+		}
+	case EntityPAN:
+		if pf.panContext {
+			policy = append(policy, "pan-context")
+		}
+	case EntityDOB, EntityDate:
+		policy = append(policy, "dates="+pf.Dates.String())
+	}
+	if p, ok := pf.placeholders[d.entity]; ok && p != defaultPlaceholders[d.entity] {
+		policy = append(policy, "placeholder="+p)
+	}
+	if pf.vault != nil {
+		policy = append(policy, "vault")
+	}
+	if pf.minConfidence > 0 {
+		policy = append(policy, fmt.Sprintf("min-confidence=%g", pf.minConfidence))
+	}
+	return policy
+}
+
+// explain returns the explanation of the match of d at bytes start to end of lines[n].
+func (d *tokenDetector) explain(lines []string, n, start, end int) *Explanation {
+	e := &Explanation{Detector: d.entity, Rule: d.rule, Policy: d.policy}
+	if d.pattern != nil && d.rule != RuleDenylist {
+		e.Pattern = d.pattern.String()
+	}
+	if d.context != nil {
+		if label, k := contextLabel(lines, n, d.context, d.window); k >= 0 {
+			e.Evidence = append(e.Evidence, fmt.Sprintf("anchor %q on line %d", label, k+1))
+		}
+	}
+	if d.recognizer != nil {
+		return e
+	}
+	s, ok := detectorScores[d.entity]
+	if d.score != nil {
+		s, ok = *d.score, true
+	}
+	if !ok {
+		return e
+	}
+	line := lines[n]
+	if start > 0 && isAlnum(line[start-1]) || end < len(line) && isAlnum(line[end]) {
+		e.Evidence = append(e.Evidence, "embedded in a longer token")
+	}
+	if s.context != nil && s.context != d.context {
+		if label, k := contextLabel(lines, n, s.context, d.window); k >= 0 {
+			e.Evidence = append(e.Evidence, fmt.Sprintf("label %q on line %d", label, k+1))
+		}
+	}
+	if s.checksum != nil {
+		if s.checksum(line[start:end]) {
+			e.Evidence = append(e.Evidence, "check character valid")
+		} else {
+			e.Evidence = append(e.Evidence, "check character invalid")
+		}
+	}
+	return e
+}
+
+// contextLabel returns the first text matched by pattern on lines[n] or the window lines above
+// it, as searched by hasContext, and its line; the line is -1 when there is none.
+func contextLabel(lines []string, n int, pattern *regexp.Regexp, window int) (string, int) {
+	if window == 0 {
+		window = contextWindow
+	}
+	for k := n; k >= 0 && k >= n-window; k-- {
+		if label := pattern.FindString(lines[k]); label != "" {
+			return label, k
+		}
+	}
+	return "", -1
+}
+
+// explainLine returns the explanation of a whole-line address or organisation match of the
+// trimmed line.
+func (pf *Filter) explainLine(entity, trimmed string) *Explanation {
+	e := &Explanation{Detector: entity, Rule: RuleKeyword}
+	switch entity {
+	case EntityOrganization:
+		e.Pattern = pf.OrganizationPattern.FindString(trimmed)
+	case EntityAddress:
+		e.Policy = []string{"profile=" + pf.profile.Name}
+		if place := pf.AddressPattern.FindString(trimmed); place != "" {
+			e.Rule, e.Pattern = RuleGazetteer, place
+			break
+		}
+		var keywords []string
+		for _, kw := range pf.AddressKeywordPattern.FindAllString(trimmed, -1) {
+			if !slices.Contains(keywords, kw) {
+				keywords = append(keywords, kw)
+			}
+		}
+		e.Pattern = strings.Join(keywords, ", ")
+	}
+	if p, ok := pf.placeholders[entity]; ok && p != defaultPlaceholders[entity] {
+		e.Policy = append(e.Policy, "placeholder="+p)
+	}
+	if pf.vault != nil {
+		e.Policy = append(e.Policy, "vault")
+	}
+	if pf.minConfidence > 0 {
+		e.Policy = append(e.Policy, fmt.Sprintf("min-confidence=%g", pf.minConfidence))
+	}
+	return e
+}
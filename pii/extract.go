@@ -0,0 +1,363 @@
+package pii
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Pages holds the extracted text of each page in document order.
+type Pages []string
+
+// Text joins the pages with form feeds, the separator pdftotext uses.
+func (p Pages) Text() string {
+	return strings.Join(p, "\f")
+}
+
+// Metadata records which engine produced a document's text and how it was configured, so a
+// run can be reproduced with the same extraction settings.
+type Metadata struct {
+	Engine  string            `json:"engine"`
+	Version string            `json:"version,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
+	Pages   int               `json:"pages"`
+}
+
+// Extractor turns a PDF into per-page text.
+type Extractor interface {
+	Name() string
+	Extract(ctx context.Context, src string) (Pages, Metadata, error)
+}
+
+// NativeExtractor parses the PDF in-process without external tools. Password opens encrypted
+// files; an empty one opens files that only restrict permissions.
+type NativeExtractor struct {
+	Password string
+}
+
+// Name implements Extractor.
+func (NativeExtractor) Name() string { return "native" }
+
+// Extract implements Extractor.
+func (e NativeExtractor) Extract(ctx context.Context, src string) (Pages, Metadata, error) {
+	meta := Metadata{Engine: "native", Version: ToolVersion}
+	if err := ctx.Err(); err != nil {
+		return nil, meta, err
+	}
+	doc, err := loadPDF(src, e.Password)
+	if err != nil {
+		return nil, meta, fmt.Errorf("native extraction failed: %w", err)
+	}
+	pages, err := doc.extractPages()
+	if err != nil {
+		return nil, meta, fmt.Errorf("native extraction failed: %w", err)
+	}
+	meta.Pages = len(pages)
+	return pages, meta, nil
+}
+
+// PdftotextExtractor runs poppler's pdftotext. Layout keeps the physical column layout,
+// which the Form 16 tables rely on. Password is passed as the user password of encrypted
+// files.
+type PdftotextExtractor struct {
+	Layout   bool
+	Password string
+}
+
+// Name implements Extractor.
+func (PdftotextExtractor) Name() string { return "pdftotext" }
+
+// Extract implements Extractor.
+func (e PdftotextExtractor) Extract(ctx context.Context, src string) (Pages, Metadata, error) {
+	meta := Metadata{
+		Engine:  "pdftotext",
+		Version: toolVersion(ctx, "pdftotext", "-v"),
+		Options: map[string]string{"layout": strconv.FormatBool(e.Layout)},
+	}
+	args := append(passwordArgs(e.Password), src, "-")
+	if e.Layout {
+		args = append([]string{"-layout"}, args...)
+	}
+	out, err := exec.CommandContext(ctx, "pdftotext", args...).Output()
+	if err != nil {
+		return nil, meta, fmt.Errorf("pdftotext extraction failed: %w", popplerError(err))
+	}
+	pages := Pages(strings.Split(strings.TrimSuffix(string(out), "\f"), "\f"))
+	meta.Pages = len(pages)
+	return pages, meta, nil
+}
+
+// OCRExtractor renders each page with pdftoppm and recognises it with tesseract. It is the
+// only engine that can read scanned certificates, at the cost of speed and accuracy.
+type OCRExtractor struct {
+	Language string
+	DPI      int
+	// Password opens encrypted files, as for PdftotextExtractor.
+	Password string
+}
+
+// Name implements Extractor.
+func (OCRExtractor) Name() string { return "ocr" }
+
+// Extract implements Extractor.
+func (e OCRExtractor) Extract(ctx context.Context, src string) (Pages, Metadata, error) {
+	lang, dpi := e.Language, e.DPI
+	if lang == "" {
+		lang = "eng"
+	}
+	if dpi <= 0 {
+		dpi = 300
+	}
+	meta := Metadata{
+		Engine:  "ocr",
+		Version: toolVersion(ctx, "tesseract", "--version"),
+		Options: map[string]string{"language": lang, "dpi": strconv.Itoa(dpi)},
+	}
+
+	ws, err := NewWorkspace("ocr")
+	if err != nil {
+		return nil, meta, err
+	}
+	defer ws.Close()
+
+	prefix := ws.Path("page")
+	args := append(passwordArgs(e.Password), "-r", strconv.Itoa(dpi), "-png", src, prefix)
+	if _, err := exec.CommandContext(ctx, "pdftoppm", args...).Output(); err != nil {
+		return nil, meta, fmt.Errorf("failed to rasterize PDF: %w", popplerError(err))
+	}
+	images, err := filepath.Glob(prefix + "-*.png")
+	if err != nil || len(images) == 0 {
+		return nil, meta, fmt.Errorf("failed to rasterize PDF: no page images produced")
+	}
+	// pdftoppm zero-pads page numbers to the width of the page count, so a lexical sort
+	// gives document order.
+	sort.Strings(images)
+
+	pages := make(Pages, 0, len(images))
+	for _, img := range images {
+		out, err := exec.CommandContext(ctx, "tesseract", img, "-", "-l", lang, "--psm", "6").Output()
+		if err != nil {
+			return nil, meta, fmt.Errorf("OCR failed on %s: %v", filepath.Base(img), err)
+		}
+		pages = append(pages, string(out))
+	}
+	meta.Pages = len(pages)
+	return pages, meta, nil
+}
+
+// WordExtractor is an Extractor that also reports where each word sits on the page. The
+// text it extracts is PageBoxes.Text, so offsets found by the detectors map directly onto
+// word boxes.
+type WordExtractor interface {
+	Extractor
+	ExtractWords(ctx context.Context, src string) ([]PageBoxes, Metadata, error)
+}
+
+// NativeWordExtractor enumerates glyph positions with the native parser.
+type NativeWordExtractor struct {
+	Password string
+}
+
+// Name implements Extractor.
+func (NativeWordExtractor) Name() string { return "native-words" }
+
+// ExtractWords implements WordExtractor.
+func (e NativeWordExtractor) ExtractWords(ctx context.Context, src string) ([]PageBoxes, Metadata, error) {
+	meta := Metadata{Engine: "native-words", Version: ToolVersion}
+	if err := ctx.Err(); err != nil {
+		return nil, meta, err
+	}
+	doc, err := loadPDF(src, e.Password)
+	if err != nil {
+		return nil, meta, fmt.Errorf("native extraction failed: %w", err)
+	}
+	pages, err := nativeWordBoxes(doc)
+	if err != nil {
+		return nil, meta, fmt.Errorf("native extraction failed: %w", err)
+	}
+	meta.Pages = len(pages)
+	return pages, meta, nil
+}
+
+// Extract implements Extractor.
+func (e NativeWordExtractor) Extract(ctx context.Context, src string) (Pages, Metadata, error) {
+	return wordPages(e.ExtractWords(ctx, src))
+}
+
+// PdftotextWordExtractor reads word boxes from pdftotext -bbox-layout.
+type PdftotextWordExtractor struct {
+	Password string
+}
+
+// Name implements Extractor.
+func (PdftotextWordExtractor) Name() string { return "pdftotext-words" }
+
+// ExtractWords implements WordExtractor.
+func (e PdftotextWordExtractor) ExtractWords(ctx context.Context, src string) ([]PageBoxes, Metadata, error) {
+	meta := Metadata{
+		Engine:  "pdftotext-words",
+		Version: toolVersion(ctx, "pdftotext", "-v"),
+		Options: map[string]string{"bbox": "layout"},
+	}
+	pages, err := pdftotextWordBoxes(ctx, src, e.Password)
+	if err != nil {
+		return nil, meta, err
+	}
+	meta.Pages = len(pages)
+	return pages, meta, nil
+}
+
+// Extract implements Extractor.
+func (e PdftotextWordExtractor) Extract(ctx context.Context, src string) (Pages, Metadata, error) {
+	return wordPages(e.ExtractWords(ctx, src))
+}
+
+func wordPages(boxes []PageBoxes, meta Metadata, err error) (Pages, Metadata, error) {
+	if err != nil {
+		return nil, meta, err
+	}
+	pages := make(Pages, len(boxes))
+	for i, p := range boxes {
+		pages[i] = p.Text
+	}
+	return pages, meta, nil
+}
+
+// ExtractText runs the selected extraction engine. In "auto" mode the native extractor is
+// tried first, pdftotext is used as a fallback when the native one fails or finds no text,
+// and OCR is the last resort for scanned certificates that have no text layer at all. Each
+// fallback is only attempted when its tools are installed, and none is attempted for a file
+// that is not a PDF. Every engine opens encrypted files with password; a file that cannot be
+// opened with it is reported as ErrEncrypted. A document without any text is reported as
+// ErrNoText.
+func ExtractText(ctx context.Context, pdfFile, engine, password string, warnings *Warnings) (Pages, Metadata, error) {
+	if extractor, ok := ExtractorByName(engine); ok {
+		return requireText(withPassword(extractor, password).Extract(ctx, pdfFile))
+	}
+
+	pages, meta, err := NativeExtractor{Password: password}.Extract(ctx, pdfFile)
+	if err == nil && strings.TrimSpace(pages.Text()) != "" || errors.Is(err, ErrUnsupportedFormat) {
+		return pages, meta, err
+	}
+	if _, lookErr := exec.LookPath("pdftotext"); lookErr == nil {
+		if err != nil {
+			warnings.Add(WarnExtractionFallback, "%v; falling back to pdftotext", err)
+		} else {
+			warnings.Add(WarnExtractionFallback, "native extractor found no text; falling back to pdftotext")
+		}
+		pages, meta, err = PdftotextExtractor{Layout: true, Password: password}.Extract(ctx, pdfFile)
+		if err == nil && strings.TrimSpace(pages.Text()) != "" || errors.Is(err, ErrUnsupportedFormat) || errors.Is(err, ErrEncrypted) {
+			return pages, meta, err
+		}
+	}
+	if !ocrAvailable() {
+		return requireText(pages, meta, err)
+	}
+	if err != nil {
+		warnings.Add(WarnExtractionFallback, "%v; falling back to OCR", err)
+	} else {
+		warnings.Add(WarnExtractionFallback, "no text layer found (scanned PDF?); falling back to OCR")
+	}
+	return requireText(OCRExtractor{Password: password}.Extract(ctx, pdfFile))
+}
+
+// requireText passes an extraction result through, turning a blank one into ErrNoText.
+func requireText(pages Pages, meta Metadata, err error) (Pages, Metadata, error) {
+	if err == nil && strings.TrimSpace(pages.Text()) == "" {
+		err = ErrNoText
+	}
+	return pages, meta, err
+}
+
+// popplerError classifies the error of a failed poppler tool by the message it printed, so an
+// encrypted or non-PDF input is reported as ErrEncrypted or ErrUnsupportedFormat.
+func popplerError(err error) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	switch stderr := string(exitErr.Stderr); {
+	case strings.Contains(stderr, "Incorrect password"):
+		return fmt.Errorf("%w: %v", ErrEncrypted, err)
+	case strings.Contains(stderr, "May not be a PDF file"):
+		return fmt.Errorf("%w: not a PDF file", ErrUnsupportedFormat)
+	}
+	return err
+}
+
+// passwordArgs returns the poppler options that open an encrypted file with password.
+func passwordArgs(password string) []string {
+	if password == "" {
+		return nil
+	}
+	return []string{"-upw", password}
+}
+
+// ocrAvailable reports whether the tools used by OCRExtractor are installed.
+func ocrAvailable() bool {
+	for _, tool := range []string{"pdftoppm", "tesseract"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// toolVersion returns the first line an external tool prints for its version flag. Poppler
+// writes it to stderr, tesseract to stdout.
+func toolVersion(ctx context.Context, name string, args ...string) string {
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Run(); err != nil && out.Len() == 0 {
+		return ""
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(out.String()), "\n")
+	return strings.TrimSpace(line)
+}
+
+// ExtractorByName maps the -extractor flag values to engines. "auto" is handled by
+// extractText and is not an engine of its own.
+func ExtractorByName(name string) (Extractor, bool) {
+	switch name {
+	case "native":
+		return NativeExtractor{}, true
+	case "pdftotext":
+		return PdftotextExtractor{Layout: true}, true
+	case "ocr":
+		return OCRExtractor{}, true
+	case "native-words":
+		return NativeWordExtractor{}, true
+	case "pdftotext-words":
+		return PdftotextWordExtractor{}, true
+	}
+	return nil, false
+}
+
+// withPassword returns a copy of the engine e that opens encrypted files with password.
+func withPassword(e Extractor, password string) Extractor {
+	switch e := e.(type) {
+	case NativeExtractor:
+		e.Password = password
+		return e
+	case PdftotextExtractor:
+		e.Password = password
+		return e
+	case OCRExtractor:
+		e.Password = password
+		return e
+	case NativeWordExtractor:
+		e.Password = password
+		return e
+	case PdftotextWordExtractor:
+		e.Password = password
+		return e
+	}
+	return e
+}
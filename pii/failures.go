@@ -0,0 +1,80 @@
+package pii
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// PanicError is returned when a detector or pipeline stage panics while processing a
+// document. The panic is recovered so that the remaining documents can still be processed.
+type PanicError struct {
+	Value     interface{}
+	StackHash string
+	Stack     string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v (stack %s)", e.Value, e.StackHash)
+}
+
+// Failure records a document that could not be processed.
+type Failure struct {
+	Document  string
+	Error     string
+	StackHash string
+}
+
+// NewPanicError captures the current goroutine stack; it must be called from the deferred
+// function that recovered the panic.
+func NewPanicError(value interface{}) *PanicError {
+	stack := string(debug.Stack())
+	return &PanicError{Value: value, StackHash: stackHash(stack), Stack: stack}
+}
+
+// stackHash returns a short fingerprint of a stack trace. Goroutine IDs, argument values and
+// PC offsets are stripped so the same crash site hashes identically across runs.
+func stackHash(stack string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(stack, "\n") {
+		if strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+		if strings.HasPrefix(line, "\t") {
+			// File/line entry: drop the trailing " +0x1f" program counter offset.
+			if i := strings.LastIndex(line, " +0x"); i >= 0 {
+				line = line[:i]
+			}
+		} else if i := strings.LastIndex(line, "("); i >= 0 {
+			// Function entry: drop the argument list.
+			line = line[:i]
+		}
+		b.WriteString(strings.TrimSpace(line))
+		b.WriteByte('\n')
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// SaveFailuresReport writes one entry per failed document so that crashes can be triaged
+// after a run without scrolling through console output.
+func SaveFailuresReport(failures []Failure, outputFile string) error {
+	file, err := CreateOutput(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create failures report: %v", err)
+	}
+	defer file.Close()
+
+	file.WriteString("=== FAILED DOCUMENTS ===\n\n")
+	for _, f := range failures {
+		file.WriteString(fmt.Sprintf("Document: %s\n", f.Document))
+		file.WriteString(fmt.Sprintf("Error: %s\n", f.Error))
+		if f.StackHash != "" {
+			file.WriteString(fmt.Sprintf("Stack hash: %s\n", f.StackHash))
+		}
+		file.WriteString("\n")
+	}
+	return nil
+}
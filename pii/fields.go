@@ -0,0 +1,191 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RetainedFields keys of the structured Form 16 fields. Figures are copied as printed.
+const (
+	FieldAssessmentYear   = "Assessment Year"
+	FieldTDSQuarters      = "TDS Quarters"
+	FieldGrossSalary      = "Gross Salary"
+	FieldDeduction80C     = "Deductions under 80C"
+	FieldTotalTaxDeducted = "Total Tax Deducted"
+	// FieldPreviousEmployerSalary and FieldPreviousEmployerTDS are the salary and tax deducted
+	// reported from other employers of the year, and FieldSection89Relief the relief for
+	// arrears of salary; all three are Part B rows.
+	FieldPreviousEmployerSalary = "Previous Employer Salary"
+	FieldPreviousEmployerTDS    = "Previous Employer TDS"
+	FieldSection89Relief        = "Section 89 Relief"
+)
+
+var (
+	assessmentYearLabel = regexp.MustCompile(`(?i)Assessment\s+Year`)
+	// quarterRowPattern matches a row of the Part A quarterly summary.
+	quarterRowPattern = regexp.MustCompile(`^\s*(Q[1-4])\b`)
+	// grossSalaryPattern heads item 1 of Part B; its figure is on the heading or on the
+	// first total row below it.
+	grossSalaryPattern = regexp.MustCompile(`(?i)^\s*(?:1\.?\s*)?Gross\s+Salary\b`)
+	subtotalRowPattern = regexp.MustCompile(`(?i)^\s*(?:\([a-z]\)\s*)?Total\b`)
+	section80CPattern  = regexp.MustCompile(`(?i)\bsection\s*80\s*C\b`)
+	// previousSalaryPattern and previousTDSPattern head the Part B rows of the salary and tax
+	// reported from other employers: "(e) Reported total amount of salary received from other
+	// employer(s)", "Tax deducted by previous employer".
+	previousSalaryPattern = regexp.MustCompile(`(?i)\bsalary\s+(?:received\s+|paid\s+)?(?:from|by)\s+(?:the\s+)?(?:other|previous|former)\s+employer`)
+	previousTDSPattern    = regexp.MustCompile(`(?i)\b(?:tax|TDS)\s+deducted\s+by\s+(?:the\s+)?(?:other|previous|former)\s+employer`)
+	section89Pattern      = regexp.MustCompile(`(?i)\bRelief\s+(?:u/s\.?|under\s+section)\s*89\b`)
+
+	// previousEmployerLabelPattern marks the lines naming a previous employer, whose name is
+	// redacted as EntityPreviousEmployer.
+	previousEmployerLabelPattern = regexp.MustCompile(`(?i)\b(?:previous|former|earlier)\s+employer|\bName\s+of\s+(?:the\s+)?other\s+employer`)
+	// previousEmployerHeadingPattern heads a table of previous employers, one per row with the
+	// name in the first column: "Name of the previous employer   TAN   Salary paid   TDS".
+	previousEmployerHeadingPattern = regexp.MustCompile(`(?i)^\s*Name\s+of\s+(?:the\s+)?(?:previous|former|earlier|other)\s+employer(?:\(s\)|s)?\s{2,}\S`)
+	// previousEmployerRowPattern matches the first column of a row, ended by two spaces.
+	previousEmployerRowPattern = regexp.MustCompile(`^\s*(` + employerRun + `)\s{2,}`)
+)
+
+// employerRun matches an employer name: capitalised words joined by single spaces, with the
+// lower-case words and punctuation of company names ("Bank of Baroda", "L&T Infotech Ltd.").
+const employerRun = `[A-Z][A-Za-z0-9&'.-]*(?: [A-Za-z&(][A-Za-z0-9&'.()-]*)*`
+
+// grossSalaryWindow is how many lines below the Gross Salary heading its total is looked for.
+const grossSalaryWindow = 10
+
+// formFields parses the business figures of a Form 16 into RetainedFields entries: the
+// assessment year, one entry per TDS quarter, the gross salary, the salary and tax reported
+// from previous employers, the 80C deduction, the Section 89 relief, the tax deducted from
+// the Part A total row and the perquisites of the Form 12BA annexure. Fields not found are
+// left out; a bundle gives one value per certificate. Every certificate is parsed by the
+// rules of its layout version, which is recorded under FieldForm16Layout. A certificate of
+// an unrecognised template (see structureGaps) is recorded as LayoutUnstructured and left
+// unparsed, its figures being redacted or kept like any other text.
+func formFields(text string) map[string][]string {
+	fields := make(map[string][]string)
+	for _, body := range certificateTexts(text) {
+		if _, unstructured := structureGaps(body); unstructured {
+			fields[FieldForm16Layout] = append(fields[FieldForm16Layout], LayoutUnstructured)
+			continue
+		}
+		certificate := certificateFormFields(body)
+		if layout := detectLayout(body); layout != nil {
+			layout.apply(body, certificate)
+			certificate[FieldForm16Layout] = []string{layout.Name}
+		}
+		for key, values := range certificate {
+			fields[key] = append(fields[key], values...)
+		}
+	}
+	return fields
+}
+
+// certificateFormFields parses the fields of formFields from the text of one certificate as
+// laid out since FY 2018-19.
+func certificateFormFields(text string) map[string][]string {
+	fields := make(map[string][]string)
+	add := func(key, value string) {
+		fields[key] = append(fields[key], value)
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		switch {
+		case quarterRowPattern.MatchString(line):
+			figures := figurePattern.FindAllString(line, -1)
+			if len(figures) < 3 {
+				continue
+			}
+			q := quarterRowPattern.FindStringSubmatch(line)[1]
+			add(FieldTDSQuarters, fmt.Sprintf("%s: amount paid %s, tax deducted %s, tax deposited %s", q, figures[0], figures[1], figures[2]))
+		case totalRowPattern.MatchString(line):
+			if figures := figurePattern.FindAllString(line, -1); len(figures) >= 2 {
+				add(FieldTotalTaxDeducted, figures[1])
+			}
+		case previousEmployerRow(lines, i, 0) && previousEmployerRowPattern.MatchString(line):
+			// A row of the previous-employer table: the salary paid comes before the tax
+			// deducted, the TAN between them is no figure.
+			figures := figurePattern.FindAllString(line, -1)
+			add(FieldPreviousEmployerSalary, figures[0])
+			if len(figures) > 1 {
+				add(FieldPreviousEmployerTDS, figures[len(figures)-1])
+			}
+		case previousSalaryPattern.MatchString(line):
+			// Checked before the gross salary: the row belongs to item 1 of Part B.
+			if figures := figurePattern.FindAllString(line, -1); len(figures) > 0 {
+				add(FieldPreviousEmployerSalary, figures[len(figures)-1])
+			}
+		case previousTDSPattern.MatchString(line):
+			if figures := figurePattern.FindAllString(line, -1); len(figures) > 0 {
+				add(FieldPreviousEmployerTDS, figures[len(figures)-1])
+			}
+		case section89Pattern.MatchString(line):
+			if figures := figurePattern.FindAllString(line, -1); len(figures) > 0 {
+				add(FieldSection89Relief, figures[len(figures)-1])
+			}
+		case grossSalaryPattern.MatchString(line):
+			if gross := grossSalary(lines, i); gross != "" {
+				add(FieldGrossSalary, gross)
+			}
+		case section80CPattern.MatchString(line):
+			// The deductible amount is the last column.
+			if figures := figurePattern.FindAllString(line, -1); len(figures) > 0 {
+				add(FieldDeduction80C, figures[len(figures)-1])
+			}
+		}
+	}
+	for _, row := range perquisites(text) {
+		add(FieldPerquisites, row)
+	}
+	if assessmentYearLabel.MatchString(text) {
+		for _, year := range assessmentYearPattern.FindAllString(text, -1) {
+			add(FieldAssessmentYear, year)
+		}
+	}
+	return fields
+}
+
+// grossSalary returns the figure of the Gross Salary heading on lines[n]: on the heading
+// itself, or on the first total row below it.
+func grossSalary(lines []string, n int) string {
+	if figures := figurePattern.FindAllString(lines[n], -1); len(figures) > 0 {
+		return figures[len(figures)-1]
+	}
+	for k := n + 1; k < len(lines) && k <= n+grossSalaryWindow; k++ {
+		// "(e) Total amount of salary received from other employer(s)" is a row of the
+		// heading, not its total.
+		if !subtotalRowPattern.MatchString(lines[k]) || previousSalaryPattern.MatchString(lines[k]) {
+			continue
+		}
+		if figures := figurePattern.FindAllString(lines[k], -1); len(figures) > 0 {
+			return figures[len(figures)-1]
+		}
+	}
+	return ""
+}
+
+// validPreviousEmployer rejects the previous-employer matches that are column headings
+// rather than a name: "Name of the previous employer   TAN of previous employer".
+func validPreviousEmployer(match string) bool {
+	first := strings.ToLower(strings.Trim(strings.Fields(match)[0], ".'-&("))
+	_, amount := amountVocabulary[first]
+	return !amount && !nameStopwords[first] && first != "details" && first != "particulars" && first != "tds"
+}
+
+// previousEmployerRow accepts the first column of lines[n] when the line holds a figure and
+// follows a previous-employer heading, or a row accepted before it.
+func previousEmployerRow(lines []string, n, _ int) bool {
+	if !figurePattern.MatchString(lines[n]) {
+		return false
+	}
+	for k := n - 1; k >= 0; k-- {
+		if previousEmployerHeadingPattern.MatchString(lines[k]) {
+			return true
+		}
+		m := previousEmployerRowPattern.FindStringSubmatch(lines[k])
+		if m == nil || !figurePattern.MatchString(lines[k]) || !validPreviousEmployer(m[1]) {
+			return false
+		}
+	}
+	return false
+}
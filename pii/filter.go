@@ -0,0 +1,1472 @@
+// Package pii detects and redacts personal data in Form 16 salary certificates. It holds the
+// redaction engine (Filter), the PDF text extractors and the report writers used by the
+// pdf-reader command, so other Go programs can embed the same pipeline.
+package pii
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// addressPlaces lists the Indian states and major city names matched by AddressPattern.
+var addressPlaces = []string{
+	"Ahmedabad", "Bangalore", "Bengaluru", "Mumbai", "Bombay", "Chennai", "Kolkata", "Calcutta",
+	"Hyderabad", "Delhi", "New Delhi", "Pune", "Jaipur", "Surat", "Lucknow", "Kanpur", "Nagpur",
+	"Indore", "Thane", "Bhopal", "Visakhapatnam", "Vizag", "Vadodara", "Baroda", "Firozabad",
+	"Ludhiana", "Patna", "Agra", "Nashik", "Faridabad", "Meerut", "Rajkot", "Kalyan", "Vasai",
+	"Varanasi", "Srinagar", "Aurangabad", "Dhanbad", "Amritsar", "Ranchi", "Gwalior", "Jabalpur",
+	"Coimbatore", "Guwahati", "Chandigarh", "Hubli", "Dharwad", "Mysore", "Mysuru", "Noida",
+	"Ghaziabad", "Kozhikode", "Calicut", "Trivandrum", "Thiruvananthapuram", "Kochi", "Ernakulam",
+	"Madurai", "Tiruchirappalli", "Trichy", "Salem", "Guntur", "Vijayawada", "Nellore", "Warangal",
+	"Karimnagar", "Raipur", "Bhubaneswar", "Cuttack", "Shimla", "Dehradun", "Gangtok", "Shillong",
+	"Imphal", "Aizawl", "Kohima", "Itanagar", "Agartala", "Gandhinagar", "Allahabad", "Prayagraj",
+	"Gorakhpur", "Bareilly", "Jodhpur", "Udaipur", "Kolhapur", "Solapur", "Ahmednagar", "Mangaluru",
+	"Mangalore", "Béngaluru", "Bilaspur", "Durgapur", "Siliguri", "Asansol", "Dibrugarh", "Panipat",
+	"Rohtak", "Hisar", "Jamshhedpur", "Bokaro", "Rourkela", "Belgaum", "Belagavi", "Saharanpur",
+	"Aligarh", "Moradabad", "Muzaffarpur", "Gaya", "Darbhanga", "Bhagalpur", "Kota", "Ajmer",
+	"Mathura", "Haldwani", "Nainital", "Pithoragarh", "Kullu", "Manali", "Shimoga", "Tumkur",
+	"Davangere", "Mangalore", "Goa", "Panaji", "Vile Parle", "Maharashtra", "Gujarat", "Karnataka",
+	"Tamil Nadu", "Uttar Pradesh", "Madhya Pradesh", "Rajasthan", "Punjab", "Haryana", "Bihar",
+	"West Bengal", "Odisha", "Kerala", "Telangana", "Andhra Pradesh", "Chhattisgarh", "Uttarakhand",
+	"Himachal Pradesh", "Assam", "Jharkhand", "Tripura", "Manipur", "Mizoram", "Nagaland",
+	"Arunachal Pradesh", "Sikkim", "Meghalaya", "Puducherry", "Ladakh", "Jammu and Kashmir",
+	"Andaman and Nicobar Islands", "Lakshadweep", "Daman and Diu", "Dadra and Nagar Haveli",
+}
+
+// addressKeywords lists the default keywords matched by AddressKeywordPattern; callers can
+// replace them with WithAddressKeywords. Abbreviations are listed without their trailing
+// period; whole-word matching makes "Rd." and "Rd" equivalent.
+var addressKeywords = []string{
+	"House", "Block", "Tower", "Flat", "Floor", "Flr", "Road", "Rd", "Street", "St", "Lane", "Ln",
+	"Sector", "Plot", "Opp", "Near", "Behind",
+}
+
+// GSTPolicy controls how GSTIN matches are treated by FilterPII.
+type GSTPolicy string
+
+const (
+	// GSTRedact replaces the whole GSTIN with [GST_REDACTED] (default).
+	GSTRedact GSTPolicy = "redact"
+	// GSTMask keeps the state code and the last three characters and masks the embedded PAN.
+	GSTMask GSTPolicy = "mask"
+	// GSTRetain leaves the GSTIN untouched and reports it under RetainedFields["Employer GSTIN"].
+	GSTRetain GSTPolicy = "retain"
+)
+
+// ParseGSTPolicy validates a policy name supplied on the command line.
+func ParseGSTPolicy(s string) (GSTPolicy, error) {
+	switch p := GSTPolicy(strings.ToLower(s)); p {
+	case GSTRedact, GSTMask, GSTRetain:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown GST policy %q (expected redact, mask or retain)", s)
+}
+
+// MatchValuePolicy controls whether FilteredData.Matches record the text that was replaced.
+type MatchValuePolicy string
+
+const (
+	// MatchValueNone records offsets only (default), so reports never contain the PII.
+	MatchValueNone MatchValuePolicy = "none"
+	// MatchValueHash records the SHA-256 of each value, so the same value can be traced across
+	// documents without storing it. Short identifiers such as phone numbers can be recovered
+	// from an unsalted hash by brute force; treat hashed reports as confidential.
+	MatchValueHash MatchValuePolicy = "hash"
+	// MatchValuePlain records each value as is, for trusted highlighting and audit tools.
+	MatchValuePlain MatchValuePolicy = "plain"
+)
+
+// ParseMatchValuePolicy validates a policy name supplied on the command line.
+func ParseMatchValuePolicy(s string) (MatchValuePolicy, error) {
+	switch p := MatchValuePolicy(strings.ToLower(s)); p {
+	case MatchValueNone, MatchValueHash, MatchValuePlain:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown match value policy %q (expected none, hash or plain)", s)
+}
+
+// maskGSTIN hides the PAN portion of a GSTIN, e.g. 27ABCDE1234F1Z5 -> 27**********1Z5.
+func maskGSTIN(gstin string) string {
+	return gstin[:2] + strings.Repeat("*", len(gstin)-5) + gstin[len(gstin)-3:]
+}
+
+// Filter contains regex patterns for identifying PII data in Form 16.
+//
+// A Filter is immutable once NewFilter returns: FilterPII only reads its configuration
+// and keeps all per-call state local, so a single filter is safe for concurrent use by many
+// goroutines. Do not modify the exported fields after construction; use Clone to derive a
+// filter with different options instead.
+type Filter struct {
+	PhonePattern   *regexp.Regexp
+	EmailPattern   *regexp.Regexp
+	GSTPattern     *regexp.Regexp
+	PANPattern     *regexp.Regexp
+	AadhaarPattern *regexp.Regexp
+	TANPattern     *regexp.Regexp
+	AddressPattern *regexp.Regexp
+	// PINCodePattern matches six-digit postal PIN codes (560001, 560 001); they are only taken
+	// as PIN codes after a PIN label or a place name, as amounts share their shape.
+	PINCodePattern *regexp.Regexp
+	// UANPattern matches 12-digit numbers; they are only taken as UANs under a UAN label and
+	// otherwise left to the Aadhaar detector.
+	UANPattern *regexp.Regexp
+	// PFAccountPattern matches EPF member account numbers such as MH/BAN/1234567/000/1234567.
+	PFAccountPattern *regexp.Regexp
+	// PassportPattern, VoterIDPattern and DrivingLicencePattern match the identity proofs
+	// sometimes attached to a Form 16: passport numbers (a letter and seven digits), EPIC voter
+	// IDs (three letters and seven digits) and driving licence numbers (state, RTO, year of
+	// issue and serial, e.g. MH12 20110012345).
+	PassportPattern       *regexp.Regexp
+	VoterIDPattern        *regexp.Regexp
+	DrivingLicencePattern *regexp.Regexp
+	// BankAccountPattern, FolioPattern and ChallanPattern match the identifiers of the Form 26AS
+	// and AIS profiles: bank account numbers, printed in full or masked (XXXXXX1234) after an
+	// account label, mutual fund folio numbers after a folio label and the serial numbers of
+	// tax challans next to their BSR code. Each reports its first capture group.
+	BankAccountPattern *regexp.Regexp
+	FolioPattern       *regexp.Regexp
+	ChallanPattern     *regexp.Regexp
+	// EmployeeIDPattern, ESIPattern and IFSCPattern match the identifiers of the payslip
+	// profile: employee codes and ESI insurance numbers together with their label, and the
+	// IFSC codes of salary accounts. Each reports its first capture group.
+	EmployeeIDPattern *regexp.Regexp
+	ESIPattern        *regexp.Regexp
+	IFSCPattern       *regexp.Regexp
+	// PreviousEmployerPattern matches the name of a previous employer after its Part B label
+	// ("Name of the previous employer: Wipro") and reports it as its first capture group. The
+	// rows of a previous-employer table are matched by their first column.
+	PreviousEmployerPattern *regexp.Regexp
+	// SSNPattern, NINOPattern and TFNPattern match the foreign tax identifiers of the optional
+	// detector packs: US Social Security numbers (123-45-6789), UK National Insurance numbers
+	// (AB 12 34 56 C) and Australian Tax File Numbers (123 456 782).
+	SSNPattern  *regexp.Regexp
+	NINOPattern *regexp.Regexp
+	TFNPattern  *regexp.Regexp
+	// NamePattern matches a capitalised run of words; it is only applied on the line right
+	// below, or on the same line as, a line matching NameLabelPattern.
+	NamePattern      *regexp.Regexp
+	NameLabelPattern *regexp.Regexp
+	// DatePattern matches dates; each match is classified by its label (see DateCategory).
+	DatePattern *regexp.Regexp
+	// Pattern for detecting organisation / company names so they are not redacted as addresses.
+	OrganizationPattern *regexp.Regexp
+	// Additional pattern that looks for generic address-related keywords (e.g., House, Road,
+	// Block, Sector, Opp., Near, etc.) to catch address lines that don't explicitly mention a
+	// city or state name.
+	AddressKeywordPattern *regexp.Regexp
+
+	// PhoneRegions lists the regions whose numbers written in national format are detected
+	// (see WithPhoneRegions); unspaced Indian numbers also use PhonePattern.
+	PhoneRegions []string
+	// GSTPolicy selects whether GSTINs are redacted, masked or retained.
+	GSTPolicy GSTPolicy
+	// EmailPolicy selects whether the domains of e-mail addresses are kept or tokenised.
+	EmailPolicy EmailPolicy
+	// AmountWords selects how amounts written in words are treated.
+	AmountWords AmountWordsPolicy
+	// MatchValues selects whether FilteredData.Matches carry the original values.
+	MatchValues MatchValuePolicy
+	// Dates lists the date categories that are redacted.
+	Dates DatePolicy
+	// HeaderFooters selects how lines repeated at the top or bottom of every page are treated.
+	HeaderFooters HeaderFooterPolicy
+
+	// addressMatcher, when set, replaces AddressPattern and AddressKeywordPattern with a single
+	// multi-pattern scan over all literal address keywords.
+	addressMatcher *KeywordMatcher
+	// addressKeywords is the keyword list behind AddressKeywordPattern and addressMatcher.
+	addressKeywords []string
+
+	// profile holds the country assumptions: registry, gazetteer and identifiers.
+	profile *Profile
+	// personNames is the profile's name gazetteer, lower-cased (see gazetteerName).
+	personNames map[string]bool
+	// recognizers is the detector registry in priority order (see Recognizer).
+	recognizers []Recognizer
+	disabled    map[string]bool
+	// enabled, when not nil, holds the only entity types detected (see WithOnly).
+	enabled      map[string]bool
+	placeholders map[string]string
+	strict       bool
+	panContext   bool
+	locale       *Locale
+	// minConfidence drops findings scored below it (see WithMinConfidence).
+	minConfidence float64
+	// explain attaches an Explanation to every match (see WithExplain).
+	explain bool
+	// timings records the time per detector (see WithTimings).
+	timings bool
+	// document is the document type set with WithDocumentProfile; nil detects it per
+	// document. documentType is the type of the document being redacted, resolved by
+	// forDocument.
+	document     *DocumentProfile
+	documentType *DocumentProfile
+	// volumeRules are the expected finding ranges checked after filtering.
+	volumeRules []VolumeRule
+	// handwriting finds handwritten regions on rendered pages; nil means StrokeDetector.
+	handwriting HandwritingDetector
+	// vault, when set, replaces placeholders with reversible tokens; it is shared by clones.
+	vault *Vault
+	// numbered replaces placeholders with tokens numbered per document (see
+	// WithNumberedPlaceholders); renumber is set on the per-document copy made by forDocument,
+	// whose vault only holds the tokens of that document.
+	numbered bool
+	renumber bool
+	// domainTokens issues the EmailOrgToken domain tokens when there is no vault, so that
+	// tokens stay stable across the documents of a filter; clones continue from a copy.
+	domainTokens *Vault
+	// allowlist holds the values that are never redacted (see WithAllowlist);
+	// allowlistPattern matches any of them.
+	allowlist        map[string]bool
+	allowlistPattern *regexp.Regexp
+	// glossary holds the terms added to the built-in tax glossary (see WithGlossary);
+	// glossaryPattern matches these and the built-in ones, nil standing for the latter.
+	glossary        []string
+	glossaryPattern *regexp.Regexp
+	// denylistPattern matches the denylist entries (see WithDenylist).
+	denylistPattern *regexp.Regexp
+	// suppressions holds the false positives marked by reviewers; it is shared by clones.
+	suppressions *SuppressionStore
+	// foreignPhonePattern matches the grouped national numbers of PhoneRegions and the
+	// international numbers of all supported regions, India's included.
+	foreignPhonePattern *regexp.Regexp
+}
+
+// Clone returns an independent copy of the filter with the given options applied on top of
+// the current configuration. The receiver is left untouched, which makes Clone suitable for
+// per-request overrides of a shared filter, concurrently with FilterPII calls on it. Only the
+// vault and the suppression store stay shared, as they are meant to outlive any one filter.
+func (pf *Filter) Clone(opts ...Option) *Filter {
+	c := *pf
+	c.disabled = make(map[string]bool, len(pf.disabled))
+	for k, v := range pf.disabled {
+		c.disabled[k] = v
+	}
+	c.placeholders = make(map[string]string, len(pf.placeholders))
+	for k, v := range pf.placeholders {
+		c.placeholders[k] = v
+	}
+	c.recognizers = make([]Recognizer, len(pf.recognizers))
+	for i, r := range pf.recognizers {
+		// Built-in recognizers read the patterns and policies of the filter they belong to.
+		if b, ok := r.(builtinRecognizer); ok {
+			r = builtinRecognizer{name: b.name, pf: &c}
+		}
+		c.recognizers[i] = r
+	}
+	c.volumeRules = slices.Clone(pf.volumeRules)
+	c.Dates = slices.Clone(pf.Dates)
+	c.PhoneRegions = slices.Clone(pf.PhoneRegions)
+	c.allowlist = maps.Clone(pf.allowlist)
+	c.enabled = maps.Clone(pf.enabled)
+	c.glossary = slices.Clone(pf.glossary)
+	c.addressKeywords = slices.Clone(pf.addressKeywords)
+	if pf.domainTokens != nil {
+		c.domainTokens = pf.domainTokens.clone()
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &c
+}
+
+// UseMultiPatternEngine switches the literal-keyword address detectors to a single
+// Aho-Corasick scan. Detectors with complex patterns keep using their individual regexes.
+func (pf *Filter) UseMultiPatternEngine() {
+	keywords := make([]string, 0, len(pf.profile.Places)+len(pf.addressKeywords))
+	keywords = append(keywords, pf.profile.Places...)
+	keywords = append(keywords, pf.addressKeywords...)
+	pf.addressMatcher = NewKeywordMatcher(keywords)
+}
+
+// FilteredData represents the cleaned data structure.
+//
+// Ordering guarantees: RemovedFields lists categories in the fixed order in which FilterPII
+// runs its detectors, and every report writer iterates RetainedFields in sorted key order,
+// so identical inputs always produce byte-identical outputs.
+type FilteredData struct {
+	CleanedText    string              `json:"cleaned_text"`
+	RemovedFields  []string            `json:"removed_fields"`
+	RetainedFields map[string][]string `json:"retained_fields"`
+	// MatchCounts holds the number of replacements made per category label (address and
+	// organisation categories count redacted lines).
+	MatchCounts map[string]int `json:"match_counts"`
+	// SampleMasks keeps up to maxSamplesPerType partially masked examples per token category
+	// so reports can show what kind of value was removed without exposing it.
+	SampleMasks map[string][]string `json:"sample_masks,omitempty"`
+	// Matches locates every detector replacement in the input text, in line order. Words
+	// removed by the dictionary filter are not listed.
+	Matches []Match `json:"matches"`
+	// Warnings lists non-fatal issues met while producing this result.
+	Warnings []Warning `json:"warnings,omitempty"`
+	// Certificates holds per-certificate findings, in document order, when the text is a
+	// bundle of several Form 16 certificates; it is empty for a single certificate.
+	Certificates []CertificateFindings `json:"certificates,omitempty"`
+	// ProcessingBasis is the record-of-processing entry the result was produced under; it is
+	// set by the caller, not by FilterPII.
+	ProcessingBasis *ProcessingBasis `json:"processing_basis,omitempty"`
+	// Blocks is the block model of the document (header, employer, employee...), written by
+	// the "blocks" output format.
+	Blocks []Block `json:"-"`
+	// RepeatedLines lists the page header and footer lines deduplicated under the
+	// HeaderFooterDedupe and HeaderFooterRemove policies.
+	RepeatedLines []RepeatedLine `json:"repeated_lines,omitempty"`
+	// Stats counts the characters and words of the input text and the placeholders of the
+	// cleaned text.
+	Stats TextStats `json:"stats"`
+	// Timings is the time per detector, and per stage when set by the caller; it is only
+	// recorded under WithTimings.
+	Timings *Timings `json:"timings,omitempty"`
+
+	// keepAmountWords tells ApplyDictionaryFilter to leave amounts in words untouched.
+	keepAmountWords bool
+	// keepEmailDomains tells ApplyDictionaryFilter to leave domains kept by EmailKeepDomain.
+	keepEmailDomains bool
+	// allowlistPattern matches the allowlisted values, which ApplyDictionaryFilter leaves.
+	allowlistPattern *regexp.Regexp
+	// glossaryPattern matches the glossary terms, which ApplyDictionaryFilter leaves; nil
+	// stands for the built-in glossary.
+	glossaryPattern *regexp.Regexp
+	// wordPlaceholder replaces words removed by ApplyDictionaryFilter.
+	wordPlaceholder string
+	// locale translates the headers of the text report.
+	locale *Locale
+	// source is the input text the offsets of Matches refer to, for the HTML report.
+	source string
+	// placeholders are those the filter writes, counted into Stats; tokenized is set when
+	// a vault (or EmailOrgToken) writes tokens as well.
+	placeholders []string
+	tokenized    bool
+}
+
+// Match is one redacted region of the input text. Start and End are byte offsets into the
+// text passed to FilterPII; Line is 1-based. Organisation matches cover the whole line,
+// address matches the line or, on a layout line shared with other columns, its address
+// columns. Type is the category label, Entity the stable entity type ("pan", "phone"...).
+// Value or ValueHash carries the original text only when enabled with WithMatchValues.
+type Match struct {
+	Type   string `json:"type"`
+	Entity string `json:"entity"`
+	Line   int    `json:"line"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	// Confidence grades the match from 0 to 1 by the strength of its pattern, corroborating
+	// labels nearby and, for Aadhaar, PAN and GSTIN, the check character.
+	Confidence float64 `json:"confidence"`
+	Value      string  `json:"value,omitempty"`
+	ValueHash  string  `json:"value_sha256,omitempty"`
+	// Explanation records the detector, rule and settings behind the match; it is only set
+	// under WithExplain.
+	Explanation *Explanation `json:"explanation,omitempty"`
+	// Fingerprint identifies the value in its context without revealing it, for marking the
+	// match as a false positive in a SuppressionStore; it is only set under WithSuppressions.
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// newMatch builds the Match for the input text line[start:end] of line number n (0-based)
+// starting at byte lineStart, scored confidence, attaching the value as configured by
+// MatchValues.
+func (pf *Filter) newMatch(entity, label string, n, lineStart int, line string, start, end int, confidence float64) Match {
+	m := Match{Type: label, Entity: entity, Line: n + 1, Start: lineStart + start, End: lineStart + end, Confidence: confidence}
+	if pf.suppressions != nil {
+		m.Fingerprint = fingerprint(line, start, end)
+	}
+	switch pf.MatchValues {
+	case MatchValuePlain:
+		m.Value = line[start:end]
+	case MatchValueHash:
+		sum := sha256.Sum256([]byte(line[start:end]))
+		m.ValueHash = hex.EncodeToString(sum[:])
+	}
+	return m
+}
+
+// maxSamplesPerType bounds the number of masked examples stored per category.
+const maxSamplesPerType = 3
+
+// maskSample keeps the first and last two characters of a value and masks the rest; short
+// values are masked completely.
+func maskSample(value string) string {
+	r := []rune(value)
+	if len(r) <= 6 {
+		return strings.Repeat("*", len(r))
+	}
+	return string(r[:2]) + strings.Repeat("*", len(r)-4) + string(r[len(r)-2:])
+}
+
+// Categories returns the removed categories together with their match counts, e.g.
+// "PAN Numbers (2)", in RemovedFields order.
+func (d FilteredData) Categories() []string {
+	out := make([]string, 0, len(d.RemovedFields))
+	for _, field := range d.RemovedFields {
+		out = append(out, fmt.Sprintf("%s (%d)", field, d.MatchCounts[field]))
+	}
+	return out
+}
+
+// RetainedKeys returns the RetainedFields categories in sorted order.
+func (d FilteredData) RetainedKeys() []string {
+	return getKeys(d.RetainedFields)
+}
+
+// NewFilter creates a new PII filter with Form 16 specific regex patterns, customised by
+// the supplied options.
+func NewFilter(opts ...Option) *Filter {
+	pf := &Filter{
+		// Indian phone number patterns (10 digits starting with 6-9)
+		PhonePattern: regexp.MustCompile(`(?:\+91|91)?[-\.\s]?[6-9]\d{9}|\b[6-9]\d{9}\b`),
+
+		// Email pattern
+		EmailPattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Z|a-z]{2,}\b`),
+
+		// GST Number pattern (15 digits) - employer's GSTIN
+		GSTPattern: regexp.MustCompile(`\b\d{2}[A-Z]{5}\d{4}[A-Z]{1}[A-Z\d]{1}[Z]{1}[A-Z\d]{1}\b`),
+
+		// PAN Number pattern
+		PANPattern: regexp.MustCompile(`\b[A-Z]{5}[0-9]{4}[A-Z]{1}\b`),
+
+		// Aadhaar Number pattern (12 digits)
+		AadhaarPattern: regexp.MustCompile(`\b\d{4}\s?\d{4}\s?\d{4}\b|\b\d{12}\b`),
+
+		// TAN (Tax Deduction Account Number)
+		TANPattern: regexp.MustCompile(`(?i)\b[A-Z]{4}[0-9]{5}[A-Z]\b`),
+
+		// Postal PIN code (6 digits, never starting with 0)
+		PINCodePattern: regexp.MustCompile(`\b[1-9]\d{2} ?\d{3}\b`),
+
+		// EPF Universal Account Number (12 digits) and PF member account number, either
+		// slashed (state/office/establishment/extension/member) or as one 22-character code
+		UANPattern:       regexp.MustCompile(`\b\d{12}\b`),
+		PFAccountPattern: regexp.MustCompile(`\b[A-Z]{2}/[A-Z]{3}/\d{1,7}/\d{1,3}/\d{1,7}\b|\b[A-Z]{5}\d{17}\b`),
+
+		// Identity proofs: passport numbers never start with Q, X or Z or have a zero in the
+		// second and last place
+		PassportPattern:       regexp.MustCompile(`\b[A-PR-WY][1-9]\d{5}[1-9]\b`),
+		VoterIDPattern:        regexp.MustCompile(`\b[A-Z]{3}\d{7}\b`),
+		DrivingLicencePattern: regexp.MustCompile(`\b[A-Z]{2}[-\s]?\d{2}[-\s]?(?:19|20)\d{2}[-\s]?\d{7}\b`),
+
+		// Form 26AS and AIS identifiers, only detected in those documents: bank accounts
+		// (9-18 digits, or masked with X or * down to the last digits), folio numbers with an
+		// optional /nn scheme suffix and challan serial numbers (5 digits, 7 for the BSR code
+		// printed next to them)
+		BankAccountPattern: regexp.MustCompile(`\b(\d{9,18}|[Xx*]{2,14}\d{3,6})(?:[^\d.,]|$)`),
+		FolioPattern:       regexp.MustCompile(`\b(\d{5,15}(?:/\d{1,4})?)(?:[^\d.,/]|$)`),
+		ChallanPattern:     regexp.MustCompile(`\b(\d{7}|\d{5})(?:[^\d.,]|$)`),
+
+		// Payslip identifiers, only detected in payslips: employee codes (E10234, EMP/2019/042)
+		// and ESI insurance numbers (10 or 17 digits) right after their label, and IFSC codes
+		// (four letters, a zero and six characters) near an IFSC label
+		EmployeeIDPattern: regexp.MustCompile(`(?i)(?:\bEmp(?:loyee)?\.?\s*(?:Code|ID|No\b\.?|Number)|\bStaff\s+(?:ID|No\b\.?))\s*[:#.\-]?\s*([A-Z]{0,4}[-/]?\d{2,10}(?:/\d{1,6})?[A-Z]?)\b`),
+		ESIPattern:        regexp.MustCompile(`(?i)\bESIC?\s*(?:IP\s*)?(?:No\b\.?|Number)?\s*[:#.\-]?\s*(\d{10}(?:\d{7})?)\b`),
+		IFSCPattern:       regexp.MustCompile(`\b([A-Z]{4}0[A-Z0-9]{6})\b`),
+
+		// Previous employers of the year in Part B, after their label
+		PreviousEmployerPattern: regexp.MustCompile(`(?i:\bName\s+of\s+(?:the\s+)?(?:previous|former|earlier|other)\s+employer(?:\(s\)|s)?|\b(?:previous|former|earlier)\s+employer(?:'s)?(?:\s+name)?)\s*[:\-]?\s*(` + employerRun + `)`),
+
+		// Foreign tax identifiers, only used when their detector pack is enabled
+		SSNPattern:  regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		NINOPattern: regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`),
+		TFNPattern:  regexp.MustCompile(`\b\d{3} ?\d{3} ?\d{2,3}\b`),
+
+		// Personal names below "Name and address of the Employee" style labels
+		NamePattern:      regexp.MustCompile(`\b` + nameRun + `\b`),
+		NameLabelPattern: regexp.MustCompile(`(?i)\bname\b`),
+
+		// Dates in the numeric and month-name formats used by TRACES and payroll systems
+		DatePattern: regexp.MustCompile(dateExpr),
+
+		// Address pattern – matches well-known Indian states or major city names.
+		// Stand-alone 6-digit numbers (potential amounts) have been removed to avoid false positives.
+		AddressPattern: placesPattern(addressPlaces),
+
+		// Organisation keywords (case-insensitive) used to identify company names so they are
+		// not mistaken for addresses.
+		OrganizationPattern: regexp.MustCompile(`(?i)\b(?:Pvt\.?\s*Ltd\.?|Private\s+Limited|Ltd\.?|Limited|LLP|L\.L\.P\.?|LLC|L\.L\.C\.?|Inc\.?|Incorporated|Corp\.?|Corporation|Company|Co\.?\s*Ltd\.?|PLC|Pte\.?\s*Ltd\.?)\b`),
+
+		// Generic keywords that frequently appear in Indian street addresses but are unlikely to
+		// appear in normal narrative text.
+		AddressKeywordPattern: keywordPattern(addressKeywords),
+		addressKeywords:       addressKeywords,
+
+		PhoneRegions: []string{DefaultPhoneRegion},
+		GSTPolicy:    GSTRedact,
+		EmailPolicy:  EmailRedact,
+		AmountWords:  AmountWordsKeep,
+		MatchValues:  MatchValueNone,
+		Dates:        DatePolicy{DateBirth},
+
+		HeaderFooters: HeaderFooterKeep,
+
+		profile:             profiles[DefaultProfile],
+		personNames:         nameSet(indianNames),
+		disabled:            make(map[string]bool),
+		placeholders:        make(map[string]string),
+		volumeRules:         slices.Clone(defaultVolumeRules),
+		foreignPhonePattern: defaultForeignPhonePattern,
+	}
+	for _, name := range pf.profile.Recognizers {
+		pf.recognizers = append(pf.recognizers, builtinRecognizer{name: name, pf: pf})
+	}
+	for _, opt := range opts {
+		opt(pf)
+	}
+	return pf
+}
+
+// PIIFilter is the name Filter had before the engine moved into this package.
+//
+// Deprecated: use Filter.
+type PIIFilter = Filter
+
+// NewPIIFilter is the name NewFilter had before the engine moved into this package.
+//
+// Deprecated: use NewFilter.
+func NewPIIFilter(opts ...Option) *Filter {
+	return NewFilter(opts...)
+}
+
+// Category labels reported in RemovedFields and MatchCounts.
+const (
+	labelPhone            = "Phone Numbers"
+	labelEmail            = "Email Addresses"
+	labelAadhaar          = "Aadhaar Numbers"
+	labelPAN              = "PAN Numbers"
+	labelGST              = "GST Numbers"
+	labelTAN              = "TAN Numbers"
+	labelPINCode          = "PIN Codes"
+	labelUAN              = "UAN Numbers"
+	labelPFAccount        = "PF Account Numbers"
+	labelPassport         = "Passport Numbers"
+	labelVoterID          = "Voter IDs"
+	labelDrivingLicence   = "Driving Licence Numbers"
+	labelBankAccount      = "Bank Account Numbers"
+	labelFolio            = "Mutual Fund Folio Numbers"
+	labelChallan          = "Challan Numbers"
+	labelEmployeeID       = "Employee Codes"
+	labelPreviousEmployer = "Previous Employers"
+	labelDesignation      = "Employee Designations"
+	labelESI              = "ESI Numbers"
+	labelIFSC             = "IFSC Codes"
+	labelName             = "Person Names"
+	labelDOB              = "Dates of Birth"
+	labelDate             = "Dates"
+	labelAddress          = "Addresses"
+	labelOrganization     = "Organizations"
+	labelIndicName        = "Indic-Script Names"
+	labelIndicAddress     = "Indic-Script Addresses"
+	labelSSN              = "US Social Security Numbers"
+	labelNINO             = "UK National Insurance Numbers"
+	labelTFN              = "Australian Tax File Numbers"
+	labelCustom           = "Denylisted Values"
+	labelNonDictionary    = "Non-Dictionary Words"
+)
+
+// tokenDetector is a regex detector whose matches are replaced in place.
+type tokenDetector struct {
+	entity   string
+	label    string
+	severity Severity
+	pattern  *regexp.Regexp
+	replace  func(match string) string
+	// validate, when set, rejects pattern matches that fail a structural check.
+	validate func(match string) bool
+	// context, when set, must match the line or one of the window lines above it for the
+	// detector to fire on that line; window defaults to contextWindow.
+	context *regexp.Regexp
+	window  int
+	// accept, when set, rejects matches by their position in the text, for checks that need
+	// the surrounding lines.
+	accept func(lines []string, n, start int) bool
+	// submatch reports the first participating capture group of each match instead of the
+	// whole match.
+	submatch bool
+	// document, when set, restricts the detector to documents of that type (see
+	// DocumentProfile).
+	document string
+	// retainAs, when set, leaves matches untouched and reports them under this RetainedFields key.
+	retainAs string
+	// recognizer, when set, is a third-party Recognizer whose matches, found by detectorsFor,
+	// replace the pattern; found holds their byte ranges by line and scores the confidence
+	// they reported, by line and start.
+	recognizer Recognizer
+	found      map[int][][]int
+	scores     map[[2]int]float64
+	// score grades the matches of a profile identifier; built-in detectors use
+	// detectorScores.
+	score *detectorScore
+	// minConfidence drops matches scored below it.
+	minConfidence float64
+	// allowlist holds the values that are retained instead of redacted.
+	allowlist map[string]bool
+	// suppressions holds the false positives that are retained instead of redacted.
+	suppressions *SuppressionStore
+	// rule and policy explain the detector's matches; they are only set under WithExplain.
+	rule   string
+	policy []string
+	// timer, when set, accumulates the time the detector takes (see WithTimings).
+	timer detectorTimer
+}
+
+// contextWindow is how many lines above a match are searched for a detector's context words.
+const contextWindow = 3
+
+// panContextPattern matches the labels Form 16 prints next to, or above, a PAN.
+var panContextPattern = regexp.MustCompile(`(?i)\bPAN\b|Permanent\s+Account`)
+
+// span is a resolved match of a token detector within a single line.
+type span struct {
+	start, end int
+	detector   *tokenDetector
+	confidence float64
+	// retainAs is the detector's retainAs, or allowlistedKey for an allowlisted value.
+	retainAs string
+	// replacement, when custom is set, is written instead of the detector's placeholder.
+	replacement string
+	custom      bool
+	// explanation is set under WithExplain.
+	explanation *Explanation
+}
+
+func placeholder(p string) func(string) string {
+	return func(string) string { return p }
+}
+
+// tokenDetectors returns the enabled token-level detectors in registry order: when two
+// matches overlap, the one from the earlier detector wins. Detectors of third-party
+// recognizers find nothing until detectorsFor has run their recognizer over the text.
+func (pf *Filter) tokenDetectors() []tokenDetector {
+	builtin := []tokenDetector{
+		{entity: EntityCustom, label: labelCustom, pattern: pf.denylistPattern},
+		// The statement identifiers are only looked for next to their labels, and before the
+		// detectors that would take their digits for a UAN, an Aadhaar or a phone number.
+		{entity: EntityBankAccount, label: labelBankAccount, pattern: pf.BankAccountPattern, submatch: true, context: bankAccountLabelPattern, window: 1, document: DocumentAIS},
+		{entity: EntityFolio, label: labelFolio, pattern: pf.FolioPattern, submatch: true, context: folioLabelPattern, window: 1, document: DocumentAIS},
+		{entity: EntityChallan, label: labelChallan, pattern: pf.ChallanPattern, submatch: true, context: challanLabelPattern, document: DocumentForm26AS},
+		{entity: EntityEmployeeID, label: labelEmployeeID, pattern: pf.EmployeeIDPattern, submatch: true, document: DocumentPayslip},
+		{entity: EntityESI, label: labelESI, pattern: pf.ESIPattern, submatch: true, document: DocumentPayslip},
+		{entity: EntityBankAccount, label: labelBankAccount, pattern: pf.BankAccountPattern, submatch: true, context: bankAccountLabelPattern, window: 1, document: DocumentPayslip},
+		{entity: EntityIFSC, label: labelIFSC, pattern: pf.IFSCPattern, submatch: true, context: ifscLabelPattern, window: 1, document: DocumentPayslip},
+		// PF account codes run first: their digits would otherwise be taken for a phone number.
+		{entity: EntityPFAccount, label: labelPFAccount, pattern: pf.PFAccountPattern},
+		{entity: EntityDrivingLicence, label: labelDrivingLicence, pattern: pf.DrivingLicencePattern, validate: ValidDrivingLicence},
+		{entity: EntityPassport, label: labelPassport, pattern: pf.PassportPattern},
+		{entity: EntityVoterID, label: labelVoterID, pattern: pf.VoterIDPattern},
+		// Indian-script addresses keep their house numbers and PIN codes (see indic.go).
+		{entity: EntityIndicAddress, label: labelIndicAddress, pattern: indicAddressPattern, validate: validIndicAddress},
+		{entity: EntityPhone, label: labelPhone, pattern: pf.PhonePattern},
+		{entity: EntityPhone, label: labelPhone, pattern: pf.foreignPhonePattern, validate: validForeignPhone(pf.PhoneRegions)},
+		{entity: EntityEmail, label: labelEmail, pattern: pf.EmailPattern},
+		{entity: EntityUAN, label: labelUAN, pattern: pf.UANPattern, accept: uanContext},
+		{entity: EntityAadhaar, label: labelAadhaar, pattern: pf.AadhaarPattern},
+		{entity: EntityPAN, label: labelPAN, pattern: pf.PANPattern},
+		{entity: EntityGST, label: labelGST, pattern: pf.GSTPattern},
+		{entity: EntityTAN, label: labelTAN, pattern: pf.TANPattern},
+		{entity: EntityPINCode, label: labelPINCode, pattern: pf.PINCodePattern, accept: pf.pinCodeContext},
+		{entity: EntityDOB, label: labelDOB, pattern: pf.DatePattern, validate: validDate, accept: pf.acceptDate(true)},
+		{entity: EntityDate, label: labelDate, pattern: pf.DatePattern, validate: validDate, accept: pf.acceptDate(false)},
+		// Previous employers are named after their label, or first in the rows of their table.
+		{entity: EntityPreviousEmployer, label: labelPreviousEmployer, pattern: pf.PreviousEmployerPattern, submatch: true, validate: validPreviousEmployer},
+		{entity: EntityPreviousEmployer, label: labelPreviousEmployer, pattern: previousEmployerRowPattern, submatch: true, validate: validPreviousEmployer, accept: previousEmployerRow},
+		// Form 12BA prints the employee's designation between the name and the PAN.
+		{entity: EntityDesignation, label: labelDesignation, pattern: designationPattern, submatch: true, context: designationLabelPattern, window: 1},
+		{entity: EntityName, label: labelName, pattern: namePhrasePattern, submatch: true, validate: pf.validName},
+		{entity: EntityName, label: labelName, pattern: pf.NamePattern, validate: pf.validLabelledName, context: pf.NameLabelPattern, window: 1},
+		// Payslips name the reporting manager or approver under labels of their own.
+		{entity: EntityName, label: labelName, pattern: pf.NamePattern, validate: pf.validManagerName, context: managerLabelPattern, window: 1, document: DocumentPayslip},
+		// The name recognizer (see ner.go) finds the names printed away from any label.
+		{entity: EntityName, label: labelName, pattern: honorificNamePattern, submatch: true, validate: pf.validName},
+		{entity: EntityName, label: labelName, pattern: pf.NamePattern, validate: pf.gazetteerName},
+		{entity: EntityOrganization, label: labelOrganization, pattern: organizationNamePattern, validate: validOrganizationName},
+		{entity: EntityIndicName, label: labelIndicName, pattern: indicHonorificNamePattern, submatch: true, validate: validIndicName},
+		{entity: EntityIndicName, label: labelIndicName, pattern: indicLabelledNamePattern, submatch: true, validate: validIndicName},
+		{entity: EntityIndicName, label: labelIndicName, pattern: indicNameRunPattern, validate: validIndicLabelledName, context: indicNameLabelPattern, window: 1},
+		{entity: EntityIndicName, label: labelIndicName, pattern: indicNameRunPattern, validate: indicGazetteerName},
+		{entity: EntitySSN, label: labelSSN, pattern: pf.SSNPattern, validate: ValidSSN},
+		{entity: EntityNINO, label: labelNINO, pattern: pf.NINOPattern, validate: ValidNINO},
+		{entity: EntityTFN, label: labelTFN, pattern: pf.TFNPattern, validate: ValidTFN, context: tfnLabelPattern},
+	}
+	builtin = append(builtin, pf.profile.identifierDetectors()...)
+	var registered []tokenDetector
+	for _, r := range pf.recognizers {
+		switch r := r.(type) {
+		case builtinRecognizer:
+			for _, d := range builtin {
+				if d.entity == r.name {
+					registered = append(registered, d)
+				}
+			}
+		case Detector:
+			registered = append(registered, tokenDetector{entity: r.Type, label: r.Label, severity: r.Severity, pattern: r.Pattern, replace: placeholder(r.Placeholder)})
+		default:
+			name := r.Name()
+			registered = append(registered, tokenDetector{entity: name, label: name, recognizer: r,
+				replace: placeholder(cmp.Or(pf.placeholderFor(name), defaultDetectorPlaceholder))})
+		}
+	}
+
+	detectors := make([]tokenDetector, 0, len(registered))
+	for _, d := range registered {
+		if pf.isDisabled(d.entity) || !pf.inDocument(d.document) {
+			continue
+		}
+		if sev, ok := defaultSeverities[d.entity]; ok {
+			d.severity = sev
+		}
+		if _, ok := pf.placeholders[d.entity]; ok || d.replace == nil {
+			d.replace = placeholder(pf.placeholderFor(d.entity))
+		}
+		switch d.entity {
+		case EntityGST:
+			switch pf.GSTPolicy {
+			case GSTMask:
+				d.replace = maskGSTIN
+			case GSTRetain:
+				d.retainAs = "Employer GSTIN"
+			}
+		case EntityPhone:
+			if d.pattern == pf.PhonePattern && !slices.Contains(pf.PhoneRegions, DefaultPhoneRegion) {
+				continue
+			}
+		case EntityAadhaar:
+			if pf.strict {
+				d.validate = ValidAadhaar
+			}
+		case EntityPAN:
+			d.validate = ValidPAN
+			if pf.panContext {
+				d.context = panContextPattern
+			}
+		case EntityDOB:
+			if !slices.Contains(pf.Dates, DateBirth) {
+				continue
+			}
+		case EntityDate:
+			if !slices.Contains(pf.Dates, DatePeriod) && !slices.Contains(pf.Dates, DateOther) {
+				continue
+			}
+		}
+		if pf.vault != nil && d.retainAs == "" {
+			d.replace = pf.vault.replacer(d.entity)
+		}
+		if d.entity == EntityEmail {
+			d.replace = pf.emailReplacer(d.replace)
+		}
+		d.minConfidence = pf.minConfidence
+		d.allowlist = pf.allowlist
+		d.suppressions = pf.suppressions
+		if pf.explain {
+			d.rule, d.policy = detectorRule(&d), pf.detectorPolicy(&d)
+		}
+		detectors = append(detectors, d)
+	}
+	return detectors
+}
+
+// LabelSeverity returns the severity of a category label as reported in RemovedFields.
+// Unknown labels, including the dictionary filter's, are SeverityLow.
+func (pf *Filter) LabelSeverity(label string) Severity {
+	for _, d := range pf.tokenDetectors() {
+		if d.label == label {
+			return d.severity
+		}
+	}
+	switch label {
+	case labelAddress:
+		return defaultSeverities[EntityAddress]
+	case labelOrganization:
+		return defaultSeverities[EntityOrganization]
+	}
+	return SeverityLow
+}
+
+// findSpans runs every detector over the original line lines[n] exactly once and resolves
+// overlaps by detector priority; matches scored below the detector's minimum confidence are
+// dropped before they can claim their range. The returned spans are sorted by start offset.
+func findSpans(lines []string, n int, detectors []tokenDetector) []span {
+	var spans []span
+	for i := range detectors {
+		d := &detectors[i]
+		started := d.timer.start()
+		spans = d.find(lines, n, spans)
+		d.timer.stop(d.entity, started)
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	return spans
+}
+
+// find appends the spans of the detector in lines[n] that do not overlap spans.
+func (d *tokenDetector) find(lines []string, n int, spans []span) []span {
+	line := lines[n]
+	if d.context != nil && !hasContext(lines, n, d.context, d.window) {
+		return spans
+	}
+	for _, loc := range d.matches(n, line) {
+		if loc[0] == loc[1] || overlapsAny(spans, loc[0], loc[1]) {
+			continue
+		}
+		if d.validate != nil && !d.validate(line[loc[0]:loc[1]]) {
+			continue
+		}
+		if d.accept != nil && !d.accept(lines, n, loc[0]) {
+			continue
+		}
+		confidence := d.confidence(lines, n, loc[0], loc[1])
+		if confidence < d.minConfidence {
+			continue
+		}
+		retainAs := d.retainAs
+		if d.allowlist[line[loc[0]:loc[1]]] {
+			retainAs = allowlistedKey
+		} else if d.suppressions.suppressed(d.entity, line, loc[0], loc[1]) {
+			retainAs = suppressedKey
+		}
+		spans = append(spans, span{start: loc[0], end: loc[1], detector: d, confidence: confidence, retainAs: retainAs})
+	}
+	return spans
+}
+
+// matches returns the byte ranges the detector reports in line n.
+func (d *tokenDetector) matches(n int, line string) [][]int {
+	if d.recognizer != nil {
+		return d.found[n]
+	}
+	if !d.submatch {
+		return d.pattern.FindAllStringIndex(line, -1)
+	}
+	var locs [][]int
+	for _, loc := range d.pattern.FindAllStringSubmatchIndex(line, -1) {
+		for g := 2; g+1 < len(loc); g += 2 {
+			if loc[g] >= 0 {
+				locs = append(locs, loc[g:g+2])
+				break
+			}
+		}
+	}
+	return locs
+}
+
+// hasContext reports whether the pattern matches lines[n] or one of the window lines above
+// it (contextWindow when window is 0). Form 16 tables often put the label in the header row
+// and the value below it.
+func hasContext(lines []string, n int, pattern *regexp.Regexp, window int) bool {
+	if window == 0 {
+		window = contextWindow
+	}
+	for k := n; k >= 0 && k >= n-window; k-- {
+		if pattern.MatchString(lines[k]) {
+			return true
+		}
+	}
+	return false
+}
+
+func overlapsAny(spans []span, start, end int) bool {
+	for _, s := range spans {
+		if start < s.end && s.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// applySpans builds the redacted line from the original line and its resolved spans.
+func applySpans(line string, spans []span) string {
+	if len(spans) == 0 {
+		return line
+	}
+	var b strings.Builder
+	prev := 0
+	for _, s := range spans {
+		b.WriteString(line[prev:s.start])
+		match := line[s.start:s.end]
+		switch {
+		case s.retainAs != "":
+			b.WriteString(match)
+		case s.custom:
+			b.WriteString(s.replacement)
+		default:
+			b.WriteString(s.detector.replace(match))
+		}
+		prev = s.end
+	}
+	b.WriteString(line[prev:])
+	return b.String()
+}
+
+// FilterPII removes or masks PII data from text.
+//
+// Each line is scanned once per detector against the original text; overlapping matches are
+// resolved by detector priority and all replacements are applied in a single pass, so the
+// per-category MatchCounts always agree with what was actually replaced. Matches never span
+// line breaks.
+func (pf *Filter) FilterPII(text string) FilteredData {
+	doc := pf.forDocument(text)
+	result := doc.filterText(text, nil)
+	doc.finish(text, &result)
+	return result
+}
+
+// forDocument returns the filter to redact the document text with: a copy of pf set to the
+// document type of text, detected unless set with WithDocumentProfile. Under
+// WithNumberedPlaceholders the copy has a vault of its own, which finish renumbers.
+func (pf *Filter) forDocument(text string) *Filter {
+	doc := *pf
+	doc.documentType = pf.document
+	if doc.documentType == nil {
+		doc.documentType = detectDocument(text)
+	}
+	if pf.numbered && pf.vault == nil {
+		doc.vault, doc.renumber = NewVault(), true
+	}
+	return &doc
+}
+
+// inDocument reports whether a detector restricted to the document type document applies to
+// the document being redacted.
+func (pf *Filter) inDocument(document string) bool {
+	return document == "" || (pf.documentType != nil && pf.documentType.Name == document)
+}
+
+// finish completes a result for the whole of text: numbered tokens are renumbered in order of
+// first appearance, repeated page headers and footers are handled by the HeaderFooters policy,
+// the structured fields of the document type are added to the retained fields, then the Form 16
+// structure and volume checks run and the block model is built.
+func (pf *Filter) finish(text string, result *FilteredData) {
+	if pf.renumber {
+		result.CleanedText = pf.vault.renumber(result.CleanedText)
+	}
+	dropped := pf.dedupeRepeatedLines(text, result)
+	for key, values := range pf.documentFields(text) {
+		result.RetainedFields[key] = uniqueSorted(append(result.RetainedFields[key], values...))
+	}
+	if pf.documentType == nil || pf.documentType.Name == DocumentForm16 {
+		result.Warnings = append(result.Warnings, structureWarnings(text)...)
+	}
+	result.Warnings = append(result.Warnings, pf.checkVolumes(text, result.Matches)...)
+	result.Blocks = pf.blockModel(text, *result)
+	for i := range result.Blocks {
+		b := &result.Blocks[i]
+		for k := range b.Lines {
+			if dropped[b.StartLine-1+k] {
+				b.Lines[k] = ""
+			}
+		}
+	}
+	result.CleanedText = dropLines(result.CleanedText, dropped)
+	result.Stats = textStats(text)
+	result.countPlaceholders()
+}
+
+// newFilteredData returns an empty result for text carrying the filter's report settings.
+func (pf *Filter) newFilteredData(text string) FilteredData {
+	return FilteredData{
+		CleanedText:      text,
+		RemovedFields:    []string{},
+		RetainedFields:   make(map[string][]string),
+		MatchCounts:      make(map[string]int),
+		SampleMasks:      make(map[string][]string),
+		Matches:          []Match{},
+		keepAmountWords:  pf.AmountWords == AmountWordsKeep,
+		keepEmailDomains: pf.EmailPolicy == EmailKeepDomain,
+		allowlistPattern: pf.allowlistPattern,
+		glossaryPattern:  pf.glossaryPattern,
+		wordPlaceholder:  pf.placeholderFor(EntityWord),
+		locale:           pf.locale,
+		source:           text,
+		placeholders:     pf.placeholderSet(),
+		tokenized:        pf.vault != nil || pf.EmailPolicy == EmailOrgToken,
+	}
+}
+
+// filterText is FilterPII without the document-level volume checks, which FilterPages runs
+// once on the whole document rather than per page. When fn is set, every finding is offered
+// to it before it is applied.
+func (pf *Filter) filterText(text string, fn func(Finding) Action) FilteredData {
+	result := pf.newFilteredData(text)
+	timer := pf.newDetectorTimer()
+	detectors := pf.timedDetectorsFor(text, timer)
+	retained := make(map[string][]string)
+	lines := strings.Split(text, "\n")
+	out := make([]string, len(lines))
+	offset := 0
+	for i, line := range lines {
+		lineStart := offset
+		offset += len(line) + 1
+		spans := findSpans(lines, i, detectors)
+		if pf.explain {
+			for k := range spans {
+				spans[k].explanation = spans[k].detector.explain(lines, i, spans[k].start, spans[k].end)
+			}
+		}
+		if fn != nil {
+			spans = offerSpans(fn, spans, i, lineStart, line)
+		}
+		for _, s := range spans {
+			if s.retainAs != "" {
+				retained[s.retainAs] = append(retained[s.retainAs], line[s.start:s.end])
+				continue
+			}
+			m := pf.newMatch(s.detector.entity, s.detector.label, i, lineStart, line, s.start, s.end, s.confidence)
+			m.Explanation = s.explanation
+			result.Matches = append(result.Matches, m)
+			result.MatchCounts[s.detector.label]++
+			if samples := result.SampleMasks[s.detector.label]; len(samples) < maxSamplesPerType {
+				result.SampleMasks[s.detector.label] = append(samples, maskSample(line[s.start:s.end]))
+			}
+		}
+		redacted := applySpans(line, spans)
+
+		// Trim leading/trailing spaces before matching to make detection resilient to PDF
+		trimmed := strings.TrimSpace(redacted)
+
+		// An allowlisted line (the employer's own name) is never redacted as a whole.
+		if pf.lineAllowed(line) && pf.wholeLineMatch(trimmed) {
+			retained[allowlistedKey] = append(retained[allowlistedKey], strings.TrimSpace(line))
+		}
+
+		// Detect organisation names: redact entire line
+		started := timer.start()
+		organization := !pf.lineAllowed(line) && pf.lineEnabled(EntityOrganization) && pf.OrganizationPattern.MatchString(trimmed)
+		timer.stop(EntityOrganization, started)
+		if organization && !pf.lineSuppressed(EntityOrganization, line, 0, len(line), retained) {
+			if m, repl, ok := pf.offerLine(fn, EntityOrganization, labelOrganization, i, lineStart, line, 0, len(line), trimmed); ok {
+				out[i] = repl
+				result.Matches = append(result.Matches, m)
+				result.MatchCounts[labelOrganization]++
+				continue
+			}
+		}
+
+		// Detect address lines containing Indian city/state names or address keywords; of a
+		// layout line only the address columns are redacted, keeping the figures beside them
+		started = timer.start()
+		address := !pf.lineAllowed(line) && pf.lineEnabled(EntityAddress) && pf.isAddressLine(trimmed)
+		var columns [][]int
+		var whole bool
+		if address {
+			columns, whole = pf.addressColumns(line)
+		}
+		timer.stop(EntityAddress, started)
+		if address {
+			if whole && !pf.lineSuppressed(EntityAddress, line, 0, len(line), retained) {
+				if m, repl, ok := pf.offerLine(fn, EntityAddress, labelAddress, i, lineStart, line, 0, len(line), trimmed); ok {
+					out[i] = repl
+					result.Matches = append(result.Matches, m)
+					result.MatchCounts[labelAddress]++
+					continue
+				}
+			} else if !whole {
+				var regions []span
+				for _, c := range columns {
+					if pf.lineSuppressed(EntityAddress, line, c[0], c[1], retained) {
+						continue
+					}
+					if m, repl, ok := pf.offerLine(fn, EntityAddress, labelAddress, i, lineStart, line, c[0], c[1], line[c[0]:c[1]]); ok {
+						regions = append(regions, span{start: c[0], end: c[1], replacement: repl, custom: true})
+						result.Matches = append(result.Matches, m)
+						result.MatchCounts[labelAddress]++
+					}
+				}
+				redacted = applySpans(line, withRegions(spans, regions))
+			}
+		}
+		if pf.AmountWords != AmountWordsRedact {
+			if values, warnings := crossCheckAmounts(lines, i, redacted); len(values) > 0 {
+				retained[amountsInWordsKey] = append(retained[amountsInWordsKey], values...)
+				result.Warnings = append(result.Warnings, warnings...)
+			}
+		}
+		if pf.AmountWords == AmountWordsDigits {
+			redacted = normalizeAmountWords(redacted)
+		}
+		out[i] = redacted
+	}
+	result.CleanedText = strings.Join(out, "\n")
+
+	for key, values := range retained {
+		result.RetainedFields[key] = uniqueSorted(values)
+	}
+	result.RemovedFields = removedFields(detectors, result.MatchCounts)
+	result.Timings = timer.timings()
+	return result
+}
+
+// removedFields lists the categories with at least one match: token categories in detector
+// priority order, then addresses and organisations.
+func removedFields(detectors []tokenDetector, counts map[string]int) []string {
+	fields := []string{}
+	for _, d := range detectors {
+		if counts[d.label] > 0 && !slices.Contains(fields, d.label) {
+			fields = append(fields, d.label)
+		}
+	}
+	for _, label := range []string{labelAddress, labelOrganization} {
+		if counts[label] > 0 && !slices.Contains(fields, label) {
+			fields = append(fields, label)
+		}
+	}
+	return fields
+}
+
+// ReviewCopy returns the text with only high-severity identifiers (Aadhaar, PAN and any
+// extra detector registered as SeverityHigh) replaced. It is meant for internal reviewers who
+// need the document to stay readable.
+func (pf *Filter) ReviewCopy(text string) string {
+	var detectors []tokenDetector
+	for _, d := range pf.detectorsFor(text) {
+		if d.severity == SeverityHigh {
+			detectors = append(detectors, d)
+		}
+	}
+	lines := strings.Split(text, "\n")
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = applySpans(line, findSpans(lines, i, detectors))
+	}
+	return strings.Join(out, "\n")
+}
+
+// FallbackReadPDFWithPdftotext attempts to extract text using the external 'pdftotext' command-line tool when the internal extractor returns no content.
+// It is only used when poppler is installed.
+func FallbackReadPDFWithPdftotext(filename string) (string, error) {
+	// Use the -layout flag to keep original layout and output to stdout ("-").
+	cmd := exec.Command("pdftotext", "-layout", filename, "-")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("fallback extraction failed: %v", err)
+	}
+	return string(out), nil
+}
+
+// SaveFilteredData saves the filtered data to a file
+func SaveFilteredData(data FilteredData, outputFile string) error {
+	file, err := CreateOutput(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer file.Close()
+
+	l := data.locale
+
+	// Write header
+	file.WriteString(l.text(msgTitle) + "\n\n")
+
+	// Write summary
+	file.WriteString(l.text(msgSummary) + "\n")
+	file.WriteString(fmt.Sprintf("%s %v\n", l.text(msgRemovedFields), data.RemovedFields))
+	file.WriteString(fmt.Sprintf("%s %v\n", l.text(msgRetainedFields), getKeys(data.RetainedFields)))
+	file.WriteString("\n")
+
+	// Write per-category counts with masked examples
+	if len(data.RemovedFields) > 0 {
+		file.WriteString(l.text(msgMatchCounts) + "\n")
+		for _, field := range data.RemovedFields {
+			line := fmt.Sprintf("  %s: %d", field, data.MatchCounts[field])
+			if samples := data.SampleMasks[field]; len(samples) > 0 {
+				line += fmt.Sprintf(" (%s %s)", l.text(msgExample), strings.Join(samples, ", "))
+			}
+			file.WriteString(line + "\n")
+		}
+		file.WriteString("\n")
+	}
+
+	// Write retained business data
+	if len(data.RetainedFields) > 0 {
+		file.WriteString(l.text(msgRetainedData) + "\n")
+		for _, fieldType := range getKeys(data.RetainedFields) {
+			values := data.RetainedFields[fieldType]
+			file.WriteString(fmt.Sprintf("%s:\n", fieldType))
+			for _, value := range values {
+				file.WriteString(fmt.Sprintf("  - %s\n", value))
+			}
+		}
+		file.WriteString("\n")
+	}
+
+	// Write the deduplicated page headers and footers
+	if len(data.RepeatedLines) > 0 {
+		file.WriteString(l.text(msgRepeatedLines) + "\n")
+		for _, r := range data.RepeatedLines {
+			file.WriteString(fmt.Sprintf("  (x%d) %s\n", r.Count, r.Text))
+		}
+		file.WriteString("\n")
+	}
+
+	// Write per-certificate findings for multi-employer bundles
+	if len(data.Certificates) > 0 {
+		file.WriteString(fmt.Sprintf("%s (%d):\n", l.text(msgCertificates), len(data.Certificates)))
+		for _, c := range data.Certificates {
+			file.WriteString(fmt.Sprintf("  %s %d:\n", l.text(msgCertificate), c.Index))
+			keys := make([]string, 0, len(c.Fields))
+			for k := range c.Fields {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				file.WriteString(fmt.Sprintf("    %s: %s\n", k, c.Fields[k]))
+			}
+			if len(c.Categories) > 0 {
+				file.WriteString(fmt.Sprintf("    %s %s\n", l.text(msgRemoved), strings.Join(c.Categories, ", ")))
+			}
+		}
+		file.WriteString("\n")
+	}
+
+	// Write warnings
+	if len(data.Warnings) > 0 {
+		file.WriteString(l.text(msgWarnings) + "\n")
+		for _, w := range data.Warnings {
+			file.WriteString(fmt.Sprintf("  [%s] %s\n", w.Code, w))
+		}
+		file.WriteString("\n")
+	}
+
+	// Write why each match was redacted (WithExplain)
+	if len(data.Matches) > 0 && data.Matches[0].Explanation != nil {
+		file.WriteString(l.text(msgExplanations) + "\n")
+		for _, m := range data.Matches {
+			file.WriteString(fmt.Sprintf("  [%d] %s: %s\n", m.Line, m.Type, m.Explanation))
+		}
+		file.WriteString("\n")
+	}
+
+	// Write where the time went (WithTimings)
+	if data.Timings != nil {
+		file.WriteString(l.text(msgTimings) + "\n")
+		for _, t := range data.Timings.Stages {
+			file.WriteString(fmt.Sprintf("  %s: %v\n", t.Name, t.Duration.Round(time.Microsecond)))
+		}
+		for _, t := range data.Timings.Detectors {
+			file.WriteString(fmt.Sprintf("  %s/%s: %v\n", StageDetect, t.Name, t.Duration.Round(time.Microsecond)))
+		}
+		file.WriteString("\n")
+	}
+
+	// Write cleaned text
+	file.WriteString(l.text(msgCleanedText) + "\n")
+	file.WriteString(strings.Repeat("=", 50) + "\n")
+	file.WriteString(data.CleanedText)
+
+	return nil
+}
+
+// SaveRawText saves the unfiltered extracted PDF text to a file for comparison
+func SaveRawText(text string, outputFile string) error {
+	file, err := CreateOutput(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create raw text file: %v", err)
+	}
+	defer file.Close()
+
+	// Optionally add a simple header for clarity
+	file.WriteString("=== RAW PDF TEXT (NO REDACTIONS) ===\n\n")
+	_, err = file.WriteString(text)
+	return err
+}
+
+// SaveReviewCopy saves the partially masked review copy of the extracted text
+func SaveReviewCopy(text string, outputFile string) error {
+	file, err := CreateOutput(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create review copy: %v", err)
+	}
+	defer file.Close()
+
+	file.WriteString("=== REVIEW COPY (HIGH-SEVERITY IDENTIFIERS MASKED) ===\n\n")
+	_, err = file.WriteString(text)
+	return err
+}
+
+// Helper function to get map keys in sorted order
+func getKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// uniqueSorted returns the distinct values of a slice in sorted order.
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// LoadWordSet reads a newline-separated list of English words from the supplied
+// file path and returns a set for O(1) existence checks.
+func LoadWordSet(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadWordSet(file)
+}
+
+// ReadWordSet is LoadWordSet for a word list read from r, such as one embedded in a binary.
+func ReadWordSet(r io.Reader) (map[string]struct{}, error) {
+	set := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		w := strings.TrimSpace(scanner.Text())
+		if w == "" {
+			continue
+		}
+		set[strings.ToLower(w)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// dictionaryWordPattern matches the tokens of the text in any script, letters with their
+// combining marks, digits and underscores, so that accented words are not split.
+var dictionaryWordPattern = regexp.MustCompile(`[\p{L}\p{M}\p{N}_]+`)
+
+// dictionaryWord reports whether the dictionary filter looks token up: whether it is written in
+// Latin letters only. Placeholders such as [WORD_REDACTED] are not, as their underscore joins
+// the letters, nor are words of Indian and other scripts, which an English word list does not
+// judge.
+func dictionaryWord(token string) bool {
+	for _, r := range token {
+		if !unicode.Is(unicode.Latin, r) && !unicode.Is(unicode.Mn, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// RedactUnknownWords scans the provided text and replaces every alphabetic
+// token that is NOT found in the supplied word-set with the placeholder
+// "[WORD_REDACTED]". Terms of the built-in tax glossary (cess, HRA, u/s, 80C...)
+// are always kept. It returns the redacted text and a sorted slice containing
+// the unique set of words that were redacted.
+func RedactUnknownWords(text string, dict map[string]struct{}) (string, []string) {
+	return skippingMatches(defaultGlossaryPattern, redactUnknownWords)(text, dict, defaultPlaceholders[EntityWord])
+}
+
+// redactUnknownWords is RedactUnknownWords with a configurable placeholder.
+func redactUnknownWords(text string, dict map[string]struct{}, placeholder string) (string, []string) {
+	redactedSet := make(map[string]struct{})
+
+	redactedText := dictionaryWordPattern.ReplaceAllStringFunc(text, func(token string) string {
+		if !dictionaryWord(token) {
+			return token
+		}
+		lower := strings.ToLower(token)
+		// Relax rule: keep very short words (<=3 letters) unconditionally.
+		if utf8.RuneCountInString(lower) <= 3 {
+			return token
+		}
+		if _, ok := dict[lower]; ok {
+			return token // English word, keep it
+		}
+		redactedSet[lower] = struct{}{}
+		return placeholder
+	})
+
+	words := make([]string, 0, len(redactedSet))
+	for w := range redactedSet {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return redactedText, words
+}
+
+// skippingMatches wraps a dictionary redaction so that it leaves the matches of pattern alone,
+// such as kept e-mail domains and allowlisted values: "Infosys" is not an English word.
+func skippingMatches(pattern *regexp.Regexp, redact func(string, map[string]struct{}, string) (string, []string)) func(string, map[string]struct{}, string) (string, []string) {
+	return func(text string, dict map[string]struct{}, placeholder string) (string, []string) {
+		seen := make(map[string]struct{})
+		var words []string
+		var b strings.Builder
+		last := 0
+		for _, loc := range append(pattern.FindAllStringIndex(text, -1), []int{len(text), len(text)}) {
+			out, found := redact(text[last:loc[0]], dict, placeholder)
+			b.WriteString(out)
+			b.WriteString(text[loc[0]:loc[1]])
+			last = loc[1]
+			for _, w := range found {
+				if _, ok := seen[w]; !ok {
+					seen[w] = struct{}{}
+					words = append(words, w)
+				}
+			}
+		}
+		sort.Strings(words)
+		return b.String(), words
+	}
+}
+
+// ApplyDictionaryFilter runs RedactUnknownWords over the cleaned text and records the
+// Non-Dictionary Words category with its replacement count. Amounts in words are skipped when
+// the filter that produced data keeps them (AmountWordsKeep), and so are e-mail domains under
+// EmailKeepDomain, allowlisted values and glossary terms (see WithGlossary). Words are
+// replaced by the filter's EntityWord placeholder. The dictionary hit rate and placeholder
+// counts of Stats are updated.
+func ApplyDictionaryFilter(data *FilteredData, dict map[string]struct{}) {
+	placeholder := data.wordPlaceholder
+	if placeholder == "" {
+		placeholder = defaultPlaceholders[EntityWord]
+	}
+	before := strings.Count(data.CleanedText, placeholder)
+	redact := redactUnknownWords
+	if data.keepAmountWords {
+		redact = redactUnknownWordsKeepingAmounts
+	}
+	if data.keepEmailDomains {
+		redact = skippingMatches(keptDomainPattern, redact)
+	}
+	if data.allowlistPattern != nil {
+		redact = skippingMatches(data.allowlistPattern, redact)
+	}
+	redact = skippingMatches(cmp.Or(data.glossaryPattern, defaultGlossaryPattern), redact)
+	updated, words := redact(data.CleanedText, dict, placeholder)
+	data.CleanedText = updated
+	redacted := strings.Count(updated, placeholder) - before
+	if len(words) > 0 {
+		data.RemovedFields = append(data.RemovedFields, labelNonDictionary)
+		data.MatchCounts[labelNonDictionary] = redacted
+	}
+	data.dictionaryStats(updated, dict, redacted)
+	data.countPlaceholders()
+	for i := range data.RepeatedLines {
+		data.RepeatedLines[i].Text, _ = redact(data.RepeatedLines[i].Text, dict, placeholder)
+	}
+	for i := range data.Blocks {
+		b := &data.Blocks[i]
+		for k, line := range b.Lines {
+			updated, _ := redact(line, dict, placeholder)
+			if n := strings.Count(updated, placeholder) - strings.Count(line, placeholder); n > 0 {
+				b.Lines[k] = updated
+				b.MatchCounts[labelNonDictionary] += n
+				b.Status = BlockRedacted
+			}
+		}
+	}
+}
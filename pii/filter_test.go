@@ -0,0 +1,74 @@
+package pii
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// concurrentText is a Form 16 snippet touching most detectors, the e-mail domain tokens and
+// the glossary included.
+const concurrentText = "Name and address of the Employee\nRavi Kumar Sharma\n" +
+	"12, MG Road, Bengaluru - 560001\nPAN of the Employee: ABCPE1234F\n" +
+	"Email: ravi.sharma@infosys.com\nMobile: +91 98765 43210\nGSTIN: 27AAPFU0939F1ZV\n" +
+	"Aadhaar No.: 2345 6789 0124\nRupees Twelve Lakh Only\f" +
+	"PART B\nGross Salary 1,20,000.00\nTAN of the Deductor: MUMA12345B\n"
+
+// runConcurrently calls fn from n goroutines at once.
+func runConcurrently(n int, fn func()) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+	wg.Wait()
+}
+
+// These tests are meant to run under go test -race, which reports any state a shared filter
+// writes while redacting.
+
+func TestFilterConcurrentUse(t *testing.T) {
+	pf := NewFilter(WithEmailPolicy(EmailOrgToken), WithNumberedPlaceholders(true))
+	want := pf.FilterPII(concurrentText)
+	wantPages, err := pf.FilterPages(Pages{concurrentText, concurrentText}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Pipeline{Filter: pf, Workers: 2}
+	runConcurrently(8, func() {
+		if got := pf.FilterPII(concurrentText); got.CleanedText != want.CleanedText {
+			t.Errorf("FilterPII = %q, want %q", got.CleanedText, want.CleanedText)
+		}
+		if got, err := pf.FilterPages(Pages{concurrentText, concurrentText}, 2); err != nil || !reflect.DeepEqual(got.Matches, wantPages.Matches) {
+			t.Errorf("FilterPages matches differ:\n%+v\n%+v", got.Matches, wantPages.Matches)
+		}
+		if _, err := p.Redact(context.Background(), Extraction{Pages: Pages{concurrentText, concurrentText}}); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestCloneConcurrentUse(t *testing.T) {
+	pf := NewFilter(WithEmailPolicy(EmailOrgToken), WithGlossary("perquisite"), WithAllowlist("MUMA12345B"))
+	want := pf.FilterPII(concurrentText)
+	runConcurrently(8, func() {
+		c := pf.Clone(WithOnly(EntityPAN, EntityEmail), WithGlossary("gratuity"), WithAllowlist("ABCPE1234F"),
+			WithDisabled(EntityEmail), WithEmailPolicy(EmailOrgToken))
+		if got := c.FilterPII(concurrentText); got.MatchCounts[labelPAN] != 0 {
+			t.Errorf("clone redacted the allowlisted PAN: %q", got.CleanedText)
+		}
+		if got := pf.FilterPII(concurrentText); got.CleanedText != want.CleanedText {
+			t.Errorf("FilterPII of the source = %q, want %q", got.CleanedText, want.CleanedText)
+		}
+	})
+	if got := pf.FilterPII(concurrentText); !reflect.DeepEqual(got.MatchCounts, want.MatchCounts) {
+		t.Errorf("clones changed the source filter: %v, want %v", got.MatchCounts, want.MatchCounts)
+	}
+	if len(pf.glossary) != 1 || pf.enabled != nil || pf.allowlist["ABCPE1234F"] {
+		t.Errorf("clones changed the options of the source filter")
+	}
+}
@@ -0,0 +1,101 @@
+package pii
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// detectorPacks are the optional detectors for foreign identifiers, by pack name. They share
+// the engine and reporting of the built-in detectors but are off until WithDetectorPacks
+// registers them, as Form 16 files rarely carry them.
+var detectorPacks = map[string][]string{
+	"us": {EntitySSN},
+	"gb": {EntityNINO},
+	"au": {EntityTFN},
+}
+
+// DetectorPackNames returns the names of the optional detector packs, sorted.
+func DetectorPackNames() []string {
+	return slices.Sorted(maps.Keys(detectorPacks))
+}
+
+// ParseDetectorPacks validates a comma-separated list of pack names ("us,gb").
+func ParseDetectorPacks(s string) ([]string, error) {
+	var packs []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if _, ok := detectorPacks[p]; !ok {
+			return nil, fmt.Errorf("unknown detector pack %q (expected %s)", p, strings.Join(DetectorPackNames(), ", "))
+		}
+		if !slices.Contains(packs, p) {
+			packs = append(packs, p)
+		}
+	}
+	return packs, nil
+}
+
+// WithDetectorPacks registers the detectors of the named packs (see DetectorPackNames) after
+// the recognizers registered so far. Unknown names are ignored.
+func WithDetectorPacks(names ...string) Option {
+	return func(pf *Filter) {
+		for _, name := range names {
+			for _, entity := range detectorPacks[name] {
+				if !slices.ContainsFunc(pf.recognizers, func(r Recognizer) bool { return r.Name() == entity }) {
+					pf.recognizers = append(pf.recognizers, builtinRecognizer{name: entity, pf: pf})
+				}
+			}
+		}
+	}
+}
+
+// tfnLabelPattern must be near an eight- or nine-digit number for it to be taken as a Tax File
+// Number.
+var tfnLabelPattern = regexp.MustCompile(`(?i)\bTFN\b|tax\s+file`)
+
+// ValidSSN reports whether s, as matched by SSNPattern, has an area, group and serial number
+// the Social Security Administration issues: no 000, 666 or 9xx area, no 00 group and no
+// 0000 serial.
+func ValidSSN(s string) bool {
+	d := digitsOf(s)
+	if len(d) != 9 {
+		return false
+	}
+	area, group, serial := d[:3], d[3:5], d[5:]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// invalidNINOPrefixes are the prefixes never allocated to a National Insurance number.
+var invalidNINOPrefixes = map[string]bool{"BG": true, "GB": true, "KN": true, "NK": true, "NT": true, "TN": true, "ZZ": true}
+
+// ValidNINO reports whether s, as matched by NINOPattern, has an allocated prefix.
+func ValidNINO(s string) bool {
+	s = strings.ReplaceAll(strings.ToUpper(s), " ", "")
+	return len(s) == 9 && !invalidNINOPrefixes[s[:2]]
+}
+
+// tfnWeights are the check weights of eight- and nine-digit Tax File Numbers.
+var tfnWeights = map[int][]int{
+	8: {10, 7, 8, 4, 6, 3, 5, 1},
+	9: {1, 4, 3, 7, 5, 8, 6, 9, 10},
+}
+
+// ValidTFN reports whether s, as matched by TFNPattern, passes the Tax File Number mod-11
+// check.
+func ValidTFN(s string) bool {
+	d := digitsOf(s)
+	weights, ok := tfnWeights[len(d)]
+	if !ok {
+		return false
+	}
+	sum := 0
+	for i, w := range weights {
+		sum += int(d[i]-'0') * w
+	}
+	return sum%11 == 0
+}
@@ -0,0 +1,70 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FieldPerquisites is the RetainedFields key of the Form 12BA perquisite table: one entry per
+// row with its nature, value, amount recovered from the employee and taxable value.
+const FieldPerquisites = "Perquisites"
+
+var (
+	form12BATitlePattern = regexp.MustCompile(`(?i)^\s*FORM\s+(?:NO\.?\s*)?12\s*BA\b`)
+	// perquisiteRowPattern starts a numbered row of the perquisite table, "2. Cars/Other
+	// automotive", and captures the nature of the perquisite up to the first figure.
+	perquisiteRowPattern = regexp.MustCompile(`^\s*\d{1,2}[.)]?\s+([A-Za-z][^\n]*?)\s+\d[\d,]*\.\d{2}\b`)
+
+	// designationLabelPattern is the Form 12BA label of the employee's details, whose value is
+	// "name, designation and PAN".
+	designationLabelPattern = regexp.MustCompile(`(?i)\bdesignation\s+and\s+PAN\b`)
+	// designationPattern captures the designation printed between the employee's name and PAN,
+	// separated by commas or columns: "RAVI KUMAR SHARMA, Senior Manager, ABCPS1234K".
+	designationPattern = regexp.MustCompile(`(?:,\s*|\s{2,})([A-Z][A-Za-z.&/ -]*[A-Za-z.])(?:\s*,\s*|\s{2,})[A-Z]{5}\d{4}[A-Z]\b`)
+)
+
+// form12BAFields parses the business figures of a standalone Form 12BA: the perquisite table
+// and the assessment or financial year.
+func form12BAFields(text string) map[string][]string {
+	fields := make(map[string][]string)
+	if rows := perquisites(text); len(rows) > 0 {
+		fields[FieldPerquisites] = rows
+	}
+	if assessmentYearLabel.MatchString(text) || financialYearLabel.MatchString(text) {
+		fields[FieldAssessmentYear] = assessmentYearPattern.FindAllString(text, -1)
+	}
+	return fields
+}
+
+// perquisites returns the rows of the perquisite tables of the Form 12BA in text, which is
+// also printed as an annexure of Form 16: "Accommodation: value 1,20,000.00, recovered
+// 20,000.00, taxable 1,00,000.00". The value, recovery and taxable amount are the last three
+// figures of a row; rows with fewer are headings. The employee's name, designation and PAN
+// are redacted by the detectors, not carried here.
+func perquisites(text string) []string {
+	var rows []string
+	in12BA := false
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case form12BATitlePattern.MatchString(line):
+			in12BA = true
+			continue
+		case formHeaderPattern.MatchString(line):
+			// The next certificate of a bundle.
+			in12BA = false
+		}
+		if !in12BA {
+			continue
+		}
+		row := perquisiteRowPattern.FindStringSubmatch(line)
+		figures := figurePattern.FindAllString(line, -1)
+		if row == nil || len(figures) < 3 {
+			continue
+		}
+		f := figures[len(figures)-3:]
+		nature := strings.Join(strings.Fields(row[1]), " ")
+		rows = append(rows, fmt.Sprintf("%s: value %s, recovered %s, taxable %s", nature, f[0], f[1], f[2]))
+	}
+	return rows
+}
@@ -0,0 +1,165 @@
+package pii
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// fuzzSeeds are the seed corpus of the fuzz targets: one snippet per identifier, Form 16
+// lines and inputs at the edges of the detectors.
+var fuzzSeeds = []string{
+	"PAN of the Employee: ABCDE1234F",
+	"Aadhaar No.: 2345 6789 0124",
+	"TAN of the Deductor: MUMA12345B",
+	"Email: ravi.sharma@example.com",
+	"GSTIN: 27AAPFU0939F1ZV",
+	"Mobile: +91 98765 43210",
+	"UAN: 100123456789\nPF Account: MH/BAN/1234567/000/1234567",
+	"FORM NO. 16\n[See rule 31(1)(a)]\nPART A\nCertificate under section 203 of the Income-tax Act, 1961\n" +
+		"Name and address of the Employer\nInfosys Technologies Ltd, Electronics City, Bengaluru - 560100\n" +
+		"Name and address of the Employee\nRavi Kumar Sharma\nAssessment Year 2024-25\n",
+	"PART B\n1. Gross Salary\n(a) Salary as per provisions contained in section 17(1)   1200000.00\n" +
+		"I, Suresh Menon, son of Ramesh Menon, working in the capacity of Director\n",
+	"कर्मचारी का नाम / Name of the Employee\nरवि कुमार शर्मा\nपता: मकान नं. 12, गांधी नगर, जयपुर - 302015",
+	"Rupees Twelve Lakh Only\nDate of Birth: 12/05/1985",
+	"",
+	"\f\f\n\t  ",
+	"[PAN_REDACTED] [NAME_REDACTED]",
+	"\xff\xfe invalid UTF-8 ABCDE1234F",
+}
+
+// invariantError reports output of the redaction engine that violates one of its guarantees.
+type invariantError struct {
+	target string
+	reason string
+}
+
+func (e *invariantError) Error() string {
+	return fmt.Sprintf("%s: invariant violated: %s", e.target, e.reason)
+}
+
+// checkFilterPII runs the regex detectors over data. Panics are recovered into a *PanicError
+// and broken guarantees are reported as an *invariantError.
+func checkFilterPII(data []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r)
+		}
+	}()
+
+	text := string(data)
+	result := NewFilter().FilterPII(text)
+	if utf8.ValidString(text) && !utf8.ValidString(result.CleanedText) {
+		return &invariantError{target: "FilterPII", reason: "valid UTF-8 input produced invalid UTF-8 output"}
+	}
+	seen := make(map[string]bool)
+	for _, field := range result.RemovedFields {
+		if seen[field] {
+			return &invariantError{target: "FilterPII", reason: "category reported twice: " + field}
+		}
+		seen[field] = true
+	}
+	return nil
+}
+
+// checkPipelineRedact runs the text pipeline of the CLI's stdin path, serve and gRPC
+// (normalize, the detectors over pages filtered on several workers, the dictionary stage and
+// verification) over data split into pages at form feeds, reporting failures as
+// checkFilterPII does. Residual matches found by verification are reported errors, not
+// broken guarantees.
+func checkPipelineRedact(data []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r)
+		}
+	}()
+
+	p := &Pipeline{
+		Filter:  NewFilter(),
+		Stages:  Stages{{Name: StageNormalize}, {Name: StageDetect}, {Name: StageDictionary}, {Name: StageVerify}},
+		Words:   map[string]struct{}{"salary": {}, "income": {}, "deduction": {}, "total": {}},
+		Workers: 4,
+	}
+	result, err := p.Redact(context.Background(), Extraction{Pages: Pages(strings.Split(string(data), "\f"))})
+	if err != nil {
+		return nil
+	}
+	cleaned := result.Data.CleanedText
+	if utf8.ValidString(result.Text) && !utf8.ValidString(cleaned) {
+		return &invariantError{target: "Pipeline.Redact", reason: "valid UTF-8 input produced invalid UTF-8 output"}
+	}
+	if strings.Count(cleaned, "\n") > strings.Count(result.Text, "\n") {
+		return &invariantError{target: "Pipeline.Redact", reason: "redaction introduced new lines"}
+	}
+	if strings.Count(cleaned, "\f") != strings.Count(result.Text, "\f") {
+		return &invariantError{target: "Pipeline.Redact", reason: "pages were lost or added"}
+	}
+	for _, m := range result.Data.Matches {
+		if m.Start < 0 || m.Start > m.End || m.End > len(result.Text) {
+			return &invariantError{target: "Pipeline.Redact", reason: fmt.Sprintf("match %d-%d outside the text", m.Start, m.End)}
+		}
+	}
+	return nil
+}
+
+// checkParsePDF runs the native parser and text extraction over data as a PDF file, reporting
+// failures as checkFilterPII does. Files it cannot read are reported errors, not broken
+// guarantees.
+func checkParsePDF(data []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r)
+		}
+	}()
+
+	doc, err := parsePDF(data, "")
+	if err != nil {
+		return nil
+	}
+	if _, err := doc.extractPages(); err != nil {
+		return nil
+	}
+	if doc.inflated > maxInflatedDocument {
+		return &invariantError{target: "parsePDF", reason: fmt.Sprintf("decompressed %d bytes, over the limit", doc.inflated)}
+	}
+	return nil
+}
+
+func FuzzFilterPII(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := checkFilterPII(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func FuzzPipelineRedact(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := checkPipelineRedact(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func FuzzParsePDF(f *testing.F) {
+	f.Add(testPDF(false, "FORM NO. 16", "PAN of the Employee: ABCPE1234F"))
+	f.Add(testPDF(true, "Name and address of the Employee", "Ravi Kumar Sharma"))
+	f.Add(testPDF(true, "Rupees Twelve Lakh Only")[:300])
+	f.Add(bytes.Replace(testPDF(false, "Ravi Kumar Sharma"), []byte("72 720 Td"), []byte("72000000034 720 Td"), 1))
+	f.Add([]byte("%PDF-1.4\n1 0 obj\n<< /Type /ObjStm /N 2 /First 8 /Length 10 >>\nstream\n1 0 2 5 <<\nendstream\nendobj\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := checkParsePDF(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
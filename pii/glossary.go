@@ -0,0 +1,72 @@
+package pii
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// taxGlossary is the built-in glossary of Form 16 and Income Tax Act terms the dictionary
+// filter always leaves in the text: abbreviations, transliterations and Latin terms that no
+// English word list carries, and short forms of sections.
+var taxGlossary = []string{
+	// Taxes, levies and reliefs.
+	"cess", "surcharge", "rebate", "TDS", "TCS", "HRA", "LTA", "perquisite", "perquisites",
+	"premia", "gratuity", "commutation", "superannuation", "encashment", "arrears", "allowance",
+	"exemption", "proviso", "provisos", "u/s", "w.e.f", "viz",
+	// Forms, returns and the people in them.
+	"Form 16", "Form 16A", "Form 12BA", "Form 12BB", "Form 26AS", "annexure", "assessee",
+	"deductor", "deductee", "deductees", "TRACES", "TAN", "PAN", "GSTIN", "Aadhaar", "CIT",
+	"ITR", "AIS", "TIS", "HUF", "AY", "FY",
+	// Payments into the government account.
+	"challan", "challans", "BSR", "CIN", "OLTAS", "NSDL", "Protean",
+	// Sections of the deductions most often quoted.
+	"80C", "80CCC", "80CCD", "80CCD(1)", "80CCD(1B)", "80CCD(2)", "80D", "80DD", "80DDB", "80E",
+	"80EE", "80EEA", "80EEB", "80G", "80GG", "80GGA", "80GGC", "80TTA", "80TTB", "80U",
+	"Chapter VI-A", "115BAC",
+	// Savings schemes and funds.
+	"EPF", "VPF", "PPF", "NPS", "NSC", "ELSS", "ULIP", "SCSS", "SSY", "UAN", "EPFO", "ESIC",
+}
+
+// glossaryPattern matches the terms of a glossary as whole words, case-insensitively and
+// longest first so that "80CCD(1B)" wins over "80CCD". A term ending in punctuation has no
+// word boundary on that side.
+func glossaryPattern(terms []string) *regexp.Regexp {
+	terms = slices.Clone(terms)
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		q := strings.Join(strings.Fields(regexp.QuoteMeta(t)), `\s+`)
+		if isAlnum(t[0]) {
+			q = `\b` + q
+		}
+		if isAlnum(t[len(t)-1]) {
+			q += `\b`
+		}
+		quoted[i] = q
+	}
+	return regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)
+}
+
+// defaultGlossaryPattern matches the terms of taxGlossary.
+var defaultGlossaryPattern = glossaryPattern(taxGlossary)
+
+// WithGlossary adds terms, such as company or product names, to the built-in glossary of tax
+// terms the dictionary filter leaves in the text. A term may hold spaces and punctuation.
+func WithGlossary(terms ...string) Option {
+	return func(pf *Filter) {
+		for _, t := range terms {
+			if t = strings.TrimSpace(t); t != "" {
+				pf.glossary = append(pf.glossary, t)
+			}
+		}
+		pf.glossaryPattern = glossaryPattern(append(slices.Clone(taxGlossary), pf.glossary...))
+	}
+}
+
+// LoadGlossary reads a glossary file: one term per line. Blank lines and lines starting with
+// # are skipped.
+func LoadGlossary(path string) ([]string, error) {
+	return readList(path, "glossary")
+}
@@ -0,0 +1,88 @@
+package pii
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DriftError reports the first difference between a produced output and its golden artifact.
+type DriftError struct {
+	Golden   string
+	Line     int
+	Expected string
+	Actual   string
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("output drifted from %s at line %d:\n  expected: %q\n  actual:   %q", e.Golden, e.Line, e.Expected, e.Actual)
+}
+
+// AssertEqualOutputs compares every format of formats written for base (see WriteOutputs)
+// with its golden artifact, named after golden the same way: with -format txt,json and golden
+// form16.golden.txt, filtered_output.json is compared with form16.golden.json. It returns the
+// drift of every format that differs, joined.
+func AssertEqualOutputs(base, golden string, formats []string) error {
+	var errs []error
+	goldens := OutputPaths(golden, formats)
+	for i, produced := range OutputPaths(base, formats) {
+		errs = append(errs, AssertEqualFile(produced, goldens[i]))
+	}
+	return errors.Join(errs...)
+}
+
+// AssertEqualFile compares the produced file with a golden file and returns a *DriftError
+// describing the first differing line when they are not identical. JSON files (.json) are
+// compared as documents: both are re-encoded before the comparison, so that key order and
+// whitespace do not count as drift.
+func AssertEqualFile(produced, golden string) error {
+	actual, err := os.ReadFile(produced)
+	if err != nil {
+		return fmt.Errorf("failed to read produced output: %v", err)
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		return fmt.Errorf("failed to read golden file: %v", err)
+	}
+	if strings.HasSuffix(golden, ".json") {
+		if actual, err = normalizeJSON(actual); err != nil {
+			return fmt.Errorf("failed to parse produced output %s: %v", produced, err)
+		}
+		if expected, err = normalizeJSON(expected); err != nil {
+			return fmt.Errorf("failed to parse golden file %s: %v", golden, err)
+		}
+	}
+	if bytes.Equal(actual, expected) {
+		return nil
+	}
+
+	actualLines := bytes.Split(actual, []byte("\n"))
+	expectedLines := bytes.Split(expected, []byte("\n"))
+	for i := 0; ; i++ {
+		var a, e []byte
+		if i < len(actualLines) {
+			a = actualLines[i]
+		}
+		if i < len(expectedLines) {
+			e = expectedLines[i]
+		}
+		if !bytes.Equal(a, e) || i >= len(actualLines) || i >= len(expectedLines) {
+			return &DriftError{Golden: golden, Line: i + 1, Expected: string(e), Actual: string(a)}
+		}
+	}
+}
+
+// normalizeJSON re-encodes a JSON document with sorted keys and fixed indentation. Numbers are
+// kept as written.
+func normalizeJSON(b []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
@@ -0,0 +1,173 @@
+package pii
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// HandwritingDetector finds handwritten regions on a rendered page image. text holds the
+// pixel boxes of the words the text layer already accounts for; they are not handwriting.
+// The default is StrokeDetector; a trained model can be plugged in with
+// WithHandwritingDetector.
+type HandwritingDetector interface {
+	Detect(img image.Image, text []image.Rectangle) []image.Rectangle
+}
+
+// StrokeDetector is a connected-component heuristic: printed glyphs are small and of uniform
+// height, while handwriting forms larger components of thin strokes. Components much taller
+// or wider than the typical glyph and only sparsely inked are flagged, then merged into
+// regions. Table rules and dense blocks such as logos are ignored.
+type StrokeDetector struct{}
+
+const (
+	// inkThreshold is the luminance (0-255) below which a pixel counts as ink.
+	inkThreshold = 128
+	// strokeFill is the largest share of its bounding box a handwritten component inks.
+	strokeFill = 0.3
+	// regionGap is how close, in pixels, flagged components must be to form one region.
+	regionGap = 12
+)
+
+// Detect implements HandwritingDetector.
+func (StrokeDetector) Detect(img image.Image, text []image.Rectangle) []image.Rectangle {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	ink := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			l := color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+			ink[y*w+x] = l < inkThreshold
+		}
+	}
+	for _, r := range text {
+		r = r.Sub(b.Min).Intersect(image.Rect(0, 0, w, h))
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				ink[y*w+x] = false
+			}
+		}
+	}
+
+	type component struct {
+		box    image.Rectangle
+		pixels int
+	}
+	var comps []component
+	seen := make([]bool, w*h)
+	var queue []int
+	for start := range ink {
+		if !ink[start] || seen[start] {
+			continue
+		}
+		c := component{box: image.Rect(start%w, start/w, start%w+1, start/w+1)}
+		seen[start] = true
+		queue = append(queue[:0], start)
+		for len(queue) > 0 {
+			p := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			px, py := p%w, p/w
+			c.pixels++
+			c.box = c.box.Union(image.Rect(px, py, px+1, py+1))
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := px+dx, py+dy
+					if nx < 0 || ny < 0 || nx >= w || ny >= h {
+						continue
+					}
+					if q := ny*w + nx; ink[q] && !seen[q] {
+						seen[q] = true
+						queue = append(queue, q)
+					}
+				}
+			}
+		}
+		// Specks are scanner noise.
+		if c.pixels >= 4 {
+			comps = append(comps, c)
+		}
+	}
+	if len(comps) == 0 {
+		return nil
+	}
+
+	heights := make([]int, len(comps))
+	for i, c := range comps {
+		heights[i] = c.box.Dy()
+	}
+	sort.Ints(heights)
+	glyph := heights[len(heights)/2]
+
+	var flagged []image.Rectangle
+	for _, c := range comps {
+		cw, ch := c.box.Dx(), c.box.Dy()
+		// Table rules are long and thin.
+		if (cw > w/4 && ch <= 3) || (ch > h/4 && cw <= 3) {
+			continue
+		}
+		if ch*2 < glyph*3 && cw < glyph*6 {
+			continue
+		}
+		if float64(c.pixels)/float64(cw*ch) > strokeFill {
+			continue
+		}
+		flagged = append(flagged, c.box)
+	}
+	return mergeRegions(flagged, regionGap)
+}
+
+// mergeRegions joins rectangles that lie within gap pixels of each other until no two
+// regions are that close.
+func mergeRegions(rects []image.Rectangle, gap int) []image.Rectangle {
+	regions := append([]image.Rectangle(nil), rects...)
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(regions); i++ {
+			for j := i + 1; j < len(regions); j++ {
+				if regions[i].Inset(-gap).Overlaps(regions[j]) {
+					regions[i] = regions[i].Union(regions[j])
+					regions = append(regions[:j], regions[j+1:]...)
+					merged = true
+					j--
+				}
+			}
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].Min.Y != regions[j].Min.Y {
+			return regions[i].Min.Y < regions[j].Min.Y
+		}
+		return regions[i].Min.X < regions[j].Min.X
+	})
+	return regions
+}
+
+// handwritingRegions runs the filter's handwriting detector on a rendered page and returns
+// the regions as boxes in points. The page's words are excluded as known text.
+func (pf *Filter) handwritingRegions(img image.Image, page PageBoxes) []WordBox {
+	if page.Width <= 0 {
+		return nil
+	}
+	detector := pf.handwriting
+	if detector == nil {
+		detector = StrokeDetector{}
+	}
+	bounds := img.Bounds()
+	scale := float64(bounds.Dx()) / page.Width
+	text := make([]image.Rectangle, len(page.Words))
+	for i, word := range page.Words {
+		text[i] = image.Rect(
+			int((word.XMin-boxPadding)*scale), int((word.YMin-boxPadding)*scale),
+			int((word.XMax+boxPadding)*scale+1), int((word.YMax+boxPadding)*scale+1),
+		).Add(bounds.Min)
+	}
+	var regions []WordBox
+	for _, r := range detector.Detect(img, text) {
+		r = r.Sub(bounds.Min)
+		regions = append(regions, WordBox{
+			XMin: float64(r.Min.X) / scale, YMin: float64(r.Min.Y) / scale,
+			XMax: float64(r.Max.X) / scale, YMax: float64(r.Max.Y) / scale,
+		})
+	}
+	return regions
+}
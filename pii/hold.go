@@ -0,0 +1,93 @@
+package pii
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// holdSuffix names the marker file that puts an output under legal hold: filtered_output.txt
+// is held while filtered_output.txt.hold exists.
+const holdSuffix = ".hold"
+
+// HoldError is returned by the output writers when the target file is under legal hold.
+type HoldError struct {
+	Path   string
+	Reason string
+}
+
+func (e *HoldError) Error() string {
+	return fmt.Sprintf("%s is under legal hold (%s); refusing to overwrite it", e.Path, e.Reason)
+}
+
+// HoldPath returns the marker file that holds path.
+func HoldPath(path string) string {
+	return path + holdSuffix
+}
+
+// CheckHold returns a *HoldError when path is under legal hold. It fails closed: a marker that
+// exists but cannot be read is reported as an error rather than as no hold.
+func CheckHold(path string) error {
+	b, err := os.ReadFile(HoldPath(path))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check legal hold of %s: %v", path, err)
+	}
+	reason, _, _ := strings.Cut(string(b), "\n")
+	return &HoldError{Path: path, Reason: reason}
+}
+
+// PlaceHold puts path under legal hold by writing its marker file with the reason and the
+// time the hold was placed. A path already under hold keeps its first marker. The hold is
+// released by deleting the marker.
+//
+// PlaceHold does not wait for running writers: CreateOutput, WriteOutput and RenameOutput
+// check the hold and then write without a lock, so a hold placed between the two does not stop
+// that write. Place holds on outputs no run is writing, and check them once the runs are done.
+func PlaceHold(path, reason string) error {
+	marker := fmt.Sprintf("%s\nplaced: %s\n", reason, time.Now().UTC().Format(time.RFC3339))
+	f, err := os.OpenFile(HoldPath(path), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o444)
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to place legal hold: %v", err)
+	}
+	_, err = f.WriteString(marker)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to place legal hold: %v", err)
+	}
+	return nil
+}
+
+// CreateOutput is os.Create for the artifacts of a run; it refuses files under legal hold.
+func CreateOutput(path string) (*os.File, error) {
+	if err := CheckHold(path); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// WriteOutput is os.WriteFile for the artifacts of a run; it refuses files under legal hold.
+func WriteOutput(path string, data []byte) error {
+	if err := CheckHold(path); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// RenameOutput is os.Rename for the artifacts of a run; it refuses to replace a file under
+// legal hold.
+func RenameOutput(from, to string) error {
+	if err := CheckHold(to); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
@@ -0,0 +1,65 @@
+package pii
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLegalHold(t *testing.T) {
+	dir := t.TempDir()
+	held := filepath.Join(dir, "run_manifest.json")
+	if err := os.WriteFile(held, []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := PlaceHold(held, "case 42"); err != nil {
+		t.Fatal(err)
+	}
+	if err := PlaceHold(held, "case 43"); err != nil {
+		t.Fatalf("placing a hold again: %v", err)
+	}
+	var hold *HoldError
+	if err := CheckHold(held); !errors.As(err, &hold) || hold.Reason != "case 42" {
+		t.Fatalf("CheckHold = %v, want the first hold", err)
+	}
+
+	// The writers report the HoldError in their own words.
+	if err := SaveManifest(NewManifest("seed"), held); err == nil || !strings.Contains(err.Error(), "under legal hold") {
+		t.Errorf("SaveManifest over a held file = %v, want a legal hold error", err)
+	}
+	if err := SaveFailuresReport([]Failure{{Document: "a.pdf", Error: "boom"}}, held); err == nil || !strings.Contains(err.Error(), "under legal hold") {
+		t.Errorf("SaveFailuresReport over a held file = %v, want a legal hold error", err)
+	}
+	staged := filepath.Join(dir, "staged.json")
+	if err := os.WriteFile(staged, []byte("[]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RenameOutput(staged, held); !errors.As(err, &hold) {
+		t.Errorf("RenameOutput onto a held file = %v, want a HoldError", err)
+	}
+	if b, _ := os.ReadFile(held); string(b) != "{}\n" {
+		t.Errorf("held file was replaced: %q", b)
+	}
+}
+
+func TestCheckHoldUnreadableMarker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filtered_output.txt")
+	if err := CheckHold(path); err != nil {
+		t.Fatalf("CheckHold without a marker = %v", err)
+	}
+	// A marker that cannot be read is not taken for the absence of a hold.
+	if err := os.Mkdir(HoldPath(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckHold(path); err == nil {
+		t.Error("CheckHold with an unreadable marker = nil, want an error")
+	}
+	if err := WriteOutput(path, []byte("text\n")); err == nil {
+		t.Error("WriteOutput with an unreadable marker = nil, want an error")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("WriteOutput wrote %s", path)
+	}
+}
@@ -0,0 +1,160 @@
+package pii
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+)
+
+// htmlColors are the highlight colours of the HTML report, given to the entity types in
+// name order.
+var htmlColors = []string{
+	"#ffb3b3", "#ffd699", "#fff099", "#c2f0c2", "#b3e0ff", "#d9b3ff",
+	"#ffb3e6", "#c6d9ec", "#e6ccb3", "#b3ffec", "#e0e0e0", "#f0c2a0",
+}
+
+// htmlReport is the data of htmlReportTemplate.
+type htmlReport struct {
+	Groups []htmlGroup
+	Lines  []htmlLine
+}
+
+// htmlGroup lists the matches of one entity type.
+type htmlGroup struct {
+	Entity, Label, Color string
+	Matches              []htmlMatch
+}
+
+// htmlMatch is one highlighted span; ID links the group listing to the span in the text.
+type htmlMatch struct {
+	ID         string
+	Line       int
+	Text       string
+	Confidence float64
+}
+
+// htmlLine is one line of the original text. Entity and ID are set when the whole line was
+// redacted (address or organisation); Segments alternate plain text and highlighted spans.
+type htmlLine struct {
+	Number     int
+	Entity, ID string
+	Segments   []htmlSegment
+}
+
+// htmlSegment is a piece of a line, highlighted when Entity is set.
+type htmlSegment struct {
+	Text, Entity, ID, Title string
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Redaction review</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+pre { font-family: monospace; font-size: 13px; line-height: 1.4; }
+.ln { color: #999; display: inline-block; width: 4em; text-align: right; margin-right: 1em; user-select: none; }
+mark { border-radius: 2px; padding: 0 1px; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+{{range .Groups}}.e-{{.Entity}} { background: {{.Color}}; }
+{{end}}</style>
+</head>
+<body>
+<h1>Redaction review</h1>
+<p>The extracted text with every detector match highlighted; whole highlighted lines were
+redacted as addresses or organisations. Words removed by the dictionary filter are not shown.
+This page contains the original personal data: keep it as confidential as the raw text.</p>
+<table>
+<tr><th>Entity</th><th>Category</th><th>Matches</th></tr>
+{{range .Groups}}<tr><td class="e-{{.Entity}}">{{.Entity}}</td><td><a href="#g-{{.Entity}}">{{.Label}}</a></td><td>{{len .Matches}}</td></tr>
+{{end}}</table>
+<pre>
+{{range .Lines}}<span class="ln">{{.Number}}</span>{{if .Entity}}<span class="e-{{.Entity}}" id="{{.ID}}">{{end}}{{range .Segments}}{{if .Entity}}<mark class="e-{{.Entity}}" id="{{.ID}}" title="{{.Title}}">{{.Text}}</mark>{{else}}{{.Text}}{{end}}{{end}}{{if .Entity}}</span>{{end}}
+{{end}}</pre>
+{{range .Groups}}<h2 id="g-{{.Entity}}">{{.Label}} ({{len .Matches}})</h2>
+<table>
+<tr><th>Line</th><th>Text</th><th>Confidence</th></tr>
+{{range .Matches}}<tr><td><a href="#{{.ID}}">{{.Line}}</a></td><td><code>{{.Text}}</code></td><td>{{printf "%.2f" .Confidence}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// SaveHTMLReport writes the input text of data as an HTML page with every match highlighted in
+// the colour of its entity type, followed by the matches grouped by entity type, so reviewers
+// can check what was removed without diffing two text files.
+func SaveHTMLReport(data FilteredData, outputFile string) error {
+	if data.source == "" && len(data.Matches) > 0 {
+		return fmt.Errorf("failed to write HTML report: the input text is not available")
+	}
+	var buf bytes.Buffer
+	if err := htmlReportTemplate.Execute(&buf, buildHTMLReport(data)); err != nil {
+		return fmt.Errorf("failed to render HTML report: %v", err)
+	}
+	if err := WriteOutput(outputFile, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
+
+// buildHTMLReport lays out the lines and match groups of data.
+func buildHTMLReport(data FilteredData) htmlReport {
+	var report htmlReport
+	groups := make(map[string]*htmlGroup)
+	var entities []string
+	for _, m := range data.Matches {
+		if groups[m.Entity] == nil {
+			groups[m.Entity] = &htmlGroup{Entity: m.Entity, Label: m.Type}
+			entities = append(entities, m.Entity)
+		}
+	}
+	sort.Strings(entities)
+	for i, entity := range entities {
+		groups[entity].Color = htmlColors[i%len(htmlColors)]
+	}
+
+	// Matches are in line order; those of a line are sorted by start, with a whole-line match
+	// ahead of the token matches it contains.
+	matches := append([]Match(nil), data.Matches...)
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End > matches[j].End
+	})
+	next := 0
+	offset := 0
+	for n, line := range strings.Split(data.source, "\n") {
+		lineStart, lineEnd := offset, offset+len(line)
+		offset = lineEnd + 1
+		l := htmlLine{Number: n + 1}
+		pos := lineStart
+		for ; next < len(matches) && matches[next].Start < offset; next++ {
+			m := matches[next]
+			if m.Start < pos || m.End > lineEnd {
+				continue
+			}
+			g := groups[m.Entity]
+			id := fmt.Sprintf("m%d", next+1)
+			g.Matches = append(g.Matches, htmlMatch{ID: id, Line: n + 1, Text: data.source[m.Start:m.End], Confidence: m.Confidence})
+			if m.Start == lineStart && m.End == lineEnd && (m.Entity == EntityAddress || m.Entity == EntityOrganization) {
+				l.Entity, l.ID = m.Entity, id
+				continue
+			}
+			l.Segments = append(l.Segments,
+				htmlSegment{Text: data.source[pos:m.Start]},
+				htmlSegment{Text: data.source[m.Start:m.End], Entity: m.Entity, ID: id, Title: fmt.Sprintf("%s (%.2f)", m.Type, m.Confidence)})
+			pos = m.End
+		}
+		l.Segments = append(l.Segments, htmlSegment{Text: data.source[pos:lineEnd]})
+		report.Lines = append(report.Lines, l)
+	}
+	for _, entity := range entities {
+		report.Groups = append(report.Groups, *groups[entity])
+	}
+	return report
+}
@@ -0,0 +1,30 @@
+package pii
+
+import (
+	"regexp"
+	"strings"
+)
+
+// drivingLicenceStates are the state and union territory codes that start a driving licence
+// number (OD and OR, TS and UK old and new codes included).
+var drivingLicenceStates = map[string]bool{
+	"AN": true, "AP": true, "AR": true, "AS": true, "BR": true, "CG": true, "CH": true, "DD": true,
+	"DL": true, "DN": true, "GA": true, "GJ": true, "HP": true, "HR": true, "JH": true, "JK": true,
+	"KA": true, "KL": true, "LA": true, "LD": true, "MH": true, "ML": true, "MN": true, "MP": true,
+	"MZ": true, "NL": true, "OD": true, "OR": true, "PB": true, "PY": true, "RJ": true, "SK": true,
+	"TN": true, "TR": true, "TS": true, "UK": true, "UP": true, "WB": true,
+}
+
+// licenceSeparators are the separators printed between the parts of a licence number.
+var licenceSeparators = regexp.MustCompile(`[\s-]`)
+
+// ValidDrivingLicence reports whether s, as matched by DrivingLicencePattern, starts with a
+// known state code and carries a plausible year of issue.
+func ValidDrivingLicence(s string) bool {
+	s = licenceSeparators.ReplaceAllString(strings.ToUpper(s), "")
+	if len(s) != 15 || !drivingLicenceStates[s[:2]] {
+		return false
+	}
+	year := s[4:8]
+	return year >= "1950" && year <= "2099"
+}
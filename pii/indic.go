@@ -0,0 +1,184 @@
+package pii
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Bilingual Form 16s print their labels, and often the names and addresses, in Devanagari or
+// another Indian script as well as in English. The Indic recognizers find the names and
+// addresses written in these scripts; the English word list of the dictionary filter does not
+// judge their words.
+
+// indicLetters is the character class body of the letters and signs of the Indian scripts,
+// with the zero-width joiners that shape their conjuncts.
+const indicLetters = `\p{Devanagari}\p{Bengali}\p{Gurmukhi}\p{Gujarati}\p{Oriya}\p{Tamil}\p{Telugu}\p{Kannada}\p{Malayalam}\x{200C}\x{200D}`
+
+// indicWord matches a word of an Indian script.
+const indicWord = `[` + indicLetters + `]+`
+
+// indicHonorifics are the titles that precede a name in the Indian scripts: Shri, Shrimati,
+// Sushri, Kumari, Dr., the late. indicKinship are the words naming a relative, which join two
+// names: "रमेश पुत्र श्री सुरेश".
+var (
+	indicHonorifics = []string{
+		// Hindi and Marathi; "डॉ" and "स्व" are followed by a dot.
+		"श्रीमती", "श्रीमान", "श्री", "सुश्री", "कुमारी", "डॉ", "स्व", "स्वर्गीय",
+		// Bengali, Gurmukhi, Gujarati, Tamil, Telugu, Kannada and Malayalam.
+		"শ্রীমতী", "শ্রী", "ਸ਼੍ਰੀਮਤੀ", "ਸ਼੍ਰੀ", "ਸ੍ਰੀ", "શ્રીમતી", "શ્રી", "திருமதி", "திரு", "శ్రీమతి", "శ్రీ",
+		"ಶ್ರೀಮತಿ", "ಶ್ರೀ", "ശ്രീമതി", "ശ്രീ",
+	}
+	indicKinship = []string{"पुत्र", "पुत्री", "पत्नी", "सुपुत्र", "सुपुत्री", "पति"}
+)
+
+// indicNameGroup captures a name of one to four words, maxNameWords, of an Indian script. The
+// name ends at the first punctuation, non-Indic word or kinship word, so that "सीमा गुप्ता
+// पत्नी श्री अमित गुप्ता" gives two names.
+var indicNameGroup = `(` + indicWord + `(?: ` + indicWord + `){0,3}?)(?:$|[^ ` + indicLetters + `]| [^` + indicLetters + `]| (?:` + quoteAll(indicKinship) + `)(?:\s|$))`
+
+// indicTitle matches an honorific or kinship word and the space or dot after it.
+var indicTitle = `(?:` + quoteAll(append(slices.Clone(indicHonorifics), indicKinship...)) + `)(?:\.\s*|\s+)`
+
+// indicHonorificNamePattern finds names after an honorific or kinship word: "श्री सुरेश मेनन".
+var indicHonorificNamePattern = regexp.MustCompile(indicTitle + indicNameGroup)
+
+// indicNameLabelPattern is the name label of the Indian scripts ("कर्मचारी का नाम", "নাম",
+// "பெயர்"). indicLabelledNamePattern finds the name after the label and a colon.
+var (
+	indicNameLabelPattern    = regexp.MustCompile(`नाम|নাম|ਨਾਮ|નામ|ନାମ|பெயர்|పేరు|ಹೆಸರು|പേര്`)
+	indicLabelledNamePattern = regexp.MustCompile(`(?:नाम|নাম|ਨਾਮ|નામ|ନାମ|பெயர்|పేరు|ಹೆಸರು|പേര്)\s*[:：\-–]\s*(?:` + indicTitle + `)?` + indicNameGroup)
+	// indicNameRunPattern matches a whole run of Indian-script words, the candidate names
+	// found below a name label or by the gazetteer.
+	indicNameRunPattern = regexp.MustCompile(indicWord + `(?: ` + indicWord + `)*`)
+)
+
+// indicStopwords are the Form 16 label words of Hindi that never form part of a name.
+var indicStopwords = map[string]bool{
+	"नाम": true, "पता": true, "कर्मचारी": true, "नियोक्ता": true, "कटौतीकर्ता": true,
+	"का": true, "की": true, "के": true, "और": true, "एवं": true, "तथा": true, "प्रारूप": true,
+	"सं": true, "संख्या": true, "भाग": true, "वेतन": true, "सकल": true, "कुल": true, "कर": true,
+	"आय": true, "धारा": true, "वर्ष": true, "निर्धारण": true, "अवधि": true, "तिमाही": true,
+	"पैन": true, "टैन": true, "आधार": true, "सत्यापन": true, "स्थान": true, "तारीख": true,
+	"दिनांक": true, "पदनाम": true, "निदेशक": true, "प्रबंधक": true, "प्रमाणपत्र": true,
+	"राशि": true, "रुपये": true, "रुपए": true, "कटौती": true, "स्रोत": true, "पर": true,
+}
+
+// indicNames is a gazetteer of common given names and surnames written in Devanagari, which
+// marks a run of two or more words holding one as a name without label or honorific.
+var indicNames = nameSet([]string{
+	"रवि", "सुरेश", "रमेश", "राजेश", "महेश", "दिनेश", "मुकेश", "अनिल", "सुनील", "अमित", "विजय",
+	"अजय", "संजय", "राहुल", "प्रिया", "पूजा", "नेहा", "सीमा", "सुनीता", "अनीता", "गीता", "लता",
+	"आशा", "उषा", "राम", "श्याम", "मोहन", "सोहन", "कृष्ण", "गोपाल", "विनोद", "प्रकाश", "अशोक",
+	"कुमार", "कुमारी", "देवी", "शर्मा", "वर्मा", "गुप्ता", "सिंह", "यादव", "मिश्रा", "मिश्र", "तिवारी",
+	"पांडे", "पाण्डेय", "जैन", "अग्रवाल", "पटेल", "शाह", "मेहता", "जोशी", "चौहान", "राठौर",
+	"ठाकुर", "सक्सेना", "श्रीवास्तव", "दुबे", "चौधरी", "खान", "मेनन", "नायर", "अय्यर", "रेड्डी",
+	"राव", "देशमुख", "पाटिल", "कुलकर्णी", "बनर्जी", "मुखर्जी", "चटर्जी", "दास", "घोष", "बोस",
+})
+
+// indicAddressPattern matches a run of Indian-script words and numbers joined by single spaces,
+// commas or hyphens: "मकान नं. 12, गांधी नगर, जयपुर, राजस्थान - 302015". Two spaces end the
+// run, as on the Latin address columns.
+var indicAddressPattern = regexp.MustCompile(`(?:[` + indicLetters + `]+\.?|[0-9]+)(?:(?:[ \t]*[,\-–][ \t]*|[ \t])(?:[` + indicLetters + `]+\.?|[0-9]+))*`)
+
+// indicAddressKeywords are the Hindi words of an address, and indicAddressSuffixes the endings
+// of locality names: "गांधीनगर", "मालवीय नगर", "जनकपुरी".
+var (
+	indicAddressKeywords = map[string]bool{
+		"मकान": true, "भवन": true, "फ्लैट": true, "गली": true, "मार्ग": true, "रोड": true,
+		"सड़क": true, "नगर": true, "कॉलोनी": true, "कालोनी": true, "सेक्टर": true, "मोहल्ला": true,
+		"ग्राम": true, "गांव": true, "गाँव": true, "पोस्ट": true, "डाकघर": true, "जिला": true,
+		"ज़िला": true, "तहसील": true, "थाना": true, "चौक": true, "बाजार": true, "बाज़ार": true,
+		"विहार": true, "अपार्टमेंट": true, "सोसाइटी": true, "मंजिल": true, "पिन": true,
+	}
+	indicAddressSuffixes = []string{"नगर", "पुरम", "विहार", "गंज", "पुरी"}
+	// indicPlaces are the states and large cities written in Devanagari.
+	indicPlaces = map[string]bool{
+		"दिल्ली": true, "मुंबई": true, "कोलकाता": true, "चेन्नई": true, "बेंगलुरु": true,
+		"हैदराबाद": true, "पुणे": true, "अहमदाबाद": true, "जयपुर": true, "लखनऊ": true, "कानपुर": true,
+		"नागपुर": true, "इंदौर": true, "भोपाल": true, "पटना": true, "वाराणसी": true, "आगरा": true,
+		"नोएडा": true, "गुरुग्राम": true, "गुड़गांव": true, "चंडीगढ़": true, "देहरादून": true,
+		"राजस्थान": true, "महाराष्ट्र": true, "गुजरात": true, "बिहार": true, "हरियाणा": true,
+		"पंजाब": true, "उत्तराखंड": true, "झारखंड": true, "छत्तीसगढ़": true, "कर्नाटक": true,
+		"तमिलनाडु": true, "केरल": true, "तेलंगाना": true, "ओडिशा": true, "असम": true,
+		"मध्य": true, "उत्तर": true, "प्रदेश": true, "बंगाल": true,
+	}
+)
+
+// quoteAll joins the quoted words as alternatives, longest first so that "श्रीमती" wins over
+// "श्री".
+func quoteAll(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = regexp.QuoteMeta(w)
+	}
+	sortByLength(quoted)
+	return strings.Join(quoted, "|")
+}
+
+// sortByLength sorts words longest first, keeping the order of words of the same length.
+func sortByLength(words []string) {
+	for i := 1; i < len(words); i++ {
+		for k := i; k > 0 && len(words[k]) > len(words[k-1]); k-- {
+			words[k], words[k-1] = words[k-1], words[k]
+		}
+	}
+}
+
+// validIndicName rejects Indian-script runs holding label words, titles or place names, and
+// runs longer than maxNameWords.
+func validIndicName(match string) bool {
+	words := strings.Fields(match)
+	if len(words) == 0 || len(words) > maxNameWords {
+		return false
+	}
+	for _, w := range words {
+		if indicStopwords[w] || indicAddressKeywords[w] || indicPlaces[w] ||
+			slices.Contains(indicHonorifics, w) || slices.Contains(indicKinship, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// validIndicLabelledName is validIndicName for the whole runs below a name label, which must
+// have two words at least.
+func validIndicLabelledName(match string) bool {
+	return len(strings.Fields(match)) >= 2 && validIndicName(match)
+}
+
+// indicGazetteerName accepts the runs of two to maxNameWords words that pass validIndicName
+// and hold a given name or surname of indicNames: "रवि कुमार शर्मा".
+func indicGazetteerName(match string) bool {
+	if !validIndicLabelledName(match) {
+		return false
+	}
+	for _, w := range strings.Fields(match) {
+		if indicNames[w] {
+			return true
+		}
+	}
+	return false
+}
+
+// validIndicAddress accepts the Indian-script runs of two words or more that hold an address
+// keyword, a locality ending or a place name.
+func validIndicAddress(match string) bool {
+	words := strings.FieldsFunc(match, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ',' || r == '-' || r == '–' || r == '.'
+	})
+	if len(words) < 2 {
+		return false
+	}
+	for _, w := range words {
+		if indicAddressKeywords[w] || indicPlaces[w] {
+			return true
+		}
+		for _, suffix := range indicAddressSuffixes {
+			if strings.HasSuffix(w, suffix) && w != suffix {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package pii
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// inferThreshold is the share of sampled values one entity type must cover for a column to be
+// classified as that type.
+const inferThreshold = 0.6
+
+// columnHints suggest an entity type from a column name, for types the detectors cannot
+// recognise in a bare value, such as names and addresses.
+var columnHints = []struct {
+	pattern *regexp.Regexp
+	entity  string
+}{
+	{regexp.MustCompile(`(?i)\b(?:pan|pan_?no|pan_?number)\b`), EntityPAN},
+	{regexp.MustCompile(`(?i)aadhaa?r|\buid\b`), EntityAadhaar},
+	{regexp.MustCompile(`(?i)e_?mail`), EntityEmail},
+	{regexp.MustCompile(`(?i)phone|mobile|contact_?no`), EntityPhone},
+	{regexp.MustCompile(`(?i)\buan\b`), EntityUAN},
+	{regexp.MustCompile(`(?i)pf_?(?:account|acc|no)`), EntityPFAccount},
+	{regexp.MustCompile(`(?i)passport`), EntityPassport},
+	{regexp.MustCompile(`(?i)voter|epic`), EntityVoterID},
+	{regexp.MustCompile(`(?i)licen[cs]e|\bdl_?no\b`), EntityDrivingLicence},
+	{regexp.MustCompile(`(?i)\bdob\b|birth`), EntityDOB},
+	{regexp.MustCompile(`(?i)pin_?code|postal_?code|\bpin\b`), EntityPINCode},
+	{regexp.MustCompile(`(?i)addr`), EntityAddress},
+	{regexp.MustCompile(`(?i)(?:^|_|\b)(?:full_?|first_?|last_?|employee_?|father_?)?name\b`), EntityName},
+}
+
+// columnSample collects what the detectors found in the sampled values of one column.
+type columnSample struct {
+	values int
+	// whole counts the values entirely covered by one finding, per entity type.
+	whole map[string]int
+	// partial counts the values with findings that do not cover them.
+	partial int
+}
+
+// InferFieldSchema samples up to sample records of a JSON or CSV export and suggests a rule per
+// column: an entity type when one covers most values, auto when free text contains PII, and
+// keep otherwise. Column names are used as hints for names and addresses, which the detectors
+// do not find in bare values. Every rule carries the share of sampled values supporting it as
+// its Confidence; the suggestion is meant to be reviewed before use.
+func (pf *Filter) InferFieldSchema(r io.Reader, format string, sample int) (FieldSchema, error) {
+	columns := make(map[string]*columnSample)
+	observe := func(column, value string) {
+		c := columns[column]
+		if c == nil {
+			c = &columnSample{whole: make(map[string]int)}
+			columns[column] = c
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return
+		}
+		c.values++
+		var findings []Finding
+		pf.FilterWithCallback(value, func(f Finding) Action {
+			findings = append(findings, f)
+			return ActionRedact
+		})
+		switch {
+		case len(findings) == 1 && coversValue(value, findings[0]):
+			c.whole[findings[0].Entity]++
+		case len(findings) > 0:
+			c.partial++
+		}
+	}
+
+	var err error
+	switch format {
+	case "csv":
+		err = sampleCSV(r, sample, observe)
+	case "json":
+		err = sampleJSON(r, sample, observe)
+	default:
+		err = fmt.Errorf("%w %q (expected json or csv)", ErrUnsupportedFormat, format)
+	}
+	if err != nil {
+		return FieldSchema{}, err
+	}
+
+	schema := FieldSchema{Default: ColumnKeep, Columns: make(map[string]FieldRule, len(columns))}
+	for column, c := range columns {
+		schema.Columns[column] = c.rule(column)
+	}
+	return schema, nil
+}
+
+// rule turns the sample of a column into a suggested rule.
+func (c *columnSample) rule(column string) FieldRule {
+	hint := ""
+	last := column[strings.LastIndex(column, ".")+1:]
+	for _, h := range columnHints {
+		if h.pattern.MatchString(last) {
+			hint = h.entity
+			break
+		}
+	}
+	if c.values == 0 {
+		if hint != "" {
+			return FieldRule{Type: hint, Confidence: 0.5}
+		}
+		return FieldRule{Type: ColumnKeep, Confidence: 0.5}
+	}
+
+	best, bestCount, found := "", 0, c.partial
+	for entity, n := range c.whole {
+		found += n
+		if n > bestCount || n == bestCount && entity < best {
+			best, bestCount = entity, n
+		}
+	}
+	share := func(n int) float64 {
+		return math.Round(float64(n)/float64(c.values)*100) / 100
+	}
+	switch {
+	case share(bestCount) >= inferThreshold:
+		return FieldRule{Type: best, Confidence: share(bestCount)}
+	case hint != "" && (found == 0 || c.whole[hint] > 0):
+		// The detectors cannot contradict the name; names and addresses are rarely found in
+		// bare values at all.
+		return FieldRule{Type: hint, Confidence: max(share(c.whole[hint]), 0.5)}
+	case found > 0:
+		return FieldRule{Type: ColumnAuto, Confidence: share(found)}
+	}
+	return FieldRule{Type: ColumnKeep, Confidence: 1}
+}
+
+// coversValue reports whether a finding spans all of value but punctuation and spaces.
+func coversValue(value string, f Finding) bool {
+	rest := value[:f.Start] + value[f.End:]
+	return strings.IndexFunc(rest, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) < 0
+}
+
+// sampleCSV passes the cells of up to sample records to observe, by header name.
+func sampleCSV(r io.Reader, sample int, observe func(column, value string)) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse CSV: %v", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	for _, name := range header {
+		observe(name, "")
+	}
+	for n := 0; n < sample; n++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse CSV: %v", err)
+		}
+		for i, cell := range record {
+			if i < len(header) {
+				observe(header[i], cell)
+			}
+		}
+	}
+	return nil
+}
+
+// sampleJSON passes the scalars of up to sample records to observe, by dotted key path. The
+// records are the elements of a top-level array, or the documents of a JSON Lines stream.
+func sampleJSON(r io.Reader, sample int, observe func(column, value string)) error {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+	// Peek at the first non-space byte to tell an array from a stream of documents.
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return nil
+		}
+		if !unicode.IsSpace(rune(b[0])) {
+			if b[0] == '[' {
+				if _, err := dec.Token(); err != nil {
+					return fmt.Errorf("failed to parse JSON: %v", err)
+				}
+			}
+			break
+		}
+		br.ReadByte()
+	}
+	for n := 0; n < sample && dec.More(); n++ {
+		var record any
+		if err := dec.Decode(&record); err != nil {
+			return fmt.Errorf("failed to parse JSON: %v", err)
+		}
+		walkJSON(record, nil, observe)
+	}
+	return nil
+}
+
+// walkJSON passes the scalars below v to observe; null and booleans count as empty values.
+func walkJSON(v any, path []string, observe func(column, value string)) {
+	if _, ok := v.(map[string]any); !ok && len(path) == 0 {
+		return
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walkJSON(v[k], append(path, k), observe)
+		}
+	case []any:
+		for _, e := range v {
+			walkJSON(e, path, observe)
+		}
+	case string:
+		observe(strings.Join(path, "."), v)
+	case json.Number:
+		observe(strings.Join(path, "."), v.String())
+	default:
+		observe(strings.Join(path, "."), "")
+	}
+}
@@ -0,0 +1,98 @@
+package pii
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInferFieldSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		// want is the suggested type per column.
+		want map[string]string
+	}{
+		{
+			name:   "csv",
+			format: "csv",
+			input: "\ufeffpan,email,employee_name,remarks,grade\n" +
+				"ABCPE1234F,ravi@infosys.com,Ravi Sharma,PAN ABCPE1234F on file,A2\n" +
+				"ABCPR5678K,asha@infosys.com,Asha Rao,,B1\n" +
+				"ABCPK4321L,kiran@infosys.com,Kiran Das,call +91 98765 43210,A1\n",
+			want: map[string]string{
+				"pan":           EntityPAN,
+				"email":         EntityEmail,
+				"employee_name": EntityName,
+				"remarks":       ColumnAuto,
+				"grade":         ColumnKeep,
+			},
+		},
+		{
+			name:   "json array with nested keys",
+			format: "json",
+			input: `[{"id":1,"contact":{"email":"ravi@infosys.com","address":null}},` +
+				`{"id":2,"contact":{"email":"asha@infosys.com","address":null}}]`,
+			want: map[string]string{
+				"id":              ColumnKeep,
+				"contact.email":   EntityEmail,
+				"contact.address": EntityAddress,
+			},
+		},
+		{
+			name:   "json lines",
+			format: "json",
+			input:  "{\"mobile\":\"+91 98765 43210\"}\n{\"mobile\":\"+91 91234 56789\"}\n",
+			want:   map[string]string{"mobile": EntityPhone},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := NewFilter().InferFieldSchema(strings.NewReader(tt.input), tt.format, 100)
+			if err != nil {
+				t.Fatalf("InferFieldSchema: %v", err)
+			}
+			if schema.Default != ColumnKeep || len(schema.Columns) != len(tt.want) {
+				t.Errorf("InferFieldSchema = %+v", schema)
+			}
+			for column, want := range tt.want {
+				rule := schema.Columns[column]
+				if rule.Type != want {
+					t.Errorf("column %q = %+v, want type %q", column, rule, want)
+				}
+				if rule.Confidence <= 0 || rule.Confidence > 1 {
+					t.Errorf("column %q confidence = %v", column, rule.Confidence)
+				}
+			}
+			// A suggested schema is valid as it stands.
+			if err := NewFilter().ValidateFieldSchema(schema, nil); err != nil {
+				t.Errorf("ValidateFieldSchema: %v", err)
+			}
+		})
+	}
+}
+
+func TestInferFieldSchemaSample(t *testing.T) {
+	// Only the first record is sampled, so the PANs further down are not seen.
+	input := "code\nA2\nABCPE1234F\nABCPR5678K\n"
+	schema, err := NewFilter().InferFieldSchema(strings.NewReader(input), "csv", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := schema.Columns["code"].Type; got != ColumnKeep {
+		t.Errorf("code = %q, want %q", got, ColumnKeep)
+	}
+	if schema, err = NewFilter().InferFieldSchema(strings.NewReader(input), "csv", 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := schema.Columns["code"].Type; got != EntityPAN {
+		t.Errorf("code, all sampled = %q, want %q", got, EntityPAN)
+	}
+	if _, err := NewFilter().InferFieldSchema(strings.NewReader(input), "xml", 1); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("format xml: error = %v, want ErrUnsupportedFormat", err)
+	}
+	if _, err := NewFilter().InferFieldSchema(strings.NewReader(`[{"a":`), "json", 10); err == nil {
+		t.Error("truncated JSON: no error")
+	}
+}
@@ -0,0 +1,159 @@
+package pii
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldForm16Layout is the RetainedFields key of the layout version of a Form 16, one of
+// Form16LayoutNames; a bundle gives one value per layout found.
+const FieldForm16Layout = "Form 16 Layout"
+
+// form16LayoutsYAML holds the layout versions of Form 16 with their markers and parsing
+// rules; the file documents its format.
+//
+//go:embed form16_layouts.yaml
+var form16LayoutsYAML []byte
+
+// form16Layout is a layout version of Form 16: the TRACES template of a range of financial
+// years, or the certificates employers printed themselves before it.
+type form16Layout struct {
+	Name    string       `yaml:"name"`
+	Period  string       `yaml:"period"`
+	Markers []string     `yaml:"markers"`
+	Fields  []layoutRule `yaml:"fields"`
+
+	markers []*regexp.Regexp
+}
+
+// layoutRule parses one retained field from the lines of a layout.
+type layoutRule struct {
+	Field   string   `yaml:"field"`
+	Line    string   `yaml:"line"`
+	Figure  int      `yaml:"figure"`
+	Columns []string `yaml:"columns"`
+	Value   string   `yaml:"value"`
+	Below   int      `yaml:"below"`
+
+	line, value *regexp.Regexp
+}
+
+// form16Layouts are the layout versions of form16_layouts.yaml, newest first.
+var form16Layouts = mustParseLayouts(form16LayoutsYAML)
+
+// mustParseLayouts parses and compiles the layout versions in b, and panics on an invalid
+// file as it is built into the binary.
+func mustParseLayouts(b []byte) []*form16Layout {
+	var layouts []*form16Layout
+	if err := yaml.Unmarshal(b, &layouts); err != nil {
+		panic(fmt.Sprintf("failed to parse form16_layouts.yaml: %v", err))
+	}
+	for _, l := range layouts {
+		for _, m := range l.Markers {
+			l.markers = append(l.markers, regexp.MustCompile(m))
+		}
+		for i := range l.Fields {
+			r := &l.Fields[i]
+			r.line = regexp.MustCompile(r.Line)
+			if r.Value != "" {
+				r.value = regexp.MustCompile(r.Value)
+			}
+			if r.Figure == 0 {
+				r.Figure = -1
+			}
+		}
+	}
+	return layouts
+}
+
+// Form16LayoutNames lists the layout versions of Form 16 that are told apart, newest first.
+func Form16LayoutNames() []string {
+	names := make([]string, len(form16Layouts))
+	for i, l := range form16Layouts {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// detectLayout returns the layout of the Form 16 certificate text: the first layout one of
+// whose markers is found in it, else the newest one. Text without a Form 16 title has none.
+func detectLayout(text string) *form16Layout {
+	lines := strings.Split(text, "\n")
+	if !slices.ContainsFunc(lines, formHeaderPattern.MatchString) {
+		return nil
+	}
+	for _, l := range form16Layouts {
+		for _, m := range l.markers {
+			if m.MatchString(text) {
+				return l
+			}
+		}
+	}
+	return form16Layouts[0]
+}
+
+// apply replaces the fields parsed by the built-in rules with those found by the layout's
+// rules, field by field; a rule finding nothing leaves the field as it was.
+func (l *form16Layout) apply(text string, fields map[string][]string) {
+	found := make(map[string][]string)
+	lines := strings.Split(text, "\n")
+	for _, r := range l.Fields {
+		for n, line := range lines {
+			m := r.line.FindStringSubmatchIndex(line)
+			if m == nil {
+				continue
+			}
+			prefix := ""
+			if len(m) > 2 && m[2] >= 0 {
+				prefix = line[m[2]:m[3]] + ": "
+			}
+			if v := r.parse(lines, n, m[1]); v != "" {
+				found[r.Field] = append(found[r.Field], prefix+v)
+			}
+		}
+	}
+	for key, values := range found {
+		fields[key] = values
+	}
+}
+
+// parse returns the value of the rule after the end of its line pattern on lines[n], or on
+// the Below lines under it.
+func (r *layoutRule) parse(lines []string, n, end int) string {
+	for k := n; k < len(lines) && k <= n+r.Below; k++ {
+		line := lines[k]
+		if k == n {
+			line = line[end:]
+		}
+		if r.value != nil {
+			if vm := r.value.FindStringSubmatch(line); vm != nil {
+				return vm[min(1, len(vm)-1)]
+			}
+			continue
+		}
+		figures := figurePattern.FindAllString(line, -1)
+		if len(r.Columns) > 0 {
+			if len(figures) < len(r.Columns) {
+				continue
+			}
+			parts := make([]string, len(r.Columns))
+			for i, c := range r.Columns {
+				parts[i] = c + " " + figures[i]
+			}
+			return strings.Join(parts, ", ")
+		}
+		i := r.Figure - 1
+		if r.Figure < 0 {
+			i = len(figures) + r.Figure
+		}
+		if i >= 0 && i < len(figures) {
+			return figures[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+package pii
+
+// headerFraction is the share of the page height, measured from the top, that holds the
+// employer's letterhead. Images lying entirely inside it are treated as logos.
+const headerFraction = 0.25
+
+// letterheadImages returns, per page, the images of src that lie entirely in the header
+// region, as boxes in the top-left coordinates of WordBox. Full-page images, such as the
+// page of a scanned certificate, extend below the header and are never included.
+func letterheadImages(src, password string) ([][]WordBox, error) {
+	doc, err := loadPDF(src, password)
+	if err != nil {
+		return nil, err
+	}
+	pages := doc.pages()
+	e := &textExtractor{doc: doc, fonts: make(map[interface{}]*pdfFont)}
+	out := make([][]WordBox, len(pages))
+	for i, page := range pages {
+		e.runs, e.images = e.runs[:0], e.images[:0]
+		e.run(doc.pageContent(page), doc.dict(page["Resources"]), identityMatrix, 0)
+		llx, lly, urx, ury := doc.mediaBox(page)
+		limit := (ury - lly) * headerFraction
+		for _, r := range e.images {
+			top, bottom := ury-r.ury, ury-r.lly
+			if bottom <= limit && bottom > 0 && r.urx > llx && r.llx < urx {
+				out[i] = append(out[i], WordBox{XMin: r.llx - llx, YMin: top, XMax: r.urx - llx, YMax: bottom})
+			}
+		}
+	}
+	return out, nil
+}
+
+// headerBand is the whole header region of a page, blanked when the positions of its images
+// cannot be determined.
+func headerBand(page PageBoxes) WordBox {
+	return WordBox{XMax: page.Width, YMax: page.Height * headerFraction}
+}
@@ -0,0 +1,168 @@
+package pii
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Report string keys. They are also the keys of the "strings" object in a locale file.
+const (
+	msgTitle          = "title"
+	msgSummary        = "summary"
+	msgRemovedFields  = "removed_fields"
+	msgRetainedFields = "retained_fields"
+	msgMatchCounts    = "match_counts"
+	msgExample        = "example"
+	msgRetainedData   = "retained_data"
+	msgRepeatedLines  = "repeated_lines"
+	msgCertificates   = "certificates"
+	msgCertificate    = "certificate"
+	msgRemoved        = "removed"
+	msgWarnings       = "warnings"
+	msgExplanations   = "explanations"
+	msgTimings        = "timings"
+	msgCleanedText    = "cleaned_text"
+)
+
+// Locale translates the placeholders written into redacted text and the section headers of
+// the text report. Missing entries fall back to English.
+type Locale struct {
+	// Placeholders maps entity types (EntityPAN, EntityWord...) to their replacement text.
+	Placeholders map[string]string `json:"placeholders"`
+	// Strings maps report string keys ("title", "summary"...) to their text.
+	Strings map[string]string `json:"strings"`
+}
+
+// englishStrings are the report strings of the default locale.
+var englishStrings = map[string]string{
+	msgTitle:          "=== FILTERED PDF DATA ===",
+	msgSummary:        "FILTERING SUMMARY:",
+	msgRemovedFields:  "- Removed PII Fields:",
+	msgRetainedFields: "- Retained Business Fields:",
+	msgMatchCounts:    "MATCH COUNTS:",
+	msgExample:        "e.g.",
+	msgRetainedData:   "RETAINED BUSINESS DATA:",
+	msgRepeatedLines:  "REPEATED PAGE HEADERS/FOOTERS:",
+	msgCertificates:   "CERTIFICATES",
+	msgCertificate:    "Certificate",
+	msgRemoved:        "Removed:",
+	msgWarnings:       "WARNINGS:",
+	msgExplanations:   "EXPLANATIONS:",
+	msgTimings:        "TIMINGS:",
+	msgCleanedText:    "CLEANED TEXT CONTENT:",
+}
+
+// locales holds the built-in translations by language code.
+var locales = map[string]Locale{
+	"en": {Placeholders: defaultPlaceholders, Strings: englishStrings},
+	"hi": {
+		Placeholders: map[string]string{
+			EntityPhone:            "[फ़ोन_हटाया_गया]",
+			EntityEmail:            "[ईमेल_हटाया_गया]",
+			EntityAadhaar:          "[आधार_हटाया_गया]",
+			EntityPAN:              "[पैन_हटाया_गया]",
+			EntityGST:              "[जीएसटी_हटाया_गया]",
+			EntityTAN:              "[टैन_हटाया_गया]",
+			EntityPINCode:          "[पिन_कोड_हटाया_गया]",
+			EntityUAN:              "[यूएएन_हटाया_गया]",
+			EntityPFAccount:        "[पीएफ_खाता_हटाया_गया]",
+			EntityPassport:         "[पासपोर्ट_हटाया_गया]",
+			EntityVoterID:          "[मतदाता_पहचान_हटाई_गई]",
+			EntityDrivingLicence:   "[ड्राइविंग_लाइसेंस_हटाया_गया]",
+			EntityBankAccount:      "[बैंक_खाता_हटाया_गया]",
+			EntityFolio:            "[फोलियो_हटाया_गया]",
+			EntityChallan:          "[चालान_हटाया_गया]",
+			EntityEmployeeID:       "[कर्मचारी_कोड_हटाया_गया]",
+			EntityESI:              "[ईएसआई_हटाया_गया]",
+			EntityIFSC:             "[आईएफएससी_हटाया_गया]",
+			EntityPreviousEmployer: "[पूर्व_नियोक्ता_हटाया_गया]",
+			EntityDesignation:      "[पदनाम_हटाया_गया]",
+			EntityName:             "[नाम_हटाया_गया]",
+			EntityDOB:              "[जन्मतिथि_हटाई_गई]",
+			EntityDate:             "[तारीख_हटाई_गई]",
+			EntityAddress:          "[पता_हटाया_गया]",
+			EntityOrganization:     "[संगठन_हटाया_गया]",
+			EntityIndicName:        "[नाम_हटाया_गया]",
+			EntityIndicAddress:     "[पता_हटाया_गया]",
+			EntityWord:             "[शब्द_हटाया_गया]",
+		},
+		Strings: map[string]string{
+			msgTitle:          "=== फ़िल्टर किया गया PDF डेटा ===",
+			msgSummary:        "फ़िल्टरिंग सारांश:",
+			msgRemovedFields:  "- हटाए गए PII क्षेत्र:",
+			msgRetainedFields: "- रखे गए व्यावसायिक क्षेत्र:",
+			msgMatchCounts:    "मिलान संख्या:",
+			msgExample:        "उदा.",
+			msgRetainedData:   "रखा गया व्यावसायिक डेटा:",
+			msgRepeatedLines:  "दोहराए गए पृष्ठ शीर्ष/पाद:",
+			msgCertificates:   "प्रमाणपत्र",
+			msgCertificate:    "प्रमाणपत्र",
+			msgRemoved:        "हटाया गया:",
+			msgWarnings:       "चेतावनियाँ:",
+			msgExplanations:   "स्पष्टीकरण:",
+			msgTimings:        "समय:",
+			msgCleanedText:    "साफ़ किया गया पाठ:",
+		},
+	},
+}
+
+// LocaleNames returns the built-in language codes in sorted order.
+func LocaleNames() []string {
+	names := make([]string, 0, len(locales))
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadLocale returns the built-in locale for lang, with the entries of the JSON file at path
+// (if path is not empty) layered on top. An unknown lang is accepted only together with a
+// file, which then defines a new language on top of English.
+func LoadLocale(lang, path string) (Locale, error) {
+	base, ok := locales[lang]
+	if !ok && path == "" {
+		return Locale{}, fmt.Errorf("unknown language %q (built in: %v)", lang, LocaleNames())
+	}
+	l := Locale{Placeholders: make(map[string]string), Strings: make(map[string]string)}
+	for k, v := range base.Placeholders {
+		l.Placeholders[k] = v
+	}
+	for k, v := range base.Strings {
+		l.Strings[k] = v
+	}
+	if path == "" {
+		return l, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Locale{}, fmt.Errorf("failed to read locale file: %v", err)
+	}
+	var extra Locale
+	if err := json.Unmarshal(b, &extra); err != nil {
+		return Locale{}, fmt.Errorf("failed to parse locale file: %v", err)
+	}
+	for k, v := range extra.Placeholders {
+		l.Placeholders[k] = v
+	}
+	for k, v := range extra.Strings {
+		if _, ok := englishStrings[k]; !ok {
+			return Locale{}, fmt.Errorf("locale file: unknown report string %q", k)
+		}
+		l.Strings[k] = v
+	}
+	return l, nil
+}
+
+// text returns the translation of a report string; a nil locale is English.
+func (l *Locale) text(key string) string {
+	if l != nil {
+		if s, ok := l.Strings[key]; ok {
+			return s
+		}
+	}
+	return englishStrings[key]
+}
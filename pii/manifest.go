@@ -0,0 +1,128 @@
+package pii
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mrand "math/rand/v2"
+	"strings"
+	"time"
+)
+
+// ToolVersion is recorded in manifests and reports.
+const ToolVersion = "2.0.0"
+
+// Manifest records how a run was performed so that its outputs can be audited and, given
+// the same seed, reproduced.
+type Manifest struct {
+	ToolVersion string    `json:"tool_version"`
+	RunID       string    `json:"run_id"`
+	StartedAt   time.Time `json:"started_at"`
+	SeedSHA256  string    `json:"seed_sha256"`
+	Policy      string    `json:"policy,omitempty"`
+	// PolicySignature identifies the signed config of the run, when a policy key was given.
+	PolicySignature *PolicySignature `json:"policy_signature,omitempty"`
+	// Preset identifies the -preset bundle of the run, if one was given.
+	Preset *PresetRecord `json:"preset,omitempty"`
+	// Data identifies the data set of the run's gazetteer and dictionary, if one is installed.
+	Data *DataRecord `json:"data,omitempty"`
+	// ProcessingBasis records why the run was performed, if it was given.
+	ProcessingBasis *ProcessingBasis   `json:"processing_basis,omitempty"`
+	Documents       []ManifestDocument `json:"documents"`
+}
+
+// ProcessingBasis is the record-of-processing entry attached to a run or API request: the
+// purpose of processing personal data, who asked for it and the ticket authorising it.
+type ProcessingBasis struct {
+	Purpose   string `json:"purpose"`
+	Requester string `json:"requester,omitempty"`
+	Ticket    string `json:"ticket,omitempty"`
+}
+
+// NewProcessingBasis returns the basis made of the given fields, or nil when all are empty.
+// Values are trimmed and must fit on one line so they cannot forge log or report entries.
+func NewProcessingBasis(purpose, requester, ticket string) (*ProcessingBasis, error) {
+	b := &ProcessingBasis{
+		Purpose:   strings.TrimSpace(purpose),
+		Requester: strings.TrimSpace(requester),
+		Ticket:    strings.TrimSpace(ticket),
+	}
+	if b.Purpose == "" && b.Requester == "" && b.Ticket == "" {
+		return nil, nil
+	}
+	for _, v := range []string{b.Purpose, b.Requester, b.Ticket} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("processing basis fields must be a single line")
+		}
+	}
+	if b.Purpose == "" {
+		return nil, fmt.Errorf("a processing basis needs a purpose")
+	}
+	return b, nil
+}
+
+// String formats the basis for log lines.
+func (b *ProcessingBasis) String() string {
+	return fmt.Sprintf("purpose=%q requester=%q ticket=%q", b.Purpose, b.Requester, b.Ticket)
+}
+
+// ManifestDocument describes one processed input and the artifacts written for it.
+type ManifestDocument struct {
+	Input       string   `json:"input"`
+	Outputs     []string `json:"outputs,omitempty"`
+	RawOutput   string   `json:"raw_output,omitempty"`
+	ReviewCopy  string   `json:"review_copy,omitempty"`
+	RedactedPDF string   `json:"redacted_pdf,omitempty"`
+	// RedactedPDFVerification is the re-scan result printed on the redacted PDF's cover page.
+	RedactedPDFVerification string `json:"redacted_pdf_verification,omitempty"`
+	Status                  string `json:"status"`
+	// SkipReason is why a -dir input was skipped without being processed (status "skipped"),
+	// e.g. an empty or truncated file.
+	SkipReason string    `json:"skip_reason,omitempty"`
+	Extraction *Metadata `json:"extraction,omitempty"`
+	Warnings   []Warning `json:"warnings,omitempty"`
+	// LegalHold is the reason the document's outputs were put under legal hold, if any.
+	LegalHold string `json:"legal_hold,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// NewSeed returns a random seed for runs where none was configured.
+func NewSeed() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NewRunRand returns the deterministic random source that every randomised feature of a run
+// must draw from, so that supplying the same seed reproduces the same output.
+func NewRunRand(seed string) *mrand.Rand {
+	return mrand.New(mrand.NewChaCha8(sha256.Sum256([]byte(seed))))
+}
+
+// NewManifest starts a manifest for a run. Only a hash of the seed is stored so the manifest
+// can be shared without allowing anyone to regenerate seeded values.
+func NewManifest(seed string) *Manifest {
+	sum := sha256.Sum256([]byte(seed))
+	id := make([]byte, 8)
+	rand.Read(id)
+	return &Manifest{
+		ToolVersion: ToolVersion,
+		RunID:       hex.EncodeToString(id),
+		StartedAt:   time.Now().UTC(),
+		SeedSHA256:  hex.EncodeToString(sum[:]),
+	}
+}
+
+// SaveManifest writes the manifest as indented JSON.
+func SaveManifest(m *Manifest, outputFile string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %v", err)
+	}
+	if err := WriteOutput(outputFile, append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package pii
+
+import (
+	"fmt"
+	"os"
+)
+
+// MapFile reads the file at path into memory; memory mapping is only used on Unix systems.
+func MapFile(path string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package pii
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// MapFile maps the file at path read-only into memory and returns its contents with the
+// function that unmaps them. The contents must not be used after unmapping.
+func MapFile(path string) ([]byte, func() error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to stat %s: %v", path, err)
+	}
+	size := info.Size()
+	if size == 0 {
+		return nil, func() error { return nil }, nil
+	}
+	if int64(int(size)) != size {
+		return nil, nil, fmt.Errorf("failed to map %s: file too large", path)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to map %s: %v", path, err)
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
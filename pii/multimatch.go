@@ -0,0 +1,105 @@
+package pii
+
+import "strings"
+
+// KeywordMatcher finds whole-word occurrences of many literal keywords in a single pass over
+// the text using an Aho-Corasick automaton. Matching is case-insensitive and uses the same
+// ASCII word boundaries as the regexp package's \b.
+type KeywordMatcher struct {
+	next [][256]int32
+	fail []int32
+	// out holds the lengths of every keyword that ends at a node, including those
+	// inherited through failure links.
+	out [][]int
+}
+
+// NewKeywordMatcher builds an automaton for the supplied keywords.
+func NewKeywordMatcher(keywords []string) *KeywordMatcher {
+	m := &KeywordMatcher{}
+	m.addNode()
+	for _, kw := range keywords {
+		kw = strings.ToLower(kw)
+		if kw == "" {
+			continue
+		}
+		node := int32(0)
+		for i := 0; i < len(kw); i++ {
+			c := kw[i]
+			if m.next[node][c] == 0 {
+				m.next[node][c] = m.addNode()
+			}
+			node = m.next[node][c]
+		}
+		m.out[node] = append(m.out[node], len(kw))
+	}
+
+	// Breadth-first construction of failure links; missing transitions are filled in so
+	// that scanning never has to walk the failure chain.
+	queue := make([]int32, 0, len(m.next))
+	for c := 0; c < 256; c++ {
+		if child := m.next[0][c]; child != 0 {
+			queue = append(queue, child)
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		m.out[node] = append(m.out[node], m.out[m.fail[node]]...)
+		for c := 0; c < 256; c++ {
+			child := m.next[node][c]
+			if child == 0 {
+				m.next[node][c] = m.next[m.fail[node]][c]
+				continue
+			}
+			m.fail[child] = m.next[m.fail[node]][c]
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+func (m *KeywordMatcher) addNode() int32 {
+	m.next = append(m.next, [256]int32{})
+	m.fail = append(m.fail, 0)
+	m.out = append(m.out, nil)
+	return int32(len(m.next) - 1)
+}
+
+// MatchString reports whether any keyword occurs in s as a whole word.
+func (m *KeywordMatcher) MatchString(s string) bool {
+	lower := strings.ToLower(s)
+	node := int32(0)
+	for i := 0; i < len(lower); i++ {
+		node = m.next[node][lower[i]]
+		for _, n := range m.out[node] {
+			start, end := i+1-n, i+1
+			if (start == 0 || !isWordByte(lower[start-1])) && (end == len(lower) || !isWordByte(lower[end])) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isWordByte mirrors the ASCII definition of a word character used by \b in RE2.
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// FindAllStringIndex returns the byte ranges of every whole-word keyword occurrence in s, in
+// the order in which they end. s must not change length when lower-cased, which holds for ASCII.
+func (m *KeywordMatcher) FindAllStringIndex(s string) [][]int {
+	lower := strings.ToLower(s)
+	var locs [][]int
+	node := int32(0)
+	for i := 0; i < len(lower); i++ {
+		node = m.next[node][lower[i]]
+		for _, n := range m.out[node] {
+			start, end := i+1-n, i+1
+			if (start == 0 || !isWordByte(lower[start-1])) && (end == len(lower) || !isWordByte(lower[end])) {
+				locs = append(locs, []int{start, end})
+			}
+		}
+	}
+	return locs
+}
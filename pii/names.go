@@ -0,0 +1,58 @@
+package pii
+
+import (
+	"regexp"
+	"strings"
+)
+
+// nameRun matches one or more capitalised words joined by single spaces, e.g. "RAVI KUMAR
+// SHARMA", "Ravi K. Sharma". Two or more spaces end the run, so the columns of a two-column
+// layout are matched separately.
+const nameRun = `[A-Z][A-Za-z'.-]*(?: [A-Z][A-Za-z'.-]*)*`
+
+// namePhrasePattern finds names introduced by fixed phrases: the verification sentence ("I,
+// SURESH MENON, son/daughter of RAMESH MENON") and inline labels ("Name of the Employee: Ravi
+// Kumar"). The name is the first capture group that participates in the match.
+var namePhrasePattern = regexp.MustCompile(
+	`\bI,\s*(` + nameRun + `),` +
+		`|(?i:\b(?:son|daughter|wife|husband)(?:/(?:son|daughter|wife|husband))*\s+of)\s+(` + nameRun + `)` +
+		`|(?i:\bname\b[^:\n]*:)\s*(` + nameRun + `)`)
+
+// maxNameWords bounds the length of a personal name; longer capitalised runs are headings or
+// company names.
+const maxNameWords = 4
+
+// nameStopwords are Form 16 vocabulary words that never form part of a personal name.
+var nameStopwords = map[string]bool{
+	"name": true, "address": true, "employee": true, "employer": true, "deductor": true,
+	"deductee": true, "specified": true, "senior": true, "citizen": true, "certificate": true,
+	"form": true, "part": true, "section": true, "income": true, "tax": true, "act": true,
+	"total": true, "salary": true, "assessment": true, "year": true, "period": true,
+	"quarter": true, "commissioner": true, "pan": true, "tan": true, "gstin": true,
+	"aadhaar": true, "verification": true, "place": true, "date": true, "designation": true,
+	"reference": true, "no": true, "amount": true, "the": true, "of": true, "and": true,
+	"software": true, "solutions": true, "services": true, "technologies": true, "bank": true,
+}
+
+// validName rejects capitalised runs that are not personal names: runs longer than
+// maxNameWords, runs holding Form 16 vocabulary or amount words, and company or address
+// lines.
+func (pf *Filter) validName(match string) bool {
+	words := strings.Fields(match)
+	if len(words) == 0 || len(words) > maxNameWords {
+		return false
+	}
+	for _, w := range words {
+		lower := strings.ToLower(strings.Trim(w, ".'-"))
+		if _, ok := amountVocabulary[lower]; ok || nameStopwords[lower] {
+			return false
+		}
+	}
+	return !pf.OrganizationPattern.MatchString(match) && !pf.isAddressLine(match)
+}
+
+// validLabelledName additionally requires at least two words for names found only by their
+// position below a label, where single capitalised words are usually table headings.
+func (pf *Filter) validLabelledName(match string) bool {
+	return len(strings.Fields(match)) >= 2 && pf.validName(match)
+}
@@ -0,0 +1,109 @@
+package pii
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The name recognizer finds personal and organisation names anywhere in the text, not only
+// next to a name label: names after an honorific or a kinship abbreviation, capitalised runs
+// the gazetteer of given names and surnames takes for a personal name, and capitalised runs
+// ending in a business word. Everything else, tax terms and transliterated section names
+// included, is left in the text.
+
+// honorificNamePattern finds names after an honorific ("Shri Ramesh Menon", "Smt. Lata") or a
+// kinship abbreviation ("S/o Ramesh Menon", "W/o. Anil Kumar"). The name is the first capture
+// group that participates in the match.
+var honorificNamePattern = regexp.MustCompile(
+	`\b(?:Mr|Mrs|Ms|Miss|Shri|Sri|Shree|Smt|Shrimati|Kumari|Km|Dr|Prof|Late)\.?\s+(` + nameRun + `)` +
+		`|\b[SDWCsdwc]/[Oo]\.?\s*(` + nameRun + `)`)
+
+// organizationNamePattern matches capitalised runs ending in a word that names a business,
+// trust or bank: "Infosys Technologies", "HDFC Bank", "Tata Consultancy Services". Names with a
+// company suffix (Ltd, LLP...) are taken with their whole line by OrganizationPattern.
+var organizationNamePattern = regexp.MustCompile(`\b(?:[A-Z][A-Za-z&'.-]*\s)+(?:Technologies|Solutions|Services|Systems|Software|Infotech|Consultants|Consultancy|Enterprises|Industries|Associates|Traders|Labs|Bank|Trust|Foundation)\b`)
+
+// indianNames is the gazetteer of the IN profile: common given names and surnames across the
+// languages of India, as they are transliterated on tax documents.
+var indianNames = []string{
+	// Given names.
+	"aarav", "aarti", "abhay", "abhishek", "aditi", "aditya", "ajay", "ajit", "akash", "akhil",
+	"akshay", "alok", "aman", "amandeep", "amar", "amit", "amitabh", "amrita", "anand", "anil",
+	"anita", "anjali", "ankit", "ankita", "anuj", "anupam", "anurag", "aparna", "archana", "arjun",
+	"arti", "arun", "aruna", "arvind", "asha", "ashish", "ashok", "ashwin", "atul", "ayesha",
+	"balaji", "bhavna", "bhavesh", "chetan", "deepa", "deepak", "deepika", "dev", "dhruv",
+	"dilip", "dinesh", "divya", "farhan", "fatima", "ganesh", "gaurav", "geeta", "girish", "gita",
+	"gopal", "gurmeet", "gurpreet", "harish", "harpreet", "hemant", "imran", "irfan", "jagdish",
+	"jaspreet", "jatin", "jaya", "jayant", "jyoti", "kailash", "kalpana", "kamal", "karan",
+	"karthik", "kavita", "kavya", "kiran", "kishore", "krishna", "kunal", "lakshmi", "lalit",
+	"lata", "madhu", "madhuri", "mahendra", "mahesh", "manish", "manisha", "manju", "manoj",
+	"manpreet", "meena", "meera", "mohammad", "mohammed", "mohan", "mohd", "mukesh", "murali",
+	"nagaraj", "nandini", "naresh", "naveen", "neelam", "neha", "nikhil", "nisha", "nitin",
+	"padma", "pankaj", "pawan", "pooja", "poonam", "pradeep", "prakash", "pramod", "pranav",
+	"prashant", "preeti", "priya", "priyanka", "rahul", "rajeev", "rajendra", "rajesh", "rajiv",
+	"rakesh", "ramesh", "rashmi", "ravi", "rekha", "renu", "ritu", "rohan", "rohit", "sachin",
+	"salman", "sameer", "sandeep", "sangeeta", "sanjay", "santosh", "sapna", "sarita", "satish",
+	"savita", "seema", "shalini", "shankar", "shilpa", "shobha", "shweta", "simran", "smita",
+	"sneha", "sonia", "srinivas", "subhash", "sudhir", "suman", "sunil", "sunita", "suresh",
+	"sushil", "sushma", "swati", "tarun", "uma", "umesh", "usha", "vandana", "varsha", "varun",
+	"venkat", "vidya", "vijay", "vikas", "vikram", "vinay", "vinod", "vishal", "vivek", "yogesh",
+	"zainab",
+	// Surnames, and the name parts written between given name and surname.
+	"acharya", "agarwal", "aggarwal", "ahmed", "arora", "bajaj", "banerjee", "bansal", "bhat",
+	"bhatia", "bhatt", "bhattacharya", "bisht", "bose", "chakraborty", "chand", "chatterjee",
+	"chaudhary", "chauhan", "chopra", "choudhary", "das", "desai", "deshmukh", "deshpande", "devi",
+	"dubey", "dutta", "fernandes", "ghosh", "goel", "goyal", "grewal", "gupta", "hegde",
+	"hussain", "iyer", "iyengar", "jain", "joshi", "kamath", "kapoor", "kaur", "khan", "khanna",
+	"kohli", "krishnan", "kulkarni", "kumar", "kumari", "lal", "malhotra", "mehta", "menon",
+	"mishra", "mittal", "mukherjee", "nair", "naidu", "nath", "negi", "pandey", "patel", "patil",
+	"pillai", "prasad", "qureshi", "raghavan", "rajan", "rao", "rathore", "rawat", "reddy",
+	"saxena", "sethi", "shah", "sharma", "shetty", "shinde", "siddiqui", "singh", "sinha",
+	"srivastava", "subramanian", "thakur", "tiwari", "trivedi", "varghese", "venkatesh", "verma",
+	"yadav",
+}
+
+// nameSet returns the lower-cased names of a gazetteer as a set.
+func nameSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[strings.ToLower(n)] = true
+	}
+	return set
+}
+
+// initialPattern matches an initial of a name: "K", "K.".
+var initialPattern = regexp.MustCompile(`^[A-Z]\.?$`)
+
+// gazetteerName accepts the capitalised runs of two to maxNameWords words that pass validName
+// and whose words, initials aside, are at least half given names or surnames of the profile's
+// gazetteer: "Ravi Kumar Sharma", "K. Suresh Venkataraman", but not "Gross Total Income".
+func (pf *Filter) gazetteerName(match string) bool {
+	words := strings.Fields(match)
+	if len(words) < 2 || !pf.validName(match) {
+		return false
+	}
+	known, counted := 0, 0
+	for _, w := range words {
+		if initialPattern.MatchString(w) {
+			continue
+		}
+		counted++
+		if pf.personNames[strings.ToLower(strings.Trim(w, ".'-"))] {
+			known++
+		}
+	}
+	return known > 0 && 2*known >= counted
+}
+
+// validOrganizationName rejects organisation matches holding Form 16 vocabulary other than
+// the business word itself, such as "Income Tax Services".
+func validOrganizationName(match string) bool {
+	words := strings.Fields(match)
+	for _, w := range words[:len(words)-1] {
+		lower := strings.ToLower(strings.Trim(w, ".'-&"))
+		if _, ok := amountVocabulary[lower]; ok || nameStopwords[lower] {
+			return false
+		}
+	}
+	return true
+}
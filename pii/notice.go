@@ -0,0 +1,87 @@
+package pii
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// courierFont is the font dictionary used for all text the PDF writers draw themselves.
+const courierFont = "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>"
+
+// RedactionNotice is the cover page prepended to a redacted PDF for auditors. Policy and
+// RunID are supplied by the caller; Source, Categories and Verification are filled in while
+// the PDF is written, so the caller can record them after the call returns.
+type RedactionNotice struct {
+	Policy       string
+	RunID        string
+	Source       string
+	Categories   []string
+	Verification string
+}
+
+// complete fills in the fields derived from the document. data is the redacted PDF without
+// the cover page: its text layer is extracted again and scanned with the same filter, and
+// the result is stated on the notice.
+func (n *RedactionNotice) complete(pf *Filter, src string, pages []PageBoxes, data []byte) {
+	texts := make([]string, len(pages))
+	for i, p := range pages {
+		texts[i] = p.Text
+	}
+	n.Source = filepath.Base(src)
+	n.Categories = pf.FilterPII(strings.Join(texts, "\n")).Categories()
+	n.Verification = verifyRedactedPDF(pf, data)
+}
+
+// verifyRedactedPDF re-extracts a redacted PDF and reports whether any detector still fires.
+func verifyRedactedPDF(pf *Filter, data []byte) string {
+	doc, err := parsePDF(data, "")
+	if err != nil {
+		return fmt.Sprintf("NOT VERIFIED (%v)", err)
+	}
+	pages, err := doc.extractPages()
+	if err != nil {
+		return fmt.Sprintf("NOT VERIFIED (%v)", err)
+	}
+	if n := len(pf.FilterPII(strings.Join(pages, "\n")).Matches); n > 0 {
+		return fmt.Sprintf("FAILED (%d detector matches remain in the text layer)", n)
+	}
+	return "PASSED (no detector matches in the output text layer)"
+}
+
+// writeCoverPage adds the notice as a page of w and returns its object number.
+func (n *RedactionNotice) writeCoverPage(w *pdfWriter, parent, fontID int, width, height float64) int {
+	lines := []string{
+		"REDACTION NOTICE",
+		"",
+		"Source document: " + n.Source,
+		"Redaction policy: " + n.Policy,
+		"Run ID: " + n.RunID,
+		"Tool version: " + ToolVersion,
+		"Generated: " + time.Now().UTC().Format(time.RFC3339),
+		"Verification: " + n.Verification,
+		"",
+		"Redacted categories:",
+	}
+	if len(n.Categories) == 0 {
+		lines = append(lines, "  none")
+	}
+	for _, c := range n.Categories {
+		lines = append(lines, "  - "+c)
+	}
+
+	const size, leading, margin = 11.0, 16.0, 56.0
+	var b strings.Builder
+	fmt.Fprintf(&b, "BT /F1 %s Tf %s TL %s %s Td\n", pdfNumber(size), pdfNumber(leading), pdfNumber(margin), pdfNumber(height-margin))
+	for _, line := range lines {
+		fmt.Fprintf(&b, "(%s) Tj T*\n", winAnsiString(line))
+	}
+	b.WriteString("ET\n")
+
+	pageID, contentID := w.alloc(), w.alloc()
+	w.writeStream(contentID, "", []byte(b.String()))
+	w.writeObject(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+		parent, pdfNumber(width), pdfNumber(height), fontID, contentID))
+	return pageID
+}
@@ -0,0 +1,422 @@
+package pii
+
+import (
+	"regexp"
+	"slices"
+)
+
+// Entity type identifiers accepted by WithDisabled and WithPlaceholder.
+const (
+	EntityPhone          = "phone"
+	EntityEmail          = "email"
+	EntityAadhaar        = "aadhaar"
+	EntityPAN            = "pan"
+	EntityGST            = "gst"
+	EntityTAN            = "tan"
+	EntityPINCode        = "pin_code"
+	EntityUAN            = "uan"
+	EntityPFAccount      = "pf_account"
+	EntityPassport       = "passport"
+	EntityVoterID        = "voter_id"
+	EntityDrivingLicence = "driving_licence"
+	EntityName           = "name"
+	EntityDOB            = "dob"
+	EntityDate           = "date"
+	EntityAddress        = "address"
+	EntityOrganization   = "organization"
+	// EntityIndicName and EntityIndicAddress are the names and addresses written in an Indian
+	// script (Devanagari, Bengali, Tamil...) on bilingual certificates.
+	EntityIndicName    = "indic_name"
+	EntityIndicAddress = "indic_address"
+	// EntityBankAccount, EntityFolio and EntityChallan are only detected in the documents of
+	// their profile (see DocumentProfile): bank accounts and folios in AIS, challans in
+	// Form 26AS.
+	EntityBankAccount = "bank_account"
+	EntityFolio       = "folio"
+	EntityChallan     = "challan"
+	// EntityEmployeeID, EntityESI and EntityIFSC are only detected in payslips; bank accounts
+	// are detected in payslips too.
+	EntityEmployeeID = "employee_id"
+	EntityESI        = "esi"
+	EntityIFSC       = "ifsc"
+	// EntityPreviousEmployer is the name of a previous employer of the year, printed in Part B
+	// of a Form 16 next to the salary it paid.
+	EntityPreviousEmployer = "previous_employer"
+	// EntityDesignation is the employee's designation, printed with the name and PAN in Form
+	// 12BA.
+	EntityDesignation = "designation"
+	// EntitySSN, EntityNINO and EntityTFN are the foreign tax identifiers of the optional
+	// detector packs (see WithDetectorPacks).
+	EntitySSN  = "ssn"
+	EntityNINO = "nino"
+	EntityTFN  = "tfn"
+	// EntityCustom is the category of the denylist (see WithDenylist).
+	EntityCustom = "custom"
+	// EntityWord is the dictionary filter's category; only its placeholder can be changed.
+	EntityWord = "word"
+)
+
+// Severity ranks how damaging the exposure of an entity type would be.
+type Severity int
+
+const (
+	SeverityLow Severity = iota
+	SeverityMedium
+	SeverityHigh
+)
+
+// String returns the lower-case name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityHigh:
+		return "high"
+	case SeverityMedium:
+		return "medium"
+	}
+	return "low"
+}
+
+// defaultSeverities assigns a severity to each built-in entity type. Aadhaar and PAN are the
+// identifiers that can be used for identity theft and are masked even in review copies, as is
+// the passport number.
+var defaultSeverities = map[string]Severity{
+	EntityPhone:            SeverityMedium,
+	EntityEmail:            SeverityMedium,
+	EntityAadhaar:          SeverityHigh,
+	EntityPAN:              SeverityHigh,
+	EntityGST:              SeverityLow,
+	EntityTAN:              SeverityLow,
+	EntityPINCode:          SeverityMedium,
+	EntityUAN:              SeverityMedium,
+	EntityPFAccount:        SeverityMedium,
+	EntityPassport:         SeverityHigh,
+	EntityVoterID:          SeverityMedium,
+	EntityDrivingLicence:   SeverityMedium,
+	EntityBankAccount:      SeverityHigh,
+	EntityFolio:            SeverityMedium,
+	EntityChallan:          SeverityLow,
+	EntityEmployeeID:       SeverityMedium,
+	EntityESI:              SeverityMedium,
+	EntityIFSC:             SeverityLow,
+	EntityPreviousEmployer: SeverityLow,
+	EntityDesignation:      SeverityLow,
+	EntityName:             SeverityMedium,
+	EntityDOB:              SeverityMedium,
+	EntityDate:             SeverityLow,
+	EntityAddress:          SeverityMedium,
+	EntityOrganization:     SeverityLow,
+	EntityIndicName:        SeverityMedium,
+	EntityIndicAddress:     SeverityMedium,
+	EntitySSN:              SeverityHigh,
+	EntityNINO:             SeverityHigh,
+	EntityTFN:              SeverityHigh,
+	EntityCustom:           SeverityHigh,
+}
+
+// defaultPlaceholders maps each built-in entity type to its replacement text.
+var defaultPlaceholders = map[string]string{
+	EntityPhone:            "[PHONE_REDACTED]",
+	EntityEmail:            "[EMAIL_REDACTED]",
+	EntityAadhaar:          "[AADHAAR_REDACTED]",
+	EntityPAN:              "[PAN_REDACTED]",
+	EntityGST:              "[GST_REDACTED]",
+	EntityTAN:              "[TAN_REDACTED]",
+	EntityPINCode:          "[PIN_REDACTED]",
+	EntityUAN:              "[UAN_REDACTED]",
+	EntityPFAccount:        "[PF_ACCOUNT_REDACTED]",
+	EntityPassport:         "[PASSPORT_REDACTED]",
+	EntityVoterID:          "[VOTER_ID_REDACTED]",
+	EntityDrivingLicence:   "[DL_REDACTED]",
+	EntityBankAccount:      "[BANK_ACCOUNT_REDACTED]",
+	EntityFolio:            "[FOLIO_REDACTED]",
+	EntityChallan:          "[CHALLAN_REDACTED]",
+	EntityEmployeeID:       "[EMPLOYEE_ID_REDACTED]",
+	EntityESI:              "[ESI_REDACTED]",
+	EntityIFSC:             "[IFSC_REDACTED]",
+	EntityPreviousEmployer: "[PREV_EMPLOYER_REDACTED]",
+	EntityDesignation:      "[DESIGNATION_REDACTED]",
+	EntityName:             "[NAME_REDACTED]",
+	EntityDOB:              "[DOB_REDACTED]",
+	EntityDate:             "[DATE_REDACTED]",
+	EntityAddress:          "[ADDRESS_REDACTED]",
+	EntityOrganization:     "[ORG_REDACTED]",
+	EntityIndicName:        "[INDIC_NAME_REDACTED]",
+	EntityIndicAddress:     "[INDIC_ADDRESS_REDACTED]",
+	EntitySSN:              "[SSN_REDACTED]",
+	EntityNINO:             "[NINO_REDACTED]",
+	EntityTFN:              "[TFN_REDACTED]",
+	EntityCustom:           "[CUSTOM_REDACTED]",
+	EntityWord:             "[WORD_REDACTED]",
+}
+
+// Detector is a caller-supplied regex detector registered with WithExtraDetector. It is a
+// Recognizer named after its Type; extra detectors are appended to the registry, so built-in
+// matches win on overlap unless the registry is reordered.
+type Detector struct {
+	// Type is the stable identifier used by WithDisabled / WithPlaceholder.
+	Type string
+	// Label is the category reported in RemovedFields and MatchCounts.
+	Label       string
+	Pattern     *regexp.Regexp
+	Placeholder string
+	// Severity defaults to SeverityLow when unset.
+	Severity Severity
+}
+
+// Option configures a Filter at construction time.
+type Option func(*Filter)
+
+// WithDisabled turns off the detectors for the given entity types.
+func WithDisabled(types ...string) Option {
+	return func(pf *Filter) {
+		for _, t := range types {
+			pf.disabled[t] = true
+		}
+	}
+}
+
+// WithOnly turns off the detectors of every entity type but the given ones, e.g. to scrub
+// only PANs and Aadhaar numbers; WithDisabled still applies to the given types. The
+// denylist is the EntityCustom type, so it has to be listed to stay on.
+func WithOnly(types ...string) Option {
+	return func(pf *Filter) {
+		pf.enabled = make(map[string]bool, len(types))
+		for _, t := range types {
+			pf.enabled[t] = true
+		}
+	}
+}
+
+// isDisabled reports whether the detectors of entity are turned off by WithDisabled or
+// WithOnly.
+func (pf *Filter) isDisabled(entity string) bool {
+	return pf.disabled[entity] || (pf.enabled != nil && !pf.enabled[entity])
+}
+
+// WithPlaceholder overrides the replacement text used for an entity type.
+func WithPlaceholder(entityType, tmpl string) Option {
+	return func(pf *Filter) {
+		pf.placeholders[entityType] = tmpl
+	}
+}
+
+// defaultDetectorPlaceholder replaces the matches of detectors and recognizers that have no
+// placeholder of their own.
+const defaultDetectorPlaceholder = "[REDACTED]"
+
+// WithExtraDetector registers an additional regex detector.
+func WithExtraDetector(d Detector) Option {
+	return func(pf *Filter) {
+		if d.Placeholder == "" {
+			d.Placeholder = defaultDetectorPlaceholder
+		}
+		if d.Label == "" {
+			d.Label = d.Type
+		}
+		pf.recognizers = append(pf.recognizers, d)
+	}
+}
+
+// WithLocale translates placeholders and report headers. Placeholders set with
+// WithPlaceholder after WithLocale take precedence.
+func WithLocale(l Locale) Option {
+	return func(pf *Filter) {
+		pf.locale = &l
+		for entity, p := range l.Placeholders {
+			pf.placeholders[entity] = p
+		}
+	}
+}
+
+// WithStrictValidation makes detectors reject matches that fail structural checks (e.g. the
+// Aadhaar Verhoeff checksum) instead of redacting every pattern match. It is also the strict
+// anonymisation policy for PDF output: raster redacted PDFs lose the letterhead images.
+func WithStrictValidation(strict bool) Option {
+	return func(pf *Filter) {
+		pf.strict = strict
+	}
+}
+
+// WithPANContext requires a PAN label ("PAN", "Permanent Account Number") on the line or one of
+// the few lines above it before a PAN-shaped token is redacted.
+func WithPANContext(required bool) Option {
+	return func(pf *Filter) {
+		pf.panContext = required
+	}
+}
+
+// WithMinConfidence leaves findings scored below min (0 to 1) in the text. Whole-line address
+// and organisation checks score fixed values, so a high minimum turns them off.
+func WithMinConfidence(min float64) Option {
+	return func(pf *Filter) {
+		pf.minConfidence = min
+	}
+}
+
+// WithGSTPolicy selects whether GSTINs are redacted, masked or retained.
+func WithGSTPolicy(policy GSTPolicy) Option {
+	return func(pf *Filter) {
+		pf.GSTPolicy = policy
+	}
+}
+
+// WithAllowlist adds values that are never redacted, such as the employer's own TAN or GSTIN
+// that must stay visible for reconciliation. A match exactly equal to a value, or a whole
+// address or organisation line equal to one once trimmed, is left in the text and listed under
+// RetainedFields["Allowlisted"].
+func WithAllowlist(values ...string) Option {
+	return func(pf *Filter) {
+		if pf.allowlist == nil {
+			pf.allowlist = make(map[string]bool)
+		}
+		for _, v := range values {
+			pf.allowlist[v] = true
+		}
+		pf.allowlistPattern = allowlistPattern(pf.allowlist)
+	}
+}
+
+// WithPhoneRegions sets the regions whose numbers written in national format are detected,
+// by code (see PhoneRegionNames); India's are only detected when "IN" is listed. Numbers
+// written in international format (+44, +1...) are detected for every supported region.
+func WithPhoneRegions(regions ...string) Option {
+	return func(pf *Filter) {
+		pf.PhoneRegions = regions
+		pf.foreignPhonePattern = foreignPhonePattern(regions)
+	}
+}
+
+// WithEmailPolicy selects whether the domains of e-mail addresses are redacted, kept or
+// mapped to organisation tokens.
+func WithEmailPolicy(policy EmailPolicy) Option {
+	return func(pf *Filter) {
+		pf.EmailPolicy = policy
+		if policy == EmailOrgToken && pf.domainTokens == nil {
+			pf.domainTokens = NewVault()
+		}
+	}
+}
+
+// WithAmountWords selects how amounts written in words are treated.
+func WithAmountWords(policy AmountWordsPolicy) Option {
+	return func(pf *Filter) {
+		pf.AmountWords = policy
+	}
+}
+
+// WithDatePolicy selects which date categories are redacted.
+func WithDatePolicy(policy DatePolicy) Option {
+	return func(pf *Filter) {
+		pf.Dates = policy
+	}
+}
+
+// WithHeaderFooterPolicy selects how lines repeated at the top or bottom of every page are
+// treated.
+func WithHeaderFooterPolicy(policy HeaderFooterPolicy) Option {
+	return func(pf *Filter) {
+		pf.HeaderFooters = policy
+	}
+}
+
+// WithAddressKeywords replaces the built-in address keywords ("House", "Road", "Near"...)
+// with the given list.
+func WithAddressKeywords(keywords ...string) Option {
+	return func(pf *Filter) {
+		pf.addressKeywords = keywords
+		pf.AddressKeywordPattern = keywordPattern(keywords)
+		if pf.addressMatcher != nil {
+			pf.UseMultiPatternEngine()
+		}
+	}
+}
+
+// WithMatchValues selects whether FilteredData.Matches carry the original values.
+func WithMatchValues(policy MatchValuePolicy) Option {
+	return func(pf *Filter) {
+		pf.MatchValues = policy
+	}
+}
+
+// WithSuppressions leaves the false positives marked in store in the text, listed under
+// "Suppressed False Positives" in the retained fields, and gives every match the Fingerprint
+// to mark it by. Suppressions added to store later apply to the filter as well.
+func WithSuppressions(store *SuppressionStore) Option {
+	return func(pf *Filter) {
+		pf.suppressions = store
+	}
+}
+
+// WithExplain attaches an Explanation to every match and finding: the detector, the rule
+// that fired and the settings behind it, for false-positive triage.
+func WithExplain(explain bool) Option {
+	return func(pf *Filter) {
+		pf.explain = explain
+	}
+}
+
+// WithTimings records the time each detector takes on a document in FilteredData.Timings,
+// for profiling slow documents.
+func WithTimings(timings bool) Option {
+	return func(pf *Filter) {
+		pf.timings = timings
+	}
+}
+
+// WithVolumeRules adds expected finding ranges. A rule replaces any earlier rule for the same
+// document type and entity; a rule without Min and Max switches that check off.
+func WithVolumeRules(rules ...VolumeRule) Option {
+	return func(pf *Filter) {
+		for _, r := range rules {
+			pf.volumeRules = slices.DeleteFunc(pf.volumeRules, func(old VolumeRule) bool {
+				return old.DocumentType == r.DocumentType && old.Entity == r.Entity
+			})
+			if r.Min != nil || r.Max != nil {
+				pf.volumeRules = append(pf.volumeRules, r)
+			}
+		}
+	}
+}
+
+// WithHandwritingDetector replaces the StrokeDetector heuristic used to find handwritten
+// regions on rendered pages, e.g. with a trained model.
+func WithHandwritingDetector(d HandwritingDetector) Option {
+	return func(pf *Filter) {
+		pf.handwriting = d
+	}
+}
+
+// WithVault switches the filter to pseudonymisation: every redacted value is replaced by a
+// stable token from v, e.g. [PAN_1], instead of its placeholder, and the original can be
+// restored with Vault.Detokenize. Retained GSTINs and dictionary words are not tokenised.
+func WithVault(v *Vault) Option {
+	return func(pf *Filter) {
+		pf.vault = v
+	}
+}
+
+// WithNumberedPlaceholders replaces each redacted value with a token numbered per document
+// instead of its placeholder: every distinct PAN becomes [PAN_1], [PAN_2]... in order of first
+// appearance, so that the employee's and the employer's PAN can still be told apart after
+// redaction. Unlike WithVault the originals are not kept, and each document is numbered from 1.
+// Under WithVault the vault's tokens are used.
+func WithNumberedPlaceholders(numbered bool) Option {
+	return func(pf *Filter) {
+		pf.numbered = numbered
+	}
+}
+
+// WithMultiPatternEngine enables the single-pass keyword matcher for address detection.
+func WithMultiPatternEngine() Option {
+	return func(pf *Filter) {
+		pf.UseMultiPatternEngine()
+	}
+}
+
+// placeholderFor returns the configured replacement text for an entity type.
+func (pf *Filter) placeholderFor(entityType string) string {
+	if p, ok := pf.placeholders[entityType]; ok {
+		return p
+	}
+	return defaultPlaceholders[entityType]
+}
@@ -0,0 +1,129 @@
+package pii
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OutputWriter writes a processed document in one output format.
+type OutputWriter func(data FilteredData, outputFile string) error
+
+// outputWriters maps every -format name to its writer.
+var outputWriters = map[string]OutputWriter{
+	"txt":    SaveFilteredData,
+	"json":   SaveFilteredDataJSON,
+	"blocks": SaveBlockModel,
+	"html":   SaveHTMLReport,
+}
+
+// formatExtensions overrides the file extension of formats that are not named after it.
+var formatExtensions = map[string]string{
+	"blocks": "blocks.json",
+}
+
+// FormatNames lists the registered output formats in name order.
+func FormatNames() []string {
+	names := make([]string, 0, len(outputWriters))
+	for name := range outputWriters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// outputPathFor returns the file a format is written to. With a single format the configured
+// output name is used unchanged; with several, the extension is swapped per format
+// (filtered_output.txt -> filtered_output.json, filtered_output.blocks.json).
+func outputPathFor(base, format string, formats []string) string {
+	if len(formats) <= 1 {
+		return base
+	}
+	ext := format
+	if e, ok := formatExtensions[format]; ok {
+		ext = e
+	}
+	return strings.TrimSuffix(base, filepath.Ext(base)) + "." + ext
+}
+
+// OutputPaths returns the files WriteOutputs writes for base, one per format.
+func OutputPaths(base string, formats []string) []string {
+	paths := make([]string, len(formats))
+	for i, format := range formats {
+		paths[i] = outputPathFor(base, format, formats)
+	}
+	return paths
+}
+
+// WriteOutputs feeds the result of a single pipeline pass to every requested writer and
+// returns the files written.
+func WriteOutputs(data FilteredData, base string, formats []string) ([]string, error) {
+	return writeOutputs(data, base, formats, nil)
+}
+
+// writeOutputs is WriteOutputs with the writers of the formats found in writers replaced.
+func writeOutputs(data FilteredData, base string, formats []string, writers map[string]OutputWriter) ([]string, error) {
+	written := make([]string, 0, len(formats))
+	for _, format := range formats {
+		write, ok := writers[format]
+		if !ok {
+			if write, ok = outputWriters[format]; !ok {
+				return written, fmt.Errorf("unknown output format %q", format)
+			}
+		}
+		path := outputPathFor(base, format, formats)
+		if err := write(data, path); err != nil {
+			return written, fmt.Errorf("error writing %s output: %v", format, err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// ReportVersion is the layout version of the JSON report. It is raised when a field is
+// renamed or removed or changes meaning; adding a field does not raise it.
+const ReportVersion = 1
+
+// Report is the JSON report of one document: the fields of FilteredData and the layout version
+// they were written in.
+type Report struct {
+	Version int `json:"report_version"`
+	FilteredData
+}
+
+// NewReport wraps data in a Report of the current layout version.
+func NewReport(data FilteredData) Report {
+	return Report{Version: ReportVersion, FilteredData: data}
+}
+
+// ParseReport decodes a JSON report. Reports written before the layout was versioned carry no
+// version and are read as version 1; reports of a newer version than this package knows are
+// rejected with ErrUnsupportedFormat.
+func ParseReport(b []byte) (*Report, error) {
+	var r Report
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse report: %v", err)
+	}
+	if r.Version == 0 {
+		r.Version = 1
+	}
+	if r.Version > ReportVersion {
+		return nil, fmt.Errorf("%w: report version %d is newer than %d", ErrUnsupportedFormat, r.Version, ReportVersion)
+	}
+	return &r, nil
+}
+
+// SaveFilteredDataJSON writes the filtered data as an indented JSON Report for downstream
+// pipelines.
+func SaveFilteredDataJSON(data FilteredData, outputFile string) error {
+	b, err := json.MarshalIndent(NewReport(data), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode filtered data: %v", err)
+	}
+	if err := WriteOutput(outputFile, append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package pii
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// FilterPages filters every page of a document on its own and reassembles the results as
+// FilterPII would report them for pages.Text(): cleaned pages are joined with form feeds and
+// matches are rebased onto the joined text. Pages are handed to a pool of workers goroutines
+// (one per CPU when workers <= 0), so large multi-page bundles are not redacted on a single
+// core. Context labels are only looked up within a page; repeated headers and footers, the
+// volume checks and the block model are handled once over the whole document. A panic on a
+// worker is returned as a *PanicError holding the stack of that worker, for the first page
+// that panicked.
+func (pf *Filter) FilterPages(pages Pages, workers int) (FilteredData, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	// Numbered tokens are issued by all workers from the vault of one document copy.
+	text := pages.Text()
+	doc := pf.forDocument(text)
+	results := make([]FilteredData, len(pages))
+	errs := make([]error, len(pages))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(pages)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = doc.filterPage(pages[i])
+			}
+		}()
+	}
+	for i := range pages {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return FilteredData{}, err
+		}
+	}
+
+	merged := doc.newFilteredData(text)
+	retained := make(map[string][]string)
+	cleaned := make([]string, len(results))
+	offset, line := 0, 0
+	for i, r := range results {
+		for _, m := range r.Matches {
+			m.Start += offset
+			m.End += offset
+			m.Line += line
+			merged.Matches = append(merged.Matches, m)
+		}
+		for label, n := range r.MatchCounts {
+			merged.MatchCounts[label] += n
+		}
+		for label, samples := range r.SampleMasks {
+			for _, sample := range samples {
+				if len(merged.SampleMasks[label]) < maxSamplesPerType {
+					merged.SampleMasks[label] = append(merged.SampleMasks[label], sample)
+				}
+			}
+		}
+		for key, values := range r.RetainedFields {
+			retained[key] = append(retained[key], values...)
+		}
+		for _, w := range r.Warnings {
+			if w.Line > 0 {
+				w.Line += line
+			}
+			merged.Warnings = append(merged.Warnings, w)
+		}
+		cleaned[i] = r.CleanedText
+		offset += len(pages[i]) + len("\f")
+		line += strings.Count(pages[i], "\n")
+	}
+	merged.CleanedText = strings.Join(cleaned, "\f")
+	for key, values := range retained {
+		merged.RetainedFields[key] = uniqueSorted(values)
+	}
+	merged.RemovedFields = removedFields(doc.tokenDetectors(), merged.MatchCounts)
+	merged.Timings = mergeTimings(results)
+	doc.finish(text, &merged)
+	return merged, nil
+}
+
+// filterPage runs filterText on one page. A panic is returned as a *PanicError captured on
+// the worker, while its stack still shows the crash site.
+func (pf *Filter) filterPage(page string) (result FilteredData, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r)
+		}
+	}()
+	return pf.filterText(page, nil), nil
+}
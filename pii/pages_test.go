@@ -0,0 +1,103 @@
+package pii
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testPages returns a Form 16 of n pages, each with identifiers and an amount in words that
+// disagrees with its figure, so that every page has matches and a warning.
+func testPages(n int) Pages {
+	pages := make(Pages, n)
+	for i := range pages {
+		var b strings.Builder
+		fmt.Fprintf(&b, "FORM NO. 16 - page %d\n", i+1)
+		b.WriteString("PAN of the Employee: ABCPE1234F\n")
+		b.WriteString("Mobile: +91 98765 43210\n")
+		for k := 0; k < 15; k++ {
+			fmt.Fprintf(&b, "%d. Salary component %d\n", k+1, k+1)
+		}
+		b.WriteString("Gross Salary 1,20,000.00\n")
+		b.WriteString("Rupees Twelve Lakh Only\n")
+		pages[i] = b.String()
+	}
+	return pages
+}
+
+func TestFilterPagesMatchesSinglePass(t *testing.T) {
+	redact := func(workers int) FilteredData {
+		p := &Pipeline{Filter: NewFilter(), Workers: workers}
+		result, err := p.Redact(context.Background(), Extraction{Pages: testPages(3)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Data
+	}
+	single, pooled := redact(1), redact(4)
+	var lines []int
+	for _, w := range single.Warnings {
+		if w.Code == WarnAmountMismatch {
+			lines = append(lines, w.Line)
+		}
+	}
+	if want := []int{20, 40, 60}; !reflect.DeepEqual(lines, want) {
+		t.Fatalf("amount mismatches on lines %v, want %v", lines, want)
+	}
+	if single.CleanedText != pooled.CleanedText {
+		t.Errorf("cleaned text differs:\n%q\n%q", single.CleanedText, pooled.CleanedText)
+	}
+	if !reflect.DeepEqual(single.Matches, pooled.Matches) {
+		t.Errorf("matches differ:\n%+v\n%+v", single.Matches, pooled.Matches)
+	}
+	if !reflect.DeepEqual(single.Warnings, pooled.Warnings) {
+		t.Errorf("warnings differ:\n%+v\n%+v", single.Warnings, pooled.Warnings)
+	}
+	if !reflect.DeepEqual(single.MatchCounts, pooled.MatchCounts) {
+		t.Errorf("match counts differ: %v, %v", single.MatchCounts, pooled.MatchCounts)
+	}
+}
+
+// crashingRecognizer panics in Find, at one of two sites.
+type crashingRecognizer struct{ site int }
+
+func (r crashingRecognizer) Name() string { return "crash" }
+
+func (r crashingRecognizer) Find(text string) []Match {
+	if r.site == 1 {
+		crashSiteOne()
+	}
+	crashSiteTwo()
+	return nil
+}
+
+func crashSiteOne() { panic("site one") }
+
+func crashSiteTwo() { panic("site two") }
+
+// A panic on a worker is returned with the stack of that worker, so different crash sites
+// give different stack hashes and the same site the same one.
+func TestFilterPagesPanicStack(t *testing.T) {
+	crash := func(site int) *PanicError {
+		pf := NewFilter(WithRecognizer(crashingRecognizer{site: site}))
+		_, err := pf.FilterPages(testPages(3), 2)
+		var pe *PanicError
+		if !errors.As(err, &pe) {
+			t.Fatalf("FilterPages error = %v, want a *PanicError", err)
+		}
+		return pe
+	}
+	one, two, again := crash(1), crash(2), crash(1)
+	if !strings.Contains(one.Stack, "crashSiteOne") {
+		t.Errorf("stack does not show the crash site:\n%s", one.Stack)
+	}
+	if one.StackHash == two.StackHash {
+		t.Errorf("two crash sites share the stack hash %s", one.StackHash)
+	}
+	if one.StackHash != again.StackHash {
+		t.Errorf("one crash site hashes to %s and %s", one.StackHash, again.StackHash)
+	}
+}
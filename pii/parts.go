@@ -0,0 +1,80 @@
+package pii
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Form 16 parts, in the order they are written.
+const (
+	PartA     = "part_a"
+	PartB     = "part_b"
+	Annexures = "annexures"
+)
+
+var (
+	partBPattern    = regexp.MustCompile(`(?i)^\s*PART\s*[-–]?\s*B\b`)
+	annexurePattern = regexp.MustCompile(`(?i)^\s*ANNEXURE\b`)
+)
+
+// Part is the text of one kind of Form 16 section. Sections of the same kind are
+// concatenated, so a multi-employer bundle yields one Part A holding every certificate's
+// Part A.
+type Part struct {
+	Name string
+	Text string
+}
+
+// SplitParts classifies every line as Part A, Part B or annexure. A section starts at its
+// "PART A", "PART B" or "ANNEXURE" heading, or at the form title just above it; text before
+// the first heading belongs to Part A. "PART B (Annexure)" is Part B. Only parts with content
+// are returned, in the order Part A, Part B, annexures.
+func SplitParts(text string) []Part {
+	lines := strings.Split(text, "\n")
+	kinds := make([]string, len(lines))
+	current := PartA
+	for i, line := range lines {
+		kind := ""
+		switch {
+		case partAPattern.MatchString(line):
+			kind = PartA
+		case partBPattern.MatchString(line):
+			kind = PartB
+		case annexurePattern.MatchString(line):
+			kind = Annexures
+		}
+		if kind != "" {
+			current = kind
+			// Pull the form title and its sub-heading lines into the new section.
+			for k := i - 1; k >= 0 && k >= i-partAWindow; k-- {
+				if formHeaderPattern.MatchString(lines[k]) {
+					for ; k < i; k++ {
+						kinds[k] = kind
+					}
+					break
+				}
+			}
+		}
+		kinds[i] = current
+	}
+
+	sections := make(map[string][]string)
+	for i, line := range lines {
+		sections[kinds[i]] = append(sections[kinds[i]], line)
+	}
+	var parts []Part
+	for _, name := range []string{PartA, PartB, Annexures} {
+		if body := strings.Join(sections[name], "\n"); strings.TrimSpace(body) != "" {
+			parts = append(parts, Part{Name: name, Text: body})
+		}
+	}
+	return parts
+}
+
+// PartPath returns the output name of one part: filtered_output.txt -> filtered_output_part_b.txt.
+func PartPath(path, part string) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s_%s%s", strings.TrimSuffix(path, ext), part, ext)
+}
@@ -0,0 +1,69 @@
+package pii
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RetainedFields keys of the structured payslip fields. Figures are copied as printed.
+const (
+	FieldPayPeriod       = "Pay Period"
+	FieldGrossEarnings   = "Gross Earnings"
+	FieldTotalDeductions = "Total Deductions"
+	FieldNetPay          = "Net Pay"
+)
+
+var (
+	payslipTitlePattern = regexp.MustCompile(`(?i)^\s*(?:Pay\s*slip|Salary\s+Slip|Pay\s+Statement)\b`)
+	// payPeriodPattern captures the month of a payslip: "Payslip for the month of March 2024",
+	// "Pay Period: Mar-2024".
+	payPeriodPattern = regexp.MustCompile(`(?i)\b(?:for\s+(?:the\s+)?month\s+of|Pay\s+Period|Salary\s+Month)\s*[:\-]?\s*([A-Za-z]{3,9}[\s,\-']*(?:19|20)?\d{2})\b`)
+	// The labels of the payslip totals; their figure is the first one after the label, as
+	// earnings and deductions are often printed side by side.
+	grossEarningsLabel   = regexp.MustCompile(`(?i)\b(?:Gross\s+(?:Earnings|Pay|Salary)|Total\s+Earnings)\b`)
+	totalDeductionsLabel = regexp.MustCompile(`(?i)\bTotal\s+Deductions?\b`)
+	netPayLabel          = regexp.MustCompile(`(?i)\bNet\s+(?:Pay|Salary|Amount\s+Payable)\b`)
+	// payslipAmountPattern matches payslip figures, which are often printed without paise
+	// (50,000) but always with digit grouping or paise, unlike years.
+	payslipAmountPattern = regexp.MustCompile(`\b\d{1,3}(?:,\d{2,3})+(?:\.\d{2})?\b|\b\d+\.\d{2}\b`)
+
+	// managerLabelPattern marks the lines naming the employee's manager or approver; the
+	// name itself is found by NamePattern. A bare "Manager" is a designation, not a label.
+	managerLabelPattern = regexp.MustCompile(`(?i)\bReporting\s+(?:Manager|Officer|To)\b|\bManager\s*(?:Name)?\s*:|\bSupervisor\b|\b(?:Approved|Authori[sz]ed)\s+by\b`)
+	// Labels of the payslip identifiers, which are matched together with their value.
+	employeeIDLabelPattern = regexp.MustCompile(`(?i)\bEmp(?:loyee)?\.?\s*(?:Code|ID|No\b\.?|Number)|\bStaff\s+(?:ID|No\b\.?)`)
+	esiLabelPattern        = regexp.MustCompile(`(?i)\bESIC?\b`)
+	ifscLabelPattern       = regexp.MustCompile(`(?i)\bIFSC?\b`)
+)
+
+// validManagerName is validLabelledName for the names next to a manager label, which must not
+// be the label itself ("Reporting Manager").
+func (pf *Filter) validManagerName(match string) bool {
+	return !managerLabelPattern.MatchString(match) && pf.validLabelledName(match)
+}
+
+// payslipFields parses the business figures of a payslip: the pay month and the gross
+// earnings, total deductions and net pay. The earnings and deduction heads themselves are
+// left in the text.
+func payslipFields(text string) map[string][]string {
+	fields := make(map[string][]string)
+	for _, line := range strings.Split(text, "\n") {
+		if m := payPeriodPattern.FindStringSubmatch(line); m != nil {
+			fields[FieldPayPeriod] = append(fields[FieldPayPeriod], m[1])
+		}
+		for key, label := range map[string]*regexp.Regexp{
+			FieldGrossEarnings:   grossEarningsLabel,
+			FieldTotalDeductions: totalDeductionsLabel,
+			FieldNetPay:          netPayLabel,
+		} {
+			loc := label.FindStringIndex(line)
+			if loc == nil {
+				continue
+			}
+			if figure := payslipAmountPattern.FindString(line[loc[1]:]); figure != "" {
+				fields[key] = append(fields[key], figure)
+			}
+		}
+	}
+	return fields
+}
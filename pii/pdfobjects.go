@@ -0,0 +1,618 @@
+package pii
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// The types below model the PDF object graph used by the native text extractor. Numbers are
+// float64, booleans are bool and null is nil.
+type (
+	pdfName    string
+	pdfString  []byte
+	pdfArray   []interface{}
+	pdfDict    map[pdfName]interface{}
+	pdfKeyword string
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// pdfLexer tokenises PDF object syntax and content streams.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+// errEndOfData is returned by the lexer when no further tokens are available.
+var errEndOfData = fmt.Errorf("unexpected end of PDF data")
+
+func isPDFWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFWhitespace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// token returns the next primitive token. Arrays and dictionaries are returned as the
+// delimiter keywords "[", "]", "<<" and ">>" and assembled by object().
+func (l *pdfLexer) token() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errEndOfData
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		return pdfName(decodeNameEscapes(l.data[start:l.pos])), nil
+	case c == '(':
+		return l.literalString()
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfKeyword("<<"), nil
+		}
+		return l.hexString()
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>"), nil
+		}
+		l.pos++
+		return pdfKeyword(">"), nil
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		// Stray delimiter such as ')'; skip it.
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	}
+	word := string(l.data[start:l.pos])
+	if (word[0] >= '0' && word[0] <= '9') || word[0] == '-' || word[0] == '+' || word[0] == '.' {
+		if f, err := strconv.ParseFloat(word, 64); err == nil {
+			return f, nil
+		}
+	}
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+func decodeNameEscapes(b []byte) string {
+	if !bytes.Contains(b, []byte("#")) {
+		return string(b)
+	}
+	var out []byte
+	for i := 0; i < len(b); i++ {
+		if b[i] == '#' && i+2 < len(b) {
+			if v, err := strconv.ParseUint(string(b[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
+
+func (l *pdfLexer) literalString() (interface{}, error) {
+	l.pos++ // opening '('
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(out), nil
+			}
+			out = append(out, c)
+		case '\\':
+			if l.pos >= len(l.data) {
+				return pdfString(out), nil
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for n := 0; n < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; n++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return pdfString(out), nil
+}
+
+func (l *pdfLexer) hexString() (interface{}, error) {
+	l.pos++ // opening '<'
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		c := l.data[l.pos]
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // closing '>'
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	hex.Decode(out, digits)
+	return pdfString(out), nil
+}
+
+// object parses a complete object, assembling arrays, dictionaries and indirect references.
+func (l *pdfLexer) object() (interface{}, error) {
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case pdfKeyword:
+		switch t {
+		case "[":
+			arr := pdfArray{}
+			for {
+				l.skipSpace()
+				if l.pos < len(l.data) && l.data[l.pos] == ']' {
+					l.pos++
+					return arr, nil
+				}
+				item, err := l.object()
+				if err != nil {
+					return arr, err
+				}
+				arr = append(arr, item)
+			}
+		case "<<":
+			dict := pdfDict{}
+			for {
+				key, err := l.token()
+				if err != nil {
+					return dict, err
+				}
+				if key == pdfKeyword(">>") {
+					return dict, nil
+				}
+				name, ok := key.(pdfName)
+				if !ok {
+					continue
+				}
+				value, err := l.object()
+				if err != nil {
+					return dict, err
+				}
+				dict[name] = value
+			}
+		}
+		return t, nil
+	case float64:
+		// Look ahead for "gen R" to form an indirect reference.
+		save := l.pos
+		if gen, err := l.token(); err == nil {
+			if g, ok := gen.(float64); ok {
+				if r, err := l.token(); err == nil && r == pdfKeyword("R") {
+					return pdfRef{num: int(t), gen: int(g)}, nil
+				}
+			}
+		}
+		l.pos = save
+		return t, nil
+	}
+	return tok, nil
+}
+
+// Limits on decompressed stream data, so that a small file of highly compressed streams (a
+// decompression bomb) cannot exhaust memory: each Flate stream, and all of them together in a
+// document. Text content stays far below either.
+const (
+	maxInflatedStream   = 64 << 20
+	maxInflatedDocument = 256 << 20
+)
+
+// pdfDocument is a loaded PDF file with its objects indexed by object number.
+type pdfDocument struct {
+	objects map[int]interface{}
+	trailer pdfDict
+	// inflated counts the bytes decompressed so far, against maxInflatedDocument.
+	inflated int64
+}
+
+var objHeaderPattern = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// loadPDF reads and indexes a PDF file, decrypting it with password when it is encrypted.
+func loadPDF(filename, password string) (*pdfDocument, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parsePDF(data, password)
+}
+
+// parsePDF indexes every "N G obj" definition by scanning the file rather than trusting the
+// cross-reference table, which makes it tolerant of damaged or incrementally updated files.
+// Later definitions of the same object number win, as they do for incremental updates. An
+// encrypted file is decrypted with password, which may be the user or the owner password; many
+// files only restrict permissions and open with an empty one. Otherwise ErrEncrypted is returned.
+func parsePDF(data []byte, password string) (*pdfDocument, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \r\n\t"), []byte("%PDF-")) {
+		return nil, fmt.Errorf("%w: not a PDF file", ErrUnsupportedFormat)
+	}
+	doc := &pdfDocument{objects: make(map[int]interface{}), trailer: pdfDict{}}
+	var objStreams []*pdfStream
+	gens := make(map[int]int)
+	lastEnd := 0
+	for _, m := range objHeaderPattern.FindAllSubmatchIndex(data, -1) {
+		if m[0] < lastEnd {
+			continue // inside the previous object's stream data
+		}
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		gens[num], _ = strconv.Atoi(string(data[m[4]:m[5]]))
+		l := &pdfLexer{data: data, pos: m[1]}
+		obj, err := l.object()
+		if err != nil {
+			continue
+		}
+		if dict, ok := obj.(pdfDict); ok {
+			save := l.pos
+			l.skipSpace()
+			if bytes.HasPrefix(data[l.pos:], []byte("stream")) {
+				stream := readStreamData(data, l.pos+len("stream"), dict)
+				l.pos = stream.end
+				switch dict["Type"] {
+				case pdfName("ObjStm"):
+					objStreams = append(objStreams, &stream.pdfStream)
+				case pdfName("XRef"):
+					mergeTrailer(doc.trailer, dict)
+				}
+				obj = &stream.pdfStream
+			} else {
+				l.pos = save
+			}
+		}
+		doc.objects[num] = obj
+		lastEnd = l.pos
+	}
+
+	for _, m := range regexp.MustCompile(`trailer\s*<<`).FindAllIndex(data, -1) {
+		l := &pdfLexer{data: data, pos: m[0] + len("trailer")}
+		if obj, err := l.object(); err == nil {
+			if dict, ok := obj.(pdfDict); ok {
+				mergeTrailer(doc.trailer, dict)
+			}
+		}
+	}
+
+	if _, encrypted := doc.trailer["Encrypt"]; encrypted {
+		if err := doc.decrypt(gens, password); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range objStreams {
+		doc.loadObjectStream(s)
+	}
+	if len(doc.objects) == 0 {
+		return nil, fmt.Errorf("no PDF objects found")
+	}
+	return doc, nil
+}
+
+func mergeTrailer(trailer, dict pdfDict) {
+	for _, key := range []pdfName{"Root", "Encrypt", "Info", "ID"} {
+		if v, ok := dict[key]; ok {
+			trailer[key] = v
+		}
+	}
+}
+
+type streamWithEnd struct {
+	pdfStream
+	end int
+}
+
+// readStreamData extracts the raw bytes following the "stream" keyword. The /Length entry is
+// used when it is direct and consistent; otherwise the data runs up to "endstream".
+func readStreamData(data []byte, pos int, dict pdfDict) streamWithEnd {
+	if pos < len(data) && data[pos] == '\r' {
+		pos++
+	}
+	if pos < len(data) && data[pos] == '\n' {
+		pos++
+	}
+	if n, ok := dict["Length"].(float64); ok {
+		end := pos + int(n)
+		if end <= len(data) && end >= pos {
+			rest := bytes.TrimLeft(data[end:min(len(data), end+32)], " \r\n\t")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				return streamWithEnd{pdfStream{dict: dict, raw: data[pos:end]}, end}
+			}
+		}
+	}
+	idx := bytes.Index(data[pos:], []byte("endstream"))
+	if idx < 0 {
+		return streamWithEnd{pdfStream{dict: dict, raw: data[pos:]}, len(data)}
+	}
+	raw := bytes.TrimRight(data[pos:pos+idx], "\r\n")
+	return streamWithEnd{pdfStream{dict: dict, raw: raw}, pos + idx}
+}
+
+// loadObjectStream adds the objects compressed inside an /ObjStm stream. Objects that were
+// already defined directly in the file take precedence.
+func (d *pdfDocument) loadObjectStream(s *pdfStream) {
+	data, err := d.decodeStream(s)
+	if err != nil {
+		return
+	}
+	n, _ := d.resolve(s.dict["N"]).(float64)
+	first, _ := d.resolve(s.dict["First"]).(float64)
+	header := &pdfLexer{data: data}
+	for i := 0; i < int(n); i++ {
+		numTok, err1 := header.token()
+		offTok, err2 := header.token()
+		num, ok1 := numTok.(float64)
+		off, ok2 := offTok.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return
+		}
+		if _, exists := d.objects[int(num)]; exists {
+			continue
+		}
+		pos := int(first) + int(off)
+		if pos < 0 || pos >= len(data) {
+			continue
+		}
+		body := &pdfLexer{data: data, pos: pos}
+		if obj, err := body.object(); err == nil {
+			d.objects[int(num)] = obj
+		}
+	}
+}
+
+// resolve follows indirect references to the referenced object.
+func (d *pdfDocument) resolve(obj interface{}) interface{} {
+	for depth := 0; depth < 32; depth++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = d.objects[ref.num]
+	}
+	return nil
+}
+
+func (d *pdfDocument) dict(obj interface{}) pdfDict {
+	switch v := d.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// decodeStream applies the stream's filters and returns the decoded bytes.
+func (d *pdfDocument) decodeStream(s *pdfStream) ([]byte, error) {
+	data := s.raw
+	var filters []interface{}
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []interface{}{f}
+	case pdfArray:
+		filters = f
+	}
+	for _, f := range filters {
+		name, _ := d.resolve(f).(pdfName)
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data, min(maxInflatedStream, maxInflatedDocument-d.inflated))
+			d.inflated += int64(len(data))
+		case "ASCIIHexDecode", "AHx":
+			data, err = asciiHexDecode(data)
+		case "ASCII85Decode", "A85":
+			data, err = ascii85Decode(data)
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib data, keeping whatever could be recovered from truncated streams.
+// Data that decompresses to more than limit bytes fails without being kept.
+func inflate(data []byte, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("decompressed streams exceed %d bytes", maxInflatedDocument)
+	}
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if int64(len(out)) > limit {
+		return nil, fmt.Errorf("stream decompresses to more than %d bytes", limit)
+	}
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func asciiHexDecode(data []byte) ([]byte, error) {
+	var digits []byte
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if !isPDFWhitespace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	_, err := hex.Decode(out, digits)
+	return out, err
+}
+
+func ascii85Decode(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out := make([]byte, len(data)*4/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
+
+// pages returns the page dictionaries in document order. Inheritable attributes such as
+// /Resources are copied down from ancestor /Pages nodes.
+func (d *pdfDocument) pages() []pdfDict {
+	root := d.dict(d.trailer["Root"])
+	if root == nil {
+		return nil
+	}
+	var out []pdfDict
+	seen := make(map[interface{}]bool)
+	var walk func(node interface{}, inherited pdfDict, depth int)
+	walk = func(node interface{}, inherited pdfDict, depth int) {
+		if depth > 64 {
+			return
+		}
+		if ref, ok := node.(pdfRef); ok {
+			if seen[ref] {
+				return
+			}
+			seen[ref] = true
+		}
+		dict := d.dict(node)
+		if dict == nil {
+			return
+		}
+		merged := pdfDict{}
+		for k, v := range inherited {
+			merged[k] = v
+		}
+		for _, key := range []pdfName{"Resources", "MediaBox", "CropBox", "Rotate"} {
+			if v, ok := dict[key]; ok {
+				merged[key] = v
+			}
+		}
+		kids, isTree := d.resolve(dict["Kids"]).(pdfArray)
+		if !isTree || dict["Type"] == pdfName("Page") {
+			page := pdfDict{}
+			for k, v := range dict {
+				page[k] = v
+			}
+			for k, v := range merged {
+				page[k] = v
+			}
+			out = append(out, page)
+			return
+		}
+		for _, kid := range kids {
+			walk(kid, merged, depth+1)
+		}
+	}
+	walk(root["Pages"], pdfDict{}, 0)
+	return out
+}
+
+// mediaBox returns the page boundaries, defaulting to US Letter when the box is missing.
+func (d *pdfDocument) mediaBox(page pdfDict) (llx, lly, urx, ury float64) {
+	box, _ := d.resolve(page["MediaBox"]).(pdfArray)
+	if len(box) != 4 {
+		return 0, 0, 612, 792
+	}
+	v := make([]float64, 4)
+	for i, item := range box {
+		v[i], _ = d.resolve(item).(float64)
+	}
+	return math.Min(v[0], v[2]), math.Min(v[1], v[3]), math.Max(v[0], v[2]), math.Max(v[1], v[3])
+}
@@ -0,0 +1,152 @@
+package pii
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testPDF returns a one-page PDF showing lines in Helvetica. With deflate the content stream
+// is Flate-compressed.
+func testPDF(deflate bool, lines ...string) []byte {
+	var content bytes.Buffer
+	content.WriteString("BT /F1 12 Tf 72 720 Td\n")
+	for _, line := range lines {
+		fmt.Fprintf(&content, "(%s) Tj 0 -14 Td\n", line)
+	}
+	content.WriteString("ET")
+	data, filter := content.Bytes(), ""
+	if deflate {
+		data, filter = zlibBytes(data), "/Filter /FlateDecode"
+	}
+
+	w := newPDFWriter()
+	catalog, pages, page, font, stream := w.alloc(), w.alloc(), w.alloc(), w.alloc(), w.alloc()
+	w.writeObject(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	w.writeObject(pages, fmt.Sprintf("<< /Type /Pages /Kids [%d 0 R] /Count 1 >>", page))
+	w.writeObject(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>", pages, font, stream))
+	w.writeObject(font, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	w.writeStream(stream, filter, data)
+	return w.finish(catalog)
+}
+
+func zlibBytes(data []byte) []byte {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write(data)
+	zw.Close()
+	return b.Bytes()
+}
+
+func TestParsePDFExtractsText(t *testing.T) {
+	for _, deflate := range []bool{false, true} {
+		t.Run(fmt.Sprintf("deflate=%v", deflate), func(t *testing.T) {
+			doc, err := parsePDF(testPDF(deflate, "FORM NO. 16", "PAN of the Employee: ABCPE1234F"), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages, err := doc.extractPages()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pages) != 1 || !strings.Contains(pages[0], "FORM NO. 16") || !strings.Contains(pages[0], "ABCPE1234F") {
+				t.Errorf("extractPages = %q", pages)
+			}
+		})
+	}
+}
+
+// Damaged files fail with an error or yield no text; they never panic.
+func TestParsePDFMalformed(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            []byte
+		wantUnsupported bool
+	}{
+		{"empty", nil, true},
+		{"not a PDF", []byte("PK\x03\x04 zip file"), true},
+		{"header only", []byte("%PDF-1.7\n%%EOF\n"), false},
+		{"truncated", testPDF(true, "PAN: ABCPE1234F")[:200], false},
+		{"unterminated dictionary", []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R\n"), false},
+		{"corrupt content stream", bytes.Replace(testPDF(true, "PAN: ABCPE1234F"), []byte("stream\nx"), []byte("stream\n\x00"), 1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parsePDF(tt.data, "")
+			if tt.wantUnsupported {
+				if !errors.Is(err, ErrUnsupportedFormat) {
+					t.Errorf("parsePDF error = %v, want ErrUnsupportedFormat", err)
+				}
+				return
+			}
+			if err != nil {
+				return
+			}
+			pages, err := doc.extractPages()
+			if err == nil && strings.TrimSpace(strings.Join(pages, "")) != "" {
+				t.Errorf("extractPages = %q, want no text", pages)
+			}
+		})
+	}
+}
+
+func TestInflate(t *testing.T) {
+	text := []byte(strings.Repeat("Gross Salary 1,20,000.00\n", 100))
+	bomb := zlibBytes(make([]byte, 4<<20))
+	tests := []struct {
+		name    string
+		data    []byte
+		limit   int64
+		want    []byte
+		wantErr bool
+	}{
+		{"text", zlibBytes(text), maxInflatedStream, text, false},
+		{"truncated stream keeps what was recovered", zlibBytes(text)[:60], maxInflatedStream, nil, false},
+		{"garbage", []byte("not zlib data"), maxInflatedStream, nil, true},
+		{"bomb over the limit", bomb, 1 << 20, nil, true},
+		{"exactly the limit", bomb, 4 << 20, make([]byte, 4<<20), false},
+		{"no budget left", zlibBytes(text), 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := inflate(tt.data, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("inflate error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil && !bytes.Equal(got, tt.want) {
+				t.Errorf("inflate returned %d bytes, want %d", len(got), len(tt.want))
+			}
+			if !tt.wantErr && int64(len(got)) > tt.limit {
+				t.Errorf("inflate returned %d bytes over the limit of %d", len(got), tt.limit)
+			}
+		})
+	}
+}
+
+// A document whose streams together decompress past maxInflatedDocument stops decoding them,
+// even when each one is under maxInflatedStream.
+func TestDecodeStreamDocumentLimit(t *testing.T) {
+	doc := &pdfDocument{objects: map[int]interface{}{}, trailer: pdfDict{}, inflated: maxInflatedDocument - 1<<10}
+	stream := &pdfStream{dict: pdfDict{"Filter": pdfName("FlateDecode")}, raw: zlibBytes(make([]byte, 1<<20))}
+	if data, err := doc.decodeStream(stream); err == nil {
+		t.Fatalf("decodeStream returned %d bytes past the document limit", len(data))
+	}
+	if doc.inflated > maxInflatedDocument {
+		t.Errorf("inflated = %d, over the document limit of %d", doc.inflated, maxInflatedDocument)
+	}
+}
+
+// Text moved far off the page keeps its line short instead of being indented to its column.
+func TestLayoutRunsFarRight(t *testing.T) {
+	runs := []textRun{
+		{x: 72, y: 720, width: 30, size: 12, text: "Name"},
+		{x: 72e9, y: 720, width: 60, size: 12, text: "Ravi Kumar Sharma"},
+	}
+	got := layoutRuns(runs)
+	if !strings.Contains(got, "Ravi Kumar Sharma") || len(got) > 2*maxLayoutColumn {
+		t.Errorf("layoutRuns returned %d bytes, want the text within %d columns", len(got), maxLayoutColumn)
+	}
+}
@@ -0,0 +1,618 @@
+package pii
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfFont knows how to turn the bytes of a shown string into Unicode text and glyph widths.
+type pdfFont struct {
+	// codeLen is the number of bytes per character code (1 for simple fonts, usually 2 for
+	// composite Type0 fonts).
+	codeLen int
+	// toUnicode maps character codes (as raw byte strings) to text, from the /ToUnicode CMap.
+	toUnicode map[string]string
+	// encoding maps single-byte codes of simple fonts to runes via /Encoding /Differences.
+	encoding map[byte]rune
+	// widths holds glyph advances in 1/1000 text space units.
+	widths       map[int]float64
+	defaultWidth float64
+}
+
+// loadFont builds a pdfFont from a font dictionary.
+func (d *pdfDocument) loadFont(fontObj interface{}) *pdfFont {
+	font := &pdfFont{codeLen: 1, widths: make(map[int]float64), defaultWidth: 500}
+	dict := d.dict(fontObj)
+	if dict == nil {
+		return font
+	}
+
+	if dict["Subtype"] == pdfName("Type0") {
+		font.codeLen = 2
+		font.defaultWidth = 1000
+		if descendants, ok := d.resolve(dict["DescendantFonts"]).(pdfArray); ok && len(descendants) > 0 {
+			cid := d.dict(descendants[0])
+			if dw, ok := d.resolve(cid["DW"]).(float64); ok {
+				font.defaultWidth = dw
+			}
+			font.loadCIDWidths(d, d.resolve(cid["W"]))
+		}
+	} else {
+		first, _ := d.resolve(dict["FirstChar"]).(float64)
+		if widths, ok := d.resolve(dict["Widths"]).(pdfArray); ok {
+			for i, w := range widths {
+				if v, ok := d.resolve(w).(float64); ok {
+					font.widths[int(first)+i] = v
+				}
+			}
+		}
+		font.loadEncoding(d, dict["Encoding"])
+	}
+
+	if stream, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decodeStream(stream); err == nil {
+			font.parseToUnicode(data)
+		}
+	}
+	return font
+}
+
+// loadCIDWidths parses a CIDFont /W array: either "c [w1 w2 ...]" or "cFirst cLast w".
+func (f *pdfFont) loadCIDWidths(d *pdfDocument, obj interface{}) {
+	arr, ok := obj.(pdfArray)
+	if !ok {
+		return
+	}
+	for i := 0; i < len(arr); {
+		start, ok := d.resolve(arr[i]).(float64)
+		if !ok || i+1 >= len(arr) {
+			return
+		}
+		if list, ok := d.resolve(arr[i+1]).(pdfArray); ok {
+			for j, w := range list {
+				if v, ok := d.resolve(w).(float64); ok {
+					f.widths[int(start)+j] = v
+				}
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(arr) {
+			return
+		}
+		end, _ := d.resolve(arr[i+1]).(float64)
+		w, _ := d.resolve(arr[i+2]).(float64)
+		for c := int(start); c <= int(end) && c-int(start) < 65536; c++ {
+			f.widths[c] = w
+		}
+		i += 3
+	}
+}
+
+// loadEncoding applies /Differences glyph names for simple fonts.
+func (f *pdfFont) loadEncoding(d *pdfDocument, obj interface{}) {
+	enc := d.dict(obj)
+	if enc == nil {
+		return
+	}
+	diffs, ok := d.resolve(enc["Differences"]).(pdfArray)
+	if !ok {
+		return
+	}
+	f.encoding = make(map[byte]rune)
+	code := 0
+	for _, item := range diffs {
+		switch v := d.resolve(item).(type) {
+		case float64:
+			code = int(v)
+		case pdfName:
+			if r, ok := glyphNameToRune(string(v)); ok && code >= 0 && code < 256 {
+				f.encoding[byte(code)] = r
+			}
+			code++
+		}
+	}
+}
+
+// glyphNames covers the Adobe glyph names that commonly appear in /Differences arrays.
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "quoteright": '\'', "quoteleft": '\'', "parenleft": '(',
+	"parenright": ')', "asterisk": '*', "plus": '+', "comma": ',', "hyphen": '-', "minus": '-',
+	"period": '.', "slash": '/', "zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9', "colon": ':', "semicolon": ';',
+	"less": '<', "equal": '=', "greater": '>', "question": '?', "at": '@', "bracketleft": '[',
+	"backslash": '\\', "bracketright": ']', "underscore": '_', "braceleft": '{', "bar": '|',
+	"braceright": '}', "endash": '–', "emdash": '—', "bullet": '•', "rupee": '₹',
+}
+
+func glyphNameToRune(name string) (rune, bool) {
+	if len(name) == 1 {
+		return rune(name[0]), true
+	}
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	if strings.HasPrefix(name, "uni") && len(name) == 7 {
+		if v, err := strconv.ParseUint(name[3:], 16, 32); err == nil {
+			return rune(v), true
+		}
+	}
+	return 0, false
+}
+
+// parseToUnicode reads the bfchar/bfrange sections of a ToUnicode CMap.
+func (f *pdfFont) parseToUnicode(data []byte) {
+	f.toUnicode = make(map[string]string)
+	l := &pdfLexer{data: data}
+	var operands []interface{}
+	for {
+		tok, err := l.object()
+		if err != nil {
+			break
+		}
+		kw, isKeyword := tok.(pdfKeyword)
+		if !isKeyword {
+			operands = append(operands, tok)
+			continue
+		}
+		switch kw {
+		case "endcodespacerange":
+			if len(operands) > 0 {
+				if lo, ok := operands[0].(pdfString); ok && len(lo) > 0 {
+					f.codeLen = len(lo)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					f.toUnicode[string(src)] = utf16BEToString(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 || len(lo) != len(hi) {
+					continue
+				}
+				start, end := bytesToInt(lo), bytesToInt(hi)
+				if end-start > 65535 {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					base := []rune(utf16BEToString(dst))
+					for c := start; c <= end && len(base) > 0; c++ {
+						text := append([]rune{}, base...)
+						text[len(text)-1] += rune(c - start)
+						f.toUnicode[string(intToBytes(c, len(lo)))] = string(text)
+					}
+				case pdfArray:
+					for j, item := range dst {
+						if s, ok := item.(pdfString); ok && start+j <= end {
+							f.toUnicode[string(intToBytes(start+j, len(lo)))] = utf16BEToString(s)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func bytesToInt(b []byte) int {
+	v := 0
+	for _, c := range b {
+		v = v<<8 | int(c)
+	}
+	return v
+}
+
+func intToBytes(v, n int) []byte {
+	out := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		out[i] = byte(v)
+		v >>= 8
+	}
+	return out
+}
+
+func utf16BEToString(b []byte) string {
+	if len(b)%2 == 1 {
+		b = append(b, 0)
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// decode converts a shown string into the text of each code and per-code widths (in 1/1000
+// units).
+func (f *pdfFont) decode(s []byte) ([]string, []float64, []bool) {
+	var texts []string
+	var widths []float64
+	var spaces []bool
+	for i := 0; i+f.codeLen <= len(s); i += f.codeLen {
+		code := s[i : i+f.codeLen]
+		c := bytesToInt(code)
+		var text string
+		if t, ok := f.toUnicode[string(code)]; ok {
+			text = t
+		} else if f.codeLen == 1 {
+			if r, ok := f.encoding[code[0]]; ok {
+				text = string(r)
+			} else if code[0] >= 0x20 {
+				text = string(rune(code[0])) // WinAnsi/Latin-1 approximation
+			}
+		}
+		texts = append(texts, text)
+		w, ok := f.widths[c]
+		if !ok {
+			w = f.defaultWidth
+		}
+		widths = append(widths, w)
+		spaces = append(spaces, f.codeLen == 1 && code[0] == ' ')
+	}
+	return texts, widths, spaces
+}
+
+// pdfMatrix is an affine transform [a b c d e f].
+type pdfMatrix [6]float64
+
+var identityMatrix = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+// multiply returns m × n.
+func (m pdfMatrix) multiply(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// textRun is a piece of text placed on the page, in default user space units.
+type textRun struct {
+	x, y   float64
+	width  float64
+	size   float64
+	text   string
+	glyphs []glyph
+}
+
+// glyph is the text of one character code and its horizontal extent on the page.
+type glyph struct {
+	text     string
+	x, width float64
+}
+
+// textExtractor interprets content streams and collects positioned text runs.
+type textExtractor struct {
+	doc   *pdfDocument
+	fonts map[interface{}]*pdfFont
+	runs  []textRun
+	// images are the bounding boxes of the images drawn, in default user space.
+	images []pdfRect
+}
+
+// pdfRect is an axis-aligned rectangle in default user space (bottom-left origin).
+type pdfRect struct {
+	llx, lly, urx, ury float64
+}
+
+// unitSquare returns the bounding box of the unit square mapped by m, the area an image
+// painted with m as CTM covers.
+func (m pdfMatrix) unitSquare() pdfRect {
+	r := pdfRect{llx: math.Inf(1), lly: math.Inf(1), urx: math.Inf(-1), ury: math.Inf(-1)}
+	for _, p := range [][2]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		x := m[0]*p[0] + m[2]*p[1] + m[4]
+		y := m[1]*p[0] + m[3]*p[1] + m[5]
+		r.llx, r.urx = math.Min(r.llx, x), math.Max(r.urx, x)
+		r.lly, r.ury = math.Min(r.lly, y), math.Max(r.ury, y)
+	}
+	return r
+}
+
+type graphicsState struct {
+	ctm       pdfMatrix
+	font      *pdfFont
+	fontSize  float64
+	charSpace float64
+	wordSpace float64
+	hScale    float64
+	leading   float64
+	rise      float64
+}
+
+func (e *textExtractor) font(resources pdfDict, name pdfName) *pdfFont {
+	fonts := e.doc.dict(resources["Font"])
+	ref := fonts[name]
+	key := interface{}(ref)
+	if _, isRef := ref.(pdfRef); !isRef {
+		key = string(name) // direct font dictionaries are keyed by resource name
+	}
+	if f, ok := e.fonts[key]; ok {
+		return f
+	}
+	f := e.doc.loadFont(ref)
+	e.fonts[key] = f
+	return f
+}
+
+// run interprets a content stream with the given resources and initial CTM.
+func (e *textExtractor) run(content []byte, resources pdfDict, ctm pdfMatrix, depth int) {
+	if depth > 8 {
+		return
+	}
+	gs := graphicsState{ctm: ctm, hScale: 1, fontSize: 1, font: &pdfFont{codeLen: 1, defaultWidth: 500}}
+	var stack []graphicsState
+	tm, tlm := identityMatrix, identityMatrix
+	l := &pdfLexer{data: content}
+	var operands []interface{}
+
+	num := func(i int) float64 {
+		if i < len(operands) {
+			if v, ok := operands[i].(float64); ok {
+				return v
+			}
+		}
+		return 0
+	}
+	show := func(s pdfString) {
+		texts, widths, spaces := gs.font.decode(s)
+		trm := pdfMatrix{gs.fontSize * gs.hScale, 0, 0, gs.fontSize, 0, gs.rise}.multiply(tm).multiply(gs.ctm)
+		advance := 0.0
+		glyphs := make([]glyph, len(widths))
+		for i, w := range widths {
+			start := pdfMatrix{1, 0, 0, 1, advance, 0}.multiply(tm).multiply(gs.ctm)[4]
+			stop := pdfMatrix{1, 0, 0, 1, advance + w/1000*gs.fontSize*gs.hScale, 0}.multiply(tm).multiply(gs.ctm)[4]
+			glyphs[i] = glyph{text: texts[i], x: start, width: stop - start}
+			tx := w/1000*gs.fontSize + gs.charSpace
+			if spaces[i] {
+				tx += gs.wordSpace
+			}
+			advance += tx * gs.hScale
+		}
+		size := math.Hypot(trm[2], trm[3])
+		end := pdfMatrix{1, 0, 0, 1, advance, 0}.multiply(tm).multiply(gs.ctm)
+		if text := strings.Join(texts, ""); strings.TrimSpace(text) != "" {
+			e.runs = append(e.runs, textRun{x: trm[4], y: trm[5], width: end[4] - trm[4], size: size, text: text, glyphs: glyphs})
+		}
+		tm = pdfMatrix{1, 0, 0, 1, advance, 0}.multiply(tm)
+	}
+	nextLine := func(tx, ty float64) {
+		tlm = pdfMatrix{1, 0, 0, 1, tx, ty}.multiply(tlm)
+		tm = tlm
+	}
+
+	for {
+		tok, err := l.object()
+		if err != nil {
+			break
+		}
+		op, isOp := tok.(pdfKeyword)
+		if !isOp {
+			operands = append(operands, tok)
+			continue
+		}
+		switch op {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			gs.ctm = pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}.multiply(gs.ctm)
+		case "BT":
+			tm, tlm = identityMatrix, identityMatrix
+		case "Tf":
+			if len(operands) > 1 {
+				if name, ok := operands[0].(pdfName); ok {
+					gs.font = e.font(resources, name)
+				}
+			}
+			gs.fontSize = num(1)
+		case "Tc":
+			gs.charSpace = num(0)
+		case "Tw":
+			gs.wordSpace = num(0)
+		case "Tz":
+			gs.hScale = num(0) / 100
+		case "TL":
+			gs.leading = num(0)
+		case "Ts":
+			gs.rise = num(0)
+		case "Td":
+			nextLine(num(0), num(1))
+		case "TD":
+			gs.leading = -num(1)
+			nextLine(num(0), num(1))
+		case "Tm":
+			tlm = pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+			tm = tlm
+		case "T*":
+			nextLine(0, -gs.leading)
+		case "Tj":
+			if len(operands) > 0 {
+				if s, ok := operands[0].(pdfString); ok {
+					show(s)
+				}
+			}
+		case "'", "\"":
+			if op == "\"" && len(operands) == 3 {
+				gs.wordSpace, gs.charSpace = num(0), num(1)
+			}
+			nextLine(0, -gs.leading)
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					show(s)
+				}
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				if arr, ok := operands[0].(pdfArray); ok {
+					for _, item := range arr {
+						switch v := item.(type) {
+						case pdfString:
+							show(v)
+						case float64:
+							tm = pdfMatrix{1, 0, 0, 1, -v / 1000 * gs.fontSize * gs.hScale, 0}.multiply(tm)
+						}
+					}
+				}
+			}
+		case "Do":
+			if len(operands) > 0 {
+				if name, ok := operands[0].(pdfName); ok {
+					e.runXObject(resources, name, gs.ctm, depth)
+				}
+			}
+		case "BI":
+			e.images = append(e.images, gs.ctm.unitSquare())
+			// Skip inline image data up to the EI operator.
+			if idx := bytes.Index(content[l.pos:], []byte("EI")); idx >= 0 {
+				l.pos += idx + 2
+			} else {
+				l.pos = len(content)
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// runXObject interprets a Form XObject referenced by a Do operator and records the placement
+// of an image XObject.
+func (e *textExtractor) runXObject(resources pdfDict, name pdfName, ctm pdfMatrix, depth int) {
+	xobjects := e.doc.dict(resources["XObject"])
+	stream, ok := e.doc.resolve(xobjects[name]).(*pdfStream)
+	if !ok {
+		return
+	}
+	if stream.dict["Subtype"] == pdfName("Image") {
+		e.images = append(e.images, ctm.unitSquare())
+		return
+	}
+	if stream.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	data, err := e.doc.decodeStream(stream)
+	if err != nil {
+		return
+	}
+	if arr, ok := e.doc.resolve(stream.dict["Matrix"]).(pdfArray); ok && len(arr) == 6 {
+		var m pdfMatrix
+		for i := range m {
+			m[i], _ = e.doc.resolve(arr[i]).(float64)
+		}
+		ctm = m.multiply(ctm)
+	}
+	formResources := e.doc.dict(stream.dict["Resources"])
+	if formResources == nil {
+		formResources = resources
+	}
+	e.run(data, formResources, ctm, depth+1)
+}
+
+// pageContent returns the concatenated, decoded content streams of a page.
+func (d *pdfDocument) pageContent(page pdfDict) []byte {
+	var parts []interface{}
+	switch c := d.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		parts = []interface{}{c}
+	case pdfArray:
+		parts = c
+	}
+	var buf bytes.Buffer
+	for _, part := range parts {
+		if s, ok := d.resolve(part).(*pdfStream); ok {
+			if data, err := d.decodeStream(s); err == nil {
+				buf.Write(data)
+				buf.WriteByte('\n')
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// maxLayoutColumn is the rightmost column layoutRuns indents text to. Text placed further right
+// than any real page is wide starts there, so that a crafted position cannot make a line
+// gigabytes long.
+const maxLayoutColumn = 1000
+
+// layoutRuns arranges text runs into lines, approximating pdftotext -layout: runs on the same
+// baseline form one line, and horizontal positions are mapped to a fixed character grid so
+// that table columns stay aligned.
+func layoutRuns(runs []textRun) string {
+	if len(runs) == 0 {
+		return ""
+	}
+	sizes := make([]float64, len(runs))
+	for i, r := range runs {
+		sizes[i] = r.size
+	}
+	sort.Float64s(sizes)
+	charWidth := math.Max(sizes[len(sizes)/2]*0.5, 1)
+
+	var out strings.Builder
+	for _, line := range baselines(runs) {
+		var b []rune
+		prevEnd := math.Inf(-1)
+		for _, r := range line {
+			col := int(math.Round(math.Min(r.x/charWidth, maxLayoutColumn)))
+			switch {
+			case col > len(b):
+				for len(b) < col {
+					b = append(b, ' ')
+				}
+			case r.x-prevEnd > r.size*0.15 && len(b) > 0 && b[len(b)-1] != ' ':
+				b = append(b, ' ')
+			}
+			b = append(b, []rune(r.text)...)
+			prevEnd = r.x + r.width
+		}
+		out.WriteString(strings.TrimRight(string(b), " "))
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
+
+// ReadPDF extracts text from a PDF without external tools. Pages are separated by form feeds,
+// matching the output of pdftotext.
+func ReadPDF(filename string) (string, error) {
+	pages, _, err := NativeExtractor{}.Extract(context.Background(), filename)
+	if err != nil {
+		return "", err
+	}
+	return pages.Text(), nil
+}
+
+// extractPages returns the laid-out text of each page.
+func (d *pdfDocument) extractPages() ([]string, error) {
+	pages := d.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages found")
+	}
+	e := &textExtractor{doc: d, fonts: make(map[interface{}]*pdfFont)}
+	out := make([]string, 0, len(pages))
+	for _, page := range pages {
+		e.runs = e.runs[:0]
+		e.run(d.pageContent(page), d.dict(page["Resources"]), identityMatrix, 0)
+		out = append(out, layoutRuns(e.runs))
+	}
+	return out, nil
+}
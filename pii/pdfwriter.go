@@ -0,0 +1,70 @@
+package pii
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// pdfWriter assembles a PDF file in memory. Object numbers are handed out with alloc so that
+// objects can reference each other before they are written.
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+	next    int
+}
+
+func newPDFWriter() *pdfWriter {
+	w := &pdfWriter{offsets: make(map[int]int), next: 1}
+	// The binary comment marks the file as containing 8-bit data for transfer tools.
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	return w
+}
+
+func (w *pdfWriter) alloc() int {
+	id := w.next
+	w.next++
+	return id
+}
+
+func (w *pdfWriter) writeObject(id int, body string) {
+	w.offsets[id] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// writeStream writes a stream object; dict holds the dictionary entries other than /Length.
+func (w *pdfWriter) writeStream(id int, dict string, data []byte) {
+	w.offsets[id] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dict, len(data))
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// finish appends the cross-reference table and trailer and returns the complete file.
+func (w *pdfWriter) finish(root int) []byte {
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", w.next)
+	for id := 1; id < w.next; id++ {
+		if off, ok := w.offsets[id]; ok {
+			fmt.Fprintf(&w.buf, "%010d 00000 n \n", off)
+		} else {
+			w.buf.WriteString("0000000000 65535 f \n")
+		}
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", w.next, root, xref)
+	return w.buf.Bytes()
+}
+
+// pdfNumber formats a coordinate without trailing zeros.
+func pdfNumber(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	for s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+	if s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,21 @@
+package pii
+
+import "time"
+
+// PerfBudget is the maximum processing time allowed per megabyte of extracted text.
+type PerfBudget time.Duration
+
+// PerMB scales an elapsed duration measured over n bytes to a per-megabyte figure.
+func PerMB(elapsed time.Duration, n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(float64(elapsed) * float64(1<<20) / float64(n))
+}
+
+// Check reports the per-megabyte processing time and whether it stays within the budget.
+// A zero budget is treated as unlimited.
+func (b PerfBudget) Check(elapsed time.Duration, n int) (time.Duration, bool) {
+	perMB := PerMB(elapsed, n)
+	return perMB, b <= 0 || perMB <= time.Duration(b)
+}
@@ -0,0 +1,104 @@
+package pii
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// benchmarkPage is one page of a Form 16 with an instance of most identifiers, so that every
+// detector family has matches to report; %d numbers the page.
+const benchmarkPage = `FORM NO. 16 - page %d
+[See rule 31(1)(a)]
+PART A
+Certificate under section 203 of the Income-tax Act, 1961 for tax deducted at source on salary
+Name and address of the Employer
+Infosys Technologies Ltd
+Plot No. 44, Electronics City, Hosur Road, Bengaluru, Karnataka - 560100
+Name and address of the Employee
+Ravi Kumar Sharma
+Flat 12B, Green Park Apartments, Near City Mall, Andheri West, Mumbai - 400058
+PAN of the Deductor: AAACI1234F    TAN of the Deductor: MUMA12345B
+PAN of the Employee: ABCPE1234F    Employee Reference No.: EMP00123
+Aadhaar No.: 2345 6789 0124    UAN: 100123456789
+PF Account: MH/BAN/1234567/000/1234567    GSTIN: 27AAPFU0939F1ZV
+Email: ravi.sharma@infosys.com    Mobile: +91 98765 43210
+Date of Birth: 12/05/1985    Period with the Employer: From 01/04/2023 To 31/03/2024
+Assessment Year 2024-25
+PART B
+1. Gross Salary
+(a) Salary as per provisions contained in section 17(1)          1,200,000.00
+(b) Value of perquisites under section 17(2)                           25,000.00
+2. Less: Allowance to the extent exempt under section 10            80,000.00
+3. Total amount of deductions under section 16                      50,000.00
+Rupees Twelve Lakh Only
+Verification
+I, Suresh Menon, son of Ramesh Menon, working in the capacity of Director
+`
+
+// benchmarkPages returns a document of n benchmarkPage pages.
+func benchmarkPages(n int) Pages {
+	pages := make(Pages, n)
+	for i := range pages {
+		pages[i] = fmt.Sprintf(benchmarkPage, i+1)
+	}
+	return pages
+}
+
+func BenchmarkFilterPII(b *testing.B) {
+	pf := NewFilter()
+	text := benchmarkPages(10).Text()
+	b.SetBytes(int64(len(text)))
+	for b.Loop() {
+		pf.FilterPII(text)
+	}
+}
+
+func BenchmarkFilterPages(b *testing.B) {
+	pf := NewFilter()
+	pages := benchmarkPages(10)
+	b.SetBytes(int64(len(pages.Text())))
+	for b.Loop() {
+		if _, err := pf.FilterPages(pages, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDetectors times each detector family on its own, with every other entity type
+// turned off.
+func BenchmarkDetectors(b *testing.B) {
+	text := benchmarkPages(10).Text()
+	for _, entity := range NewFilter().EntityTypes() {
+		pf := NewFilter(WithOnly(entity))
+		b.Run(entity, func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			for b.Loop() {
+				pf.FilterPII(text)
+			}
+		})
+	}
+}
+
+// BenchmarkPipeline runs the default stages (normalize, detect, verify) and the dictionary
+// stage over a whole document, as the CLI does.
+func BenchmarkPipeline(b *testing.B) {
+	words := make(map[string]struct{})
+	for _, w := range strings.Fields(strings.ToLower(benchmarkPage)) {
+		words[w] = struct{}{}
+	}
+	stages := Stages{{Name: StageNormalize}, {Name: StageDetect}, {Name: StageDictionary}, {Name: StageVerify}}
+	for _, workers := range []int{1, 4} {
+		p := &Pipeline{Filter: NewFilter(), Stages: stages, Words: words, Workers: workers}
+		e := Extraction{Pages: benchmarkPages(10)}
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(e.Pages.Text())))
+			for b.Loop() {
+				if _, err := p.Redact(context.Background(), e); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package pii
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// phonePlan is the numbering plan of one region, a small port of the libphonenumber
+// metadata: enough to tell a telephone number from an amount or a reference number.
+type phonePlan struct {
+	// countryCode is dialled after "+"; trunk is the national prefix dropped from it.
+	countryCode string
+	trunk       string
+	// number matches the valid national significant numbers (without trunk prefix).
+	number *regexp.Regexp
+	// national matches the number as written inside the region, separators included.
+	national string
+	// minDigits and maxDigits bound the length of the national significant number.
+	minDigits, maxDigits int
+}
+
+// DefaultPhoneRegion is the region whose national numbers are detected by default. Unspaced
+// Indian numbers are matched by Filter.PhonePattern, grouped ones (+91 98765 43210) and those
+// of the other regions by phonePlans.
+const DefaultPhoneRegion = "IN"
+
+// phonePlans holds the numbering plans of the supported regions.
+var phonePlans = map[string]phonePlan{
+	"IN": {
+		countryCode: "91", trunk: "0", minDigits: 10, maxDigits: 10,
+		number:   regexp.MustCompile(`^[6-9]\d{9}$`),
+		national: `\b0?[6-9]\d{4}[ .-]\d{5}\b|\b0?[6-9]\d{2}[ .-]\d{3}[ .-]\d{4}\b`,
+	},
+	"US": nanpPlan,
+	"CA": nanpPlan,
+	"GB": {
+		countryCode: "44", trunk: "0", minDigits: 9, maxDigits: 10,
+		number:   regexp.MustCompile(`^(?:[1-3]\d{8,9}|7[1-9]\d{8}|8\d{8,9})$`),
+		national: `\b0\d{2,4}[ .-]?\d{3,4}[ .-]?\d{3,4}\b`,
+	},
+	"AE": {
+		countryCode: "971", trunk: "0", minDigits: 8, maxDigits: 9,
+		number:   regexp.MustCompile(`^(?:[2-4679]\d{7}|5[024-8]\d{7})$`),
+		national: `\b0\d{1,2}[ .-]?\d{3}[ .-]?\d{4}\b`,
+	},
+	"SG": {
+		countryCode: "65", minDigits: 8, maxDigits: 8,
+		number:   regexp.MustCompile(`^[3689]\d{7}$`),
+		national: `\b[3689]\d{3}[ .-]?\d{4}\b`,
+	},
+	"AU": {
+		countryCode: "61", trunk: "0", minDigits: 9, maxDigits: 9,
+		number:   regexp.MustCompile(`^[2-478]\d{8}$`),
+		national: `\b0[2-478][ .-]?\d{4}[ .-]?\d{4}\b|\b04\d{2}[ .-]?\d{3}[ .-]?\d{3}\b`,
+	},
+}
+
+// nanpPlan is the North American Numbering Plan shared by the United States and Canada.
+var nanpPlan = phonePlan{
+	countryCode: "1", trunk: "1", minDigits: 10, maxDigits: 10,
+	number:   regexp.MustCompile(`^[2-9]\d{2}[2-9]\d{6}$`),
+	national: `(?:\b1[ .-]?)?(?:\(\d{3}\)[ .-]?|\b\d{3}[ .-])\d{3}[ .-]\d{4}\b`,
+}
+
+// PhoneRegionNames returns the supported phone regions, sorted.
+func PhoneRegionNames() []string {
+	var names []string
+	for name := range phonePlans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParsePhoneRegions validates a comma-separated list of region codes ("IN,GB,US").
+func ParsePhoneRegions(s string) ([]string, error) {
+	var regions []string
+	for _, r := range strings.Split(s, ",") {
+		r = strings.ToUpper(strings.TrimSpace(r))
+		if r == "" {
+			continue
+		}
+		if _, ok := phonePlans[r]; !ok {
+			return nil, fmt.Errorf("unknown phone region %q (expected %s)", r, strings.Join(PhoneRegionNames(), ", "))
+		}
+		if !slices.Contains(regions, r) {
+			regions = append(regions, r)
+		}
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("no phone region given")
+	}
+	return regions, nil
+}
+
+// phoneSeparator is what may stand between the digit groups of a written number.
+const phoneSeparator = `[ .()-]{0,2}`
+
+// foreignPhonePattern matches the numbers of the supported regions written in international
+// format (+44 20 7946 0958, +91 98765 43210), and those of the listed regions written in
+// national format (020 7946 0958, 98765 43210).
+func foreignPhonePattern(regions []string) *regexp.Regexp {
+	var alts []string
+	for _, name := range slices.Sorted(maps.Keys(phonePlans)) {
+		r := phonePlans[name]
+		alt := fmt.Sprintf(`\+%s[ .-]?(?:\(0\)[ .-]?)?\(?\d(?:%s\d){%d,%d}\b`,
+			r.countryCode, phoneSeparator, r.minDigits-1, r.maxDigits-1)
+		if !slices.Contains(alts, alt) {
+			alts = append(alts, alt)
+		}
+	}
+	for _, name := range regions {
+		if r, ok := phonePlans[name]; ok && !slices.Contains(alts, r.national) {
+			alts = append(alts, r.national)
+		}
+	}
+	return regexp.MustCompile(strings.Join(alts, "|"))
+}
+
+// defaultForeignPhonePattern is foreignPhonePattern for the default region.
+var defaultForeignPhonePattern = foreignPhonePattern([]string{DefaultPhoneRegion})
+
+// validForeignPhone reports whether a foreignPhonePattern match is a valid number of its
+// region: of any supported region after "+", of one of regions otherwise.
+func validForeignPhone(regions []string) func(string) bool {
+	return func(match string) bool {
+		international := strings.HasPrefix(match, "+")
+		digits := digitsOf(strings.Replace(match, "(0)", "", 1))
+		for name, r := range phonePlans {
+			number := digits
+			if international {
+				if !strings.HasPrefix(number, r.countryCode) {
+					continue
+				}
+				number = number[len(r.countryCode):]
+			} else {
+				if !slices.Contains(regions, name) {
+					continue
+				}
+				// No national significant number starts with the trunk prefix.
+				number = strings.TrimPrefix(number, r.trunk)
+			}
+			if len(number) >= r.minDigits && len(number) <= r.maxDigits && r.number.MatchString(number) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// digitsOf returns the digits of s.
+func digitsOf(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if '0' <= s[i] && s[i] <= '9' {
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
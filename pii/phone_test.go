@@ -0,0 +1,33 @@
+package pii
+
+import "testing"
+
+func TestPhoneNumbers(t *testing.T) {
+	tests := []struct {
+		name    string
+		regions []string
+		text    string
+		want    string
+	}{
+		{"IN spaced international", nil, "Mobile: +91 98765 43210", "Mobile: [PHONE_REDACTED]"},
+		{"IN 3+3+4 international", nil, "Mobile: +91 987 654 3210", "Mobile: [PHONE_REDACTED]"},
+		{"IN unspaced international", nil, "Mobile: +919876543210", "Mobile: [PHONE_REDACTED]"},
+		{"IN spaced national", nil, "Mobile: 98765 43210", "Mobile: [PHONE_REDACTED]"},
+		{"IN national not starting 6-9", nil, "Mobile: 58765 43210", "Mobile: 58765 43210"},
+		{"GB international", nil, "Tel: +44 20 7946 0958", "Tel: [PHONE_REDACTED]"},
+		{"IN international outside regions", []string{"GB"}, "Mobile: +91 98765 43210", "Mobile: [PHONE_REDACTED]"},
+		{"IN national outside regions", []string{"GB"}, "Mobile: 98765 43210", "Mobile: 98765 43210"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.regions != nil {
+				opts = append(opts, WithPhoneRegions(tt.regions...))
+			}
+			pf := NewFilter(opts...)
+			if got := pf.FilterPII(tt.text).CleanedText; got != tt.want {
+				t.Errorf("FilterPII(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package pii
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// pinLabelPattern matches the labels printed before a postal PIN code.
+	pinLabelPattern = regexp.MustCompile(`(?i)\bPIN(?:\s*code)?\b|\bpincode\b|\bpostal\s+code\b`)
+	// currencyBefore matches a rupee sign or abbreviation at the end of the text before an
+	// amount.
+	currencyBefore = regexp.MustCompile(`(?i)(?:₹|\bRs\.?|\bINR)\s*$`)
+)
+
+// pinSeparators are the characters allowed between a label or place name and the PIN code
+// after it: "PIN: 560001", "Bengaluru - 560 001".
+const pinSeparators = " \t,.:-–#"
+
+// pinCodeContext reports whether the six digits starting at byte start of lines[n] are a
+// PIN code rather than an amount. Salaries and tax figures share the shape of a PIN code, so
+// the digits must follow a PIN label or a state or city name of the profile, with only
+// separators in between, and must not be written as money: after a rupee sign, or with a
+// decimal point or digit grouping next to them.
+func (pf *Filter) pinCodeContext(lines []string, n, start int) bool {
+	line := lines[n]
+	loc := pf.PINCodePattern.FindStringIndex(line[start:])
+	if loc == nil || loc[0] != 0 {
+		return false
+	}
+	end := start + loc[1]
+	if isAmountSeparator(line, end) && end+1 < len(line) && isDigit(line[end+1]) {
+		return false
+	}
+	if start > 1 && isAmountSeparator(line, start-1) && isDigit(line[start-2]) {
+		return false
+	}
+	before := line[:start]
+	if currencyBefore.MatchString(before) {
+		return false
+	}
+	before = strings.TrimRight(before, pinSeparators)
+	for _, pattern := range []*regexp.Regexp{pinLabelPattern, pf.AddressPattern} {
+		if locs := pattern.FindAllStringIndex(before, -1); len(locs) > 0 && locs[len(locs)-1][1] == len(before) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAmountSeparator reports whether line[i] is a decimal point or a digit group separator.
+func isAmountSeparator(line string, i int) bool {
+	return i < len(line) && (line[i] == '.' || line[i] == ',')
+}
+
+// isDigit reports whether c is an ASCII digit.
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,216 @@
+package pii
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Pipeline is the whole flow of a document: extraction, the stages of a pipeline definition
+// around the filter's detectors, and the outputs. Every part is injected, so that the flow can
+// be run with a fake Extractor or in-memory Writers and its outputs compared to golden files
+// (see AssertEqualFile). A Pipeline holds no state of its own and may run any number of
+// documents, concurrently.
+type Pipeline struct {
+	// Extractor reads the text of documents; nil tries the engines in turn, as ExtractText
+	// does in "auto" mode.
+	Extractor Extractor
+	// Password opens encrypted documents with the built-in extractors.
+	Password string
+	Filter   *Filter
+	// Stages is the pipeline definition; nil runs DefaultStages.
+	Stages Stages
+	// Words is the dictionary of StageDictionary (see LoadWordSet).
+	Words map[string]struct{}
+	// Workers is the FilterPages pool of multi-page documents (0 = one per CPU); 1 filters
+	// the whole text in one pass, as FilterPII.
+	Workers int
+	// Formats are the outputs Write produces (see FormatNames). A format found in Writers is
+	// written by that writer instead of the registered one.
+	Formats []string
+	Writers map[string]OutputWriter
+	// Progress, when set, is told about each step, e.g. to print it.
+	Progress func(msg string)
+}
+
+// Extraction is the text of a document as read by Pipeline.Extract.
+type Extraction struct {
+	Pages    Pages
+	Metadata Metadata
+	// Warnings records the fallbacks the extraction took.
+	Warnings Warnings
+	// Duration is the time the extraction took; it is zero for text that was not read by
+	// Extract, such as text piped in, which then has no extract stage in its Timings.
+	Duration time.Duration
+}
+
+// PipelineResult is a document that went through a Pipeline.
+type PipelineResult struct {
+	Extraction Extraction
+	// Text is the text the detectors ran on: the extracted pages as rewritten by the stages
+	// before detect.
+	Text string
+	// Data is the redacted document; its Warnings start with those of the extraction. When a
+	// stage fails, only its Warnings are set.
+	Data FilteredData
+	// Elapsed is the time detection and the stages after it took, which PerfBudget checks.
+	Elapsed time.Duration
+	// Outputs lists the files written by Run.
+	Outputs []string
+}
+
+// Run extracts the document src, redacts it and writes its outputs, named after base as
+// WriteOutputs names them.
+func (p *Pipeline) Run(ctx context.Context, src, base string) (PipelineResult, error) {
+	e, err := p.Extract(ctx, src)
+	if err != nil {
+		return PipelineResult{Extraction: e}, err
+	}
+	result, err := p.Redact(ctx, e)
+	if err != nil {
+		return result, err
+	}
+	result.Outputs, err = p.Write(result.Data, base)
+	return result, err
+}
+
+// Extract reads the text of the document src. A document without any text is reported as
+// ErrNoText, one that Password does not open as ErrEncrypted.
+func (p *Pipeline) Extract(ctx context.Context, src string) (Extraction, error) {
+	var e Extraction
+	var err error
+	started := time.Now()
+	if p.Extractor == nil {
+		e.Pages, e.Metadata, err = ExtractText(ctx, src, "auto", p.Password, &e.Warnings)
+	} else {
+		e.Pages, e.Metadata, err = requireText(withPassword(p.Extractor, p.Password).Extract(ctx, src))
+	}
+	e.Duration = time.Since(started)
+	return e, err
+}
+
+// Redact runs the stages before detect on the extracted text, filters the result and runs the
+// stages after detect on it. Timings are recorded when the filter records them (see
+// WithTimings).
+func (p *Pipeline) Redact(ctx context.Context, e Extraction) (PipelineResult, error) {
+	result := PipelineResult{Extraction: e}
+	before, after := p.stages().Split()
+	var timings *Timings
+	if p.Filter.timings {
+		timings = new(Timings)
+		if e.Duration > 0 {
+			timings.Stages = append(timings.Stages, Timing{Name: StageExtract, Duration: e.Duration})
+		}
+	}
+
+	pages := e.Pages
+	if len(before) > 0 {
+		text := pages.Text()
+		for _, st := range before {
+			started := time.Now()
+			var err error
+			if text, err = st.Rewrite(ctx, text); err != nil {
+				return result, err
+			}
+			timings.AddStage(st.Name, started)
+		}
+		pages = Pages(strings.Split(text, "\f"))
+	}
+	result.Text = pages.Text()
+
+	started := time.Now()
+	var data FilteredData
+	if p.Workers != 1 && len(pages) > 1 {
+		p.progress(fmt.Sprintf("Filtering PII data on %d pages...", len(pages)))
+		var err error
+		if data, err = p.Filter.FilterPages(pages, p.Workers); err != nil {
+			result.Data.Warnings = slices.Clone(e.Warnings)
+			return result, err
+		}
+	} else {
+		p.progress("Filtering PII data...")
+		data = p.Filter.FilterPII(result.Text)
+	}
+	if timings != nil {
+		timings.AddStage(StageDetect, started)
+		timings.Detectors = data.Timings.Detectors
+		data.Timings = timings
+	}
+	warnings := append(slices.Clone(e.Warnings), data.Warnings...)
+	if err := p.runStages(ctx, after, &data, &warnings, true); err != nil {
+		// The warnings tell what failed, e.g. the residual matches of verification.
+		result.Data.Warnings = warnings
+		return result, err
+	}
+	result.Elapsed = time.Since(started)
+	data.Warnings = warnings
+	result.Data = data
+	return result, nil
+}
+
+// Finish runs the stages after detect on data that the filter produced on its own, such as
+// the certificates of a bundle; warnings receives the warnings of the stages.
+func (p *Pipeline) Finish(ctx context.Context, data *FilteredData, warnings *Warnings) error {
+	_, after := p.stages().Split()
+	return p.runStages(ctx, after, data, warnings, false)
+}
+
+// Write writes data in every format of Formats, named after base as WriteOutputs names them,
+// and returns the files written.
+func (p *Pipeline) Write(data FilteredData, base string) ([]string, error) {
+	return writeOutputs(data, base, p.Formats, p.Writers)
+}
+
+// stages returns the pipeline definition.
+func (p *Pipeline) stages() Stages {
+	if p.Stages == nil {
+		return DefaultStages
+	}
+	return p.Stages
+}
+
+// progress reports a step to Progress, if set.
+func (p *Pipeline) progress(msg string) {
+	if p.Progress != nil {
+		p.Progress(msg)
+	}
+}
+
+// runStages runs stages, the stages after detect, on data in order: the dictionary filter,
+// verification and plugin stages, which rewrite the cleaned text. With progress each stage
+// is reported to Progress. Each stage is timed into data.Timings when it is set.
+func (p *Pipeline) runStages(ctx context.Context, stages Stages, data *FilteredData, warnings *Warnings, progress bool) error {
+	report := func(msg string) {
+		if progress {
+			p.progress(msg)
+		}
+	}
+	for _, st := range stages {
+		started := time.Now()
+		switch st.Name {
+		case StageDictionary:
+			if p.Words == nil {
+				return fmt.Errorf("the %s stage needs a word list", StageDictionary)
+			}
+			report("Redacting non-dictionary English words using offline list...")
+			ApplyDictionaryFilter(data, p.Words)
+		case StageVerify:
+			// Nothing is written unless the detectors come up empty on the final text.
+			report("Verifying redacted text...")
+			if err := p.Filter.VerifyRedacted(*data, warnings); err != nil {
+				return err
+			}
+		default:
+			report(fmt.Sprintf("Running stage %s...", st.Name))
+			text, err := st.Rewrite(ctx, data.CleanedText)
+			if err != nil {
+				return err
+			}
+			data.CleanedText = text
+		}
+		data.Timings.AddStage(st.Name, started)
+	}
+	return nil
+}
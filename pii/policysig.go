@@ -0,0 +1,185 @@
+package pii
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PolicySignatureExt is appended to a config file's name to find its detached signature:
+// policy.yaml is signed by policy.yaml.sig.
+const PolicySignatureExt = ".sig"
+
+// PolicySignature identifies the signed config a run used, for the manifest.
+type PolicySignature struct {
+	File string `json:"file"`
+	// SHA256 is the hash of the config file as signed.
+	SHA256 string `json:"sha256"`
+	// KeyID is the first 8 bytes of the SHA-256 of the public key, in hex.
+	KeyID string `json:"key_id"`
+	// Signature is the Ed25519 signature of the file, in base64.
+	Signature string `json:"signature"`
+}
+
+// GeneratePolicyKey returns a new Ed25519 key pair in PEM form: the private key (PKCS #8)
+// that signs policies and the public key (PKIX) that workers verify them with. OpenSSL reads
+// and writes the same formats.
+func GeneratePolicyKey() (private, public []byte, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode private key: %v", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode public key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), nil
+}
+
+// LoadPolicyPublicKey reads a PEM Ed25519 public key.
+func LoadPolicyPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %v", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is not an Ed25519 key", path)
+	}
+	return pub, nil
+}
+
+// LoadPolicyPrivateKey reads a PEM Ed25519 private key.
+func LoadPolicyPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %v", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is not an Ed25519 key", path)
+	}
+	return priv, nil
+}
+
+// readPEM returns the DER bytes of the first PEM block of the given type in the file.
+func readPEM(path, blockType string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %v", err)
+	}
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return nil, fmt.Errorf("no %s block in %s", blockType, path)
+		}
+		if block.Type == blockType {
+			return block.Bytes, nil
+		}
+	}
+}
+
+// SignPolicy signs the config file or preset bundle at path with key and writes the signature
+// next to it (path + PolicySignatureExt). The file must be a valid config or bundle.
+func SignPolicy(path string, key ed25519.PrivateKey) (*PolicySignature, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	if isPreset(path) {
+		_, err = parsePreset(path, b)
+	} else {
+		_, err = parseConfig(path, b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sig := ed25519.Sign(key, b)
+	encoded := base64.StdEncoding.EncodeToString(sig)
+	if err := os.WriteFile(path+PolicySignatureExt, []byte(encoded+"\n"), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write signature: %v", err)
+	}
+	return newPolicySignature(path, b, key.Public().(ed25519.PublicKey), encoded), nil
+}
+
+// LoadSignedConfig is LoadConfig for a policy that must carry a valid signature by key. It
+// refuses a config without a signature file, and one modified after signing or signed with
+// another key. The config is parsed from the bytes that were verified.
+func LoadSignedConfig(path string, key ed25519.PublicKey) (*Config, *PolicySignature, error) {
+	b, sig, err := readSigned(path, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := parseConfig(path, b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, sig, nil
+}
+
+// LoadSignedPreset is LoadPreset for a bundle that must carry a valid signature by key, as
+// LoadSignedConfig.
+func LoadSignedPreset(path string, key ed25519.PublicKey) (*Preset, *PolicySignature, error) {
+	b, sig, err := readSigned(path, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := parsePreset(path, b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, sig, nil
+}
+
+// readSigned reads the policy file at path and checks it against its signature by key.
+func readSigned(path string, key ed25519.PublicKey) ([]byte, *PolicySignature, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	sigText, err := os.ReadFile(path + PolicySignatureExt)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("policy %s is not signed (no %s)", path, path+PolicySignatureExt)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read signature: %v", err)
+	}
+	encoded := strings.TrimSpace(string(sigText))
+	sig, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, nil, fmt.Errorf("malformed signature %s", path+PolicySignatureExt)
+	}
+	if !ed25519.Verify(key, b, sig) {
+		return nil, nil, fmt.Errorf("policy %s does not match its signature: it was modified after signing or signed with another key", path)
+	}
+	return b, newPolicySignature(path, b, key, encoded), nil
+}
+
+// newPolicySignature describes the signature sig of the contents b of path by key.
+func newPolicySignature(path string, b []byte, key ed25519.PublicKey, sig string) *PolicySignature {
+	sum := sha256.Sum256(b)
+	id := sha256.Sum256(key)
+	return &PolicySignature{File: path, SHA256: hex.EncodeToString(sum[:]), KeyID: hex.EncodeToString(id[:8]), Signature: sig}
+}
@@ -0,0 +1,114 @@
+package pii
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testPolicyKeys writes a new key pair to dir and loads it back.
+func testPolicyKeys(t *testing.T, dir, name string) (ed25519.PrivateKey, ed25519.PublicKey) {
+	t.Helper()
+	privPEM, pubPEM, err := GeneratePolicyKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privPath, pubPath := filepath.Join(dir, name+".key"), filepath.Join(dir, name+".pub")
+	if err := os.WriteFile(privPath, privPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pubPath, pubPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	priv, err := LoadPolicyPrivateKey(privPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := LoadPolicyPublicKey(pubPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicyPublicKey(privPath); err == nil {
+		t.Error("LoadPolicyPublicKey accepted a private key file")
+	}
+	return priv, pub
+}
+
+func TestLoadSignedConfig(t *testing.T) {
+	const policy = "email_policy: keep-domain\ndisabled: [tan]\n"
+	tests := []struct {
+		name string
+		// tamper changes the signed policy or its signature before it is loaded.
+		tamper  func(t *testing.T, path string, other ed25519.PrivateKey)
+		wantErr bool
+	}{
+		{"signed", func(t *testing.T, path string, other ed25519.PrivateKey) {}, false},
+		{"modified after signing", func(t *testing.T, path string, other ed25519.PrivateKey) {
+			writeTestFile(t, path, "email_policy: redact\ndisabled: [tan]\n")
+		}, true},
+		{"signed with another key", func(t *testing.T, path string, other ed25519.PrivateKey) {
+			if _, err := SignPolicy(path, other); err != nil {
+				t.Fatal(err)
+			}
+		}, true},
+		{"no signature", func(t *testing.T, path string, other ed25519.PrivateKey) {
+			os.Remove(path + PolicySignatureExt)
+		}, true},
+		{"malformed signature", func(t *testing.T, path string, other ed25519.PrivateKey) {
+			writeTestFile(t, path+PolicySignatureExt, "not base64!\n")
+		}, true},
+		{"truncated signature", func(t *testing.T, path string, other ed25519.PrivateKey) {
+			writeTestFile(t, path+PolicySignatureExt, "c2lnbmF0dXJl\n")
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			priv, pub := testPolicyKeys(t, dir, "compliance")
+			other, _ := testPolicyKeys(t, dir, "other")
+			path := filepath.Join(dir, "policy.yaml")
+			writeTestFile(t, path, policy)
+			signed, err := SignPolicy(path, priv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(t, path, other)
+
+			cfg, sig, err := LoadSignedConfig(path, pub)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadSignedConfig error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if cfg.EmailPolicy != "keep-domain" {
+				t.Errorf("EmailPolicy = %q, want the signed keep-domain", cfg.EmailPolicy)
+			}
+			if *sig != *signed {
+				t.Errorf("signature = %+v, want %+v", sig, signed)
+			}
+		})
+	}
+}
+
+// An invalid policy is not signed.
+func TestSignPolicyInvalid(t *testing.T) {
+	dir := t.TempDir()
+	priv, _ := testPolicyKeys(t, dir, "compliance")
+	path := filepath.Join(dir, "policy.yaml")
+	writeTestFile(t, path, "disabled: [\n")
+	if _, err := SignPolicy(path, priv); err == nil {
+		t.Fatal("SignPolicy signed an invalid config")
+	}
+	if _, err := os.Stat(path + PolicySignatureExt); !os.IsNotExist(err) {
+		t.Errorf("signature written for an invalid config: %v", err)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
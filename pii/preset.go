@@ -0,0 +1,291 @@
+package pii
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PresetExt is the extension of preset bundles (see LoadPreset).
+const PresetExt = ".bundle"
+
+// Files of a preset bundle. Only PresetInfoFile is required.
+const (
+	// PresetInfoFile holds the name and description of the preset (see PresetInfo).
+	PresetInfoFile = "preset.yaml"
+	// PresetConfigFile is the policy: a Config in YAML.
+	PresetConfigFile = "config.yaml"
+	// PresetWordsFile replaces english_words.txt as the dictionary.
+	PresetWordsFile = "words.txt"
+	// PresetAllowlistFile, PresetDenylistFile and PresetAddressKeywordsFile are lists in the
+	// format of the -allowlist, -denylist and -address-keywords files.
+	PresetAllowlistFile       = "allowlist.txt"
+	PresetDenylistFile        = "denylist.txt"
+	PresetAddressKeywordsFile = "address_keywords.txt"
+	// PresetPlacesFile replaces the place names of the profile's address gazetteer, one per line.
+	PresetPlacesFile = "places.txt"
+	// PresetTemplatesFile holds placeholder and report string templates in the format of a
+	// -lang-file, layered on top of the run's language.
+	PresetTemplatesFile = "lang.json"
+)
+
+// presetFiles lists the files a bundle may hold, in the order they are listed and packed.
+var presetFiles = []string{PresetInfoFile, PresetConfigFile, PresetWordsFile, PresetAllowlistFile, PresetDenylistFile, PresetAddressKeywordsFile, PresetPlacesFile, PresetTemplatesFile}
+
+// PresetInfo is the PresetInfoFile of a bundle.
+type PresetInfo struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// PresetFile describes one file of a bundle, for listings and the manifest.
+type PresetFile struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Preset is a complete configuration shared as a single zip file, so that teams run the same
+// policy, dictionaries, gazetteer and templates without syncing several files.
+type Preset struct {
+	PresetInfo
+	// File is the path the bundle was loaded from.
+	File string
+	// Files lists the files of the bundle in presetFiles order.
+	Files []PresetFile
+	// Config is the policy; nil when the bundle has none.
+	Config *Config
+	// Words is the dictionary; nil when the bundle has none.
+	Words           map[string]struct{}
+	Allowlist       []string
+	Denylist        []string
+	AddressKeywords []string
+	Places          []string
+	// Templates is layered on top of the run's locale by Locale.
+	Templates *Locale
+}
+
+// LoadPreset reads and validates the bundle at path.
+func LoadPreset(path string) (*Preset, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preset: %v", err)
+	}
+	return parsePreset(path, b)
+}
+
+// parsePreset parses and validates the contents b of the bundle at path. Unknown files are
+// errors, so that a misspelt file name is not silently ignored.
+func parsePreset(path string, b []byte) (*Preset, error) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open preset %s: %v", path, err)
+	}
+	contents := make(map[string][]byte)
+	for _, f := range zr.File {
+		if !slices.Contains(presetFiles, f.Name) {
+			return nil, fmt.Errorf("preset %s: unknown file %q (expected %s)", path, f.Name, strings.Join(presetFiles, ", "))
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("preset %s: failed to open %s: %v", path, f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("preset %s: failed to read %s: %v", path, f.Name, err)
+		}
+		contents[f.Name] = data
+	}
+	info, ok := contents[PresetInfoFile]
+	if !ok {
+		return nil, fmt.Errorf("preset %s: missing %s", path, PresetInfoFile)
+	}
+
+	p := &Preset{File: path}
+	if err := yaml.Unmarshal(info, &p.PresetInfo); err != nil {
+		return nil, fmt.Errorf("preset %s: failed to parse %s: %v", path, PresetInfoFile, err)
+	}
+	if p.Name == "" {
+		return nil, fmt.Errorf("preset %s: %s: name is required", path, PresetInfoFile)
+	}
+	for _, name := range presetFiles {
+		data, ok := contents[name]
+		if !ok {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		p.Files = append(p.Files, PresetFile{Name: name, Size: len(data), SHA256: hex.EncodeToString(sum[:])})
+		where := path + ":" + name
+		switch name {
+		case PresetConfigFile:
+			if p.Config, err = parseConfig(where, data); err != nil {
+				return nil, err
+			}
+		case PresetWordsFile:
+			p.Words = make(map[string]struct{})
+			for _, w := range listLines(data, false) {
+				p.Words[strings.ToLower(w)] = struct{}{}
+			}
+		case PresetAllowlistFile:
+			p.Allowlist = listLines(data, true)
+		case PresetDenylistFile:
+			p.Denylist = listLines(data, true)
+			if _, err := CompileDenylist(p.Denylist); err != nil {
+				return nil, fmt.Errorf("%s: %v", where, err)
+			}
+		case PresetAddressKeywordsFile:
+			p.AddressKeywords = listLines(data, true)
+		case PresetPlacesFile:
+			p.Places = listLines(data, true)
+		case PresetTemplatesFile:
+			var l Locale
+			if err := json.Unmarshal(data, &l); err != nil {
+				return nil, fmt.Errorf("%s: %v", where, err)
+			}
+			for k := range l.Strings {
+				if _, ok := englishStrings[k]; !ok {
+					return nil, fmt.Errorf("%s: unknown report string %q", where, k)
+				}
+			}
+			p.Templates = &l
+		}
+	}
+	return p, nil
+}
+
+// listLines returns the non-blank lines of a list file; with comments, lines starting with #
+// are skipped as in readList.
+func listLines(data []byte, comments bool) []string {
+	var values []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		v := strings.TrimSpace(scanner.Text())
+		if v == "" || comments && strings.HasPrefix(v, "#") {
+			continue
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// PresetRecord identifies the preset bundle a run used, for the manifest.
+type PresetRecord struct {
+	File  string       `json:"file"`
+	Name  string       `json:"name"`
+	Files []PresetFile `json:"files"`
+}
+
+// Record returns the manifest entry of the preset.
+func (p *Preset) Record() *PresetRecord {
+	return &PresetRecord{File: p.File, Name: p.Name, Files: p.Files}
+}
+
+// Options returns the filter options of the preset: its policy, lists and gazetteer. Apply
+// them after WithProfile and WithLocale, as config options are.
+func (p *Preset) Options() []Option {
+	var opts []Option
+	if p.Config != nil {
+		// parsePreset has already validated the config.
+		opts, _ = p.Config.Options()
+	}
+	if len(p.Places) > 0 {
+		opts = append(opts, WithPlaces(p.Places...))
+	}
+	if len(p.AddressKeywords) > 0 {
+		opts = append(opts, WithAddressKeywords(p.AddressKeywords...))
+	}
+	if len(p.Allowlist) > 0 {
+		opts = append(opts, WithAllowlist(p.Allowlist...))
+	}
+	if len(p.Denylist) > 0 {
+		pattern, _ := CompileDenylist(p.Denylist)
+		opts = append(opts, WithDenylist(pattern))
+	}
+	return opts
+}
+
+// Stages returns the pipeline of the preset's policy, or DefaultStages.
+func (p *Preset) Stages() Stages {
+	return p.Config.Stages()
+}
+
+// Locale returns base with the preset's templates layered on top.
+func (p *Preset) Locale(base Locale) Locale {
+	if p.Templates == nil {
+		return base
+	}
+	l := Locale{Placeholders: make(map[string]string), Strings: make(map[string]string)}
+	for _, m := range []map[string]string{base.Placeholders, p.Templates.Placeholders} {
+		for k, v := range m {
+			l.Placeholders[k] = v
+		}
+	}
+	for _, m := range []map[string]string{base.Strings, p.Templates.Strings} {
+		for k, v := range m {
+			l.Strings[k] = v
+		}
+	}
+	return l
+}
+
+// PackPreset writes the bundle made of info and the files named in sources (bundle file name
+// to the path of its contents) to path. The bundle is validated before it is written.
+func PackPreset(path string, info PresetInfo, sources map[string]string) error {
+	for name := range sources {
+		if !slices.Contains(presetFiles, name) || name == PresetInfoFile {
+			return fmt.Errorf("unknown preset file %q", name)
+		}
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	infoYAML, err := yaml.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %v", PresetInfoFile, err)
+	}
+	for _, name := range presetFiles {
+		data := infoYAML
+		if name != PresetInfoFile {
+			src, ok := sources[name]
+			if !ok {
+				continue
+			}
+			if data, err = os.ReadFile(src); err != nil {
+				return fmt.Errorf("failed to read %s: %v", name, err)
+			}
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %v", name, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to add %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write preset: %v", err)
+	}
+	if _, err := parsePreset(path, buf.Bytes()); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write preset: %v", err)
+	}
+	return nil
+}
+
+// isPreset reports whether path names a preset bundle rather than a config file.
+func isPreset(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), PresetExt)
+}
@@ -0,0 +1,241 @@
+package pii
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Profile holds the country-specific assumptions of the detectors: which national
+// identifiers run, the gazetteer and street words that mark an address line, and the regions
+// whose phone numbers are written in national format. A country is added as a Profile value
+// passed to RegisterProfile; its identifiers are data and validators run by the same engine
+// as the built-in detectors.
+type Profile struct {
+	// Name is the country code the profile is selected by, e.g. "IN".
+	Name string
+	// Recognizers is the detector registry in priority order: built-in entity types
+	// (EntityPhone, EntityPAN...) and the entity types of Identifiers.
+	Recognizers []string
+	// Identifiers are the profile's own detectors.
+	Identifiers []Identifier
+	// Places are the state and city names that mark an address line on their own.
+	Places []string
+	// AddressKeywords are the street words that mark an address line together with a second
+	// keyword or a house number.
+	AddressKeywords []string
+	// Names are the given names and surnames the name recognizer takes for a personal name
+	// wherever they are printed.
+	Names []string
+	// PhoneRegions are the regions whose national phone numbers are detected.
+	PhoneRegions []string
+}
+
+// Identifier is a national identifier detector of a Profile.
+type Identifier struct {
+	// Entity is the stable identifier used by WithDisabled / WithPlaceholder.
+	Entity      string
+	Label       string
+	Placeholder string
+	Severity    Severity
+	Pattern     *regexp.Regexp
+	// Validate, when set, rejects matches that fail a structural check.
+	Validate func(string) bool
+	// Confidence is the score of a bare match (defaultConfidence when zero); a Context label
+	// on the line or the lines above raises it and a Checksum raises or lowers it, as for the
+	// built-in detectors.
+	Confidence float64
+	Context    *regexp.Regexp
+	Checksum   func(string) bool
+}
+
+// DefaultProfile is the profile of NewFilter: Indian identifiers, places and phone numbers.
+const DefaultProfile = "IN"
+
+var (
+	profilesMu sync.RWMutex
+	profiles   = map[string]*Profile{
+		"IN": {
+			Name:            "IN",
+			Recognizers:     builtinRecognizers,
+			Places:          addressPlaces,
+			AddressKeywords: addressKeywords,
+			Names:           indianNames,
+			PhoneRegions:    []string{DefaultPhoneRegion},
+		},
+		"US": &usProfile,
+		"GB": &gbProfile,
+	}
+)
+
+// RegisterProfile adds p, or replaces the profile of the same name.
+func RegisterProfile(p Profile) error {
+	if p.Name == "" {
+		return fmt.Errorf("profile: name is required")
+	}
+	for _, id := range p.Identifiers {
+		if id.Entity == "" || id.Pattern == nil {
+			return fmt.Errorf("profile %s: identifier entity and pattern are required", p.Name)
+		}
+		if _, ok := defaultPlaceholders[id.Entity]; ok {
+			return fmt.Errorf("profile %s: identifier %q is a built-in entity type", p.Name, id.Entity)
+		}
+	}
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profiles[strings.ToUpper(p.Name)] = &p
+	return nil
+}
+
+// ProfileNames returns the names of the registered profiles, sorted.
+func ProfileNames() []string {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	return slices.Sorted(maps.Keys(profiles))
+}
+
+// LookupProfile returns the profile registered under name.
+func LookupProfile(name string) (Profile, error) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	p, ok := profiles[strings.ToUpper(name)]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q (expected %s)", name, strings.Join(slices.Sorted(maps.Keys(profiles)), ", "))
+	}
+	return *p, nil
+}
+
+// WithProfile replaces the country assumptions of the filter with those of p: the built-in
+// recognizers, the address and name gazetteers, the address keywords and the phone regions. Detectors and
+// recognizers added by earlier options are kept after the profile's. Apply it before options
+// that change the address keywords or phone regions.
+func WithProfile(p Profile) Option {
+	return func(pf *Filter) {
+		pf.profile = &p
+		var extra []Recognizer
+		for _, r := range pf.recognizers {
+			if _, ok := r.(builtinRecognizer); !ok {
+				extra = append(extra, r)
+			}
+		}
+		pf.recognizers = nil
+		for _, name := range p.Recognizers {
+			pf.recognizers = append(pf.recognizers, builtinRecognizer{name: name, pf: pf})
+		}
+		pf.recognizers = append(pf.recognizers, extra...)
+		pf.AddressPattern = placesPattern(p.Places)
+		pf.personNames = nameSet(p.Names)
+		WithAddressKeywords(p.AddressKeywords...)(pf)
+		WithPhoneRegions(p.PhoneRegions...)(pf)
+	}
+}
+
+// WithPlaces replaces the place names of the profile's address gazetteer, which mark an
+// address line on their own. Apply it after WithProfile.
+func WithPlaces(places ...string) Option {
+	return func(pf *Filter) {
+		p := *pf.profile
+		p.Places = slices.Clone(places)
+		pf.profile = &p
+		pf.AddressPattern = placesPattern(p.Places)
+		if pf.addressMatcher != nil {
+			pf.UseMultiPatternEngine()
+		}
+	}
+}
+
+// placesPattern matches any of the place names of a gazetteer as whole words.
+func placesPattern(places []string) *regexp.Regexp {
+	if len(places) == 0 {
+		// A pattern that never matches.
+		return regexp.MustCompile(`[^\x00-\x{10FFFF}]`)
+	}
+	quoted := make([]string, len(places))
+	for i, p := range places {
+		quoted[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+}
+
+// identifierDetectors returns the detectors of the profile's identifiers.
+func (p *Profile) identifierDetectors() []tokenDetector {
+	detectors := make([]tokenDetector, 0, len(p.Identifiers))
+	for _, id := range p.Identifiers {
+		detectors = append(detectors, tokenDetector{
+			entity:   id.Entity,
+			label:    cmp.Or(id.Label, id.Entity),
+			severity: id.Severity,
+			pattern:  id.Pattern,
+			validate: id.Validate,
+			replace:  placeholder(cmp.Or(id.Placeholder, defaultDetectorPlaceholder)),
+			score: &detectorScore{
+				base:     cmp.Or(id.Confidence, defaultConfidence),
+				context:  id.Context,
+				checksum: id.Checksum,
+			},
+		})
+	}
+	return detectors
+}
+
+// usProfile is the United States profile: Social Security and Employer Identification
+// numbers, states and large cities, and NANP phone numbers.
+var usProfile = Profile{
+	Name:        "US",
+	Recognizers: []string{EntityPhone, EntityEmail, EntitySSN, "ein", EntityDOB, EntityDate, EntityName},
+	Identifiers: []Identifier{{
+		Entity:      "ein",
+		Label:       "US Employer Identification Numbers",
+		Placeholder: "[EIN_REDACTED]",
+		Severity:    SeverityMedium,
+		Pattern:     regexp.MustCompile(`\b\d{2}-\d{7}\b`),
+		Validate:    validEIN,
+		Confidence:  0.6,
+		Context:     regexp.MustCompile(`(?i)\bEIN\b|employer\s+identification|\bFEIN\b`),
+	}},
+	Places: []string{
+		"Alabama", "Alaska", "Arizona", "California", "Colorado", "Connecticut", "Delaware", "Florida",
+		"Georgia", "Hawaii", "Illinois", "Indiana", "Massachusetts", "Michigan", "Minnesota",
+		"New Jersey", "New York", "North Carolina", "Ohio", "Oregon", "Pennsylvania", "Texas",
+		"Virginia", "Washington", "Wisconsin", "Chicago", "Houston", "Los Angeles", "San Francisco",
+		"San Jose", "Seattle", "Boston", "Dallas", "Austin", "Atlanta", "Phoenix", "Philadelphia",
+	},
+	AddressKeywords: []string{
+		"Street", "St", "Avenue", "Ave", "Road", "Rd", "Boulevard", "Blvd", "Drive", "Dr", "Lane",
+		"Ln", "Suite", "Ste", "Apt", "Apartment", "Floor", "Court", "Ct", "Parkway", "Highway",
+	},
+	PhoneRegions: []string{"US", "CA"},
+}
+
+// unassignedEINPrefixes are the EIN prefixes the IRS has never assigned.
+var unassignedEINPrefixes = map[string]bool{
+	"00": true, "07": true, "08": true, "09": true, "17": true, "18": true, "19": true, "28": true,
+	"29": true, "49": true, "69": true, "70": true, "78": true, "79": true, "89": true, "96": true,
+	"97": true,
+}
+
+// validEIN reports whether an EIN starts with an assigned prefix.
+func validEIN(s string) bool {
+	return !unassignedEINPrefixes[s[:2]]
+}
+
+// gbProfile is the United Kingdom profile: National Insurance numbers, nations and large
+// cities, and UK phone numbers.
+var gbProfile = Profile{
+	Name:        "GB",
+	Recognizers: []string{EntityPhone, EntityEmail, EntityNINO, EntityDOB, EntityDate, EntityName},
+	Places: []string{
+		"England", "Scotland", "Wales", "Northern Ireland", "London", "Birmingham", "Manchester",
+		"Leeds", "Glasgow", "Edinburgh", "Liverpool", "Bristol", "Sheffield", "Cardiff", "Belfast",
+		"Newcastle", "Nottingham", "Leicester", "Southampton", "Reading", "Cambridge", "Oxford",
+	},
+	AddressKeywords: []string{
+		"Street", "St", "Road", "Rd", "Lane", "Avenue", "Close", "Crescent", "Flat", "House",
+		"Court", "Drive", "Place", "Square", "Gardens", "Terrace", "Way", "Mews",
+	},
+	PhoneRegions: []string{"GB"},
+}
@@ -0,0 +1,144 @@
+package pii
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ProvenanceExt is appended to the name of an artifact that cannot carry a provenance header,
+// such as a PDF or JSON report, to find its detached header: report.json is described by
+// report.json.provenance.
+const ProvenanceExt = ".provenance"
+
+// The two lines of a provenance header. Text artifacts start with them; other artifacts have
+// them in their ProvenanceExt file.
+const (
+	provenanceLine = "# pdf-reader provenance "
+	signatureLine  = "# pdf-reader signature "
+)
+
+// ErrNoProvenance means an artifact has neither a provenance header nor a ProvenanceExt file.
+var ErrNoProvenance = errors.New("no provenance header")
+
+// Provenance says which run produced a redacted artifact: the tool and its version, the run ID
+// of the manifest, the policy and the hash of the input, so that recipients can trace an
+// artifact back without access to the input or the pipeline.
+type Provenance struct {
+	Tool        string    `json:"tool"`
+	ToolVersion string    `json:"tool_version"`
+	RunID       string    `json:"run_id"`
+	Policy      string    `json:"policy,omitempty"`
+	InputSHA256 string    `json:"input_sha256,omitempty"`
+	Created     time.Time `json:"created"`
+	// SHA256 is the hash of the artifact without its header.
+	SHA256 string `json:"sha256"`
+	// KeyID identifies the signing key as PolicySignature.KeyID does.
+	KeyID string `json:"key_id"`
+}
+
+// SignArtifact signs the artifact at path with key and records p, with its hash and key ID
+// set, as its provenance header: prepended to .txt files, and written to path+ProvenanceExt
+// for every other file. The signature covers the provenance line and the artifact.
+func SignArtifact(path string, p Provenance, key ed25519.PrivateKey) error {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read artifact: %v", err)
+	}
+	sum := sha256.Sum256(body)
+	id := sha256.Sum256(key.Public().(ed25519.PublicKey))
+	p.SHA256, p.KeyID = hex.EncodeToString(sum[:]), hex.EncodeToString(id[:8])
+	b, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode provenance: %v", err)
+	}
+	line := provenanceLine + string(b) + "\n"
+	sig := ed25519.Sign(key, append([]byte(line), body...))
+	header := line + signatureLine + base64.StdEncoding.EncodeToString(sig) + "\n"
+	if embedsProvenance(path) {
+		err = WriteOutput(path, append([]byte(header), body...))
+	} else {
+		err = WriteOutput(path+ProvenanceExt, []byte(header))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write provenance header: %v", err)
+	}
+	return nil
+}
+
+// Artifact is a redacted artifact as read by ReadArtifact.
+type Artifact struct {
+	Provenance Provenance
+	// Body is the artifact without its provenance header.
+	Body []byte
+	// Detached is set when the header was read from the ProvenanceExt file.
+	Detached bool
+
+	line, signature []byte
+}
+
+// ReadArtifact reads the artifact at path and its provenance header, and checks that the
+// artifact was not modified after it was signed. The signature itself is checked by Verify.
+func ReadArtifact(path string) (*Artifact, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %v", err)
+	}
+	a := &Artifact{Body: b}
+	header := b
+	if !embedsProvenance(path) || !bytes.HasPrefix(b, []byte(provenanceLine)) {
+		if header, err = os.ReadFile(path + ProvenanceExt); err != nil {
+			return nil, ErrNoProvenance
+		}
+		a.Detached = true
+	}
+
+	line, rest, ok := bytes.Cut(header, []byte("\n"))
+	sigLine, rest, ok2 := bytes.Cut(rest, []byte("\n"))
+	encoded, ok3 := bytes.CutPrefix(sigLine, []byte(signatureLine))
+	if !ok || !ok2 || !ok3 || !bytes.HasPrefix(line, []byte(provenanceLine)) {
+		return nil, fmt.Errorf("malformed provenance header")
+	}
+	if err := json.Unmarshal(line[len(provenanceLine):], &a.Provenance); err != nil {
+		return nil, fmt.Errorf("malformed provenance header: %v", err)
+	}
+	if a.signature, err = base64.StdEncoding.DecodeString(string(encoded)); err != nil || len(a.signature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("malformed signature in provenance header")
+	}
+	a.line = append(bytes.Clone(line), '\n')
+	if !a.Detached {
+		a.Body = rest
+	}
+	sum := sha256.Sum256(a.Body)
+	if hex.EncodeToString(sum[:]) != a.Provenance.SHA256 {
+		return nil, fmt.Errorf("artifact was modified after it was signed (sha256 does not match the provenance header)")
+	}
+	return a, nil
+}
+
+// Verify checks the signature of the artifact against key.
+func (a *Artifact) Verify(key ed25519.PublicKey) error {
+	id := sha256.Sum256(key)
+	if keyID := hex.EncodeToString(id[:8]); keyID != a.Provenance.KeyID {
+		return fmt.Errorf("artifact is signed by key %s, not %s", a.Provenance.KeyID, keyID)
+	}
+	if !ed25519.Verify(key, append(bytes.Clone(a.line), a.Body...), a.signature) {
+		return fmt.Errorf("signature does not match the artifact")
+	}
+	return nil
+}
+
+// embedsProvenance reports whether the artifact at path carries its provenance header in
+// place: only plain text does, other formats would no longer parse.
+func embedsProvenance(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".txt")
+}
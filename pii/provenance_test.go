@@ -0,0 +1,112 @@
+package pii
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSignArtifact(t *testing.T) {
+	const body = "PAN of the Employee: [PAN_REDACTED]\nGross Salary 1,20,000.00\n"
+	tests := []struct {
+		name string
+		// tamper changes the artifact at path, its header file header or both after signing.
+		tamper func(t *testing.T, path, header string, other ed25519.PrivateKey)
+		// verifyWithOther checks the signature against the other key.
+		verifyWithOther bool
+		wantReadErr     bool
+		wantVerifyErr   bool
+	}{
+		{"signed", func(t *testing.T, path, header string, other ed25519.PrivateKey) {}, false, false, false},
+		{"body modified", func(t *testing.T, path, header string, other ed25519.PrivateKey) {
+			replaceInFile(t, path, "[PAN_REDACTED]", "ABCPE1234F")
+		}, false, true, false},
+		{"provenance edited", func(t *testing.T, path, header string, other ed25519.PrivateKey) {
+			replaceInFile(t, header, `"run_id":"run-1"`, `"run_id":"run-2"`)
+		}, false, false, true},
+		{"signed again with another key", func(t *testing.T, path, header string, other ed25519.PrivateKey) {
+			if path == header {
+				a, err := ReadArtifact(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				writeTestFile(t, path, string(a.Body))
+			}
+			if err := SignArtifact(path, Provenance{Tool: "pdf-reader", RunID: "run-1"}, other); err != nil {
+				t.Fatal(err)
+			}
+		}, false, false, true},
+		{"checked against another key", func(t *testing.T, path, header string, other ed25519.PrivateKey) {}, true, false, true},
+		{"malformed signature", func(t *testing.T, path, header string, other ed25519.PrivateKey) {
+			replaceInFile(t, header, signatureLine, signatureLine+"!")
+		}, false, true, false},
+		{"no header", func(t *testing.T, path, header string, other ed25519.PrivateKey) {
+			if path == header {
+				a, _ := ReadArtifact(path)
+				writeTestFile(t, path, string(a.Body))
+			} else {
+				os.Remove(header)
+			}
+		}, false, true, false},
+	}
+	for _, ext := range []string{".txt", ".json"} {
+		for _, tt := range tests {
+			t.Run(ext+"/"+tt.name, func(t *testing.T) {
+				dir := t.TempDir()
+				priv, pub := testPolicyKeys(t, dir, "sender")
+				other, otherPub := testPolicyKeys(t, dir, "other")
+				path := filepath.Join(dir, "filtered_output"+ext)
+				writeTestFile(t, path, body)
+				if err := SignArtifact(path, Provenance{Tool: "pdf-reader", RunID: "run-1"}, priv); err != nil {
+					t.Fatal(err)
+				}
+				header := path
+				if ext != ".txt" {
+					header = path + ProvenanceExt
+				}
+				tt.tamper(t, path, header, other)
+
+				a, err := ReadArtifact(path)
+				if (err != nil) != tt.wantReadErr {
+					t.Fatalf("ReadArtifact error = %v, wantErr %v", err, tt.wantReadErr)
+				}
+				if err != nil {
+					return
+				}
+				if string(a.Body) != body {
+					t.Errorf("Body = %q, want the artifact without its header", a.Body)
+				}
+				key := pub
+				if tt.verifyWithOther {
+					key = otherPub
+				}
+				if err := a.Verify(key); (err != nil) != tt.wantVerifyErr {
+					t.Errorf("Verify error = %v, wantErr %v", err, tt.wantVerifyErr)
+				}
+			})
+		}
+	}
+}
+
+func TestReadArtifactUnsigned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	writeTestFile(t, path, "{}\n")
+	if _, err := ReadArtifact(path); !errors.Is(err, ErrNoProvenance) {
+		t.Errorf("ReadArtifact = %v, want ErrNoProvenance", err)
+	}
+}
+
+func replaceInFile(t *testing.T, path, old, new string) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), old) {
+		t.Fatalf("%s does not contain %q", path, old)
+	}
+	writeTestFile(t, path, strings.Replace(string(b), old, new, 1))
+}
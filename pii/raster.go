@@ -0,0 +1,198 @@
+package pii
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RasterDPI is the resolution pages are rendered at for the image-only redacted PDF.
+const RasterDPI = 150
+
+// boxPadding widens each redaction box, in points, so anti-aliased glyph edges are covered.
+const boxPadding = 1.5
+
+type rasterPage struct {
+	width, height float64 // page size in points
+	image         image.Image
+}
+
+// SaveRasterRedactedPDF renders every page of src to an image, paints black boxes over the
+// words selected by RedactionBoxes and writes a new PDF containing only those images. No text
+// layer, annotation or metadata of the original survives, which makes this the safe choice
+// for PDFs whose internal structure cannot be edited reliably. It returns the number of
+// boxes drawn. When notice is non-nil it is completed and prepended as a cover page. Under
+// strict validation, images in the header region (the employer's logo and letterhead) are
+// blanked as well; when the PDF cannot be parsed to locate them, the whole header region is.
+// Regions that look handwritten are reported as WarnHandwriting warnings for manual review
+// and, under strict validation, blanked too. Requires pdftotext and pdftoppm from poppler.
+func SaveRasterRedactedPDF(ctx context.Context, pf *Filter, src, password, outputFile string, notice *RedactionNotice, warnings *Warnings) (int, error) {
+	pageBoxes, err := pdftotextWordBoxes(ctx, src, password)
+	if err != nil {
+		return 0, err
+	}
+	images, cleanup, err := rasterizePages(ctx, src, password, RasterDPI)
+	if err != nil {
+		return 0, err
+	}
+	defer cleanup()
+	if len(images) != len(pageBoxes) {
+		return 0, fmt.Errorf("rasterizer produced %d pages but the text layer has %d", len(images), len(pageBoxes))
+	}
+
+	var logos [][]WordBox
+	if pf.strict {
+		if logos, err = letterheadImages(src, password); err != nil || len(logos) != len(pageBoxes) {
+			logos = make([][]WordBox, len(pageBoxes))
+			for i, page := range pageBoxes {
+				logos[i] = []WordBox{headerBand(page)}
+			}
+		}
+	}
+
+	pages := make([]rasterPage, len(images))
+	drawn := 0
+	for i, path := range images {
+		img, err := loadJPEG(path)
+		if err != nil {
+			return 0, err
+		}
+		page := pageBoxes[i]
+		boxes := pf.RedactionBoxes(page)
+		var blank []WordBox
+		if logos != nil {
+			blank = logos[i]
+		}
+		if regions := pf.handwritingRegions(img, page); len(regions) > 0 {
+			warnings.Add(WarnHandwriting, "page %d: %d possibly handwritten region(s) that were not read; review them manually", i+1, len(regions))
+			if pf.strict {
+				blank = append(blank, regions...)
+			}
+		}
+		pages[i] = rasterPage{width: page.Width, height: page.Height, image: paintBoxes(img, page.Width, boxes, blank)}
+		drawn += len(boxes)
+	}
+
+	data := imagePDF(pages, nil)
+	if notice != nil {
+		notice.complete(pf, src, pageBoxes, data)
+		data = imagePDF(pages, notice)
+	}
+	if err := WriteOutput(outputFile, data); err != nil {
+		return 0, fmt.Errorf("failed to write redacted PDF: %v", err)
+	}
+	return drawn, nil
+}
+
+// rasterizePages renders src, opened with password, with pdftoppm into a workspace and returns
+// the page images in document order.
+func rasterizePages(ctx context.Context, src, password string, dpi int) ([]string, func(), error) {
+	ws, err := NewWorkspace("raster")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { ws.Close() }
+
+	prefix := ws.Path("page")
+	args := append(passwordArgs(password), "-r", strconv.Itoa(dpi), "-jpeg", src, prefix)
+	if _, err := exec.CommandContext(ctx, "pdftoppm", args...).Output(); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to rasterize PDF: %w", popplerError(err))
+	}
+	images, _ := filepath.Glob(prefix + "-*.jpg")
+	if len(images) == 0 {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to rasterize PDF: no page images produced")
+	}
+	sort.Slice(images, func(i, j int) bool { return pageNumber(images[i]) < pageNumber(images[j]) })
+	return images, cleanup, nil
+}
+
+// pageNumber extracts N from pdftoppm's "page-N.jpg" file names.
+func pageNumber(path string) int {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	n, _ := strconv.Atoi(name[strings.LastIndex(name, "-")+1:])
+	return n
+}
+
+func loadJPEG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open page image: %v", err)
+	}
+	defer f.Close()
+	img, err := jpeg.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode page image %s: %v", filepath.Base(path), err)
+	}
+	return img, nil
+}
+
+// paintBoxes copies img, fills the blank regions with white and each box with black,
+// converting from points to pixels using the ratio of the image width to the page width.
+func paintBoxes(img image.Image, pageWidth float64, boxes, blank []WordBox) image.Image {
+	bounds := img.Bounds()
+	out := image.NewRGBA(bounds)
+	draw.Draw(out, bounds, img, bounds.Min, draw.Src)
+	if pageWidth <= 0 {
+		return out
+	}
+	scale := float64(bounds.Dx()) / pageWidth
+	fill := func(b WordBox, c color.Color) {
+		r := image.Rect(
+			int((b.XMin-boxPadding)*scale), int((b.YMin-boxPadding)*scale),
+			int((b.XMax+boxPadding)*scale+1), int((b.YMax+boxPadding)*scale+1),
+		).Add(bounds.Min).Intersect(bounds)
+		draw.Draw(out, r, image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	for _, b := range blank {
+		fill(b, color.White)
+	}
+	for _, b := range boxes {
+		fill(b, color.Black)
+	}
+	return out
+}
+
+// imagePDF builds a PDF with one full-page JPEG image per page, preceded by the notice's
+// cover page when notice is non-nil.
+func imagePDF(pages []rasterPage, notice *RedactionNotice) []byte {
+	w := newPDFWriter()
+	catalog, pagesID := w.alloc(), w.alloc()
+
+	var kids []string
+	if notice != nil {
+		fontID := w.alloc()
+		w.writeObject(fontID, courierFont)
+		cover := notice.writeCoverPage(w, pagesID, fontID, pages[0].width, pages[0].height)
+		kids = append(kids, fmt.Sprintf("%d 0 R", cover))
+	}
+	for _, p := range pages {
+		pageID, contentID, imageID := w.alloc(), w.alloc(), w.alloc()
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+
+		var jpg bytes.Buffer
+		jpeg.Encode(&jpg, p.image, &jpeg.Options{Quality: 85})
+		bounds := p.image.Bounds()
+		w.writeStream(imageID, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode",
+			bounds.Dx(), bounds.Dy()), jpg.Bytes())
+
+		width, height := pdfNumber(p.width), pdfNumber(p.height)
+		w.writeStream(contentID, "", []byte(fmt.Sprintf("q %s 0 0 %s 0 0 cm /Im0 Do Q", width, height)))
+		w.writeObject(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesID, width, height, imageID, contentID))
+	}
+	w.writeObject(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	w.writeObject(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	return w.finish(catalog)
+}
@@ -0,0 +1,176 @@
+package pii
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Recognizer finds one kind of personal data in text. The filter keeps a registry of
+// recognizers in priority order: the built-in detectors, named after their entity types
+// (EntityPAN, EntityPhone...), followed by those added with WithExtraDetector and
+// WithRecognizer. When matches of two recognizers overlap, the earlier one wins.
+//
+// A third-party recognizer is reported under its Name, which serves as both entity type
+// (for WithDisabled, WithPlaceholder and the severity defaults) and category label; the
+// filter only uses the Start, End and Confidence of the matches it returns (an unset
+// Confidence scores 0.8), and ignores matches that cross a line break. Address and organisation lines are detected separately and are
+// not part of the registry. Find must be safe for concurrent use.
+type Recognizer interface {
+	Name() string
+	// Find returns the matches in text; Start and End are byte offsets into text.
+	Find(text string) []Match
+}
+
+// builtinRecognizers are the names of the built-in recognizers in their default order. The
+// statement identifiers, PF account codes, identity proof numbers and Indian-script addresses
+// come first: their digits would otherwise be taken for a phone number or a PIN code.
+var builtinRecognizers = []string{
+	EntityBankAccount, EntityFolio, EntityChallan, EntityEmployeeID, EntityESI, EntityIFSC, EntityPFAccount, EntityDrivingLicence, EntityPassport, EntityVoterID, EntityIndicAddress, EntityPhone, EntityEmail,
+	EntityUAN, EntityAadhaar, EntityPAN, EntityGST, EntityTAN, EntityPINCode, EntityDOB, EntityDate,
+	EntityPreviousEmployer, EntityDesignation, EntityName, EntityOrganization, EntityIndicName,
+}
+
+// builtinRecognizer is the registry entry of a built-in detector. Its patterns and policies
+// are those of the filter it belongs to.
+type builtinRecognizer struct {
+	name string
+	pf   *Filter
+}
+
+// Name implements Recognizer.
+func (r builtinRecognizer) Name() string { return r.name }
+
+// Find implements Recognizer. Retained values (GSTRetain) and allowlisted values are not
+// reported.
+func (r builtinRecognizer) Find(text string) []Match {
+	var detectors []tokenDetector
+	for _, d := range r.pf.tokenDetectors() {
+		if d.entity == r.name && d.retainAs == "" {
+			detectors = append(detectors, d)
+		}
+	}
+	var found []Match
+	lines := strings.Split(text, "\n")
+	offset := 0
+	for n, line := range lines {
+		for _, s := range findSpans(lines, n, detectors) {
+			if s.retainAs != "" {
+				continue
+			}
+			found = append(found, r.pf.newMatch(s.detector.entity, s.detector.label, n, offset, line, s.start, s.end, s.confidence))
+		}
+		offset += len(line) + 1
+	}
+	return found
+}
+
+// Name implements Recognizer.
+func (d Detector) Name() string { return d.Type }
+
+// Find implements Recognizer.
+func (d Detector) Find(text string) []Match {
+	label := d.Label
+	if label == "" {
+		label = d.Type
+	}
+	var found []Match
+	offset := 0
+	for n, line := range strings.Split(text, "\n") {
+		for _, loc := range d.Pattern.FindAllStringIndex(line, -1) {
+			if loc[0] < loc[1] {
+				found = append(found, Match{Type: label, Entity: d.Type, Line: n + 1, Start: offset + loc[0], End: offset + loc[1]})
+			}
+		}
+		offset += len(line) + 1
+	}
+	return found
+}
+
+// detectorsFor returns tokenDetectors with the matches of third-party recognizers in text
+// attached, ready for findSpans over the lines of text.
+func (pf *Filter) detectorsFor(text string) []tokenDetector {
+	return pf.timedDetectorsFor(text, nil)
+}
+
+// timedDetectorsFor is detectorsFor with the detectors timed by timer, from the Find of
+// third-party recognizers on.
+func (pf *Filter) timedDetectorsFor(text string, timer detectorTimer) []tokenDetector {
+	detectors := pf.tokenDetectors()
+	var lineStarts []int
+	for i := range detectors {
+		d := &detectors[i]
+		d.timer = timer
+		if d.recognizer == nil {
+			continue
+		}
+		started := timer.start()
+		if lineStarts == nil {
+			lineStarts = []int{0}
+			for i := 0; i < len(text); i++ {
+				if text[i] == '\n' {
+					lineStarts = append(lineStarts, i+1)
+				}
+			}
+		}
+		d.found = make(map[int][][]int)
+		d.scores = make(map[[2]int]float64)
+		for _, m := range d.recognizer.Find(text) {
+			if m.Start < 0 || m.End > len(text) || m.Start >= m.End {
+				continue
+			}
+			n := sort.SearchInts(lineStarts, m.Start+1) - 1
+			if strings.Contains(text[m.Start:m.End], "\n") {
+				continue
+			}
+			d.found[n] = append(d.found[n], []int{m.Start - lineStarts[n], m.End - lineStarts[n]})
+			if m.Confidence > 0 {
+				d.scores[[2]int{n, m.Start - lineStarts[n]}] = m.Confidence
+			}
+		}
+		timer.stop(d.entity, started)
+	}
+	return detectors
+}
+
+// Recognizers returns the registry in priority order.
+func (pf *Filter) Recognizers() []Recognizer {
+	return slices.Clone(pf.recognizers)
+}
+
+// WithRecognizer registers r. A recognizer of the same name, built-in or not, is replaced and
+// r takes over its priority; otherwise r runs after the recognizers registered so far.
+func WithRecognizer(r Recognizer) Option {
+	return func(pf *Filter) {
+		for i, old := range pf.recognizers {
+			if old.Name() == r.Name() {
+				pf.recognizers[i] = r
+				return
+			}
+		}
+		pf.recognizers = append(pf.recognizers, r)
+	}
+}
+
+// WithoutRecognizers removes the named recognizers from the registry.
+func WithoutRecognizers(names ...string) Option {
+	return func(pf *Filter) {
+		pf.recognizers = slices.DeleteFunc(pf.recognizers, func(r Recognizer) bool {
+			return slices.Contains(names, r.Name())
+		})
+	}
+}
+
+// WithRecognizerOrder moves the named recognizers to the front of the registry in the given
+// order; the others keep their relative order behind them. Unknown names are ignored.
+func WithRecognizerOrder(names ...string) Option {
+	return func(pf *Filter) {
+		rank := func(r Recognizer) int {
+			if i := slices.Index(names, r.Name()); i >= 0 {
+				return i
+			}
+			return len(names)
+		}
+		slices.SortStableFunc(pf.recognizers, func(a, b Recognizer) int { return rank(a) - rank(b) })
+	}
+}
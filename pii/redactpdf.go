@@ -0,0 +1,154 @@
+package pii
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// courierAdvance is the advance width of every Courier glyph as a fraction of the font size.
+const courierAdvance = 0.6
+
+// SaveRedactedPDF writes a redacted copy of src that keeps a selectable text layer. Word
+// positions come from the native extractor; every page is rebuilt from scratch with the
+// words that survive redaction redrawn in Courier, stretched to their original width, and
+// black boxes drawn over the words selected by RedactionBoxes. Nothing from the original
+// content streams, fonts, images or metadata is copied, so redacted text cannot be recovered
+// from the file. Page graphics such as table rules and logos are not reproduced; use
+// SaveRasterRedactedPDF when visual fidelity matters. When notice is non-nil it is completed
+// and prepended as a cover page. An encrypted src is opened with password; the output is not
+// encrypted. It returns the number of boxes drawn.
+func SaveRedactedPDF(ctx context.Context, pf *Filter, src, password, outputFile string, notice *RedactionNotice) (int, error) {
+	doc, err := loadPDF(src, password)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PDF: %v", err)
+	}
+	pages, err := nativeWordBoxes(doc)
+	if err != nil {
+		return 0, fmt.Errorf("failed to locate words: %v", err)
+	}
+	words := 0
+	for _, p := range pages {
+		words += len(p.Words)
+	}
+	if words == 0 {
+		return 0, fmt.Errorf("failed to locate words: the PDF has no text layer")
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	data, drawn := textPDF(pf, pages, nil)
+	if notice != nil {
+		notice.complete(pf, src, pages, data)
+		data, _ = textPDF(pf, pages, notice)
+	}
+	if err := WriteOutput(outputFile, data); err != nil {
+		return 0, fmt.Errorf("failed to write redacted PDF: %v", err)
+	}
+	return drawn, nil
+}
+
+// textPDF builds the text-preserving redacted PDF and returns it with the number of boxes.
+func textPDF(pf *Filter, pages []PageBoxes, notice *RedactionNotice) ([]byte, int) {
+	w := newPDFWriter()
+	catalog, pagesID, fontID := w.alloc(), w.alloc(), w.alloc()
+	w.writeObject(fontID, courierFont)
+
+	var kids []string
+	if notice != nil {
+		cover := notice.writeCoverPage(w, pagesID, fontID, pages[0].Width, pages[0].Height)
+		kids = append(kids, fmt.Sprintf("%d 0 R", cover))
+	}
+	drawn := 0
+	for _, page := range pages {
+		pageID, contentID := w.alloc(), w.alloc()
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+
+		boxes := pf.RedactionBoxes(page)
+		redacted := make(map[WordBox]bool, len(boxes))
+		for _, b := range boxes {
+			redacted[b] = true
+		}
+		w.writeStream(contentID, "", []byte(redactedPageContent(page, redacted)))
+		w.writeObject(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesID, pdfNumber(page.Width), pdfNumber(page.Height), fontID, contentID))
+		drawn += len(boxes)
+	}
+	w.writeObject(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	w.writeObject(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	return w.finish(catalog), drawn
+}
+
+// redactedPageContent draws the kept words as text and the redacted ones as filled boxes.
+// WordBox coordinates have a top-left origin and are flipped to PDF user space here.
+func redactedPageContent(page PageBoxes, redacted map[WordBox]bool) string {
+	var b strings.Builder
+	for _, word := range page.Words {
+		if redacted[word] {
+			continue
+		}
+		size := word.YMax - word.YMin
+		n := len([]rune(word.Text))
+		if size <= 0 || n == 0 {
+			continue
+		}
+		scale := (word.XMax - word.XMin) / (float64(n) * courierAdvance * size) * 100
+		baseline := page.Height - word.YMax + size*0.2
+		fmt.Fprintf(&b, "BT /F1 %s Tf %s Tz %s %s Td (%s) Tj ET\n",
+			pdfNumber(size), pdfNumber(scale), pdfNumber(word.XMin), pdfNumber(baseline), winAnsiString(word.Text))
+	}
+	b.WriteString("0 g\n")
+	for _, word := range page.Words {
+		if !redacted[word] {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s %s %s re f\n",
+			pdfNumber(word.XMin-boxPadding), pdfNumber(page.Height-word.YMax-boxPadding),
+			pdfNumber(word.XMax-word.XMin+2*boxPadding), pdfNumber(word.YMax-word.YMin+2*boxPadding))
+	}
+	return b.String()
+}
+
+// winAnsiString encodes text as the body of a PDF literal string. Characters outside
+// Latin-1 cannot be shown with a standard font and are replaced by '?'.
+func winAnsiString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0xff:
+			b.WriteByte('?')
+		case r < 0x80:
+			b.WriteRune(r)
+		default:
+			fmt.Fprintf(&b, "\\%03o", r)
+		}
+	}
+	return b.String()
+}
+
+// SaveRedactedPDFMode writes the redacted PDF with the engine chosen by -pdf-mode. In "auto"
+// mode the text-preserving writer is tried first and the page rasterizer is used when the
+// PDF cannot be parsed natively, provided pdftoppm is installed. Both open an encrypted src
+// with password.
+func SaveRedactedPDFMode(ctx context.Context, mode string, pf *Filter, src, password, outputFile string, notice *RedactionNotice, warnings *Warnings) (int, error) {
+	switch mode {
+	case "text":
+		return SaveRedactedPDF(ctx, pf, src, password, outputFile, notice)
+	case "raster":
+		return SaveRasterRedactedPDF(ctx, pf, src, password, outputFile, notice, warnings)
+	}
+	boxes, err := SaveRedactedPDF(ctx, pf, src, password, outputFile, notice)
+	if err == nil {
+		return boxes, nil
+	}
+	if _, lookErr := exec.LookPath("pdftoppm"); lookErr != nil {
+		return 0, err
+	}
+	warnings.Add(WarnRedactedPDFFallback, "%v; rasterizing pages instead", err)
+	return SaveRasterRedactedPDF(ctx, pf, src, password, outputFile, notice, warnings)
+}
@@ -0,0 +1,173 @@
+package pii
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// HeaderFooterPolicy controls how lines repeated at the top or bottom of the pages, such as
+// the certificate number, PAN and TAN that TRACES prints on every page, are treated.
+type HeaderFooterPolicy string
+
+const (
+	// HeaderFooterKeep redacts and counts every occurrence (default).
+	HeaderFooterKeep HeaderFooterPolicy = "keep"
+	// HeaderFooterDedupe keeps the first occurrence in the cleaned text and drops the others;
+	// their findings are counted once and the line is listed in RepeatedLines.
+	HeaderFooterDedupe HeaderFooterPolicy = "dedupe"
+	// HeaderFooterRemove drops every occurrence from the cleaned text; the redacted line is
+	// only listed in RepeatedLines, with the findings of its first occurrence.
+	HeaderFooterRemove HeaderFooterPolicy = "remove"
+)
+
+// ParseHeaderFooterPolicy validates a policy name supplied on the command line.
+func ParseHeaderFooterPolicy(s string) (HeaderFooterPolicy, error) {
+	switch p := HeaderFooterPolicy(strings.ToLower(s)); p {
+	case HeaderFooterKeep, HeaderFooterDedupe, HeaderFooterRemove:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown header/footer policy %q (expected keep, dedupe or remove)", s)
+}
+
+// RepeatedLine is a header or footer line found on several pages.
+type RepeatedLine struct {
+	// Text is the redacted text of the first occurrence.
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+	// Lines are the 1-based line numbers of the occurrences in the input text.
+	Lines []int `json:"lines"`
+}
+
+// edgeLines is how many non-blank lines at the top and at the bottom of a page are
+// considered header or footer lines.
+const edgeLines = 3
+
+// repeatedLines finds the header and footer lines of text, whose pages are separated by form
+// feeds. Lines are compared with their whitespace collapsed and digits ignored, so page
+// numbers do not tell them apart. A line is repeated when it is at the edge of at least two
+// pages and of at least half the pages. It returns the 0-based line numbers of the
+// occurrences of every repeated line, in order of first occurrence.
+func repeatedLines(text string) [][]int {
+	lines := strings.Split(text, "\n")
+	var pages [][]int
+	page := []int{}
+	for n, line := range lines {
+		if strings.Contains(line, "\f") && len(page) > 0 {
+			pages = append(pages, page)
+			page = []int{}
+		}
+		if strings.TrimSpace(strings.ReplaceAll(line, "\f", "")) != "" {
+			page = append(page, n)
+		}
+	}
+	pages = append(pages, page)
+	if len(pages) < 2 {
+		return nil
+	}
+
+	occurrences := make(map[string][]int)
+	pageCount := make(map[string]int)
+	var keys []string
+	for _, page := range pages {
+		edges := page
+		if len(page) > 2*edgeLines {
+			edges = append(slices.Clone(page[:edgeLines]), page[len(page)-edgeLines:]...)
+		}
+		seen := make(map[string]bool)
+		for _, n := range edges {
+			key := lineKey(lines[n])
+			if _, ok := occurrences[key]; !ok {
+				keys = append(keys, key)
+			}
+			occurrences[key] = append(occurrences[key], n)
+			if !seen[key] {
+				seen[key] = true
+				pageCount[key]++
+			}
+		}
+	}
+	var repeated [][]int
+	for _, key := range keys {
+		if c := pageCount[key]; c >= 2 && 2*c >= len(pages) {
+			repeated = append(repeated, occurrences[key])
+		}
+	}
+	return repeated
+}
+
+// lineKey normalises a line for comparison across pages.
+func lineKey(line string) string {
+	key := strings.Join(strings.Fields(strings.ReplaceAll(line, "\f", "")), " ")
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return '#'
+		}
+		return r
+	}, key)
+}
+
+// dedupeRepeatedLines applies the HeaderFooters policy to a result for text: the findings
+// on all but the first occurrence of each repeated line are dropped and the line is listed
+// in RepeatedLines. It returns the 0-based numbers of the lines to leave out of the cleaned
+// text; nil under HeaderFooterKeep.
+func (pf *Filter) dedupeRepeatedLines(text string, result *FilteredData) map[int]bool {
+	if pf.HeaderFooters == "" || pf.HeaderFooters == HeaderFooterKeep {
+		return nil
+	}
+	cleaned := strings.Split(result.CleanedText, "\n")
+	dropped := make(map[int]bool)
+	for _, occurrences := range repeatedLines(text) {
+		r := RepeatedLine{Count: len(occurrences)}
+		if first := occurrences[0]; first < len(cleaned) {
+			r.Text = strings.TrimSpace(strings.ReplaceAll(cleaned[first], "\f", ""))
+		}
+		for i, n := range occurrences {
+			r.Lines = append(r.Lines, n+1)
+			if i > 0 || pf.HeaderFooters == HeaderFooterRemove {
+				dropped[n] = true
+			}
+		}
+		result.RepeatedLines = append(result.RepeatedLines, r)
+		repeats := occurrences[1:]
+		result.Matches = slices.DeleteFunc(result.Matches, func(m Match) bool {
+			if slices.Contains(repeats, m.Line-1) {
+				result.MatchCounts[m.Type]--
+				return true
+			}
+			return false
+		})
+	}
+	// Repeats differing in digits may hold findings the first occurrence lacks.
+	result.RemovedFields = slices.DeleteFunc(result.RemovedFields, func(label string) bool {
+		return result.MatchCounts[label] <= 0
+	})
+	for label, n := range result.MatchCounts {
+		if n <= 0 {
+			delete(result.MatchCounts, label)
+		}
+	}
+	return dropped
+}
+
+// dropLines removes the given lines from a text. A page break on a dropped line is kept.
+func dropLines(text string, dropped map[int]bool) string {
+	if len(dropped) == 0 {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	feeds := ""
+	for n, line := range lines {
+		if dropped[n] {
+			feeds += strings.Repeat("\f", strings.Count(line, "\f"))
+			continue
+		}
+		out = append(out, feeds+line)
+		feeds = ""
+	}
+	if feeds != "" {
+		out = append(out, feeds)
+	}
+	return strings.Join(out, "\n")
+}
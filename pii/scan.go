@@ -0,0 +1,229 @@
+package pii
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unsafe"
+)
+
+// ScanStats summarises a streaming scan.
+type ScanStats struct {
+	Lines int
+	Bytes int64
+	// MatchCounts holds the number of replacements per category label.
+	MatchCounts map[string]int
+}
+
+// Matches returns the total number of replacements.
+func (s ScanStats) Matches() int {
+	n := 0
+	for _, c := range s.MatchCounts {
+		n += c
+	}
+	return n
+}
+
+// lineScanner redacts a stream line by line with the detectors of a filter. Label context is
+// looked up in the lines above, so it keeps the last contextWindow lines.
+type lineScanner struct {
+	pf        *Filter
+	detectors []tokenDetector
+	custom    bool
+	window    []string
+	w         *bufio.Writer
+	stats     ScanStats
+}
+
+func (pf *Filter) newLineScanner(w io.Writer) *lineScanner {
+	// A stream is one document: numbered tokens count from 1 in it, in line order.
+	doc := pf.forDocument("")
+	s := &lineScanner{
+		pf:        doc,
+		detectors: doc.tokenDetectors(),
+		window:    make([]string, 0, contextWindow+1),
+		w:         bufio.NewWriterSize(w, 1<<20),
+		stats:     ScanStats{MatchCounts: make(map[string]int)},
+	}
+	for _, d := range s.detectors {
+		s.custom = s.custom || d.recognizer != nil
+	}
+	return s
+}
+
+// scanLine redacts one line, without its line break, and writes it. Lines without findings
+// are written as they are, so scanning clean text allocates nothing per line.
+func (s *lineScanner) scanLine(line string, last bool) {
+	if len(s.window) == cap(s.window) {
+		copy(s.window, s.window[1:])
+		s.window = s.window[:len(s.window)-1]
+	}
+	s.window = append(s.window, line)
+	n := len(s.window) - 1
+	if s.custom {
+		for i := range s.detectors {
+			d := &s.detectors[i]
+			if d.recognizer == nil {
+				continue
+			}
+			d.found = map[int][][]int{n: nil}
+			d.scores = make(map[[2]int]float64)
+			for _, m := range d.recognizer.Find(line) {
+				if m.Start >= 0 && m.End <= len(line) && m.Start < m.End {
+					d.found[n] = append(d.found[n], []int{m.Start, m.End})
+					if m.Confidence > 0 {
+						d.scores[[2]int{n, m.Start}] = m.Confidence
+					}
+				}
+			}
+		}
+	}
+
+	spans := findSpans(s.window, n, s.detectors)
+	for _, sp := range spans {
+		if sp.retainAs == "" {
+			s.stats.MatchCounts[sp.detector.label]++
+		}
+	}
+	out := applySpans(line, spans)
+	trimmed := strings.TrimSpace(out)
+	switch {
+	case trimmed == "", s.pf.lineAllowed(line):
+	case s.pf.lineEnabled(EntityOrganization) && s.pf.OrganizationPattern.MatchString(trimmed):
+		out = s.pf.placeholderFor(EntityOrganization)
+		s.stats.MatchCounts[labelOrganization]++
+	case s.pf.lineEnabled(EntityAddress) && s.pf.isAddressLine(trimmed):
+		columns, whole := s.pf.addressColumns(line)
+		if whole {
+			out = s.pf.placeholderFor(EntityAddress)
+			s.stats.MatchCounts[labelAddress]++
+			break
+		}
+		regions := make([]span, len(columns))
+		for i, c := range columns {
+			regions[i] = span{start: c[0], end: c[1], replacement: s.pf.placeholderFor(EntityAddress), custom: true}
+		}
+		out = applySpans(line, withRegions(spans, regions))
+		s.stats.MatchCounts[labelAddress] += len(columns)
+	}
+	s.w.WriteString(out)
+	if !last {
+		s.w.WriteByte('\n')
+	}
+	s.stats.Lines++
+	s.stats.Bytes += int64(len(line))
+	if !last {
+		s.stats.Bytes++
+	}
+}
+
+// ScanReader redacts r line by line into w with the filter's detectors and the line-level
+// address and organisation checks. Unlike FilterPII it never holds more than a few lines in
+// memory, which suits multi-GB logs and exports; it builds no match list or report.
+func (pf *Filter) ScanReader(r io.Reader, w io.Writer) (ScanStats, error) {
+	s := pf.newLineScanner(w)
+	br := bufio.NewReaderSize(r, 1<<20)
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF {
+			if line != "" {
+				s.scanLine(line, true)
+			}
+			break
+		}
+		if err != nil {
+			return s.stats, fmt.Errorf("failed to read input: %v", err)
+		}
+		s.scanLine(line[:len(line)-1], false)
+	}
+	if err := s.w.Flush(); err != nil {
+		return s.stats, fmt.Errorf("failed to write output: %v", err)
+	}
+	return s.stats, nil
+}
+
+// ScanBytes is ScanReader over data already in memory, typically a file mapped with MapFile.
+// The detectors run directly on the bytes of data without copying each line into a string,
+// so data must not change during the scan.
+func (pf *Filter) ScanBytes(data []byte, w io.Writer) (ScanStats, error) {
+	s := pf.newLineScanner(w)
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		last := end < 0
+		if last {
+			end = len(data)
+		}
+		s.scanLine(unsafe.String(unsafe.SliceData(data), end), last)
+		if last {
+			break
+		}
+		data = data[end+1:]
+	}
+	if err := s.w.Flush(); err != nil {
+		return s.stats, fmt.Errorf("failed to write output: %v", err)
+	}
+	return s.stats, nil
+}
+
+// MaxChunkLine is the longest line a ChunkScanner buffers while waiting for its line break.
+const MaxChunkLine = 1 << 20
+
+// ChunkScanner is the line scanner of ScanReader fed with text in chunks of any size, for
+// callers that receive the text piecewise, such as a streaming RPC. Each Write redacts and
+// writes the lines completed by the chunk; Close redacts the last, unterminated line.
+type ChunkScanner struct {
+	s       *lineScanner
+	partial bytes.Buffer
+}
+
+// NewChunkScanner returns a ChunkScanner writing the redacted text to w.
+func (pf *Filter) NewChunkScanner(w io.Writer) *ChunkScanner {
+	return &ChunkScanner{s: pf.newLineScanner(w)}
+}
+
+// Write redacts the lines completed by p and flushes them to the underlying writer. A line
+// growing past MaxChunkLine fails with ErrLineTooLong rather than being buffered on: cutting
+// it could split a value the detectors would otherwise find.
+func (c *ChunkScanner) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		end := bytes.IndexByte(p, '\n')
+		if end < 0 {
+			end = len(p)
+		}
+		if c.partial.Len()+end > MaxChunkLine {
+			return n - len(p), fmt.Errorf("%w: over %d bytes without a line break", ErrLineTooLong, MaxChunkLine)
+		}
+		if end == len(p) {
+			c.partial.Write(p)
+			break
+		}
+		if c.partial.Len() == 0 {
+			c.s.scanLine(string(p[:end]), false)
+		} else {
+			c.partial.Write(p[:end])
+			c.s.scanLine(c.partial.String(), false)
+			c.partial.Reset()
+		}
+		p = p[end+1:]
+	}
+	if err := c.s.w.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to write output: %v", err)
+	}
+	return n, nil
+}
+
+// Close redacts the text after the last line break, if any, and returns the statistics of
+// the scan.
+func (c *ChunkScanner) Close() (ScanStats, error) {
+	if c.partial.Len() > 0 {
+		c.s.scanLine(c.partial.String(), true)
+		c.partial.Reset()
+	}
+	if err := c.s.w.Flush(); err != nil {
+		return c.s.stats, fmt.Errorf("failed to write output: %v", err)
+	}
+	return c.s.stats, nil
+}
@@ -0,0 +1,49 @@
+package pii
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestChunkScannerMatchesScanReader(t *testing.T) {
+	text := strings.Join(testPages(2), "\n") + "last line without a break"
+	var want bytes.Buffer
+	if _, err := NewFilter().ScanReader(strings.NewReader(text), &want); err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{1, 7, 64, len(text)} {
+		var got bytes.Buffer
+		c := NewFilter().NewChunkScanner(&got)
+		for rest := text; rest != ""; {
+			n := min(size, len(rest))
+			if _, err := c.Write([]byte(rest[:n])); err != nil {
+				t.Fatal(err)
+			}
+			rest = rest[n:]
+		}
+		if _, err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("chunks of %d bytes:\n%s\nwant:\n%s", size, got.String(), want.String())
+		}
+	}
+}
+
+func TestChunkScannerLineTooLong(t *testing.T) {
+	var out bytes.Buffer
+	c := NewFilter().NewChunkScanner(&out)
+	chunk := bytes.Repeat([]byte("x"), MaxChunkLine/4)
+	var err error
+	for i := 0; i < 5 && err == nil; i++ {
+		_, err = c.Write(chunk)
+	}
+	if !errors.Is(err, ErrLineTooLong) {
+		t.Fatalf("Write of a %d-byte line = %v, want ErrLineTooLong", 5*len(chunk), err)
+	}
+	if c.partial.Len() > MaxChunkLine {
+		t.Errorf("buffered %d bytes, want at most %d", c.partial.Len(), MaxChunkLine)
+	}
+}
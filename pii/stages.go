@@ -0,0 +1,218 @@
+package pii
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Built-in stages of a pipeline definition (see Config.Pipeline).
+const (
+	// StageNormalize rewrites look-alike characters of extracted text (see NormalizeText).
+	StageNormalize = "normalize"
+	// StageDetect runs the filter's detectors.
+	StageDetect = "detect"
+	// StageDictionary redacts words missing from the word list (see ApplyDictionaryFilter).
+	// It is left out of DefaultStages: the name recognizer of StageDetect removes likely
+	// names without the tax terms and abbreviations around them.
+	StageDictionary = "dictionary"
+	// StageVerify re-scans the cleaned text (see VerifyRedacted).
+	StageVerify = "verify"
+	// StageOutputs writes the outputs of the run; it is always the last stage.
+	StageOutputs = "outputs"
+)
+
+// Stage is one step of a pipeline definition: a built-in stage, a plugin registered with
+// RegisterStage, or an external command. In a config file it may be written as just its name.
+type Stage struct {
+	Name string `json:"name" yaml:"name"`
+	// Command, when set, runs the stage as an external program: the text is written to its
+	// standard input and its standard output replaces it. A non-zero exit fails the document.
+	Command []string `json:"command,omitempty" yaml:"command,omitempty"`
+}
+
+// UnmarshalJSON accepts a stage object or a bare stage name.
+func (s *Stage) UnmarshalJSON(b []byte) error {
+	var name string
+	if json.Unmarshal(b, &name) == nil {
+		*s = Stage{Name: name}
+		return nil
+	}
+	type stage Stage
+	return json.Unmarshal(b, (*stage)(s))
+}
+
+// UnmarshalYAML accepts a stage mapping or a bare stage name.
+func (s *Stage) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = Stage{Name: node.Value}
+		return nil
+	}
+	type stage Stage
+	return node.Decode((*stage)(s))
+}
+
+// Stages is the ordered stage list of a run. Stages before detect rewrite the extracted text;
+// stages after it rewrite the cleaned text.
+type Stages []Stage
+
+// DefaultStages is the pipeline of runs whose config defines none.
+var DefaultStages = Stages{{Name: StageNormalize}, {Name: StageDetect}, {Name: StageVerify}, {Name: StageOutputs}}
+
+// String lists the stage names in order, e.g. "normalize → detect → outputs".
+func (s Stages) String() string {
+	names := make([]string, len(s))
+	for i, st := range s {
+		names[i] = st.Name
+	}
+	return strings.Join(names, " → ")
+}
+
+// Has reports whether the stage name is part of s.
+func (s Stages) Has(name string) bool {
+	return slices.ContainsFunc(s, func(st Stage) bool { return st.Name == name })
+}
+
+// StageFunc is a plugin stage: it returns text rewritten.
+type StageFunc func(ctx context.Context, text string) (string, error)
+
+var (
+	stageFuncsMu sync.RWMutex
+	stageFuncs   = map[string]StageFunc{}
+)
+
+// RegisterStage adds a plugin stage that pipeline definitions can name, or replaces the
+// plugin of the same name. Built-in stage names cannot be taken.
+func RegisterStage(name string, fn StageFunc) error {
+	if name == "" || fn == nil {
+		return fmt.Errorf("stage: name and function are required")
+	}
+	if isBuiltinStage(name) {
+		return fmt.Errorf("stage %q is a built-in stage", name)
+	}
+	stageFuncsMu.Lock()
+	defer stageFuncsMu.Unlock()
+	stageFuncs[name] = fn
+	return nil
+}
+
+func isBuiltinStage(name string) bool {
+	switch name {
+	case StageNormalize, StageDetect, StageDictionary, StageVerify, StageOutputs:
+		return true
+	}
+	return false
+}
+
+// validate checks that detect, verify and outputs appear once each with outputs last, that
+// normalize comes before detect and the dictionary and verify after it, and that every other
+// stage is a command or a registered plugin.
+func (s Stages) validate() error {
+	seen := make(map[string]bool)
+	for i, st := range s {
+		if seen[st.Name] {
+			return fmt.Errorf("stage %q appears twice", st.Name)
+		}
+		seen[st.Name] = true
+		switch {
+		case st.Name == "":
+			return fmt.Errorf("stage %d: name is required", i+1)
+		case isBuiltinStage(st.Name) && len(st.Command) > 0:
+			return fmt.Errorf("stage %q is built in and takes no command", st.Name)
+		case st.Name == StageNormalize && seen[StageDetect]:
+			return fmt.Errorf("stage %q must come before %q", StageNormalize, StageDetect)
+		case (st.Name == StageDictionary || st.Name == StageVerify) && !seen[StageDetect]:
+			return fmt.Errorf("stage %q must come after %q", st.Name, StageDetect)
+		case !isBuiltinStage(st.Name) && len(st.Command) == 0:
+			stageFuncsMu.RLock()
+			_, ok := stageFuncs[st.Name]
+			stageFuncsMu.RUnlock()
+			if !ok {
+				return fmt.Errorf("stage %q is neither built in nor registered, and has no command", st.Name)
+			}
+		}
+	}
+	for _, required := range []string{StageDetect, StageVerify, StageOutputs} {
+		if !seen[required] {
+			return fmt.Errorf("stage %q is required", required)
+		}
+	}
+	if s[len(s)-1].Name != StageOutputs {
+		return fmt.Errorf("stage %q must be the last stage", StageOutputs)
+	}
+	return nil
+}
+
+// Split returns the stages before detect and the stages between detect and outputs.
+func (s Stages) Split() (before, after Stages) {
+	i := slices.IndexFunc(s, func(st Stage) bool { return st.Name == StageDetect })
+	if i < 0 {
+		return s, nil
+	}
+	after = slices.DeleteFunc(slices.Clone(s[i+1:]), func(st Stage) bool { return st.Name == StageOutputs })
+	return s[:i], after
+}
+
+// Rewrite runs a stage that rewrites text: normalize, a registered plugin or a command.
+// Other built-in stages return text unchanged.
+func (st Stage) Rewrite(ctx context.Context, text string) (string, error) {
+	if len(st.Command) > 0 {
+		cmd := exec.CommandContext(ctx, st.Command[0], st.Command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		cmd.Stderr = os.Stderr
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("stage %s: %v", st.Name, err)
+		}
+		return out.String(), nil
+	}
+	if st.Name == StageNormalize {
+		return NormalizeText(text), nil
+	}
+	stageFuncsMu.RLock()
+	fn, ok := stageFuncs[st.Name]
+	stageFuncsMu.RUnlock()
+	if !ok {
+		return text, nil
+	}
+	out, err := fn(ctx, text)
+	if err != nil {
+		return "", fmt.Errorf("stage %s: %v", st.Name, err)
+	}
+	return out, nil
+}
+
+// normalizeReplacer maps the look-alike characters PDF text layers are full of to the ASCII the
+// detectors expect: non-breaking spaces, Unicode hyphens, typographic ligatures and invisible
+// characters that would split a PAN or a name.
+var normalizeReplacer = func() *strings.Replacer {
+	pairs := []string{
+		"\r\n", "\n", "\r", "\n",
+		"\u00a0", " ", "\u2007", " ", "\u202f", " ",
+		"\u2010", "-", "\u2011", "-", "\u2012", "-", "\u2013", "-", "\u2212", "-",
+		"\ufb00", "ff", "\ufb01", "fi", "\ufb02", "fl", "\ufb03", "ffi", "\ufb04", "ffl", "\ufb05", "st", "\ufb06", "st",
+		"\u00ad", "", "\u200b", "", "\ufeff", "",
+	}
+	// Fullwidth ASCII, as in ＡＢＣＤＥ１２３４Ｆ.
+	for r := rune(0xff01); r <= 0xff5e; r++ {
+		pairs = append(pairs, string(r), string(r-0xff01+'!'))
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// NormalizeText rewrites the look-alike characters of extracted text to the ASCII the
+// detectors match: non-breaking spaces, Unicode hyphens, ligatures, fullwidth letters and
+// digits, soft hyphens and zero-width spaces, and CR line breaks. Form feeds are kept. Match
+// offsets refer to the normalised text.
+func NormalizeText(text string) string {
+	return normalizeReplacer.Replace(text)
+}
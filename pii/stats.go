@@ -0,0 +1,125 @@
+package pii
+
+import (
+	"cmp"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextStats describes the extracted text and what redaction left of it.
+type TextStats struct {
+	// Characters and Words count the extracted text before redaction; words are separated by
+	// white space.
+	Characters int `json:"characters"`
+	Words      int `json:"words"`
+	// Pages has the same counts per page, for text with form-feed page breaks.
+	Pages []PageStats `json:"pages"`
+	// DictionaryWords is the number of words the dictionary filter looked up (longer than
+	// three letters) and DictionaryHits those it found; both are zero until
+	// ApplyDictionaryFilter has run.
+	DictionaryWords   int     `json:"dictionary_words"`
+	DictionaryHits    int     `json:"dictionary_hits"`
+	DictionaryHitRate float64 `json:"dictionary_hit_rate"`
+	// Placeholders counts the placeholders in the cleaned text. Vault tokens are counted
+	// per entity type under a key such as "[PAN_n]".
+	Placeholders map[string]int `json:"placeholders"`
+}
+
+// PageStats holds the counts of one page; Page is 1-based.
+type PageStats struct {
+	Page       int `json:"page"`
+	Characters int `json:"characters"`
+	Words      int `json:"words"`
+}
+
+// PlaceholderTotal returns the number of placeholders in the cleaned text.
+func (s TextStats) PlaceholderTotal() int {
+	total := 0
+	for _, n := range s.Placeholders {
+		total += n
+	}
+	return total
+}
+
+// textStats counts the characters and words of text, in total and per page.
+func textStats(text string) TextStats {
+	stats := TextStats{Placeholders: make(map[string]int)}
+	for i, page := range strings.Split(text, "\f") {
+		p := PageStats{Page: i + 1, Characters: utf8.RuneCountInString(page), Words: len(strings.Fields(page))}
+		stats.Characters += p.Characters
+		stats.Words += p.Words
+		stats.Pages = append(stats.Pages, p)
+	}
+	// The form feeds between pages are characters of the text too.
+	stats.Characters += len(stats.Pages) - 1
+	return stats
+}
+
+// placeholderSet returns the placeholders the filter can write, sorted.
+func (pf *Filter) placeholderSet() []string {
+	seen := make(map[string]bool)
+	for entity := range defaultPlaceholders {
+		seen[pf.placeholderFor(entity)] = true
+	}
+	for entity := range pf.placeholders {
+		seen[pf.placeholderFor(entity)] = true
+	}
+	for _, d := range pf.profile.identifierDetectors() {
+		seen[pf.placeholderFor(d.entity)] = true
+		seen[d.replace("")] = true
+	}
+	for _, r := range pf.recognizers {
+		switch r := r.(type) {
+		case builtinRecognizer:
+		case Detector:
+			seen[r.Placeholder] = true
+		default:
+			seen[cmp.Or(pf.placeholderFor(r.Name()), defaultDetectorPlaceholder)] = true
+		}
+	}
+	delete(seen, "")
+	set := make([]string, 0, len(seen))
+	for p := range seen {
+		set = append(set, p)
+	}
+	sort.Strings(set)
+	return set
+}
+
+// vaultTokenPattern matches the tokens written by a Vault.
+var vaultTokenPattern = regexp.MustCompile(`\[([A-Z_]+)_\d+\]`)
+
+// countPlaceholders recounts Stats.Placeholders from the cleaned text.
+func (d *FilteredData) countPlaceholders() {
+	counts := make(map[string]int)
+	for _, p := range d.placeholders {
+		if n := strings.Count(d.CleanedText, p); n > 0 {
+			counts[p] = n
+		}
+	}
+	if d.tokenized {
+		for _, m := range vaultTokenPattern.FindAllStringSubmatch(d.CleanedText, -1) {
+			counts["["+m[1]+"_n]"]++
+		}
+	}
+	d.Stats.Placeholders = counts
+}
+
+// dictionaryStats records the lookups of the dictionary filter over its output text: the
+// words it kept because dict has them, and the redacted ones.
+func (d *FilteredData) dictionaryStats(text string, dict map[string]struct{}, redacted int) {
+	hits := 0
+	for _, w := range dictionaryWordPattern.FindAllString(text, -1) {
+		if _, ok := dict[strings.ToLower(w)]; ok && dictionaryWord(w) && utf8.RuneCountInString(w) > 3 {
+			hits++
+		}
+	}
+	d.Stats.DictionaryHits = hits
+	d.Stats.DictionaryWords = hits + redacted
+	if d.Stats.DictionaryWords > 0 {
+		d.Stats.DictionaryHitRate = math.Round(float64(hits)/float64(d.Stats.DictionaryWords)*1000) / 1000
+	}
+}
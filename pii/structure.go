@@ -0,0 +1,95 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// LayoutUnstructured is the FieldForm16Layout value of a certificate whose template was not
+// recognised.
+const LayoutUnstructured = "unstructured"
+
+// minAnchorShare is the share of the anchors of a part that must be found for the part to be
+// parsed. Employers may word or leave out a few rows, but a part missing most of them is a
+// template of their own.
+const minAnchorShare = 0.5
+
+// structureAnchor is a heading or row the parsers rely on.
+type structureAnchor struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// structureParts are the anchors of Part A and Part B of Form 16. Part A is generated by
+// TRACES and rarely departs from them; Part B is where employers customise.
+var structureParts = []struct {
+	name    string
+	anchors []structureAnchor
+}{
+	{"Part A", []structureAnchor{
+		{"PART A heading", partAPattern},
+		{"Certificate under section 203", regexp.MustCompile(`(?i)Certificate\s+under\s+section\s+203`)},
+		{"Name and address of the Employer", employerLabelPattern},
+		{"Assessment Year", assessmentYearLabel},
+		{"quarterly summary", quarterTablePattern},
+		{"Total (Rs.) row", totalRowPattern},
+	}},
+	{"Part B", []structureAnchor{
+		{"PART B heading", partBPattern},
+		{"Gross Salary", grossSalaryPattern},
+		{"Income chargeable under the head Salaries", regexp.MustCompile(`(?i)Income\s+chargeable\s+under\s+the\s+head\s+\W?Salaries`)},
+		{"Chapter VI-A", regexp.MustCompile(`(?i)Chapter\s*VI\s*-?\s*A\b`)},
+		{"Tax on total income", regexp.MustCompile(`(?i)Tax\s+on\s+total\s+income`)},
+		{"Verification", verificationPattern},
+	}},
+}
+
+// structureGaps checks the certificate text against the anchors of each part. It returns one
+// entry per part with anchors missing, naming them, and whether a part has too few of its
+// anchors to be parsed. Text without a Form 16 title has no structure to check.
+func structureGaps(text string) (gaps []string, unstructured bool) {
+	if detectLayout(text) == nil {
+		return nil, false
+	}
+	lines := strings.Split(text, "\n")
+	for _, part := range structureParts {
+		var missing []string
+		for _, a := range part.anchors {
+			if !slices.ContainsFunc(lines, a.pattern.MatchString) {
+				missing = append(missing, a.name)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		gaps = append(gaps, fmt.Sprintf("%s missing %s", part.name, strings.Join(missing, ", ")))
+		if found := len(part.anchors) - len(missing); float64(found) < minAnchorShare*float64(len(part.anchors)) {
+			unstructured = true
+		}
+	}
+	return gaps, unstructured
+}
+
+// structureWarnings returns a WarnUnstructured warning for every certificate of the Form 16
+// text that formFields leaves unparsed, listing the anchors that were not found so that the
+// template can be added to form16_layouts.yaml.
+func structureWarnings(text string) []Warning {
+	var warnings []Warning
+	certificates := certificateTexts(text)
+	for i, body := range certificates {
+		gaps, unstructured := structureGaps(body)
+		if !unstructured {
+			continue
+		}
+		where := "document"
+		if len(certificates) > 1 {
+			where = fmt.Sprintf("certificate %d", i+1)
+		}
+		warnings = append(warnings, Warning{Code: WarnUnstructured, Message: fmt.Sprintf(
+			"Form 16 template of the %s not recognised, redacted without parsing its fields: %s",
+			where, strings.Join(gaps, "; "))})
+	}
+	return warnings
+}
@@ -0,0 +1,366 @@
+package pii
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Column types of a FieldSchema besides the entity types.
+const (
+	// ColumnKeep leaves the column untouched.
+	ColumnKeep = "keep"
+	// ColumnAuto runs the detectors over the column's text and redacts what they find.
+	ColumnAuto = "auto"
+)
+
+// Column actions of a FieldRule.
+const (
+	// ColumnRedact replaces the value with the entity's placeholder (default).
+	ColumnRedact = "redact"
+	// ColumnHash replaces the value with a keyed hash, so records stay joinable on it.
+	ColumnHash = "hash"
+	// ColumnMask keeps the first and last two characters, as the report samples do.
+	ColumnMask = "mask"
+)
+
+// FieldSchema maps the columns of a structured export to how they are scrubbed. It is loaded
+// from a YAML or JSON file:
+//
+//	default: keep
+//	columns:
+//	  pan: {type: pan, action: hash}
+//	  email: email
+//	  employee.phone: phone
+//	  remarks: auto
+//
+// A column is a CSV header or a JSON object key; nested JSON keys can be given as a dotted
+// path, which wins over the bare key. Columns not listed follow Default (keep or auto).
+type FieldSchema struct {
+	Default string               `json:"default" yaml:"default"`
+	Columns map[string]FieldRule `json:"columns" yaml:"columns"`
+}
+
+// FieldRule is how one column is scrubbed. In a schema file it may be written as just the
+// type, e.g. "pan: pan".
+type FieldRule struct {
+	// Type is an entity type (the whole value is that PII), ColumnAuto or ColumnKeep.
+	Type string `json:"type" yaml:"type"`
+	// Action is ColumnRedact (default), ColumnHash or ColumnMask.
+	Action string `json:"action,omitempty" yaml:"action,omitempty"`
+	// Confidence is how sure `schema infer` was of the type; informational only.
+	Confidence float64 `json:"confidence,omitempty" yaml:"confidence,omitempty"`
+}
+
+// UnmarshalJSON accepts a rule object or a bare type name.
+func (r *FieldRule) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*r = FieldRule{Type: s}
+		return nil
+	}
+	type rule FieldRule
+	return json.Unmarshal(b, (*rule)(r))
+}
+
+// UnmarshalYAML accepts a rule mapping or a bare type name.
+func (r *FieldRule) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*r = FieldRule{Type: node.Value}
+		return nil
+	}
+	type rule FieldRule
+	return node.Decode((*rule)(r))
+}
+
+// LoadFieldSchema reads a schema file; files ending in .json are parsed as JSON and
+// everything else as YAML.
+func LoadFieldSchema(path string) (*FieldSchema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %v", err)
+	}
+	var s FieldSchema
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &s)
+	} else {
+		err = yaml.Unmarshal(b, &s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema %s: %v", path, err)
+	}
+	return &s, nil
+}
+
+// FieldStats counts the values scrubbed per column.
+type FieldStats struct {
+	Records  int
+	Redacted map[string]int
+}
+
+// fieldRedactor scrubs the values of one export.
+type fieldRedactor struct {
+	pf      *Filter
+	schema  FieldSchema
+	hashKey []byte
+	stats   FieldStats
+}
+
+// newFieldRedactor checks the schema against the filter's entity types.
+func (pf *Filter) newFieldRedactor(schema FieldSchema, hashKey []byte) (*fieldRedactor, error) {
+	known := make(map[string]bool)
+	for entity := range defaultPlaceholders {
+		known[entity] = true
+	}
+	for _, r := range pf.recognizers {
+		known[r.Name()] = true
+	}
+	switch schema.Default {
+	case "":
+		schema.Default = ColumnKeep
+	case ColumnKeep, ColumnAuto:
+	default:
+		return nil, fmt.Errorf("invalid schema: default must be keep or auto, not %q", schema.Default)
+	}
+	for column, rule := range schema.Columns {
+		if rule.Type != ColumnKeep && rule.Type != ColumnAuto && !known[rule.Type] {
+			return nil, fmt.Errorf("invalid schema: column %q: unknown type %q", column, rule.Type)
+		}
+		switch rule.Action {
+		case "", ColumnRedact, ColumnMask:
+		case ColumnHash:
+			if len(hashKey) == 0 {
+				return nil, fmt.Errorf("invalid schema: column %q is hashed but no hash key was given", column)
+			}
+		default:
+			return nil, fmt.Errorf("invalid schema: column %q: unknown action %q (expected redact, hash or mask)", column, rule.Action)
+		}
+	}
+	return &fieldRedactor{pf: pf, schema: schema, hashKey: hashKey, stats: FieldStats{Redacted: make(map[string]int)}}, nil
+}
+
+// ValidateFieldSchema reports the first problem of schema: an unknown type or action, or a
+// hashed column without hashKey.
+func (pf *Filter) ValidateFieldSchema(schema FieldSchema, hashKey []byte) error {
+	_, err := pf.newFieldRedactor(schema, hashKey)
+	return err
+}
+
+// rule returns the rule of the column at path: by dotted path, then by its last key.
+func (fr *fieldRedactor) rule(path []string) (string, FieldRule) {
+	column := strings.Join(path, ".")
+	if r, ok := fr.schema.Columns[column]; ok {
+		return column, r
+	}
+	if len(path) > 0 {
+		if r, ok := fr.schema.Columns[path[len(path)-1]]; ok {
+			return column, r
+		}
+	}
+	return column, FieldRule{Type: fr.schema.Default}
+}
+
+// replace applies the action of rule to a PII value of entity.
+func (fr *fieldRedactor) replace(rule FieldRule, entity, value string) string {
+	switch rule.Action {
+	case ColumnHash:
+		mac := hmac.New(sha256.New, fr.hashKey)
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil))[:16]
+	case ColumnMask:
+		return maskSample(value)
+	}
+	if fr.pf.vault != nil {
+		return fr.pf.vault.Token(entity, value)
+	}
+	return fr.pf.placeholderFor(entity)
+}
+
+// scrub returns the scrubbed text of a value of column and whether anything was replaced.
+func (fr *fieldRedactor) scrub(column string, rule FieldRule, value string) (string, bool) {
+	switch rule.Type {
+	case ColumnKeep:
+		return value, false
+	case ColumnAuto:
+		changed := false
+		data := fr.pf.FilterWithCallback(value, func(f Finding) Action {
+			changed = true
+			if rule.Action == "" || rule.Action == ColumnRedact {
+				return ActionRedact
+			}
+			return ActionReplace(fr.replace(rule, f.Entity, f.Text))
+		})
+		if changed {
+			fr.stats.Redacted[column]++
+		}
+		return data.CleanedText, changed
+	}
+	if value == "" {
+		return value, false
+	}
+	fr.stats.Redacted[column]++
+	return fr.replace(rule, rule.Type, value), true
+}
+
+// RedactJSON scrubs a JSON export column-aware: the values of every object key are handled
+// by the schema rule of the key, and arrays inherit the rule of the key holding them. The
+// input may be one document or a stream of documents (JSON Lines); key order and number
+// literals are preserved. A redacted number becomes null, since a placeholder is not a
+// number; hashed and masked numbers become strings.
+func (pf *Filter) RedactJSON(r io.Reader, w io.Writer, schema FieldSchema, hashKey []byte) (FieldStats, error) {
+	fr, err := pf.newFieldRedactor(schema, hashKey)
+	if err != nil {
+		return FieldStats{}, err
+	}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	bw := bufio.NewWriter(w)
+	for dec.More() {
+		if err := fr.jsonValue(dec, bw, nil); err != nil {
+			return fr.stats, fmt.Errorf("failed to parse JSON: %v", err)
+		}
+		bw.WriteByte('\n')
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fr.stats, fmt.Errorf("failed to parse JSON: unexpected data after the last document")
+	}
+	if err := bw.Flush(); err != nil {
+		return fr.stats, fmt.Errorf("failed to write JSON: %v", err)
+	}
+	return fr.stats, nil
+}
+
+// jsonValue copies the next value of dec to w, scrubbing the scalars by the rule of path.
+// Objects of a top-level array are counted as records.
+func (fr *fieldRedactor) jsonValue(dec *json.Decoder, w *bufio.Writer, path []string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			if len(path) == 0 {
+				fr.stats.Records++
+			}
+			w.WriteByte('{')
+			for first := true; dec.More(); first = false {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				if !first {
+					w.WriteByte(',')
+				}
+				w.Write(marshalJSON(key))
+				w.WriteByte(':')
+				if err := fr.jsonValue(dec, w, append(path, key.(string))); err != nil {
+					return err
+				}
+			}
+			w.WriteByte('}')
+		case '[':
+			w.WriteByte('[')
+			for first := true; dec.More(); first = false {
+				if !first {
+					w.WriteByte(',')
+				}
+				if err := fr.jsonValue(dec, w, path); err != nil {
+					return err
+				}
+			}
+			w.WriteByte(']')
+		}
+		// The closing delimiter.
+		_, err := dec.Token()
+		return err
+	case string:
+		column, rule := fr.rule(path)
+		out, _ := fr.scrub(column, rule, t)
+		w.Write(marshalJSON(out))
+	case json.Number:
+		column, rule := fr.rule(path)
+		out, changed := fr.scrub(column, rule, t.String())
+		if changed && (rule.Action == "" || rule.Action == ColumnRedact) {
+			w.WriteString("null")
+		} else if changed {
+			w.Write(marshalJSON(out))
+		} else {
+			w.WriteString(t.String())
+		}
+	default:
+		// Booleans and null carry no PII.
+		w.Write(marshalJSON(t))
+	}
+	return nil
+}
+
+// RedactCSV scrubs a CSV export whose first row holds the column names. Every cell is
+// handled by the schema rule of its column; the header and column order are kept.
+func (pf *Filter) RedactCSV(r io.Reader, w io.Writer, schema FieldSchema, hashKey []byte) (FieldStats, error) {
+	fr, err := pf.newFieldRedactor(schema, hashKey)
+	if err != nil {
+		return FieldStats{}, err
+	}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cw := csv.NewWriter(w)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return fr.stats, nil
+	}
+	if err != nil {
+		return fr.stats, fmt.Errorf("failed to parse CSV: %v", err)
+	}
+	// A UTF-8 byte order mark written by spreadsheet exports is not part of the name.
+	names := append([]string(nil), header...)
+	if len(names) > 0 {
+		names[0] = strings.TrimPrefix(names[0], "\ufeff")
+	}
+	cw.Write(header)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fr.stats, fmt.Errorf("failed to parse CSV: %v", err)
+		}
+		for i, cell := range record {
+			if i >= len(names) {
+				break
+			}
+			column, rule := fr.rule([]string{names[i]})
+			record[i], _ = fr.scrub(column, rule, cell)
+		}
+		cw.Write(record)
+		fr.stats.Records++
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fr.stats, fmt.Errorf("failed to write CSV: %v", err)
+	}
+	return fr.stats, nil
+}
+
+// marshalJSON encodes a scalar without escaping HTML characters, which would change values
+// that need no redaction.
+func marshalJSON(v any) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
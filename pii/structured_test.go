@@ -0,0 +1,135 @@
+package pii
+
+import (
+	"strings"
+	"testing"
+)
+
+// testHashKey is the HashKeyEnv key of the structured export tests.
+var testHashKey = []byte("0123456789abcdef")
+
+func TestRedactJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema FieldSchema
+		input  string
+		want   string
+		// redacted is the number of values scrubbed per column.
+		redacted map[string]int
+	}{
+		{
+			name:     "only configured keys",
+			schema:   FieldSchema{Columns: map[string]FieldRule{"pan": {Type: EntityPAN}}},
+			input:    `{"pan":"ABCPE1234F","note":"PAN ABCPE1234F","email":"ravi@infosys.com"}`,
+			want:     `{"pan":"[PAN_REDACTED]","note":"PAN ABCPE1234F","email":"ravi@infosys.com"}`,
+			redacted: map[string]int{"pan": 1},
+		},
+		{
+			name: "dotted path wins over the bare key",
+			schema: FieldSchema{Columns: map[string]FieldRule{
+				"phone":          {Type: ColumnKeep},
+				"employee.phone": {Type: EntityPhone},
+			}},
+			input:    `{"phone":"1800 425 1234","employee":{"phone":"+91 98765 43210"}}`,
+			want:     `{"phone":"1800 425 1234","employee":{"phone":"[PHONE_REDACTED]"}}`,
+			redacted: map[string]int{"employee.phone": 1},
+		},
+		{
+			name:     "arrays inherit the rule of their key",
+			schema:   FieldSchema{Columns: map[string]FieldRule{"emails": {Type: EntityEmail, Action: ColumnMask}}},
+			input:    `[{"emails":["ravi@infosys.com",""],"id":7}]`,
+			want:     `[{"emails":["ra************om",""],"id":7}]`,
+			redacted: map[string]int{"emails": 1},
+		},
+		{
+			name:     "redacted numbers become null",
+			schema:   FieldSchema{Columns: map[string]FieldRule{"aadhaar": {Type: EntityAadhaar}}},
+			input:    "{\"aadhaar\":234567890124,\"gross\":1.20e5}\n{\"aadhaar\":null}",
+			want:     "{\"aadhaar\":null,\"gross\":1.20e5}\n{\"aadhaar\":null}",
+			redacted: map[string]int{"aadhaar": 1},
+		},
+		{
+			name:     "auto default",
+			schema:   FieldSchema{Default: ColumnAuto, Columns: map[string]FieldRule{"tan": {Type: ColumnKeep}}},
+			input:    `{"remarks":"PAN ABCPE1234F","tan":"MUMA12345B"}`,
+			want:     `{"remarks":"PAN [PAN_REDACTED]","tan":"MUMA12345B"}`,
+			redacted: map[string]int{"remarks": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			stats, err := NewFilter().RedactJSON(strings.NewReader(tt.input), &out, tt.schema, nil)
+			if err != nil {
+				t.Fatalf("RedactJSON: %v", err)
+			}
+			if got := strings.TrimSuffix(out.String(), "\n"); got != tt.want {
+				t.Errorf("RedactJSON =\n%s\nwant\n%s", got, tt.want)
+			}
+			if len(stats.Redacted) != len(tt.redacted) {
+				t.Errorf("Redacted = %v, want %v", stats.Redacted, tt.redacted)
+			}
+			for column, n := range tt.redacted {
+				if stats.Redacted[column] != n {
+					t.Errorf("Redacted = %v, want %v", stats.Redacted, tt.redacted)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactCSV(t *testing.T) {
+	const input = "\ufeffname,pan,email,remarks\n" +
+		"Ravi Sharma,ABCPE1234F,ravi@infosys.com,PAN ABCPE1234F\n" +
+		"Asha Rao,ABCPR5678K,asha@infosys.com,\n"
+	schema := FieldSchema{Columns: map[string]FieldRule{
+		"name":  {Type: EntityName},
+		"pan":   {Type: EntityPAN, Action: ColumnHash},
+		"email": {Type: EntityEmail, Action: ColumnMask},
+	}}
+	var out strings.Builder
+	stats, err := NewFilter().RedactCSV(strings.NewReader(input), &out, schema, testHashKey)
+	if err != nil {
+		t.Fatalf("RedactCSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 || lines[0] != "\ufeffname,pan,email,remarks" {
+		t.Fatalf("RedactCSV =\n%s", out.String())
+	}
+	first, second := strings.Split(lines[1], ","), strings.Split(lines[2], ",")
+	if first[0] != "[NAME_REDACTED]" || first[2] != "ra************om" {
+		t.Errorf("row 1 = %q", first)
+	}
+	// Hashed values are keyed and stable, so rows stay joinable on them without the PAN.
+	if len(first[1]) != 16 || strings.Contains(lines[1], "ABCPE1234F,") || first[1] == second[1] {
+		t.Errorf("hashed PANs = %q, %q", first[1], second[1])
+	}
+	if first[3] != "PAN ABCPE1234F" {
+		t.Errorf("remarks, not in the schema, = %q; want it kept", first[3])
+	}
+	if stats.Records != 2 || stats.Redacted["pan"] != 2 || stats.Redacted["remarks"] != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestValidateFieldSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  FieldSchema
+		hashKey []byte
+		wantErr bool
+	}{
+		{"valid", FieldSchema{Default: ColumnAuto, Columns: map[string]FieldRule{"pan": {Type: EntityPAN, Action: ColumnHash}}}, testHashKey, false},
+		{"unknown default", FieldSchema{Default: "redact"}, nil, true},
+		{"unknown type", FieldSchema{Columns: map[string]FieldRule{"pan": {Type: "tax_id"}}}, nil, true},
+		{"unknown action", FieldSchema{Columns: map[string]FieldRule{"pan": {Type: EntityPAN, Action: "drop"}}}, nil, true},
+		{"hash without key", FieldSchema{Columns: map[string]FieldRule{"pan": {Type: EntityPAN, Action: ColumnHash}}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewFilter().ValidateFieldSchema(tt.schema, tt.hashKey); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFieldSchema error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
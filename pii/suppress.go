@@ -0,0 +1,229 @@
+package pii
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// suppressedKey is the RetainedFields key of the values left in the text by a Suppression.
+const suppressedKey = "Suppressed False Positives"
+
+// DefaultSuppressionTTL is how long a suppression lasts when no expiry is given: a review
+// cycle, after which the finding is reported again and has to be confirmed anew.
+const DefaultSuppressionTTL = 90 * 24 * time.Hour
+
+// Suppression silences one false positive reported by a reviewer: a value of an entity type,
+// known only by its SHA-256, in the context of its line, until it expires.
+type Suppression struct {
+	Entity string `json:"entity"`
+	// Fingerprint is the Match.Fingerprint of the finding: the SHA-256 of the value and a hash
+	// of the rest of its line.
+	Fingerprint string    `json:"fingerprint"`
+	Reviewer    string    `json:"reviewer"`
+	Reason      string    `json:"reason"`
+	Created     time.Time `json:"created"`
+	Expires     time.Time `json:"expires"`
+}
+
+// Actions recorded in the audit trail of a SuppressionStore.
+const (
+	SuppressionAdded   = "added"
+	SuppressionRemoved = "removed"
+	SuppressionExpired = "expired"
+)
+
+// SuppressionEvent is an entry of the audit trail of a SuppressionStore.
+type SuppressionEvent struct {
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	Entity      string    `json:"entity"`
+	Fingerprint string    `json:"fingerprint"`
+	Reviewer    string    `json:"reviewer,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+// SuppressionStore persists the false positives marked by reviewers so that they are not
+// reported again in later runs (see WithSuppressions). Every change is recorded in its audit
+// trail. A store is safe for concurrent use, and changes apply to filters already using it.
+type SuppressionStore struct {
+	mu           sync.RWMutex
+	Suppressions []Suppression      `json:"suppressions"`
+	Audit        []SuppressionEvent `json:"audit"`
+}
+
+// LoadSuppressions reads the store at path; a missing file is an empty store.
+func LoadSuppressions(path string) (*SuppressionStore, error) {
+	s := &SuppressionStore{}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suppressions: %v", err)
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("failed to parse suppressions %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Save replaces the file at path with the store.
+func (s *SuppressionStore) Save(path string) error {
+	s.mu.RLock()
+	b, err := json.MarshalIndent(s, "", "  ")
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode suppressions: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".suppressions-*")
+	if err != nil {
+		return fmt.Errorf("failed to write suppressions: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(append(b, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write suppressions: %v", err)
+	}
+	return nil
+}
+
+// Add suppresses the finding of entity with the given fingerprint until ttl from now
+// (DefaultSuppressionTTL when 0), replacing an earlier suppression of it. The reviewer and
+// the reason are required for the audit trail.
+func (s *SuppressionStore) Add(entity, fingerprint, reviewer, reason string, ttl time.Duration) (Suppression, error) {
+	if entity == "" || !validFingerprint(fingerprint) {
+		return Suppression{}, fmt.Errorf("suppression needs an entity type and a fingerprint from a report's matches")
+	}
+	if reviewer == "" || reason == "" {
+		return Suppression{}, fmt.Errorf("suppression needs a reviewer and a reason")
+	}
+	if ttl <= 0 {
+		ttl = DefaultSuppressionTTL
+	}
+	now := time.Now().UTC()
+	sup := Suppression{Entity: entity, Fingerprint: fingerprint, Reviewer: reviewer, Reason: reason, Created: now, Expires: now.Add(ttl)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(now)
+	s.Suppressions = slices.DeleteFunc(s.Suppressions, func(old Suppression) bool {
+		return old.Entity == entity && old.Fingerprint == fingerprint
+	})
+	s.Suppressions = append(s.Suppressions, sup)
+	s.Audit = append(s.Audit, SuppressionEvent{Time: now, Action: SuppressionAdded, Entity: entity, Fingerprint: fingerprint, Reviewer: reviewer, Reason: reason})
+	return sup, nil
+}
+
+// Remove lifts the suppression of the finding of entity with the given fingerprint, so that
+// it is reported again.
+func (s *SuppressionStore) Remove(entity, fingerprint, reviewer, reason string) error {
+	now := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(now)
+	n := len(s.Suppressions)
+	s.Suppressions = slices.DeleteFunc(s.Suppressions, func(old Suppression) bool {
+		return old.Entity == entity && old.Fingerprint == fingerprint
+	})
+	if len(s.Suppressions) == n {
+		return fmt.Errorf("no suppression of %s %s", entity, fingerprint)
+	}
+	s.Audit = append(s.Audit, SuppressionEvent{Time: now, Action: SuppressionRemoved, Entity: entity, Fingerprint: fingerprint, Reviewer: reviewer, Reason: reason})
+	return nil
+}
+
+// Active returns the suppressions that have not expired.
+func (s *SuppressionStore) Active() []Suppression {
+	now := time.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var active []Suppression
+	for _, sup := range s.Suppressions {
+		if now.Before(sup.Expires) {
+			active = append(active, sup)
+		}
+	}
+	return active
+}
+
+// expire moves the suppressions expired at now to the audit trail. s.mu must be held.
+func (s *SuppressionStore) expire(now time.Time) {
+	s.Suppressions = slices.DeleteFunc(s.Suppressions, func(sup Suppression) bool {
+		if now.Before(sup.Expires) {
+			return false
+		}
+		s.Audit = append(s.Audit, SuppressionEvent{Time: sup.Expires, Action: SuppressionExpired, Entity: sup.Entity, Fingerprint: sup.Fingerprint})
+		return true
+	})
+}
+
+// suppressed reports whether the finding of entity at bytes start to end of line is an
+// active suppression.
+func (s *SuppressionStore) suppressed(entity, line string, start, end int) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.Suppressions) == 0 {
+		return false
+	}
+	fp := fingerprint(line, start, end)
+	now := time.Now()
+	for _, sup := range s.Suppressions {
+		if sup.Entity == entity && sup.Fingerprint == fp && now.Before(sup.Expires) {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprint identifies the value at bytes start to end of line in its context: the SHA-256
+// of the value, as in Match.ValueHash, and a hash of the rest of the line with digits and
+// spacing normalised, so that the same label and value still match when the amounts beside
+// them change from one certificate to the next.
+func fingerprint(line string, start, end int) string {
+	value := sha256.Sum256([]byte(strings.TrimSpace(line[start:end])))
+	rest := strings.Map(func(r rune) rune {
+		if '0' <= r && r <= '9' {
+			return '#'
+		}
+		return r
+	}, strings.ToLower(line[:start]+" "+line[end:]))
+	context := sha256.Sum256([]byte(strings.Join(strings.Fields(rest), " ")))
+	return hex.EncodeToString(value[:]) + ":" + hex.EncodeToString(context[:8])
+}
+
+// validFingerprint reports whether fp has the form written by fingerprint.
+func validFingerprint(fp string) bool {
+	value, context, ok := strings.Cut(fp, ":")
+	if !ok || len(value) != 64 || len(context) != 16 {
+		return false
+	}
+	_, err := hex.DecodeString(value + context)
+	return err == nil
+}
+
+// lineSuppressed reports whether the address or organisation finding covering line[start:end]
+// is a suppressed false positive, recording its text in retained when it is.
+func (pf *Filter) lineSuppressed(entity, line string, start, end int, retained map[string][]string) bool {
+	if !pf.suppressions.suppressed(entity, line, start, end) {
+		return false
+	}
+	retained[suppressedKey] = append(retained[suppressedKey], strings.TrimSpace(line[start:end]))
+	return true
+}
@@ -0,0 +1,173 @@
+package pii
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// StageExtract names the time spent extracting the text of a document in Timings.Stages; the
+// other stages are named after the pipeline (StageNormalize, StageDetect...).
+const StageExtract = "extract"
+
+// Timing is the time one stage or detector took on a document.
+type Timing struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Timings breaks down where the time redacting a document went, to find out whether a slow
+// document is held up by, say, the address gazetteer or the dictionary pass. They are only
+// recorded under WithTimings.
+type Timings struct {
+	// Stages lists the pipeline stages in the order they ran. The filter only runs detect,
+	// so they are set by the caller; the outputs stage cannot time itself into the outputs.
+	Stages []Timing `json:"stages,omitempty"`
+	// Detectors lists the detectors of the detect stage by entity type, slowest first.
+	// Address and organisation line detection are listed as EntityAddress and
+	// EntityOrganization; third-party recognizers include the time of their Find. Under
+	// FilterPages the times of the pages are added up, whichever worker filtered them.
+	Detectors []Timing `json:"detectors"`
+}
+
+// AddStage appends the time a stage took since started. A nil t records nothing.
+func (t *Timings) AddStage(name string, started time.Time) {
+	if t == nil {
+		return
+	}
+	t.Stages = append(t.Stages, Timing{Name: name, Duration: time.Since(started)})
+}
+
+// detectorTimer accumulates the time spent per detector over one text. A nil timer records
+// nothing and does not read the clock.
+type detectorTimer map[string]time.Duration
+
+// start returns the time to pass to stop.
+func (t detectorTimer) start() time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// stop adds the time since started to the detector name.
+func (t detectorTimer) stop(name string, started time.Time) {
+	if t != nil {
+		t[name] += time.Since(started)
+	}
+}
+
+// newDetectorTimer returns a timer when pf records timings, nil otherwise.
+func (pf *Filter) newDetectorTimer() detectorTimer {
+	if !pf.timings {
+		return nil
+	}
+	return make(detectorTimer)
+}
+
+// timings returns the detector times of t, slowest first, or nil for a nil timer.
+func (t detectorTimer) timings() *Timings {
+	if t == nil {
+		return nil
+	}
+	timings := &Timings{Detectors: []Timing{}}
+	for name, d := range t {
+		timings.Detectors = append(timings.Detectors, Timing{Name: name, Duration: d})
+	}
+	slices.SortFunc(timings.Detectors, func(a, b Timing) int {
+		return cmp.Or(cmp.Compare(b.Duration, a.Duration), cmp.Compare(a.Name, b.Name))
+	})
+	return timings
+}
+
+// mergeTimings adds the detector times of results, filtered separately, into one.
+func mergeTimings(results []FilteredData) *Timings {
+	var t detectorTimer
+	for _, r := range results {
+		if r.Timings == nil {
+			continue
+		}
+		if t == nil {
+			t = make(detectorTimer)
+		}
+		for _, d := range r.Timings.Detectors {
+			t[d.Name] += d.Duration
+		}
+	}
+	return t.timings()
+}
+
+// TimingMetrics accumulates the Timings of many documents for a metrics endpoint. It is safe
+// for concurrent use.
+type TimingMetrics struct {
+	mu        sync.Mutex
+	documents int
+	stages    map[string]time.Duration
+	detectors map[string]time.Duration
+}
+
+// Add counts one document and adds its timings; nil timings only count the document.
+func (m *TimingMetrics) Add(t *Timings) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stages == nil {
+		m.stages, m.detectors = make(map[string]time.Duration), make(map[string]time.Duration)
+	}
+	m.documents++
+	if t == nil {
+		return
+	}
+	for _, s := range t.Stages {
+		m.stages[s.Name] += s.Duration
+	}
+	for _, d := range t.Detectors {
+		m.detectors[d.Name] += d.Duration
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format: the number of
+// documents and the seconds spent per stage and per detector, as counters.
+func (m *TimingMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cw := &countingWriter{w: w}
+	fmt.Fprintf(cw, "# HELP pdf_reader_documents_total Documents redacted.\n# TYPE pdf_reader_documents_total counter\npdf_reader_documents_total %d\n", m.documents)
+	for _, metric := range []struct {
+		name, label, help string
+		values            map[string]time.Duration
+	}{
+		{"pdf_reader_stage_seconds_total", "stage", "Time spent per pipeline stage.", m.stages},
+		{"pdf_reader_detector_seconds_total", "detector", "Time spent per detector of the detect stage.", m.detectors},
+	} {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s counter\n", metric.name, metric.help, metric.name)
+		names := make([]string, 0, len(metric.values))
+		for name := range metric.values {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Fprintf(cw, "%s{%s=%q} %g\n", metric.name, metric.label, name, metric.values[name].Seconds())
+		}
+	}
+	return cw.n, cw.err
+}
+
+// countingWriter counts the bytes written to w and keeps the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
@@ -0,0 +1,77 @@
+package pii
+
+import "strings"
+
+// Verhoeff tables used by the Aadhaar checksum.
+var (
+	verhoeffD = [10][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 2, 3, 4, 0, 6, 7, 8, 9, 5},
+		{2, 3, 4, 0, 1, 7, 8, 9, 5, 6},
+		{3, 4, 0, 1, 2, 8, 9, 5, 6, 7},
+		{4, 0, 1, 2, 3, 9, 5, 6, 7, 8},
+		{5, 9, 8, 7, 6, 0, 4, 3, 2, 1},
+		{6, 5, 9, 8, 7, 1, 0, 4, 3, 2},
+		{7, 6, 5, 9, 8, 2, 1, 0, 4, 3},
+		{8, 7, 6, 5, 9, 3, 2, 1, 0, 4},
+		{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	}
+	verhoeffP = [8][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 5, 7, 6, 2, 8, 3, 0, 9, 4},
+		{5, 8, 0, 3, 7, 9, 6, 1, 4, 2},
+		{8, 9, 1, 6, 0, 4, 3, 5, 2, 7},
+		{9, 4, 5, 3, 1, 2, 7, 6, 8, 0},
+		{4, 2, 8, 6, 5, 7, 3, 9, 0, 1},
+		{2, 7, 9, 3, 8, 0, 6, 4, 1, 5},
+		{7, 0, 4, 6, 9, 1, 3, 2, 5, 8},
+	}
+)
+
+// ValidAadhaar reports whether a matched Aadhaar number (spaces allowed) is structurally valid:
+// 12 digits, not starting with 0 or 1, with a correct Verhoeff check digit.
+func ValidAadhaar(match string) bool {
+	digits := make([]int, 0, 12)
+	for _, c := range match {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = append(digits, int(c-'0'))
+		case c == ' ':
+		default:
+			return false
+		}
+	}
+	if len(digits) != 12 || digits[0] < 2 {
+		return false
+	}
+	check := 0
+	for i := range digits {
+		check = verhoeffD[check][verhoeffP[i%8][digits[len(digits)-1-i]]]
+	}
+	return check == 0
+}
+
+// panHolderTypes are the valid fourth characters of a PAN, which encode the holder type:
+// individual, company, HUF, firm, AOP, trust, BOI, local authority, artificial juridical
+// person and government.
+const panHolderTypes = "PCHFATBLJG"
+
+// ValidPAN reports whether a matched PAN is structurally valid: five letters, four digits and
+// a letter, with a known holder type in the fourth position. Section codes and similar
+// references that happen to share the shape are rejected.
+func ValidPAN(match string) bool {
+	if len(match) != 10 {
+		return false
+	}
+	for i := 0; i < len(match); i++ {
+		c := match[i]
+		if i >= 5 && i < 9 {
+			if c < '0' || c > '9' {
+				return false
+			}
+		} else if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return strings.IndexByte(panHolderTypes, match[3]) >= 0
+}
@@ -0,0 +1,233 @@
+package pii
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// VaultPassphraseEnv is the environment variable the vault passphrase is read from; keeping
+// it off the command line keeps it out of process listings and shell history.
+const VaultPassphraseEnv = "PII_VAULT_PASSPHRASE"
+
+const (
+	// vaultMagic starts every vault file and is authenticated with the ciphertext.
+	vaultMagic = "PIIVLT01"
+	// vaultIterations is the PBKDF2-HMAC-SHA256 work factor of the vault key.
+	vaultIterations = 600000
+	vaultSaltSize   = 16
+)
+
+// tokenPattern matches the tokens a Vault hands out, e.g. [PAN_1] or [PF_ACCOUNT_12].
+var tokenPattern = regexp.MustCompile(`\[[A-Z][A-Z0-9_]*_\d+\]`)
+
+// Vault maps pseudonymisation tokens to the values they replace. Every distinct value of an
+// entity type gets its own stable token, so [PAN_1] stands for the same PAN wherever it
+// appears, across documents and across runs that share the vault file. A Vault is safe for
+// concurrent use.
+type Vault struct {
+	mu sync.Mutex
+	// Tokens maps each token to its original value.
+	Tokens map[string]string `json:"tokens"`
+	// Counters holds the last number issued per token prefix.
+	Counters map[string]int `json:"counters"`
+
+	// byValue maps token prefix and value to the token issued for them.
+	byValue map[string]string
+}
+
+// NewVault returns an empty vault.
+func NewVault() *Vault {
+	return &Vault{Tokens: make(map[string]string), Counters: make(map[string]int), byValue: make(map[string]string)}
+}
+
+// Token returns the token standing for value, issuing a new one for a value not seen before.
+func (v *Vault) Token(entity, value string) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	prefix := strings.ToUpper(entity)
+	key := prefix + "\x00" + value
+	if token, ok := v.byValue[key]; ok {
+		return token
+	}
+	v.Counters[prefix]++
+	token := fmt.Sprintf("[%s_%d]", prefix, v.Counters[prefix])
+	// The value may point into a mapped file (ScanBytes); keep a copy of its own.
+	v.Tokens[token] = strings.Clone(value)
+	v.byValue[key] = token
+	return token
+}
+
+// clone returns a copy of the vault that issues its tokens independently of v.
+func (v *Vault) clone() *Vault {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return &Vault{Tokens: maps.Clone(v.Tokens), Counters: maps.Clone(v.Counters), byValue: maps.Clone(v.byValue)}
+}
+
+// Len returns the number of tokens in the vault.
+func (v *Vault) Len() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return len(v.Tokens)
+}
+
+// Detokenize replaces every token of the vault in text with its original value. It returns
+// the restored text, the number of tokens replaced and the tokens found that the vault does
+// not know.
+func (v *Vault) Detokenize(text string) (string, int, []string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	restored := 0
+	var unknown []string
+	out := tokenPattern.ReplaceAllStringFunc(text, func(token string) string {
+		value, ok := v.Tokens[token]
+		if !ok {
+			unknown = append(unknown, token)
+			return token
+		}
+		restored++
+		return value
+	})
+	return out, restored, uniqueSorted(unknown)
+}
+
+// LoadVault decrypts the vault at path with passphrase. A missing file gives an empty vault,
+// so the first run creates it.
+func LoadVault(path, passphrase string) (*Vault, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("vault passphrase is empty (set %s)", VaultPassphraseEnv)
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewVault(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault: %v", err)
+	}
+	if len(data) < len(vaultMagic)+vaultSaltSize || !bytes.HasPrefix(data, []byte(vaultMagic)) {
+		return nil, fmt.Errorf("failed to read vault: %s is not a vault file", path)
+	}
+	salt := data[len(vaultMagic) : len(vaultMagic)+vaultSaltSize]
+	aead, err := vaultCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	rest := data[len(vaultMagic)+vaultSaltSize:]
+	if len(rest) < aead.NonceSize() {
+		return nil, fmt.Errorf("failed to read vault: file is truncated")
+	}
+	plain, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], []byte(vaultMagic))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt vault: wrong passphrase or corrupted file")
+	}
+	v := NewVault()
+	if err := json.Unmarshal(plain, v); err != nil {
+		return nil, fmt.Errorf("failed to decode vault: %v", err)
+	}
+	for token, value := range v.Tokens {
+		i := strings.LastIndex(token, "_")
+		if i < 0 {
+			return nil, fmt.Errorf("failed to decode vault: malformed token %q", token)
+		}
+		prefix := strings.TrimPrefix(token[:i], "[")
+		v.byValue[prefix+"\x00"+value] = token
+	}
+	return v, nil
+}
+
+// Save encrypts the vault with AES-256-GCM under a key derived from passphrase and a fresh
+// salt, and replaces the file at path. The file is only readable by its owner.
+func (v *Vault) Save(path, passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("vault passphrase is empty (set %s)", VaultPassphraseEnv)
+	}
+	v.mu.Lock()
+	plain, err := json.Marshal(v)
+	v.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode vault: %v", err)
+	}
+	salt := make([]byte, vaultSaltSize)
+	rand.Read(salt)
+	aead, err := vaultCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	out := append([]byte(vaultMagic), salt...)
+	out = append(out, nonce...)
+	out = aead.Seal(out, nonce, plain, []byte(vaultMagic))
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".vault-*")
+	if err != nil {
+		return fmt.Errorf("failed to write vault: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(out)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write vault: %v", err)
+	}
+	return nil
+}
+
+// renumber rewrites the tokens of v in text so that the tokens of every prefix are numbered
+// from 1 in order of first appearance. It gives the numbering of a document vault that does
+// not depend on the order workers issued its tokens in, nor has gaps for tokens that were
+// issued but not written, e.g. on a line then redacted as a whole.
+func (v *Vault) renumber(text string) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	counters := make(map[string]int)
+	renamed := make(map[string]string)
+	return tokenPattern.ReplaceAllStringFunc(text, func(token string) string {
+		if _, ok := v.Tokens[token]; !ok {
+			return token
+		}
+		if r, ok := renamed[token]; ok {
+			return r
+		}
+		prefix := token[1:strings.LastIndexByte(token, '_')]
+		counters[prefix]++
+		renamed[token] = fmt.Sprintf("[%s_%d]", prefix, counters[prefix])
+		return renamed[token]
+	})
+}
+
+// replacer returns the replacement function issuing tokens for entity.
+func (v *Vault) replacer(entity string) func(string) string {
+	return func(value string) string {
+		return v.Token(entity, value)
+	}
+}
+
+func vaultCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, vaultIterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive vault key: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
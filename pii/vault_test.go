@@ -0,0 +1,36 @@
+package pii
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadVaultTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"numbered token", "[PAN_1]", false},
+		{"no underscore", "[PAN]", true},
+		{"empty token", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "vault.enc")
+			v := NewVault()
+			v.Tokens[tt.token] = "ABCPE1234F"
+			if err := v.Save(path, "passphrase"); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadVault(path, "passphrase")
+			if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "malformed token")) {
+				t.Errorf("LoadVault = %v, want a malformed token error", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("LoadVault = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package pii
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// VerifyRedacted runs every detector again over the final cleaned text of data, after PII and
+// dictionary redaction. Placeholders never match a detector, so a residual match means a
+// value was missed or a later step, such as the dictionary filter, left a detectable
+// fragment behind. Each residual match is reported as a WarnResidualPII warning by type and
+// line only, and an error is returned when any remain. Suppressed false positives are not
+// residual, even where the redaction changed the line they were marked in.
+func (pf *Filter) VerifyRedacted(data FilteredData, warnings *Warnings) error {
+	suppressed := data.RetainedFields[suppressedKey]
+	residual := slices.DeleteFunc(pf.FilterPII(data.CleanedText).Matches, func(m Match) bool {
+		return slices.Contains(suppressed, strings.TrimSpace(data.CleanedText[m.Start:m.End]))
+	})
+	for _, m := range residual {
+		warnings.AddLine(WarnResidualPII, m.Line, "%s detector still matches the redacted text", m.Type)
+	}
+	if len(residual) > 0 {
+		return fmt.Errorf("verification failed: %d detector matches remain in the redacted text", len(residual))
+	}
+	return nil
+}
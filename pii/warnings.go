@@ -0,0 +1,59 @@
+package pii
+
+import "fmt"
+
+// Warning codes reported in FilteredData.Warnings and the run manifest.
+const (
+	WarnExtractionFallback = "extraction_fallback"
+	WarnNoText             = "no_text"
+	WarnPerfBudget         = "perf_budget_exceeded"
+	// WarnRedactedPDFFallback means the redacted PDF was rasterized because the text-preserving
+	// writer could not handle the input.
+	WarnRedactedPDFFallback = "redacted_pdf_fallback"
+	// WarnAmountMismatch means an amount in words does not agree with the figure it restates.
+	WarnAmountMismatch = "amount_words_mismatch"
+	// WarnFindingVolume means a document has unusually few or many findings of one entity
+	// type for its document type (see VolumeRule).
+	WarnFindingVolume = "finding_volume_anomaly"
+	// WarnHandwriting means a rendered page has regions that look handwritten; OCR and the
+	// detectors cannot read them, so they need a manual review.
+	WarnHandwriting = "handwriting_suspected"
+	// WarnResidualPII means a detector still matches the redacted text; the outputs of the
+	// document are not written.
+	WarnResidualPII = "residual_pii"
+	// WarnUnstructured means a Form 16 certificate follows a template whose parts were not
+	// recognised; it is redacted without parsing its figures into the retained fields.
+	WarnUnstructured = "unstructured"
+)
+
+// Warning is a non-fatal issue encountered while processing a document. Warnings are kept in
+// the report and manifest so automated consumers can react to them.
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Line is the 1-based line of the text the warning is about, or 0 when it is about the
+	// document as a whole.
+	Line int `json:"line,omitempty"`
+}
+
+// String returns the message, prefixed with the line when the warning has one.
+func (w Warning) String() string {
+	if w.Line > 0 {
+		return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+	}
+	return w.Message
+}
+
+// Warnings collects the warnings of one document. They are only recorded: showing them, e.g.
+// on the console, is up to the caller.
+type Warnings []Warning
+
+// Add records a warning.
+func (w *Warnings) Add(code, format string, args ...interface{}) {
+	*w = append(*w, Warning{Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// AddLine records a warning about line (1-based) of the text.
+func (w *Warnings) AddLine(code string, line int, format string, args ...interface{}) {
+	*w = append(*w, Warning{Code: code, Message: fmt.Sprintf(format, args...), Line: line})
+}
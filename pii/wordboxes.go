@@ -0,0 +1,295 @@
+package pii
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WordBox is one word on a page with its bounding box in PDF points. The origin is the
+// top-left corner of the page, the convention of pdftotext -bbox and of rendered page images.
+// Start and End are the byte offsets of the word in PageBoxes.Text.
+type WordBox struct {
+	Text                   string
+	XMin, YMin, XMax, YMax float64
+	Start, End             int
+}
+
+// PageBoxes holds the size of a page, the words found on it and the text the detectors run
+// on: one line per visual line, words separated by single spaces. Words are in text order.
+type PageBoxes struct {
+	Width, Height float64
+	Words         []WordBox
+	Text          string
+}
+
+// linkWords builds a page from words already grouped into visual lines, assigning each word
+// its offsets in the page text.
+func linkWords(width, height float64, lines [][]WordBox) PageBoxes {
+	page := PageBoxes{Width: width, Height: height}
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		for k, w := range line {
+			if k > 0 {
+				b.WriteByte(' ')
+			}
+			w.Start = b.Len()
+			b.WriteString(w.Text)
+			w.End = b.Len()
+			page.Words = append(page.Words, w)
+		}
+	}
+	page.Text = b.String()
+	return page
+}
+
+// pdftotextWordBoxes runs pdftotext -bbox-layout and parses the XHTML it prints. Poppler
+// nests words in <page>, <flow>, <block> and <line> elements; its line grouping is kept.
+func pdftotextWordBoxes(ctx context.Context, src, password string) ([]PageBoxes, error) {
+	args := append(passwordArgs(password), "-bbox-layout", src, "-")
+	out, err := exec.CommandContext(ctx, "pdftotext", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract word boxes: %w", popplerError(err))
+	}
+	return parseBBoxHTML(strings.NewReader(string(out)))
+}
+
+// parseBBoxHTML reads the output of pdftotext -bbox or -bbox-layout. Plain -bbox output has
+// no <line> elements, in which case lines are rebuilt with groupLines.
+func parseBBoxHTML(r io.Reader) ([]PageBoxes, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var pages []PageBoxes
+	var lines [][]WordBox
+	var loose []WordBox
+	var width, height float64
+	flush := func() {
+		if loose != nil {
+			lines = append(lines, groupLines(loose)...)
+		}
+		pages = append(pages, linkWords(width, height, lines))
+		lines, loose = nil, nil
+	}
+	inPage, inLine := false, false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse word boxes: %v", err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "page":
+				flush()
+				inPage = false
+			case "line":
+				inLine = false
+			}
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "page":
+				if inPage {
+					flush()
+				}
+				inPage = true
+				width, height = floatAttr(t, "width"), floatAttr(t, "height")
+			case "line":
+				inLine = true
+				lines = append(lines, nil)
+			case "word":
+				if !inPage {
+					continue
+				}
+				var text string
+				if err := dec.DecodeElement(&text, &t); err != nil {
+					return nil, fmt.Errorf("failed to parse word boxes: %v", err)
+				}
+				w := WordBox{
+					Text: text,
+					XMin: floatAttr(t, "xMin"), YMin: floatAttr(t, "yMin"),
+					XMax: floatAttr(t, "xMax"), YMax: floatAttr(t, "yMax"),
+				}
+				if inLine {
+					lines[len(lines)-1] = append(lines[len(lines)-1], w)
+				} else {
+					loose = append(loose, w)
+				}
+			}
+		}
+	}
+	if inPage {
+		flush()
+	}
+	return pages, nil
+}
+
+func floatAttr(e xml.StartElement, name string) float64 {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			v, _ := strconv.ParseFloat(a.Value, 64)
+			return v
+		}
+	}
+	return 0
+}
+
+// groupLines clusters words whose vertical centres are within half a line height of each
+// other and orders each line left to right.
+func groupLines(words []WordBox) [][]WordBox {
+	sorted := append([]WordBox(nil), words...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].YMin < sorted[j].YMin })
+
+	var lines [][]WordBox
+	var centre, height float64
+	for _, w := range sorted {
+		c := (w.YMin + w.YMax) / 2
+		if len(lines) == 0 || math.Abs(c-centre) > height/2 {
+			lines = append(lines, nil)
+			centre, height = c, w.YMax-w.YMin
+		}
+		lines[len(lines)-1] = append(lines[len(lines)-1], w)
+	}
+	for _, line := range lines {
+		sort.SliceStable(line, func(i, j int) bool { return line[i].XMin < line[j].XMin })
+	}
+	return lines
+}
+
+// RedactionBoxes returns the words on a page that must be covered. Each line of the page
+// text is run through the same detectors as FilterPII and matches are mapped back to words
+// through their offsets, so a word is covered exactly when its text would have been replaced
+// in the filtered output. Retained GSTINs are left visible; whole lines are covered for
+// organisation matches, and address lines or their address columns for address matches.
+func (pf *Filter) RedactionBoxes(page PageBoxes) []WordBox {
+	if page.Text == "" && len(page.Words) > 0 {
+		page = linkWords(page.Width, page.Height, groupLines(page.Words))
+	}
+	detectors := pf.detectorsFor(page.Text)
+	var boxes []WordBox
+	next, offset := 0, 0
+	texts := strings.Split(page.Text, "\n")
+	for n, text := range texts {
+		lineStart, lineEnd := offset, offset+len(text)
+		offset = lineEnd + 1
+		first := next
+		for next < len(page.Words) && page.Words[next].Start < lineEnd {
+			next++
+		}
+		line := page.Words[first:next]
+
+		spans := findSpans(texts, n, detectors)
+		trimmed := strings.TrimSpace(applySpans(text, spans))
+		if !pf.lineAllowed(text) && pf.wholeLineMatch(trimmed) {
+			columns, whole := pf.addressColumns(text)
+			if whole || pf.lineEnabled(EntityOrganization) && pf.OrganizationPattern.MatchString(trimmed) {
+				boxes = append(boxes, line...)
+				continue
+			}
+			regions := make([]span, len(columns))
+			for i, c := range columns {
+				regions[i] = span{start: c[0], end: c[1]}
+			}
+			spans = withRegions(spans, regions)
+		}
+		for _, w := range line {
+			start, end := w.Start-lineStart, w.End-lineStart
+			for _, s := range spans {
+				if s.retainAs == "" && start < s.end && s.start < end {
+					boxes = append(boxes, w)
+					break
+				}
+			}
+		}
+	}
+	return boxes
+}
+
+// nativeWordBoxes enumerates glyph positions with the native extractor and joins them into
+// words. A word ends at a whitespace glyph or where the gap to the next glyph exceeds 15% of
+// the font size, the same threshold layoutRuns uses to insert a space.
+func nativeWordBoxes(d *pdfDocument) ([]PageBoxes, error) {
+	pages := d.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages found")
+	}
+	e := &textExtractor{doc: d, fonts: make(map[interface{}]*pdfFont)}
+	out := make([]PageBoxes, 0, len(pages))
+	for _, page := range pages {
+		e.runs = e.runs[:0]
+		e.run(d.pageContent(page), d.dict(page["Resources"]), identityMatrix, 0)
+		llx, lly, urx, ury := d.mediaBox(page)
+		var lines [][]WordBox
+		for _, line := range baselines(e.runs) {
+			var words []WordBox
+			var cur *WordBox
+			var prevEnd float64
+			for _, r := range line {
+				// Ascender and descender are approximated as 80% and 20% of the font size.
+				top, bottom := ury-(r.y+r.size*0.8), ury-(r.y-r.size*0.2)
+				for _, g := range r.glyphs {
+					if strings.TrimSpace(g.text) == "" || (cur != nil && g.x-prevEnd > r.size*0.15) {
+						if cur != nil {
+							words = append(words, *cur)
+							cur = nil
+						}
+						if strings.TrimSpace(g.text) == "" {
+							continue
+						}
+					}
+					if cur == nil {
+						cur = &WordBox{XMin: g.x - llx, YMin: top, XMax: g.x + g.width - llx, YMax: bottom}
+					}
+					cur.Text += g.text
+					cur.XMax = math.Max(cur.XMax, g.x+g.width-llx)
+					cur.YMin, cur.YMax = math.Min(cur.YMin, top), math.Max(cur.YMax, bottom)
+					prevEnd = g.x + g.width
+				}
+			}
+			if cur != nil {
+				words = append(words, *cur)
+			}
+			if len(words) > 0 {
+				lines = append(lines, words)
+			}
+		}
+		out = append(out, linkWords(urx-llx, ury-lly, lines))
+	}
+	return out, nil
+}
+
+// baselines groups runs sharing a baseline, top to bottom, each ordered left to right.
+func baselines(runs []textRun) [][]textRun {
+	sorted := append([]textRun(nil), runs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].y > sorted[j].y })
+	var lines [][]textRun
+	for _, r := range sorted {
+		n := len(lines)
+		if n > 0 {
+			first := lines[n-1][0]
+			if math.Abs(first.y-r.y) <= math.Max(first.size, r.size)*0.4 {
+				lines[n-1] = append(lines[n-1], r)
+				continue
+			}
+		}
+		lines = append(lines, []textRun{r})
+	}
+	for _, line := range lines {
+		sort.SliceStable(line, func(i, j int) bool { return line[i].x < line[j].x })
+	}
+	return lines
+}
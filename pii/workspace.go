@@ -0,0 +1,110 @@
+package pii
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var (
+	// tempRoot is the directory workspaces are created in; empty means os.TempDir.
+	tempRoot string
+
+	workspacesMu sync.Mutex
+	// workspaces holds the directories of the open workspaces.
+	workspaces = make(map[string]bool)
+)
+
+// SetTempRoot makes new workspaces go under dir instead of the system temporary directory,
+// e.g. a tmpfs mount such as /dev/shm so page images and uploads never reach the disk.
+func SetTempRoot(dir string) error {
+	if dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("invalid temporary directory: %v", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("invalid temporary directory: %s is not a directory", dir)
+		}
+	}
+	workspacesMu.Lock()
+	tempRoot = dir
+	workspacesMu.Unlock()
+	return nil
+}
+
+// Workspace is a private temporary directory for the intermediate files of one job, such as
+// rasterised pages or an uploaded PDF. Only its owner can read it (0700), and Close removes
+// it with everything inside; CleanupWorkspaces removes the workspaces still open when the
+// process is interrupted.
+type Workspace struct {
+	dir string
+}
+
+// NewWorkspace creates a workspace whose directory name starts with pdf-reader-<purpose>-.
+func NewWorkspace(purpose string) (*Workspace, error) {
+	workspacesMu.Lock()
+	defer workspacesMu.Unlock()
+	dir, err := os.MkdirTemp(tempRoot, "pdf-reader-"+purpose+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s work directory: %v", purpose, err)
+	}
+	// MkdirTemp already uses 0700; make sure a permissive umask or root does not widen it.
+	if err := os.Chmod(dir, 0o700); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to create %s work directory: %v", purpose, err)
+	}
+	workspaces[dir] = true
+	return &Workspace{dir: dir}, nil
+}
+
+// Dir returns the workspace directory.
+func (w *Workspace) Dir() string { return w.dir }
+
+// Path returns the path of name inside the workspace.
+func (w *Workspace) Path(name string) string { return filepath.Join(w.dir, name) }
+
+// CreateTemp creates a new file in the workspace, as os.CreateTemp does.
+func (w *Workspace) CreateTemp(pattern string) (*os.File, error) {
+	return os.CreateTemp(w.dir, pattern)
+}
+
+// Close removes the workspace and its contents. It is safe to call more than once.
+func (w *Workspace) Close() error {
+	workspacesMu.Lock()
+	defer workspacesMu.Unlock()
+	if !workspaces[w.dir] {
+		return nil
+	}
+	delete(workspaces, w.dir)
+	if err := os.RemoveAll(w.dir); err != nil {
+		return fmt.Errorf("failed to remove work directory: %v", err)
+	}
+	return nil
+}
+
+// ActiveWorkspaces returns the directories of the workspaces not yet closed, sorted. Tests use
+// it to check that a job leaves no temporary files behind.
+func ActiveWorkspaces() []string {
+	workspacesMu.Lock()
+	defer workspacesMu.Unlock()
+	dirs := make([]string, 0, len(workspaces))
+	for dir := range workspaces {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// CleanupWorkspaces removes every open workspace; it is meant for signal handlers, where the
+// deferred Close calls of the running jobs will not run.
+func CleanupWorkspaces() {
+	workspacesMu.Lock()
+	defer workspacesMu.Unlock()
+	for dir := range workspaces {
+		os.RemoveAll(dir)
+		delete(workspaces, dir)
+	}
+}
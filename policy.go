@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pdf-reader/pii"
+)
+
+// runPolicy implements "pdf-reader policy": it creates the compliance key pair, signs a
+// -config file or -preset bundle with the private key, and verifies a signed one against the
+// public key as workers started with -policy-key do.
+func runPolicy(args []string) error {
+	usage := fmt.Errorf("usage: policy keygen -out compliance | policy sign -key compliance.key policy.yaml | policy verify -key compliance.pub policy.yaml")
+	if len(args) == 0 {
+		return usage
+	}
+	fs := flag.NewFlagSet("policy "+args[0], flag.ExitOnError)
+	switch args[0] {
+	case "keygen":
+		out := fs.String("out", "compliance", "key file prefix: writes PREFIX.key (private, keep offline) and PREFIX.pub")
+		fs.Parse(args[1:])
+		private, public, err := pii.GeneratePolicyKey()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*out+".key", private, 0o600); err != nil {
+			return fmt.Errorf("failed to write private key: %v", err)
+		}
+		if err := os.WriteFile(*out+".pub", public, 0o644); err != nil {
+			return fmt.Errorf("failed to write public key: %v", err)
+		}
+		fmt.Printf("Wrote %s.key and %s.pub\n", *out, *out)
+	case "sign":
+		keyFile := fs.String("key", "compliance.key", "Ed25519 private key (PEM)")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return usage
+		}
+		key, err := pii.LoadPolicyPrivateKey(*keyFile)
+		if err != nil {
+			return err
+		}
+		sig, err := pii.SignPolicy(fs.Arg(0), key)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Signed %s (sha256 %s, key %s) into %s\n", sig.File, sig.SHA256, sig.KeyID, sig.File+pii.PolicySignatureExt)
+	case "verify":
+		keyFile := fs.String("key", "compliance.pub", "Ed25519 public key (PEM)")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return usage
+		}
+		key, err := pii.LoadPolicyPublicKey(*keyFile)
+		if err != nil {
+			return err
+		}
+		var sig *pii.PolicySignature
+		if strings.EqualFold(filepath.Ext(fs.Arg(0)), pii.PresetExt) {
+			_, sig, err = pii.LoadSignedPreset(fs.Arg(0), key)
+		} else {
+			_, sig, err = pii.LoadSignedConfig(fs.Arg(0), key)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Policy %s is signed by key %s (sha256 %s)\n", sig.File, sig.KeyID, sig.SHA256)
+	default:
+		return usage
+	}
+	return nil
+}
+
+// policyFlags are the flags of the CLI, serve and watch that change what is redacted or how.
+// With a -policy-key they must keep their defaults, since the signature does not cover them.
+var policyFlags = []string{
+	"gst", "email", "profile", "document", "phone-regions", "redact", "keep", "packs",
+	"amount-words", "dates", "page-headers", "allowlist", "denylist", "glossary", "wordlist",
+	"address-keywords", "suppressions", "match-values", "min-confidence", "pan-context", "strict",
+	"numbered", "engine", "lang", "lang-file", "data-dir", "vault", "review",
+}
+
+// policyOverrides returns the policy flags of fs set to something other than their default.
+func policyOverrides(fs *flag.FlagSet) []string {
+	var overrides []string
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range policyFlags {
+			if f.Name == name && f.Value.String() != f.DefValue {
+				overrides = append(overrides, "-"+name)
+			}
+		}
+	})
+	return overrides
+}
+
+// loadedPolicy is the filter configuration of a run, from its -config file or -preset bundle.
+type loadedPolicy struct {
+	opts   []pii.Option
+	stages pii.Stages
+	// signature is set when the policy was verified against a -policy-key.
+	signature *pii.PolicySignature
+	// preset, when set, also supplies the dictionary and templates of the run.
+	preset *pii.Preset
+}
+
+// loadPolicy loads the -config file or -preset bundle and returns its filter options and
+// pipeline stages. With a -policy-key one of them is required and must carry a valid signature
+// by that key, and flags overriding its settings are refused, so that only approved policies
+// run; the signature is returned for the manifest.
+func loadPolicy(configFile, presetFile, keyFile string, fs *flag.FlagSet) (loadedPolicy, error) {
+	if configFile != "" && presetFile != "" {
+		return loadedPolicy{}, fmt.Errorf("-config and -preset cannot be combined; pack the config into the preset instead")
+	}
+	var key ed25519.PublicKey
+	if keyFile != "" {
+		if configFile == "" && presetFile == "" {
+			return loadedPolicy{}, fmt.Errorf("-policy-key requires a signed -config or -preset")
+		}
+		if overrides := policyOverrides(fs); len(overrides) > 0 {
+			return loadedPolicy{}, fmt.Errorf("flags %s are not covered by the policy signature; set them in the signed policy instead", strings.Join(overrides, ", "))
+		}
+		var err error
+		if key, err = pii.LoadPolicyPublicKey(keyFile); err != nil {
+			return loadedPolicy{}, err
+		}
+	}
+
+	p := loadedPolicy{stages: pii.DefaultStages}
+	var err error
+	switch {
+	case presetFile != "" && key != nil:
+		p.preset, p.signature, err = pii.LoadSignedPreset(presetFile, key)
+	case presetFile != "":
+		p.preset, err = pii.LoadPreset(presetFile)
+	case configFile != "":
+		var cfg *pii.Config
+		if key != nil {
+			cfg, p.signature, err = pii.LoadSignedConfig(configFile, key)
+		} else {
+			cfg, err = pii.LoadConfig(configFile)
+		}
+		if err != nil {
+			return loadedPolicy{}, err
+		}
+		// The config has already been validated.
+		p.opts, _ = cfg.Options()
+		p.stages = cfg.Stages()
+		return p, nil
+	default:
+		return p, nil
+	}
+	if err != nil {
+		return loadedPolicy{}, err
+	}
+	p.opts = p.preset.Options()
+	p.stages = p.preset.Stages()
+	return p, nil
+}
+
+// loadPolicyData loads the gazetteer and dictionary data of the -data-dir, unless the policy
+// is signed: installed data would change the detectors behind the signature's back.
+func loadPolicyData(dir string, p loadedPolicy) (*pii.DataSet, error) {
+	if p.signature != nil {
+		return nil, nil
+	}
+	return loadDataSet(dir)
+}
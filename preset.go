@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"pdf-reader/pii"
+)
+
+// runPreset implements "pdf-reader preset": it packs a team's policy, dictionaries, gazetteer
+// and templates into a single bundle for -preset, and shows what a bundle holds.
+func runPreset(args []string) error {
+	usage := fmt.Errorf("usage: preset pack -out hr-distribution.bundle [-name NAME] [-config rules.yaml] [-words words.txt] ... | preset show hr-distribution.bundle")
+	if len(args) == 0 {
+		return usage
+	}
+	fs := flag.NewFlagSet("preset "+args[0], flag.ExitOnError)
+	switch args[0] {
+	case "pack":
+		out := fs.String("out", "", "bundle file to write, e.g. hr-distribution"+pii.PresetExt)
+		name := fs.String("name", "", "name of the preset (default: the bundle file name)")
+		description := fs.String("description", "", "what the preset is for")
+		sources := map[string]*string{
+			pii.PresetConfigFile:          fs.String("config", "", "YAML or JSON -config file: the policy"),
+			pii.PresetWordsFile:           fs.String("words", "", "dictionary used instead of english_words.txt"),
+			pii.PresetAllowlistFile:       fs.String("allowlist", "", "-allowlist file"),
+			pii.PresetDenylistFile:        fs.String("denylist", "", "-denylist file"),
+			pii.PresetAddressKeywordsFile: fs.String("address-keywords", "", "-address-keywords file"),
+			pii.PresetPlacesFile:          fs.String("places", "", "address gazetteer: one state or city name per line, replacing the profile's"),
+			pii.PresetTemplatesFile:       fs.String("lang-file", "", "placeholder and report string templates (-lang-file format)"),
+		}
+		fs.Parse(args[1:])
+		if *out == "" || fs.NArg() != 0 {
+			return usage
+		}
+		if !strings.EqualFold(filepath.Ext(*out), pii.PresetExt) {
+			return fmt.Errorf("-out must end in %s", pii.PresetExt)
+		}
+		info := pii.PresetInfo{Name: *name, Description: *description}
+		if info.Name == "" {
+			info.Name = strings.TrimSuffix(filepath.Base(*out), filepath.Ext(*out))
+		}
+		files := make(map[string]string)
+		for file, src := range sources {
+			if *src != "" {
+				files[file] = *src
+			}
+		}
+		// A JSON config is converted by the bundle's YAML parser, as JSON is valid YAML.
+		if err := pii.PackPreset(*out, info, files); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote preset %s to %s (%d files)\n", info.Name, *out, len(files)+1)
+	case "show":
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return usage
+		}
+		p, err := pii.LoadPreset(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Preset: %s\n", p.Name)
+		if p.Description != "" {
+			fmt.Printf("Description: %s\n", p.Description)
+		}
+		fmt.Printf("Pipeline: %s\n", p.Stages())
+		for _, f := range p.Files {
+			fmt.Printf("  %-22s %9d bytes  sha256 %s\n", f.Name, f.Size, f.SHA256)
+		}
+	default:
+		return usage
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+// Protocol buffer wire types used by the messages of redactor.proto.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// protoBuffer encodes a protocol buffer message field by field. Zero values are omitted, as
+// proto3 does.
+type protoBuffer []byte
+
+func (b *protoBuffer) tag(field, wire int) {
+	*b = binary.AppendUvarint(*b, uint64(field)<<3|uint64(wire))
+}
+
+func (b *protoBuffer) varint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, wireVarint)
+	*b = binary.AppendUvarint(*b, v)
+}
+
+func (b *protoBuffer) double(field int, v float64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, wireFixed64)
+	*b = binary.LittleEndian.AppendUint64(*b, math.Float64bits(v))
+}
+
+func (b *protoBuffer) bytes(field int, p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	b.tag(field, wireBytes)
+	*b = binary.AppendUvarint(*b, uint64(len(p)))
+	*b = append(*b, p...)
+}
+
+func (b *protoBuffer) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+// strings encodes a repeated string field; unlike a singular field, empty elements are kept.
+func (b *protoBuffer) strings(field int, values []string) {
+	for _, s := range values {
+		b.tag(field, wireBytes)
+		*b = binary.AppendUvarint(*b, uint64(len(s)))
+		*b = append(*b, s...)
+	}
+}
+
+// message encodes a nested message field; nil messages are omitted.
+func (b *protoBuffer) message(field int, m protoBuffer) {
+	if m == nil {
+		return
+	}
+	b.tag(field, wireBytes)
+	*b = binary.AppendUvarint(*b, uint64(len(m)))
+	*b = append(*b, m...)
+}
+
+// countMap encodes a map<string, int64> field, sorted by key so that the encoding is stable.
+func (b *protoBuffer) countMap(field int, m map[string]int) {
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		entry := protoBuffer{}
+		entry.string(1, k)
+		entry.varint(2, uint64(m[k]))
+		b.message(field, entry)
+	}
+}
+
+// parseProto calls fn for each field of the encoded message msg: v holds the value of varint
+// and fixed-size fields and p the payload of length-delimited ones. Unknown fields are left to
+// fn to ignore.
+func parseProto(msg []byte, fn func(field, wire int, v uint64, p []byte) error) error {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return fmt.Errorf("malformed field key")
+		}
+		msg = msg[n:]
+		field, wire := int(key>>3), int(key&7)
+		var v uint64
+		var p []byte
+		switch wire {
+		case wireVarint:
+			if v, n = binary.Uvarint(msg); n <= 0 {
+				return fmt.Errorf("malformed varint in field %d", field)
+			}
+			msg = msg[n:]
+		case wireFixed64:
+			if len(msg) < 8 {
+				return fmt.Errorf("truncated field %d", field)
+			}
+			v, msg = binary.LittleEndian.Uint64(msg), msg[8:]
+		case wireFixed32:
+			if len(msg) < 4 {
+				return fmt.Errorf("truncated field %d", field)
+			}
+			v, msg = uint64(binary.LittleEndian.Uint32(msg)), msg[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || size > uint64(len(msg)-n) {
+				return fmt.Errorf("truncated field %d", field)
+			}
+			p, msg = msg[n:n+int(size)], msg[n+int(size):]
+		default:
+			return fmt.Errorf("unsupported wire type %d in field %d", wire, field)
+		}
+		if field == 0 {
+			return fmt.Errorf("invalid field number 0")
+		}
+		if err := fn(field, wire, v, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"pdf-reader/pii"
+)
+
+// HashKeyEnv holds the key of the hash action of redact-fields. Hashes made with the same key
+// can be joined across exports; without the key they cannot be brute-forced.
+const HashKeyEnv = "PII_HASH_KEY"
+
+// runRedactFields implements "pdf-reader redact-fields": it scrubs a JSON or CSV export of a
+// payroll system column by column as mapped by a schema file, keeping the structure of the
+// export intact.
+func runRedactFields(args []string) error {
+	fs := flag.NewFlagSet("redact-fields", flag.ExitOnError)
+	schemaFile := fs.String("schema", "", "YAML or JSON schema mapping columns to a PII type, auto or keep")
+	in := fs.String("in", "", "JSON, JSON Lines or CSV export to scrub")
+	out := fs.String("out", "", "scrubbed output file (default: <name>_redacted<ext>)")
+	format := fs.String("format", "auto", "input format: json, csv or auto (from the file extension)")
+	configFile := fs.String("config", "", "YAML or JSON file with custom detectors, placeholders and disabled entity types")
+	fs.Parse(args)
+
+	if *schemaFile == "" || *in == "" {
+		return fmt.Errorf("-schema and -in are required")
+	}
+	if *format == "auto" {
+		var err error
+		if *format, err = exportFormat(*in); err != nil {
+			return err
+		}
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown -format %q (expected json, csv or auto)", *format)
+	}
+	if *out == "" {
+		ext := filepath.Ext(*in)
+		*out = strings.TrimSuffix(*in, ext) + "_redacted" + ext
+	}
+	if samePath(*in, *out) {
+		return fmt.Errorf("-out must not overwrite the input")
+	}
+
+	schema, err := pii.LoadFieldSchema(*schemaFile)
+	if err != nil {
+		return err
+	}
+	key := []byte(os.Getenv(HashKeyEnv))
+	for column, rule := range schema.Columns {
+		if rule.Action == pii.ColumnHash && len(key) == 0 {
+			return fmt.Errorf("column %q is hashed: set %s to the hash key", column, HashKeyEnv)
+		}
+	}
+	var filterOpts []pii.Option
+	if *configFile != "" {
+		cfg, err := pii.LoadConfig(*configFile)
+		if err != nil {
+			return err
+		}
+		filterOpts, _ = cfg.Options()
+	}
+	filter := pii.NewFilter(filterOpts...)
+	if err := filter.ValidateFieldSchema(*schema, key); err != nil {
+		return err
+	}
+
+	src, err := os.Open(*in)
+	if err != nil {
+		return fmt.Errorf("failed to open input: %v", err)
+	}
+	defer src.Close()
+	if err := pii.CheckHold(*out); err != nil {
+		return err
+	}
+	dst, err := pii.CreateOutput(*out)
+	if err != nil {
+		return fmt.Errorf("failed to create output: %v", err)
+	}
+	defer dst.Close()
+
+	var stats pii.FieldStats
+	if *format == "csv" {
+		stats, err = filter.RedactCSV(src, dst, *schema, key)
+	} else {
+		stats, err = filter.RedactJSON(src, dst, *schema, key)
+	}
+	if err != nil {
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to write output: %v", err)
+	}
+
+	fmt.Printf("Scrubbed %d records from %s to %s\n", stats.Records, *in, *out)
+	columns := make([]string, 0, len(stats.Redacted))
+	for column := range stats.Redacted {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		fmt.Printf("  %s: %d values\n", column, stats.Redacted[column])
+	}
+	return nil
+}
+
+// exportFormat tells the format of a structured export from its file extension.
+func exportFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv", nil
+	case ".json", ".jsonl", ".ndjson":
+		return "json", nil
+	}
+	return "", fmt.Errorf("cannot tell the format of %s; use -format json or csv", path)
+}
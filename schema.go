@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"pdf-reader/pii"
+)
+
+// runSchema implements "pdf-reader schema infer": it samples a JSON or CSV export, suggests
+// how each column is scrubbed and writes the suggestion as a redact-fields schema to edit.
+func runSchema(args []string) error {
+	if len(args) == 0 || args[0] != "infer" {
+		return fmt.Errorf("usage: schema infer -in export.csv [-out schema.yaml]")
+	}
+	fs := flag.NewFlagSet("schema infer", flag.ExitOnError)
+	in := fs.String("in", "", "JSON, JSON Lines or CSV export to sample")
+	out := fs.String("out", "schema.yaml", "schema file to write")
+	format := fs.String("format", "auto", "input format: json, csv or auto (from the file extension)")
+	sample := fs.Int("sample", 200, "number of records to sample")
+	configFile := fs.String("config", "", "YAML or JSON file with custom detectors, placeholders and disabled entity types")
+	fs.Parse(args[1:])
+
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+	if *sample < 1 {
+		return fmt.Errorf("invalid -sample %d (expected 1 or more)", *sample)
+	}
+	if *format == "auto" {
+		var err error
+		if *format, err = exportFormat(*in); err != nil {
+			return err
+		}
+	}
+	var filterOpts []pii.Option
+	if *configFile != "" {
+		cfg, err := pii.LoadConfig(*configFile)
+		if err != nil {
+			return err
+		}
+		filterOpts, _ = cfg.Options()
+	}
+	filter := pii.NewFilter(filterOpts...)
+
+	src, err := os.Open(*in)
+	if err != nil {
+		return fmt.Errorf("failed to open input: %v", err)
+	}
+	defer src.Close()
+	schema, err := filter.InferFieldSchema(src, *format, *sample)
+	if err != nil {
+		return err
+	}
+
+	b, err := yaml.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %v", err)
+	}
+	header := fmt.Sprintf("# Suggested by schema infer from the first %[2]d records of %[1]s.\n"+
+		"# Review every column: type is an entity type, auto or keep; action is redact, hash or mask.\n", *in, *sample)
+	if err := pii.CheckHold(*out); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, append([]byte(header), b...), 0o644); err != nil {
+		return fmt.Errorf("failed to write schema: %v", err)
+	}
+
+	columns := make([]string, 0, len(schema.Columns))
+	for column := range schema.Columns {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		rule := schema.Columns[column]
+		fmt.Printf("  %-30s %-16s %.2f\n", column, rule.Type, rule.Confidence)
+	}
+	fmt.Printf("Schema written to %s; review it, then run redact-fields -schema %s\n", *out, *out)
+	return nil
+}
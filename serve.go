@@ -0,0 +1,554 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"pdf-reader/pii"
+)
+
+// DefaultServeAddr is the listen address of the serve subcommand.
+const DefaultServeAddr = "localhost:8080"
+
+// server redacts uploaded PDFs with a filter and word list shared by all requests; requests
+// overriding its options get a clone of it.
+type server struct {
+	filter *pii.Filter
+	// signedPolicy refuses per-request overrides, as the filter runs a policy verified
+	// against a -policy-key.
+	signedPolicy bool
+	// pipeline is what every upload goes through; its Password is set per request.
+	pipeline  pii.Pipeline
+	maxUpload int64
+	// requirePurpose rejects requests without a processing purpose.
+	requirePurpose bool
+	// suppressions, when set, is the false-positive store of the filter, saved to
+	// suppressionsFile after every change.
+	suppressions     *pii.SuppressionStore
+	suppressionsFile string
+	// suppressionsToken is the bearer token that POST and DELETE /v1/suppressions require.
+	suppressionsToken string
+	// metrics adds up the timings of every redacted document for GET /metrics; timings also
+	// returns them in each JSON response.
+	metrics pii.TimingMetrics
+	timings bool
+}
+
+// runServe implements "pdf-reader serve": an HTTP service with
+//
+//	POST   /v1/redact        multipart upload (field "file"); returns the FilteredData as JSON,
+//	                         or the cleaned text with ?format=txt; the optional purpose,
+//	                         requester and ticket fields record the processing basis in the log
+//	                         and the JSON response, and the password field opens an encrypted
+//	                         PDF; the redact, keep, gst, dates and email fields override the
+//	                         flags of the same name for this request
+//	GET    /v1/suppressions  the active false-positive suppressions (with -suppressions)
+//	POST   /v1/suppressions  marks a finding of a response as a false positive: JSON {"entity",
+//	                         "fingerprint", "reviewer", "reason", "ttl"} (with
+//	                         -suppressions-token, sent as "Authorization: Bearer <token>")
+//	DELETE /v1/suppressions  lifts a suppression: JSON {"entity", "fingerprint", "reviewer",
+//	                         "reason"} (with -suppressions-token, like POST)
+//	GET    /healthz          liveness check
+//	GET    /metrics          documents redacted and the seconds spent per stage and detector,
+//	                         in the Prometheus text format
+//
+// With -grpc-addr the same filter is also served as the gRPC service of redactor.proto (see
+// grpcHandler).
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", DefaultServeAddr, "listen address")
+	grpcAddr := fs.String("grpc-addr", "", "also serve the gRPC API of redactor.proto on this address, over cleartext HTTP/2")
+	maxUploadMB := fs.Int64("max-upload-mb", 32, "largest accepted upload in MB")
+	extractor := fs.String("extractor", "auto", "text extraction engine (see the main -extractor flag)")
+	gstPolicy := fs.String("gst", string(pii.GSTRedact), "GSTIN handling: redact, mask or retain")
+	emailPolicy := fs.String("email", "", "e-mail addresses: redact, keep-domain or org-token (default redact, or the -config email_policy)")
+	profileName := fs.String("profile", pii.DefaultProfile, "country profile of the detectors: "+strings.Join(pii.ProfileNames(), ", "))
+	documentName := fs.String("document", documentAuto, "document type: auto, "+strings.Join(pii.DocumentProfileNames(), ", "))
+	phoneRegions := fs.String("phone-regions", "", "comma-separated regions whose national phone numbers are detected (default IN, or the -config phone_regions)")
+	redact := fs.String("redact", "", "comma-separated entity types to redact, leaving all others in the text (see the main -redact flag)")
+	keep := fs.String("keep", "", "comma-separated entity types left in the text, e.g. tan,gst")
+	packs := fs.String("packs", "", "comma-separated optional detector packs for foreign tax identifiers: us, gb, au")
+	amountWords := fs.String("amount-words", string(pii.AmountWordsKeep), "amounts in words: keep, digits or redact")
+	dates := fs.String("dates", string(pii.DateBirth), "date categories to redact: dob, period, other, all or none")
+	pageHeaders := fs.String("page-headers", string(pii.HeaderFooterKeep), "lines repeated at the top or bottom of every page: keep, dedupe or remove")
+	panContext := fs.Bool("pan-context", false, "only redact PAN-shaped tokens with a PAN label nearby")
+	numbered := fs.Bool("numbered", false, "replace each distinct value with a token numbered per request ([PAN_1], [PAN_2]...) instead of its placeholder")
+	minConfidence := fs.Float64("min-confidence", 0, "only redact findings with at least this confidence (0-1)")
+	matchValues := fs.String("match-values", string(pii.MatchValueNone), "original values in the matches list: none, hash or plain")
+	explain := fs.Bool("explain", false, "record the detector, rule and policy behind each match in the matches list")
+	timings := fs.Bool("timings", false, "also return the time each stage and detector took in the JSON response (GET /metrics has the totals either way)")
+	allowlist := fs.String("allowlist", "", "file with one value per line that is never redacted")
+	denylist := fs.String("denylist", "", "file with one value per line (literal or /regular expression/) that is always redacted")
+	glossary := fs.String("glossary", "", "file with one term per line that the dictionary stage never redacts, on top of the built-in tax glossary")
+	wordList := fs.String("wordlist", "", "comma-separated word lists merged into the dictionary of the dictionary stage; \"default\" keeps the embedded english_words.txt (or the -preset's or -data-dir's)")
+	suppressionsFile := fs.String("suppressions", "", "store of false positives left in the text, managed through /v1/suppressions")
+	suppressionsToken := fs.String("suppressions-token", "", "file holding the bearer token that POST and DELETE /v1/suppressions require; without it the store is read-only over HTTP")
+	configFile := fs.String("config", "", "YAML or JSON file with custom detectors, placeholders and disabled entity types")
+	presetFile := fs.String("preset", "", "preset bundle holding the policy, dictionaries, gazetteer and templates, instead of -config")
+	policyKey := fs.String("policy-key", "", "compliance public key (PEM); the server then refuses to start unless -config or -preset is signed with it")
+	dataDir := fs.String("data-dir", pii.DefaultDataDir, "directory of the gazetteer and dictionary data installed with the data subcommand")
+	lang := fs.String("lang", "en", "language of placeholders: "+strings.Join(pii.LocaleNames(), ", "))
+	langFile := fs.String("lang-file", "", "JSON file with extra or overriding translations")
+	requirePurpose := fs.Bool("require-purpose", false, "reject requests that do not state a processing purpose")
+	tmpDir := fs.String("tmp-dir", "", "directory for per-request work directories, e.g. a tmpfs such as /dev/shm (default: system temporary directory)")
+	fs.Parse(args)
+
+	if _, ok := pii.ExtractorByName(*extractor); !ok && *extractor != "auto" {
+		return fmt.Errorf("unknown -extractor %q", *extractor)
+	}
+	if err := pii.SetTempRoot(*tmpDir); err != nil {
+		return err
+	}
+	if *minConfidence < 0 || *minConfidence > 1 {
+		return fmt.Errorf("invalid -min-confidence %g (expected 0 to 1)", *minConfidence)
+	}
+	profile, err := pii.LookupProfile(*profileName)
+	if err != nil {
+		return err
+	}
+	document, err := lookupDocument(*documentName)
+	if err != nil {
+		return err
+	}
+	gst, err := pii.ParseGSTPolicy(*gstPolicy)
+	if err != nil {
+		return err
+	}
+	amounts, err := pii.ParseAmountWordsPolicy(*amountWords)
+	if err != nil {
+		return err
+	}
+	datePolicy, err := pii.ParseDatePolicy(*dates)
+	if err != nil {
+		return err
+	}
+	headerFooters, err := pii.ParseHeaderFooterPolicy(*pageHeaders)
+	if err != nil {
+		return err
+	}
+	values, err := pii.ParseMatchValuePolicy(*matchValues)
+	if err != nil {
+		return err
+	}
+	locale, err := pii.LoadLocale(*lang, *langFile)
+	if err != nil {
+		return err
+	}
+	loaded, err := loadPolicy(*configFile, *presetFile, *policyKey, fs)
+	if err != nil {
+		return err
+	}
+	if loaded.signature != nil {
+		log.Printf("Policy %s verified: sha256 %s, key %s", loaded.signature.File, loaded.signature.SHA256, loaded.signature.KeyID)
+	}
+	var words map[string]struct{}
+	if loaded.preset != nil {
+		locale, words = loaded.preset.Locale(locale), loaded.preset.Words
+		log.Printf("Preset %s loaded from %s", loaded.preset.Name, loaded.preset.File)
+	}
+	data, err := loadPolicyData(*dataDir, loaded)
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		log.Printf("Data set %s loaded from %s", data.Version, data.Dir)
+		if words == nil {
+			words = data.Words
+		}
+	}
+	filterOpts := []pii.Option{pii.WithProfile(profile), pii.WithGSTPolicy(gst), pii.WithAmountWords(amounts), pii.WithDatePolicy(datePolicy), pii.WithHeaderFooterPolicy(headerFooters), pii.WithPANContext(*panContext), pii.WithLocale(locale), pii.WithMatchValues(values), pii.WithMinConfidence(*minConfidence), pii.WithExplain(*explain), pii.WithTimings(true)}
+	if *numbered {
+		filterOpts = append(filterOpts, pii.WithNumberedPlaceholders(true))
+	}
+	if document != nil {
+		filterOpts = append(filterOpts, pii.WithDocumentProfile(document))
+	}
+	if data != nil {
+		filterOpts = append(filterOpts, data.Options(profile.Name)...)
+	}
+	filterOpts = append(filterOpts, loaded.opts...)
+	if *emailPolicy != "" {
+		email, err := pii.ParseEmailPolicy(*emailPolicy)
+		if err != nil {
+			return err
+		}
+		filterOpts = append(filterOpts, pii.WithEmailPolicy(email))
+	}
+	detectorPacks, err := pii.ParseDetectorPacks(*packs)
+	if err != nil {
+		return err
+	}
+	filterOpts = append(filterOpts, pii.WithDetectorPacks(detectorPacks...))
+	redactTypes, keepTypes := pii.ParseEntityTypes(*redact), pii.ParseEntityTypes(*keep)
+	if len(redactTypes) > 0 {
+		filterOpts = append(filterOpts, pii.WithOnly(redactTypes...))
+	}
+	filterOpts = append(filterOpts, pii.WithDisabled(keepTypes...))
+	if *denylist != "" {
+		pattern, err := pii.LoadDenylist(*denylist)
+		if err != nil {
+			return err
+		}
+		filterOpts = append(filterOpts, pii.WithDenylist(pattern))
+	}
+	if *allowlist != "" {
+		values, err := pii.LoadAllowlist(*allowlist)
+		if err != nil {
+			return err
+		}
+		filterOpts = append(filterOpts, pii.WithAllowlist(values...))
+	}
+	if *glossary != "" {
+		terms, err := pii.LoadGlossary(*glossary)
+		if err != nil {
+			return err
+		}
+		filterOpts = append(filterOpts, pii.WithGlossary(terms...))
+	}
+	if *phoneRegions != "" {
+		regions, err := pii.ParsePhoneRegions(*phoneRegions)
+		if err != nil {
+			return err
+		}
+		filterOpts = append(filterOpts, pii.WithPhoneRegions(regions...))
+	}
+	var suppressions *pii.SuppressionStore
+	if *suppressionsFile != "" {
+		if suppressions, err = pii.LoadSuppressions(*suppressionsFile); err != nil {
+			return err
+		}
+		filterOpts = append(filterOpts, pii.WithSuppressions(suppressions))
+	}
+	var token string
+	if *suppressionsToken != "" {
+		if *suppressionsFile == "" {
+			return fmt.Errorf("-suppressions-token needs -suppressions")
+		}
+		data, err := os.ReadFile(*suppressionsToken)
+		if err != nil {
+			return fmt.Errorf("failed to read suppressions token: %v", err)
+		}
+		if token = strings.TrimSpace(string(data)); token == "" {
+			return fmt.Errorf("suppressions token file %s is empty", *suppressionsToken)
+		}
+	}
+	stages := loaded.stages
+	if stages == nil {
+		stages = pii.DefaultStages
+	}
+	if stages.Has(pii.StageDictionary) {
+		if words, err = mergeWordLists(parseWordLists(*wordList), words); err != nil {
+			return err
+		}
+	}
+
+	filter := pii.NewFilter(filterOpts...)
+	if err := filter.CheckEntityTypes(redactTypes...); err != nil {
+		return fmt.Errorf("invalid -redact: %v", err)
+	}
+	if err := filter.CheckEntityTypes(keepTypes...); err != nil {
+		return fmt.Errorf("invalid -keep: %v", err)
+	}
+	s := &server{
+		filter:    filter,
+		maxUpload: *maxUploadMB << 20,
+
+		signedPolicy:     loaded.signature != nil,
+		requirePurpose:   *requirePurpose,
+		suppressions:     suppressions,
+		suppressionsFile: *suppressionsFile,
+		timings:          *timings,
+
+		suppressionsToken: token,
+	}
+	s.pipeline = pii.Pipeline{Filter: s.filter, Stages: loaded.stages, Words: words, Workers: 1}
+	s.pipeline.Extractor, _ = pii.ExtractorByName(*extractor)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/redact", s.handleRedact)
+	if suppressions != nil {
+		mux.HandleFunc("GET /v1/suppressions", s.handleListSuppressions)
+		// Changing the store needs a token: anyone who can reach the server could otherwise
+		// lift the redaction of any value.
+		if s.suppressionsToken != "" {
+			mux.HandleFunc("POST /v1/suppressions", s.handleSuppress)
+			mux.HandleFunc("DELETE /v1/suppressions", s.handleSuppress)
+		}
+	}
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		s.metrics.WriteTo(w)
+	})
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       2 * time.Minute,
+		WriteTimeout:      5 * time.Minute,
+	}
+	errc := make(chan error, 2)
+	if *grpcAddr != "" {
+		// gRPC streams may stay open for as long as the client sends text, so only the
+		// headers have a deadline.
+		var protocols http.Protocols
+		protocols.SetUnencryptedHTTP2(true)
+		grpcSrv := &http.Server{
+			Addr:              *grpcAddr,
+			Handler:           s.grpcHandler(),
+			Protocols:         &protocols,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		fmt.Printf("Serving gRPC on %s (%s)\n", *grpcAddr, grpcService)
+		go func() { errc <- grpcSrv.ListenAndServe() }()
+	}
+	fmt.Printf("Serving on http://%s (POST /v1/redact)\n", *addr)
+	go func() { errc <- srv.ListenAndServe() }()
+	return <-errc
+}
+
+// handleRedact extracts and redacts one uploaded PDF. The upload is written to a temporary
+// file for the extractors and removed before the response is sent.
+func (s *server) handleRedact(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "txt" {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown format %q (expected json or txt)", format))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUpload)
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		status := http.StatusBadRequest
+		if _, ok := err.(*http.MaxBytesError); ok {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, fmt.Sprintf("failed to read upload: %v", err))
+		return
+	}
+	defer upload.Close()
+
+	basis, err := pii.NewProcessingBasis(r.FormValue("purpose"), r.FormValue("requester"), r.FormValue("ticket"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if basis == nil && s.requirePurpose {
+		writeError(w, http.StatusBadRequest, "a processing purpose is required")
+		return
+	}
+
+	filter, err := s.requestFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if basis != nil {
+		log.Printf("Redacting upload %s (%d bytes) %s", header.Filename, header.Size, basis)
+	} else {
+		log.Printf("Redacting upload %s (%d bytes)", header.Filename, header.Size)
+	}
+	data, err := s.redactPDF(r.Context(), filter, upload, r.FormValue("password"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.As(err, new(unreadableError)) {
+			status = http.StatusUnprocessableEntity
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	data.ProcessingBasis = basis
+
+	if format == "txt" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, data.CleanedText)
+		return
+	}
+	writeJSON(w, http.StatusOK, pii.NewReport(data))
+}
+
+// unreadableError is an extraction failure: the upload is not a PDF the extractors can read.
+type unreadableError struct {
+	err error
+}
+
+func (e unreadableError) Error() string {
+	return e.err.Error()
+}
+
+// requestOverrides are the form fields of POST /v1/redact that override the filter's options
+// for one request.
+var requestOverrides = []string{"redact", "keep", "gst", "dates", "email"}
+
+// requestFilter returns the filter of a request: the server's own, or a clone of it with the
+// options of the request's override fields. Overrides are refused under a signed policy.
+func (s *server) requestFilter(r *http.Request) (*pii.Filter, error) {
+	var opts []pii.Option
+	for _, field := range requestOverrides {
+		value := r.FormValue(field)
+		if value == "" {
+			continue
+		}
+		if s.signedPolicy {
+			return nil, fmt.Errorf("the %s field is not covered by the policy signature", field)
+		}
+		switch field {
+		case "redact", "keep":
+			types := pii.ParseEntityTypes(value)
+			if err := s.filter.CheckEntityTypes(types...); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", field, err)
+			}
+			if field == "redact" {
+				opts = append(opts, pii.WithOnly(types...))
+			} else {
+				opts = append(opts, pii.WithDisabled(types...))
+			}
+		case "gst":
+			policy, err := pii.ParseGSTPolicy(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid gst: %v", err)
+			}
+			opts = append(opts, pii.WithGSTPolicy(policy))
+		case "dates":
+			policy, err := pii.ParseDatePolicy(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid dates: %v", err)
+			}
+			opts = append(opts, pii.WithDatePolicy(policy))
+		case "email":
+			policy, err := pii.ParseEmailPolicy(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid email: %v", err)
+			}
+			opts = append(opts, pii.WithEmailPolicy(policy))
+		}
+	}
+	if len(opts) == 0 {
+		return s.filter, nil
+	}
+	return s.filter.Clone(opts...), nil
+}
+
+// redactPDF stores the PDF read from upload in a private workspace, for the extractors, then
+// extracts it, opening it with password when it is encrypted, and redacts it with filter. The
+// workspace is removed before it returns.
+func (s *server) redactPDF(ctx context.Context, filter *pii.Filter, upload io.Reader, password string) (pii.FilteredData, error) {
+	ws, err := pii.NewWorkspace("upload")
+	if err != nil {
+		return pii.FilteredData{}, err
+	}
+	defer ws.Close()
+	tmp, err := ws.CreateTemp("form16-*.pdf")
+	if err != nil {
+		return pii.FilteredData{}, fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	_, err = io.Copy(tmp, upload)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return pii.FilteredData{}, fmt.Errorf("failed to store upload: %v", err)
+	}
+
+	p := s.pipeline
+	p.Filter, p.Password = filter, password
+	extraction, err := p.Extract(ctx, tmp.Name())
+	if err != nil {
+		return pii.FilteredData{}, unreadableError{err}
+	}
+	run, err := p.Redact(ctx, extraction)
+	if err != nil {
+		return pii.FilteredData{}, err
+	}
+	data := run.Data
+	s.metrics.Add(data.Timings)
+	if !s.timings {
+		data.Timings = nil
+	}
+	return data, nil
+}
+
+// suppressionRequest is the body of POST and DELETE /v1/suppressions.
+type suppressionRequest struct {
+	Entity      string `json:"entity"`
+	Fingerprint string `json:"fingerprint"`
+	Reviewer    string `json:"reviewer"`
+	Reason      string `json:"reason"`
+	// TTL is a Go duration ("2160h"); the default is pii.DefaultSuppressionTTL.
+	TTL string `json:"ttl"`
+}
+
+// handleListSuppressions returns the active suppressions.
+func (s *server) handleListSuppressions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]pii.Suppression{"suppressions": s.suppressions.Active()})
+}
+
+// handleSuppress adds (POST) or lifts (DELETE) the suppression of a finding and saves the
+// store. The filter sees the change from the next request on.
+func (s *server) handleSuppress(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.suppressionsToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "a valid suppressions token is required")
+		return
+	}
+	var req suppressionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse request: %v", err))
+		return
+	}
+	status, body := http.StatusOK, interface{}(map[string]string{"status": "lifted"})
+	if r.Method == http.MethodPost {
+		var ttl time.Duration
+		if req.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(req.TTL); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid ttl: %v", err))
+				return
+			}
+		}
+		sup, err := s.suppressions.Add(req.Entity, req.Fingerprint, req.Reviewer, req.Reason, ttl)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		status, body = http.StatusCreated, sup
+	} else if err := s.suppressions.Remove(req.Entity, req.Fingerprint, req.Reviewer, req.Reason); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err := s.suppressions.Save(s.suppressionsFile); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	log.Printf("Suppression %s: %s %s by %s", r.Method, req.Entity, req.Fingerprint, req.Reviewer)
+	writeJSON(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError sends {"error": msg} with the given status.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"pdf-reader/pii"
+)
+
+// StreamInput is the -in value (or first argument) that reads already-extracted text from
+// stdin and writes the redacted text to stdout.
+const StreamInput = "-"
+
+// redactStream redacts already-extracted text, such as the output of "pdftotext form16.pdf -",
+// read from r and writes only the cleaned text to w (the JSON report with -format json), so
+// the tool can be chained with other UNIX tools. Warnings are printed to stderr and no manifest
+// or raw text file is written.
+func redactStream(r io.Reader, w io.Writer, opts runOptions) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read standard input: %v", err)
+	}
+	piiFilter, err := newFilter(opts)
+	if err != nil {
+		return err
+	}
+	wordSet, err := opts.wordSet()
+	if err != nil {
+		return err
+	}
+	// pdftotext separates pages with form feeds, which FilterPages can spread over workers.
+	pipeline := opts.pipeline(piiFilter, wordSet, false)
+	run, err := pipeline.Redact(context.Background(), pii.Extraction{Pages: pii.Pages(strings.Split(string(b), "\f"))})
+	warnings := &warningPrinter{out: os.Stderr, color: stderrColor}
+	warnings.flush(run.Data.Warnings)
+	if err != nil {
+		return err
+	}
+	if opts.reviewFile != "" {
+		if err := pii.SaveReviewCopy(piiFilter.ReviewCopy(run.Text), opts.reviewFile); err != nil {
+			return fmt.Errorf("error saving review copy: %v", err)
+		}
+	}
+	data := run.Data
+	data.ProcessingBasis = opts.basis
+
+	if opts.formats[0] == "json" {
+		out, err := json.MarshalIndent(pii.NewReport(data), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode filtered data: %v", err)
+		}
+		if _, err := w.Write(append(out, '\n')); err != nil {
+			return fmt.Errorf("failed to write standard output: %v", err)
+		}
+		return nil
+	}
+	if _, err := io.WriteString(w, data.CleanedText); err != nil {
+		return fmt.Errorf("failed to write standard output: %v", err)
+	}
+	return nil
+}
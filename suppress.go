@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"pdf-reader/pii"
+)
+
+// DefaultSuppressionsFile is the store the suppress subcommand works on.
+const DefaultSuppressionsFile = "suppressions.json"
+
+// runSuppress implements "pdf-reader suppress": it marks a finding of a JSON report, by its
+// entity type and fingerprint, as a false positive that later runs with -suppressions leave in
+// the text, lifts such a suppression again, or lists the active ones. Every change is recorded
+// in the audit trail of the store.
+func runSuppress(args []string) error {
+	fs := flag.NewFlagSet("suppress", flag.ExitOnError)
+	store := fs.String("store", DefaultSuppressionsFile, "suppression store, created when missing")
+	entity := fs.String("entity", "", "entity type of the finding (entity in the report's matches)")
+	fingerprint := fs.String("fingerprint", "", "fingerprint of the finding (fingerprint in the matches of a report written with -suppressions)")
+	reviewer := fs.String("reviewer", "", "person marking the finding, recorded in the audit trail")
+	reason := fs.String("reason", "", "why the finding is not PII, recorded in the audit trail")
+	ttl := fs.Duration("ttl", pii.DefaultSuppressionTTL, "how long the suppression lasts")
+	remove := fs.Bool("remove", false, "lift the suppression of the finding instead")
+	list := fs.Bool("list", false, "list the active suppressions")
+	fs.Parse(args)
+
+	suppressions, err := pii.LoadSuppressions(*store)
+	if err != nil {
+		return err
+	}
+	if *list {
+		for _, s := range suppressions.Active() {
+			fmt.Printf("%s %s expires %s (%s: %s)\n", s.Entity, s.Fingerprint, s.Expires.Format(time.DateOnly), s.Reviewer, s.Reason)
+		}
+		return nil
+	}
+	if *remove {
+		if err := suppressions.Remove(*entity, *fingerprint, *reviewer, *reason); err != nil {
+			return err
+		}
+		fmt.Printf("Lifted the suppression of %s %s\n", *entity, *fingerprint)
+	} else {
+		s, err := suppressions.Add(*entity, *fingerprint, *reviewer, *reason, *ttl)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Suppressed %s %s until %s\n", s.Entity, s.Fingerprint, s.Expires.Format(time.DateOnly))
+	}
+	return suppressions.Save(*store)
+}
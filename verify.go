@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"pdf-reader/pii"
+)
+
+// runVerify implements "pdf-reader verify": the check recipients of redacted artifacts run
+// without the inputs or the pipeline. For each artifact it reads the provenance header written
+// with -sign-key, checks the signature against the sender's public key and scans the text
+// again for anything the detectors still match. With -audit-log it also checks the hash chain
+// of an audit log written with the main -audit-log flag, against the last hash printed by a
+// run with -audit-hash, and with -audit-value lists the records that removed a value.
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	keyFile := fs.String("key", "", "Ed25519 public key (PEM) of the sender, from policy keygen; without it the signature is not checked")
+	profile := fs.String("profile", pii.DefaultProfile, "country profile of the detectors: "+strings.Join(pii.ProfileNames(), ", "))
+	auditLog := fs.String("audit-log", "", "also check the hash chain of this audit log")
+	auditHash := fs.String("audit-hash", "", "last hash printed by a run of the -audit-log and kept elsewhere, which a record must have; without it, lines rewritten along with the hashes after them go unnoticed")
+	auditKeyFile := fs.String("audit-key", "", "audit key of the run, for -audit-value")
+	auditValue := fs.String("audit-value", "", "list the -audit-log records that removed this value (needs -audit-key)")
+	fs.Parse(args)
+	if fs.NArg() == 0 && *auditLog == "" {
+		return fmt.Errorf("usage: verify [-key sender.pub] [-audit-log audit.jsonl] artifact...")
+	}
+	p, err := pii.LookupProfile(*profile)
+	if err != nil {
+		return err
+	}
+	piiFilter := pii.NewFilter(pii.WithProfile(p))
+	var key ed25519.PublicKey
+	if *keyFile != "" {
+		if key, err = pii.LoadPolicyPublicKey(*keyFile); err != nil {
+			return err
+		}
+	}
+
+	failed, checked := 0, fs.NArg()
+	if *auditLog != "" {
+		checked++
+		if err := verifyAuditLog(*auditLog, *auditHash, *auditKeyFile, *auditValue); err != nil {
+			failed++
+			fmt.Println(paint(colorRed, fmt.Sprintf("%s: FAILED: %v", *auditLog, err)))
+		}
+	} else if *auditValue != "" || *auditHash != "" {
+		return fmt.Errorf("-audit-value and -audit-hash need -audit-log")
+	}
+	for _, path := range fs.Args() {
+		if err := verifyArtifact(piiFilter, path, key); err != nil {
+			failed++
+			fmt.Println(paint(colorRed, fmt.Sprintf("%s: FAILED: %v", path, err)))
+			continue
+		}
+		fmt.Println(paint(colorGreen, path+": OK"))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d artifacts failed verification", failed, checked)
+	}
+	return nil
+}
+
+// verifyAuditLog checks the chain of an audit log and the known hash of a record, if set, and
+// prints the records that removed value, if set.
+func verifyAuditLog(path, known, keyFile, value string) error {
+	n, err := pii.VerifyAuditLog(path, known)
+	if err != nil {
+		return err
+	}
+	if known == "" {
+		fmt.Println(paint(colorYellow, fmt.Sprintf("%s: chain only, rewritten records go unnoticed (no -audit-hash)", path)))
+	}
+	fmt.Println(paint(colorGreen, fmt.Sprintf("%s: OK (%d records)", path, n)))
+	if value == "" {
+		return nil
+	}
+	if keyFile == "" {
+		return fmt.Errorf("-audit-value needs -audit-key")
+	}
+	key, err := pii.LoadAuditKey(keyFile)
+	if err != nil {
+		return err
+	}
+	records, err := pii.FindAuditValue(path, key, value)
+	if err != nil {
+		return err
+	}
+	for _, rec := range records {
+		fmt.Printf("%s: removed from %s on %s (run %s, record %d)\n", path, rec.Input, rec.Time.Format("2006-01-02 15:04:05 MST"), rec.RunID, rec.Seq)
+	}
+	if len(records) == 0 {
+		fmt.Printf("%s: value not found in any record\n", path)
+	}
+	return nil
+}
+
+// verifyArtifact checks one artifact and prints what it found.
+func verifyArtifact(piiFilter *pii.Filter, path string, key ed25519.PublicKey) error {
+	a, err := pii.ReadArtifact(path)
+	if err != nil {
+		return err
+	}
+	p := a.Provenance
+	fmt.Printf("%s: %s %s, run %s, policy %q, created %s\n", path, p.Tool, p.ToolVersion, p.RunID, p.Policy, p.Created.Format("2006-01-02 15:04:05 MST"))
+	if p.InputSHA256 != "" {
+		fmt.Printf("%s: input sha256 %s\n", path, p.InputSHA256)
+	}
+	if key != nil {
+		if err := a.Verify(key); err != nil {
+			return err
+		}
+		fmt.Printf("%s: signed by key %s\n", path, p.KeyID)
+	} else {
+		fmt.Println(paint(colorYellow, fmt.Sprintf("%s: signature by key %s not checked (no -key)", path, p.KeyID)))
+	}
+
+	text, err := artifactText(path, a)
+	if errors.Is(err, pii.ErrNoText) {
+		fmt.Println(paint(colorYellow, fmt.Sprintf("%s: no text layer, PII patterns not checked", path)))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// The residual matches are printed as warnings, by type and line only.
+	var warnings pii.Warnings
+	err = piiFilter.VerifyRedacted(pii.FilteredData{CleanedText: text}, &warnings)
+	stdoutWarnings().flush(warnings)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: no PII patterns found\n", path)
+	return nil
+}
+
+// artifactText returns the text of an artifact to scan for PII: the text layer of a PDF, the
+// cleaned text of a JSON report, and any other artifact as it is.
+func artifactText(path string, a *pii.Artifact) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		var warnings pii.Warnings
+		pages, _, err := pii.ExtractText(context.Background(), path, "auto", "", &warnings)
+		stdoutWarnings().flush(warnings)
+		if err != nil {
+			return "", err
+		}
+		return pages.Text(), nil
+	case ".json":
+		if r, err := pii.ParseReport(a.Body); err == nil && r.CleanedText != "" {
+			return r.CleanedText, nil
+		}
+	}
+	return string(a.Body), nil
+}
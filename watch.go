@@ -0,0 +1,390 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pdf-reader/pii"
+)
+
+// watchStagingDir is the directory inside the outbox where a document's outputs are written
+// before they are moved into the outbox, so that consumers never pick up partial results.
+const watchStagingDir = ".partial"
+
+// watcher is the state of a watch daemon: its folders, the settings every document is
+// processed with and the PDFs seen on the previous scan of the inbox.
+type watcher struct {
+	inbox, outbox, archive, quarantine string
+	opts                               runOptions
+	manifest                           pii.Manifest
+	// redactedPDF also writes a redacted PDF of every document.
+	redactedPDF bool
+	// seen holds the size and modification time of the inbox PDFs on the previous scan; a PDF
+	// is only processed once both are unchanged, so files still being copied are left alone.
+	seen map[string]os.FileInfo
+	// moveMu serialises moving files into the archive, quarantine and outbox, where names are
+	// chosen by uniquePath, between documents processed concurrently.
+	moveMu sync.Mutex
+}
+
+// watchResult is the outcome of processing one inbox PDF on a worker.
+type watchResult struct {
+	path string
+	err  error
+}
+
+// runWatch implements "pdf-reader watch": a drop-folder daemon that polls -inbox, redacts each
+// PDF once it has stopped growing, writes its outputs and manifest to -outbox and moves the
+// original, with its raw extracted text, to -archive, or to -quarantine with a failure report
+// when it cannot be redacted. A document interrupted by a crash stays in the inbox and is
+// processed again on the next start.
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	inbox := fs.String("inbox", "inbox", "directory polled for new PDFs")
+	outbox := fs.String("outbox", "outbox", "directory receiving the outputs and manifest of each redacted PDF")
+	archive := fs.String("archive", "archive", "directory the originals and their raw extracted text are moved to once redacted")
+	quarantine := fs.String("quarantine", "quarantine", "directory the originals are moved to, with a failure report, when redaction fails")
+	interval := fs.Duration("interval", 2*time.Second, "time between scans of the inbox")
+	once := fs.Bool("once", false, "process the PDFs already in the inbox and exit")
+	extractor := fs.String("extractor", "auto", "text extraction engine (see the main -extractor flag)")
+	gstPolicy := fs.String("gst", string(pii.GSTRedact), "GSTIN handling: redact, mask or retain")
+	profileName := fs.String("profile", pii.DefaultProfile, "country profile of the detectors: "+strings.Join(pii.ProfileNames(), ", "))
+	documentName := fs.String("document", documentAuto, "document type: auto, "+strings.Join(pii.DocumentProfileNames(), ", "))
+	amountWords := fs.String("amount-words", string(pii.AmountWordsKeep), "amounts in words: keep, digits or redact")
+	dates := fs.String("dates", string(pii.DateBirth), "date categories to redact: dob, period, other, all or none")
+	pageHeaders := fs.String("page-headers", string(pii.HeaderFooterKeep), "lines repeated at the top or bottom of every page: keep, dedupe or remove")
+	matchValues := fs.String("match-values", string(pii.MatchValueNone), "original values in the JSON matches list: none, hash or plain")
+	configFile := fs.String("config", "", "YAML or JSON file with custom detectors, placeholders, disabled entity types and pipeline")
+	presetFile := fs.String("preset", "", "preset bundle holding the policy, dictionaries, gazetteer and templates, instead of -config")
+	policyKey := fs.String("policy-key", "", "compliance public key (PEM); the daemon then refuses to start unless -config or -preset is signed with it")
+	dataDir := fs.String("data-dir", pii.DefaultDataDir, "directory of the gazetteer and dictionary data installed with the data subcommand")
+	wordList := fs.String("wordlist", "", "comma-separated word lists merged into the dictionary of the dictionary stage; \"default\" keeps the embedded english_words.txt (or the -preset's or -data-dir's)")
+	redact := fs.String("redact", "", "comma-separated entity types to redact, leaving all others in the text (see the main -redact flag)")
+	keep := fs.String("keep", "", "comma-separated entity types left in the text, e.g. tan,gst")
+	glossary := fs.String("glossary", "", "file with one term per line that the dictionary stage never redacts, on top of the built-in tax glossary")
+	lang := fs.String("lang", "en", "language of placeholders and report headers: "+strings.Join(pii.LocaleNames(), ", "))
+	langFile := fs.String("lang-file", "", "JSON file with extra or overriding translations")
+	redactedPDF := fs.Bool("redacted-pdf", false, "also write NAME_redacted.pdf with detected PII blacked out to the outbox")
+	pdfMode := fs.String("pdf-mode", "auto", "redacted PDF engine: text, raster or auto")
+	tmpDir := fs.String("tmp-dir", "", "directory for the private per-job work directories, e.g. a tmpfs such as /dev/shm")
+	policy := fs.String("policy", "default", "name of the redaction policy, recorded in the manifests")
+	jobs := fs.Int("jobs", 1, "documents redacted at the same time; with -max-jobs, the fewest")
+	maxJobs := fs.Int("max-jobs", 0, "scale the documents redacted at the same time between -jobs and this, following the queue depth and system load (0 = always -jobs)")
+	maxLoad := fs.Float64("max-load", 1, "1-minute load average per CPU above which -max-jobs scaling removes a worker")
+	minFreeMem := fs.Float64("min-free-mem", 10, "percentage of available memory below which -max-jobs scaling removes a worker")
+	opts := runOptions{engine: "regex"}
+	fs.StringVar(&opts.password, "password", "", "password of encrypted PDFs (see the main -password flag); PDFs it does not open are quarantined, as the daemon never prompts")
+	fs.Var(&opts.formats, "format", "output format(s), repeatable or comma-separated: "+strings.Join(pii.FormatNames(), ", "))
+	fs.IntVar(&opts.workers, "workers", 1, "filter the pages of multi-page documents concurrently on this many workers (0 = one per CPU)")
+	fs.BoolVar(&opts.strict, "strict", false, "strict policy: reject identifiers failing checksums (Aadhaar)")
+	fs.BoolVar(&opts.timings, "timings", false, "record the time each stage and detector took in the reports")
+	fs.BoolVar(&opts.numbered, "numbered", false, "replace each distinct value with a token numbered per document ([PAN_1], [PAN_2]...) instead of its placeholder")
+	fs.Parse(args)
+
+	if *interval <= 0 {
+		return fmt.Errorf("invalid -interval %v (expected a positive duration)", *interval)
+	}
+	if _, ok := pii.ExtractorByName(*extractor); !ok && *extractor != "auto" {
+		return fmt.Errorf("unknown -extractor %q", *extractor)
+	}
+	switch *pdfMode {
+	case "auto", "text", "raster":
+	default:
+		return fmt.Errorf("unknown -pdf-mode %q (expected auto, text or raster)", *pdfMode)
+	}
+	if opts.workers < 0 {
+		return fmt.Errorf("invalid -workers %d (expected 0 or more)", opts.workers)
+	}
+	if *jobs < 1 {
+		return fmt.Errorf("invalid -jobs %d (expected 1 or more)", *jobs)
+	}
+	if *maxJobs != 0 && *maxJobs < *jobs {
+		return fmt.Errorf("invalid -max-jobs %d (expected 0 or at least -jobs)", *maxJobs)
+	}
+	if *maxLoad <= 0 {
+		return fmt.Errorf("invalid -max-load %v (expected a positive number)", *maxLoad)
+	}
+	if *minFreeMem < 0 || *minFreeMem > 100 {
+		return fmt.Errorf("invalid -min-free-mem %v (expected a percentage)", *minFreeMem)
+	}
+	if err := pii.SetTempRoot(*tmpDir); err != nil {
+		return err
+	}
+	var err error
+	opts.extractor, opts.pdfMode, opts.policy = *extractor, *pdfMode, *policy
+	if opts.gstPolicy, err = pii.ParseGSTPolicy(*gstPolicy); err != nil {
+		return err
+	}
+	if opts.profile, err = pii.LookupProfile(*profileName); err != nil {
+		return err
+	}
+	if opts.document, err = lookupDocument(*documentName); err != nil {
+		return err
+	}
+	if opts.amountWords, err = pii.ParseAmountWordsPolicy(*amountWords); err != nil {
+		return err
+	}
+	if opts.dates, err = pii.ParseDatePolicy(*dates); err != nil {
+		return err
+	}
+	if opts.headerFooters, err = pii.ParseHeaderFooterPolicy(*pageHeaders); err != nil {
+		return err
+	}
+	if opts.matchValues, err = pii.ParseMatchValuePolicy(*matchValues); err != nil {
+		return err
+	}
+	if opts.locale, err = pii.LoadLocale(*lang, *langFile); err != nil {
+		return err
+	}
+	loaded, err := loadPolicy(*configFile, *presetFile, *policyKey, fs)
+	if err != nil {
+		return err
+	}
+	opts.configOpts, opts.stages = loaded.opts, loaded.stages
+	opts.wordLists, opts.glossary = parseWordLists(*wordList), *glossary
+	opts.redact, opts.keep = pii.ParseEntityTypes(*redact), pii.ParseEntityTypes(*keep)
+	if loaded.signature != nil {
+		log.Printf("Policy %s verified: sha256 %s, key %s", loaded.signature.File, loaded.signature.SHA256, loaded.signature.KeyID)
+	}
+	if loaded.preset != nil {
+		opts.locale, opts.words = loaded.preset.Locale(opts.locale), loaded.preset.Words
+	}
+	if opts.data, err = loadPolicyData(*dataDir, loaded); err != nil {
+		return err
+	}
+	if len(opts.formats) == 0 {
+		opts.formats = formatList{"txt"}
+	}
+	// Settings the filter rejects, such as unknown -redact types, would quarantine every PDF.
+	if _, err := newFilter(opts); err != nil {
+		return err
+	}
+
+	seed := pii.NewSeed()
+	opts.rng = pii.NewRunRand(seed)
+	w := &watcher{inbox: *inbox, outbox: *outbox, archive: *archive, quarantine: *quarantine, opts: opts, manifest: *pii.NewManifest(seed), redactedPDF: *redactedPDF, seen: make(map[string]os.FileInfo)}
+	w.manifest.Policy = opts.policy
+	w.manifest.PolicySignature = loaded.signature
+	if loaded.preset != nil {
+		w.manifest.Preset = loaded.preset.Record()
+	}
+	if opts.data != nil {
+		w.manifest.Data = opts.data.Record()
+	}
+	for _, dir := range []string{w.inbox, w.outbox, w.archive, w.quarantine} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create directory: %v", err)
+		}
+	}
+	// Outputs left staged by an interrupted run belong to documents still in the inbox.
+	if err := os.RemoveAll(filepath.Join(w.outbox, watchStagingDir)); err != nil {
+		return fmt.Errorf("failed to clear staging directory: %v", err)
+	}
+
+	fmt.Printf("Watching %s (outbox %s, archive %s, quarantine %s; pipeline %s)\n", w.inbox, w.outbox, w.archive, w.quarantine, opts.stages)
+	scaler := &autoscaler{min: *jobs, max: *maxJobs, maxLoad: *maxLoad, minFreeMem: *minFreeMem}
+	workers := *jobs
+	// pending holds the PDFs queued or being processed, which later scans must not queue again.
+	pending := make(map[string]bool)
+	var queue []string
+	running := 0
+	done := make(chan watchResult)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for scan := true; ; {
+		if scan {
+			ready, err := w.scan(*once)
+			if err != nil {
+				return w.drain(done, running, err)
+			}
+			for _, path := range ready {
+				if !pending[path] {
+					pending[path] = true
+					queue = append(queue, path)
+				}
+			}
+			scan = false
+		}
+		workers = scaler.resize(workers, len(queue), running)
+		for ; running < workers && len(queue) > 0; running++ {
+			path := queue[0]
+			queue = queue[1:]
+			go func() {
+				done <- watchResult{path: path, err: w.process(path)}
+			}()
+		}
+		if *once && running == 0 && len(queue) == 0 {
+			return nil
+		}
+		select {
+		case res := <-done:
+			running--
+			delete(pending, res.path)
+			if res.err != nil {
+				return w.drain(done, running, res.err)
+			}
+			if running == 0 {
+				// Work directories can only be attributed to a document while it runs alone.
+				for _, dir := range pii.ActiveWorkspaces() {
+					logError("Temporary work directory was not removed: %s", dir)
+				}
+				pii.CleanupWorkspaces()
+			}
+		case <-ticker.C:
+			scan = !*once
+		}
+	}
+}
+
+// drain waits for the running documents to finish and returns err, the first failure that
+// stops the daemon.
+func (w *watcher) drain(done <-chan watchResult, running int, err error) error {
+	for ; running > 0; running-- {
+		if res := <-done; res.err != nil {
+			logError("%v", res.err)
+		}
+	}
+	return err
+}
+
+// scan lists the inbox PDFs that are ready: unchanged since the previous scan or, with all,
+// every PDF present. Hidden files are skipped, so uploaders can copy to .name.pdf and rename.
+func (w *watcher) scan(all bool) ([]string, error) {
+	entries, err := os.ReadDir(w.inbox)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inbox: %v", err)
+	}
+	seen := make(map[string]os.FileInfo)
+	var ready []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".pdf") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			// Moved away since the directory was read.
+			continue
+		}
+		path := filepath.Join(w.inbox, name)
+		seen[path] = info
+		if prev, ok := w.seen[path]; all || ok && prev.Size() == info.Size() && prev.ModTime().Equal(info.ModTime()) {
+			ready = append(ready, path)
+			delete(seen, path)
+		}
+	}
+	w.seen = seen
+	sort.Strings(ready)
+	return ready, nil
+}
+
+// process redacts one inbox PDF into the staging directory, then moves the outputs to the
+// outbox and the original to the archive, or the original to the quarantine. Only failures to
+// move files are returned: they would make the daemon pick the same PDF up again.
+func (w *watcher) process(path string) error {
+	name := filepath.Base(path)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	staging := filepath.Join(w.outbox, watchStagingDir, name)
+	if err := os.MkdirAll(staging, 0o700); err != nil {
+		return fmt.Errorf("failed to create staging directory: %v", err)
+	}
+	// The staging directory itself goes once the last document using it is done.
+	defer os.Remove(filepath.Dir(staging))
+	defer os.RemoveAll(staging)
+
+	base := filepath.Join(staging, stem)
+	opts := w.opts
+	if w.redactedPDF {
+		opts.redactedPDF = base + "_redacted.pdf"
+	}
+	doc := pii.ManifestDocument{Input: path, Status: "ok"}
+	result, procErr := processDocument(path, base+"_filtered.txt", base+"_raw.txt", opts)
+	doc.Warnings, doc.Extraction = result.warnings, result.extraction
+
+	w.moveMu.Lock()
+	defer w.moveMu.Unlock()
+	dest := w.archive
+	if procErr != nil {
+		dest = w.quarantine
+		doc.Status, doc.Error = "failed", procErr.Error()
+		logError("Error processing %s: %v", path, procErr)
+	}
+	archived, err := uniquePath(dest, name)
+	if err != nil {
+		return err
+	}
+	archivedStem := strings.TrimSuffix(archived, filepath.Ext(archived))
+	if err := pii.RenameOutput(path, archived); err != nil {
+		return fmt.Errorf("failed to move %s: %v", path, err)
+	}
+	doc.Input = archived
+	if _, err := os.Stat(base + "_raw.txt"); err == nil {
+		doc.RawOutput = archivedStem + "_raw.txt"
+		if err := pii.RenameOutput(base+"_raw.txt", doc.RawOutput); err != nil {
+			return fmt.Errorf("failed to move raw text of %s: %v", name, err)
+		}
+	}
+	if procErr != nil {
+		failure := pii.Failure{Document: name, Error: doc.Error}
+		if pe, ok := procErr.(*pii.PanicError); ok {
+			failure.StackHash = pe.StackHash
+		}
+		if err := pii.SaveFailuresReport([]pii.Failure{failure}, archivedStem+"_failure.txt"); err != nil {
+			return err
+		}
+		fmt.Printf("Quarantined %s\n", archived)
+		return nil
+	}
+
+	// Outputs keep the name the PDF had in the inbox; a name seen before gets the suffix its
+	// archived original got.
+	outStem := filepath.Join(w.outbox, filepath.Base(archivedStem))
+	staged := result.outputs
+	if opts.redactedPDF != "" {
+		staged = append(staged, opts.redactedPDF)
+	}
+	for _, file := range staged {
+		final := outStem + strings.TrimPrefix(file, base)
+		if err := pii.RenameOutput(file, final); err != nil {
+			return fmt.Errorf("failed to move output of %s: %v", name, err)
+		}
+		if file == opts.redactedPDF {
+			doc.RedactedPDF = final
+		} else {
+			doc.Outputs = append(doc.Outputs, final)
+		}
+	}
+	doc.RedactedPDFVerification = result.verification
+	manifest := w.manifest
+	manifest.Documents = []pii.ManifestDocument{doc}
+	if err := pii.SaveManifest(&manifest, outStem+"_manifest.json"); err != nil {
+		return err
+	}
+	fmt.Printf("Archived %s; outputs in %s\n", archived, w.outbox)
+	return nil
+}
+
+// uniquePath returns dir/name, or dir/name-2, dir/name-3... (before the extension) when that
+// file already exists, so that a resubmitted PDF never overwrites an earlier one.
+func uniquePath(dir, name string) (string, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	path := filepath.Join(dir, name)
+	for i := 2; ; i++ {
+		_, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return path, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check %s: %v", path, err)
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", stem, i, ext))
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"pdf-reader/pii"
+)
+
+// englishWords is the default dictionary of the dictionary stage, so that the binary runs
+// without english_words.txt in the working directory.
+//
+//go:embed english_words.txt
+var englishWords string
+
+// builtinWords parses englishWords once per process; the set is shared and never modified.
+var builtinWords = sync.OnceValues(func() (map[string]struct{}, error) {
+	return pii.ReadWordSet(strings.NewReader(englishWords))
+})
+
+// wordListDefault names, in a -wordlist list, the dictionary the run has without the flag:
+// the -preset's, the -data-dir's or the embedded english_words.txt.
+const wordListDefault = "default"
+
+// parseWordLists splits a comma-separated -wordlist value.
+func parseWordLists(value string) []string {
+	var lists []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			lists = append(lists, name)
+		}
+	}
+	return lists
+}
+
+// mergeWordLists returns the union of the word lists named by -wordlist, where
+// wordListDefault stands for fallback (the embedded list when nil). Without lists the
+// dictionary is fallback alone.
+func mergeWordLists(lists []string, fallback map[string]struct{}) (map[string]struct{}, error) {
+	if fallback == nil && (len(lists) == 0 || slices.Contains(lists, wordListDefault)) {
+		words, err := builtinWords()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the embedded english word list: %v", err)
+		}
+		fallback = words
+	}
+	if len(lists) == 0 {
+		return fallback, nil
+	}
+	merged := make(map[string]struct{})
+	for _, name := range lists {
+		if name == wordListDefault {
+			maps.Copy(merged, fallback)
+			continue
+		}
+		words, err := pii.LoadWordSet(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load word list %s: %v", name, err)
+		}
+		maps.Copy(merged, words)
+	}
+	return merged, nil
+}